@@ -0,0 +1,595 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	defaultACMEDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	acmeALPNProto           = "acme-tls/1"
+	acmeRenewBefore         = 30 * 24 * time.Hour
+	acmeCheckInterval       = 12 * time.Hour
+	acmeRetryInterval       = time.Hour
+	acmePollTimeout         = 2 * time.Minute
+	acmeAccountKey          = "acme_account"
+	acmeCertificateKey      = "acme_certificate"
+)
+
+// oidACMEIdentifier is the certificate extension carrying the tls-alpn-01 key authorization (RFC 8737)
+var oidACMEIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// ACMEAccount is the persisted ACME account
+type ACMEAccount struct {
+	KeyPEM string
+	URL    string
+}
+
+// ACMECertificate is the persisted certificate chain and its private key
+type ACMECertificate struct {
+	Domains []string
+	CertPEM string
+	KeyPEM  string
+}
+
+// acmeDirectory lists the endpoints of an ACME server
+type acmeDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+type acmeOrder struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+}
+
+type acmeChallenge struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Status string `json:"status"`
+}
+
+type acmeAuthorization struct {
+	Status     string          `json:"status"`
+	Identifier acmeIdentifier  `json:"identifier"`
+	Challenges []acmeChallenge `json:"challenges"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// acmeProblem is an RFC 7807 error returned by the ACME server
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+func (p *acmeProblem) Error() string {
+	return fmt.Sprintf("acme: %s: %s", p.Type, p.Detail)
+}
+
+// ACMEManager obtains and renews a certificate for the configured domains using the
+// tls-alpn-01 challenge, so only the HTTPS listener needs to be reachable
+type ACMEManager struct {
+	storage      storage.Storage
+	domains      []string
+	email        string
+	directoryURL string
+	client       *http.Client
+
+	directory  acmeDirectory
+	accountKey *ecdsa.PrivateKey
+	accountURL string
+	nonce      string
+
+	certificate *tls.Certificate
+	challenges  map[string]*tls.Certificate
+	mutex       sync.RWMutex
+}
+
+func NewACMEManager(storage storage.Storage, domains []string, email, directoryURL string) *ACMEManager {
+	if directoryURL == "" {
+		directoryURL = defaultACMEDirectoryURL
+	}
+	return &ACMEManager{
+		storage:      storage,
+		domains:      domains,
+		email:        email,
+		directoryURL: directoryURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		challenges:   make(map[string]*tls.Certificate),
+	}
+}
+
+// TLSConfig returns the server TLS settings with certificates served by the manager
+func (m *ACMEManager) tlsConfig() *tls.Config {
+	settings := serverTLSConfig()
+	settings.GetCertificate = m.getCertificate
+	settings.NextProtos = []string{"http/1.1", acmeALPNProto}
+	if config.Current().HTTP2 {
+		settings.NextProtos = append([]string{"h2"}, settings.NextProtos...)
+	}
+	return settings
+}
+
+// GetCertificate answers tls-alpn-01 validation handshakes with the challenge certificate
+// and every other handshake with the current certificate
+func (m *ACMEManager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, proto := range hello.SupportedProtos {
+		if proto == acmeALPNProto {
+			if challenge, exists := m.challenges[strings.ToLower(hello.ServerName)]; exists {
+				return challenge, nil
+			}
+			return nil, fmt.Errorf("no pending ACME challenge for %s", hello.ServerName)
+		}
+	}
+	if m.certificate == nil {
+		return nil, errors.New("certificate not yet issued")
+	}
+	return m.certificate, nil
+}
+
+// Start loads any stored certificate and keeps it renewed until ctx is cancelled. The
+// HTTPS listener must already be accepting, as ordering a certificate starts at once.
+func (m *ACMEManager) Start(ctx context.Context) {
+	if port := config.Current().Port; port != 443 {
+		logging.Warn("ACME validation connects on port 443, which must be forwarded to port", port)
+	}
+	var stored ACMECertificate
+	if err := m.storage.Load(acmeCertificateKey, &stored); err == nil && sameDomains(stored.Domains, m.domains) {
+		if certificate, err := parseCertificate(stored.CertPEM, stored.KeyPEM); err == nil {
+			m.mutex.Lock()
+			m.certificate = certificate
+			m.mutex.Unlock()
+		} else {
+			logging.Error("Error loading stored certificate:", err)
+		}
+	} else if err != nil && err != storage.ErrNotFound {
+		logging.Error("Error loading stored certificate:", err)
+	}
+
+	go func() {
+		for {
+			delay := acmeCheckInterval
+			if m.needsRenewal() {
+				if err := m.obtain(ctx); err != nil {
+					logging.Error("Error obtaining certificate:", err)
+					delay = acmeRetryInterval
+				} else {
+					logging.Info("Certificate issued for", strings.Join(m.domains, ", "))
+				}
+			}
+			if !exchange.SleepContext(ctx, delay) {
+				return
+			}
+		}
+	}()
+}
+
+func (m *ACMEManager) needsRenewal() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.certificate == nil || time.Until(m.certificate.Leaf.NotAfter) < acmeRenewBefore
+}
+
+// Obtain runs a full ACME order for the configured domains and stores the result
+func (m *ACMEManager) obtain(ctx context.Context) error {
+	if err := m.fetchDirectory(ctx); err != nil {
+		return err
+	}
+	if err := m.ensureAccount(ctx); err != nil {
+		return err
+	}
+
+	identifiers := []acmeIdentifier{}
+	for _, domain := range m.domains {
+		identifiers = append(identifiers, acmeIdentifier{Type: "dns", Value: domain})
+	}
+	resp, body, err := m.post(ctx, m.directory.NewOrder, map[string]interface{}{"identifiers": identifiers})
+	if err != nil {
+		return err
+	}
+	orderURL := resp.Header.Get("Location")
+	var order acmeOrder
+	if err := json.Unmarshal(body, &order); err != nil {
+		return err
+	}
+
+	for _, authorizationURL := range order.Authorizations {
+		if err := m.authorize(ctx, authorizationURL); err != nil {
+			return err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.domains[0]},
+		DNSNames: m.domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+	if _, _, err := m.post(ctx, order.Finalize, map[string]string{"csr": base64.RawURLEncoding.EncodeToString(csr)}); err != nil {
+		return err
+	}
+	if err := m.poll(ctx, orderURL, &order, func() string { return order.Status }); err != nil {
+		return err
+	}
+
+	_, chain, err := m.post(ctx, order.Certificate, nil)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	stored := ACMECertificate{
+		Domains: m.domains,
+		CertPEM: string(chain),
+		KeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+	certificate, err := parseCertificate(stored.CertPEM, stored.KeyPEM)
+	if err != nil {
+		return err
+	}
+	if err := m.storage.Save(acmeCertificateKey, stored); err != nil {
+		logging.Error("Error saving certificate:", err)
+	}
+
+	m.mutex.Lock()
+	m.certificate = certificate
+	m.mutex.Unlock()
+	return nil
+}
+
+// Authorize completes the tls-alpn-01 challenge of one authorization
+func (m *ACMEManager) authorize(ctx context.Context, authorizationURL string) error {
+	var authorization acmeAuthorization
+	_, body, err := m.post(ctx, authorizationURL, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &authorization); err != nil {
+		return err
+	}
+	if authorization.Status == "valid" {
+		return nil
+	}
+
+	var challenge *acmeChallenge
+	for i := range authorization.Challenges {
+		if authorization.Challenges[i].Type == "tls-alpn-01" {
+			challenge = &authorization.Challenges[i]
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no tls-alpn-01 challenge offered for %s", authorization.Identifier.Value)
+	}
+
+	domain := strings.ToLower(authorization.Identifier.Value)
+	challengeCert, err := m.challengeCertificate(domain, challenge.Token)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	m.challenges[domain] = challengeCert
+	m.mutex.Unlock()
+	defer func() {
+		m.mutex.Lock()
+		delete(m.challenges, domain)
+		m.mutex.Unlock()
+	}()
+
+	if _, _, err := m.post(ctx, challenge.URL, map[string]interface{}{}); err != nil {
+		return err
+	}
+	return m.poll(ctx, authorizationURL, &authorization, func() string { return authorization.Status })
+}
+
+// ChallengeCertificate builds the self-signed certificate that proves control of domain
+func (m *ACMEManager) challengeCertificate(domain, token string) (*tls.Certificate, error) {
+	digest := sha256.Sum256([]byte(token + "." + jwkThumbprint(&m.accountKey.PublicKey)))
+	extension, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(time.Now().UnixNano()),
+		Subject:         pkix.Name{CommonName: domain},
+		DNSNames:        []string{domain},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: oidACMEIdentifier, Critical: true, Value: extension}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// Poll re-reads an order or authorization until status reports it valid
+func (m *ACMEManager) poll(ctx context.Context, url string, v interface{}, status func() string) error {
+	deadline := time.Now().Add(acmePollTimeout)
+	for {
+		switch status() {
+		case "valid":
+			return nil
+		case "invalid":
+			return fmt.Errorf("acme: %s became invalid", url)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("acme: timed out waiting for %s", url)
+		}
+		if !exchange.SleepContext(ctx, 2*time.Second) {
+			return ctx.Err()
+		}
+		_, body, err := m.post(ctx, url, nil)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *ACMEManager) fetchDirectory(ctx context.Context) error {
+	if m.directory.NewOrder != "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.directoryURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("acme directory returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(&m.directory)
+}
+
+// EnsureAccount loads the stored account or registers a new one
+func (m *ACMEManager) ensureAccount(ctx context.Context) error {
+	if m.accountURL != "" {
+		return nil
+	}
+	var account ACMEAccount
+	err := m.storage.Load(acmeAccountKey, &account)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	if err == nil {
+		block, _ := pem.Decode([]byte(account.KeyPEM))
+		if block == nil {
+			return errors.New("stored ACME account key is not PEM")
+		}
+		if m.accountKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+			return err
+		}
+		m.accountURL = account.URL
+		return nil
+	}
+
+	if m.accountKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return err
+	}
+	request := map[string]interface{}{"termsOfServiceAgreed": true}
+	if m.email != "" {
+		request["contact"] = []string{"mailto:" + m.email}
+	}
+	resp, _, err := m.post(ctx, m.directory.NewAccount, request)
+	if err != nil {
+		return err
+	}
+	m.accountURL = resp.Header.Get("Location")
+
+	keyDER, err := x509.MarshalECPrivateKey(m.accountKey)
+	if err != nil {
+		return err
+	}
+	return m.storage.Save(acmeAccountKey, ACMEAccount{
+		KeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		URL:    m.accountURL,
+	})
+}
+
+// Post sends a JWS-signed request; a nil payload makes it a POST-as-GET. A stale nonce is
+// retried once with the fresh nonce the server returned.
+func (m *ACMEManager) post(ctx context.Context, url string, payload interface{}) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		resp, body, err := m.postOnce(ctx, url, payload)
+		var problem *acmeProblem
+		if errors.As(err, &problem) && problem.Type == "urn:ietf:params:acme:error:badNonce" && attempt == 0 {
+			continue
+		}
+		return resp, body, err
+	}
+}
+
+func (m *ACMEManager) postOnce(ctx context.Context, url string, payload interface{}) (*http.Response, []byte, error) {
+	if m.nonce == "" {
+		if err := m.fetchNonce(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	body, err := m.signJWS(url, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/jose+json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	m.nonce = resp.Header.Get("Replay-Nonce")
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		problem := &acmeProblem{}
+		if json.Unmarshal(data, problem) != nil || problem.Type == "" {
+			problem.Type = resp.Status
+			problem.Detail = string(data)
+		}
+		return resp, data, problem
+	}
+	return resp, data, nil
+}
+
+func (m *ACMEManager) fetchNonce(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, m.directory.NewNonce, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	m.nonce = resp.Header.Get("Replay-Nonce")
+	if m.nonce == "" {
+		return errors.New("acme server returned no nonce")
+	}
+	return nil
+}
+
+// SignJWS wraps payload in a flattened JWS signed with the account key (ES256). The
+// account is identified by its URL once registered and by its public key before that.
+func (m *ACMEManager) signJWS(url string, payload interface{}) ([]byte, error) {
+	protected := map[string]interface{}{"alg": "ES256", "nonce": m.nonce, "url": url}
+	if m.accountURL != "" {
+		protected["kid"] = m.accountURL
+	} else {
+		protected["jwk"] = jwk(&m.accountKey.PublicKey)
+	}
+	m.nonce = ""
+
+	protectedJSON, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	encodedPayload := ""
+	if payload != nil {
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		encodedPayload = base64.RawURLEncoding.EncodeToString(payloadJSON)
+	}
+	encodedProtected := base64.RawURLEncoding.EncodeToString(protectedJSON)
+
+	digest := sha256.Sum256([]byte(encodedProtected + "." + encodedPayload))
+	r, s, err := ecdsa.Sign(rand.Reader, m.accountKey, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return json.Marshal(map[string]string{
+		"protected": encodedProtected,
+		"payload":   encodedPayload,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+}
+
+// Jwk returns the JSON Web Key of a P-256 public key with members in thumbprint order
+func jwk(key *ecdsa.PublicKey) map[string]string {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   base64.RawURLEncoding.EncodeToString(x),
+		"y":   base64.RawURLEncoding.EncodeToString(y),
+	}
+}
+
+// JwkThumbprint computes the RFC 7638 thumbprint used in key authorizations
+func jwkThumbprint(key *ecdsa.PublicKey) string {
+	// encoding/json sorts map keys, which yields the required canonical form
+	canonical, _ := json.Marshal(jwk(key))
+	digest := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// ParseCertificate builds a tls.Certificate with its leaf parsed so expiry can be checked
+func parseCertificate(certPEM, keyPEM string) (*tls.Certificate, error) {
+	certificate, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+	if certificate.Leaf == nil {
+		if certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &certificate, nil
+}
+
+func sameDomains(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
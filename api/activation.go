@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// systemdFirstFD is the first file descriptor systemd passes to a socket-activated service
+const systemdFirstFD = 3
+
+// ActivatedListeners returns the sockets systemd passed to this process through LISTEN_FDS
+// and LISTEN_PID, so a socket unit can bind privileged ports for a service that runs
+// unprivileged. The variables are cleared so processes started later do not claim them.
+func activatedListeners() []net.Listener {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if pid != os.Getpid() || count <= 0 {
+		return nil
+	}
+	listeners := []net.Listener{}
+	for fd := systemdFirstFD; fd < systemdFirstFD+count; fd++ {
+		file := os.NewFile(uintptr(fd), "systemd-socket")
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			logging.Error("Error accepting socket from systemd:", err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
+
+// SameListenAddress reports whether a socket bound to bound can stand in for a listener
+// configured on address. TCP sockets match on port, since the unit decides which
+// interfaces to bind; unix sockets match on path.
+func sameListenAddress(bound net.Addr, address string) bool {
+	if bound.Network() == "unix" {
+		return bound.String() == address
+	}
+	_, boundPort, err := net.SplitHostPort(bound.String())
+	if err != nil {
+		return false
+	}
+	_, port, err := net.SplitHostPort(address)
+	return err == nil && port == boundPort
+}
+
+// TakeActivatedLocked claims the systemd socket matching address. The caller holds
+// inheritedMutex.
+func takeActivatedLocked(address string) (net.Listener, bool) {
+	for i, listener := range activated {
+		if sameListenAddress(listener.Addr(), address) {
+			activated = append(activated[:i], activated[i+1:]...)
+			return listener, true
+		}
+	}
+	return nil, false
+}
+
+// UnusedActivatedListeners closes the systemd sockets no listener was configured for
+func UnusedActivatedListeners() {
+	inheritedMutex.Lock()
+	defer inheritedMutex.Unlock()
+	for _, listener := range activated {
+		logging.Warn("Closing systemd socket", listener.Addr().String(), "that matches no configured listener")
+		listener.Close()
+	}
+	activated = nil
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// AdminRoutes registers the operator endpoints
+func (s *Server) adminRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/config", s.requireAdmin(s.handleAdminConfig))
+	mux.HandleFunc("/admin/stats", s.requireAdmin(s.handleAdminStats))
+	mux.HandleFunc("/admin/users", s.requireAdmin(s.handleAdminUsers))
+
+	// Profiling is registered here rather than through the net/http/pprof side effects on
+	// http.DefaultServeMux, which is never served
+	mux.HandleFunc("/debug/pprof/", s.requireAdmin(requireDebug(pprof.Index)))
+	mux.HandleFunc("/debug/pprof/cmdline", s.requireAdmin(requireDebug(pprof.Cmdline)))
+	mux.HandleFunc("/debug/pprof/profile", s.requireAdmin(requireDebug(pprof.Profile)))
+	mux.HandleFunc("/debug/pprof/symbol", s.requireAdmin(requireDebug(pprof.Symbol)))
+	mux.HandleFunc("/debug/pprof/trace", s.requireAdmin(requireDebug(pprof.Trace)))
+	mux.HandleFunc("/debug/vars", s.requireAdmin(requireDebug(expvar.Handler().ServeHTTP)))
+	return mux
+}
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+}
+
+// RequireDebug hides debug endpoints unless DebugEndpoints is enabled, checked per
+// request so it can be switched on at runtime
+func requireDebug(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !config.Current().DebugEndpoints {
+			writeProblem(w, r, "Unknown endpoint", http.StatusNotFound)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// StartAdmin serves the operator endpoints on AdminPort. When AdminClientCAFile is set only
+// clients presenting a certificate issued by that CA complete the handshake; if the CA
+// cannot be loaded the listener is not started rather than started unprotected.
+func (s *Server) startAdmin(cfg config.Config, handler http.Handler) {
+	server := newHTTPServer(fmt.Sprintf("%s:%d", cfg.Host, cfg.AdminPort), handler)
+
+	certFile, keyFile := cfg.AdminCertFile, cfg.AdminKeyFile
+	if certFile == "" {
+		certFile, keyFile = cfg.CertFile, cfg.KeyFile
+	}
+	useTLS := certFile != "" || s.certificates != nil
+	if useTLS {
+		server.TLSConfig = serverTLSConfig()
+		if certFile == "" {
+			server.TLSConfig.GetCertificate = s.certificates.getCertificate
+		}
+	}
+	if cfg.AdminClientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(cfg.AdminClientCAFile)
+		if err != nil {
+			logging.Error("Error loading admin client CA:", err)
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			logging.Error("Error loading admin client CA: no certificates in", cfg.AdminClientCAFile)
+			return
+		}
+		server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		server.TLSConfig.ClientCAs = pool
+	}
+	fmt.Println("Admin server starting on", server.Addr)
+	s.serve(server, "Admin server", certFile, keyFile)
+}
+
+// RequireAdmin only lets through clients holding a verified admin certificate, the
+// configured bearer token or, with OIDCIssuer set, a token from that issuer whose claims
+// authorize it. Without any of these, admin endpoints are disabled entirely.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			next(w, r)
+			return
+		}
+		cfg := config.Current()
+		token := cfg.AdminToken
+		if token == "" && cfg.OIDCIssuer == "" {
+			writeProblem(w, r, "Admin endpoints are disabled", http.StatusForbidden)
+			return
+		}
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cfg.OIDCIssuer != "" && looksLikeJWT(provided) {
+			claims, err := s.oidc.verify(r.Context(), provided, cfg)
+			if err == errOIDCUnauthorized {
+				subject, _ := claims["sub"].(string)
+				logging.Info("Refused admin access to OIDC subject", subject)
+				writeProblem(w, r, "Token does not grant admin access", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				logging.Debug("Rejected OIDC token:", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin", error="invalid_token"`)
+				writeProblem(w, r, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+			return
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeProblem(w, r, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RedactedConfig hides secrets from the effective config before it is returned
+func redactedConfig(cfg config.Config) config.Config {
+	if cfg.AdminToken != "" {
+		cfg.AdminToken = "[redacted]"
+	}
+	if cfg.BasicAuthPasswordHash != "" {
+		cfg.BasicAuthPasswordHash = "[redacted]"
+	}
+	if len(cfg.APIKeys) > 0 {
+		keys := make(map[string]string, len(cfg.APIKeys))
+		for name := range cfg.APIKeys {
+			keys[name] = "[redacted]"
+		}
+		cfg.APIKeys = keys
+	}
+	// Upstream headers often carry provider API keys
+	cfg.UpstreamHeaders = redactedHeaders(cfg.UpstreamHeaders)
+	if len(cfg.UpstreamHostHeaders) > 0 {
+		hosts := make(map[string]map[string]string, len(cfg.UpstreamHostHeaders))
+		for host, headers := range cfg.UpstreamHostHeaders {
+			hosts[host] = redactedHeaders(headers)
+		}
+		cfg.UpstreamHostHeaders = hosts
+	}
+	// Broker and proxy URLs may carry credentials
+	cfg.UpstreamProxy = redactedURL(cfg.UpstreamProxy)
+	cfg.NATSURL = redactedURL(cfg.NATSURL)
+	cfg.MQTTURL = redactedURL(cfg.MQTTURL)
+	cfg.RedisURL = redactedURL(cfg.RedisURL)
+	return cfg
+}
+
+// RedactedHeaders hides every header value but the User-Agent
+func redactedHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if http.CanonicalHeaderKey(name) != "User-Agent" {
+			value = "[redacted]"
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// RedactedURL hides the user information of a URL
+func redactedURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw
+	}
+	parsed.User = url.User("[redacted]")
+	return parsed.String()
+}
+
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redactedConfig(config.Current()))
+
+	case http.MethodPatch:
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeProblem(w, r, "Failed to parse config", http.StatusBadRequest)
+			return
+		}
+		cfg, err := config.Update(patch)
+		if err != nil {
+			writeProblem(w, r, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if s.applyConfig != nil {
+			s.applyConfig(cfg)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redactedConfig(cfg))
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleAggregates serves a market's candles from the aggregation engine at one of the
+// AggregationTimeframes, the latest of them partial
+func (s *Server) handleAggregates(w http.ResponseWriter, r *http.Request) {
+	market := symbolParam(r)
+	if _, exists := s.tracker.MarketInfo(market); !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	query := r.URL.Query()
+	timeframe := query.Get("timeframe")
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	candles, ok := s.tracker.Aggregates().Candles(market, timeframe, limit, time.Now())
+	if !ok {
+		writeProblem(w, r, "Unsupported 'timeframe' parameter", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"market":     market,
+		"timeframe":  strings.ToLower(timeframe),
+		"candles":    candles,
+		"late_ticks": s.tracker.Aggregates().LateTicks(market),
+	})
+}
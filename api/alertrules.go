@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleAlertRules manages the alert rules of the user a request acts as
+func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	user := requestUser(r)
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]tracker.AlertRule{"alerts": s.alerts.List(user)})
+
+	case r.Method == http.MethodGet:
+		rule, exists := s.alerts.Get(user, id)
+		if !exists {
+			writeProblem(w, r, "Alert rule not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rule)
+
+	case r.Method == http.MethodPost && id == "":
+		var rule tracker.AlertRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeProblem(w, r, "Failed to parse alert rule", http.StatusBadRequest)
+			return
+		}
+		if err := tracker.NormalizeAlertRule(&rule); err != nil {
+			writeProblem(w, r, "Invalid alert rule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		rule.Owner = user
+		if tracker.FuturesConditions[rule.Condition] {
+			rule.Symbol = s.tracker.ResolveFuturesPair(rule.Symbol)
+			if _, exists := s.tracker.MarketView().FuturesPrices[rule.Symbol]; !exists {
+				writeError(w, r, tracker.ErrSymbolNotFound)
+				return
+			}
+		} else {
+			rule.Symbol = s.tracker.ResolveSymbol(rule.Symbol)
+			if _, exists := s.tracker.MarketInfo(rule.Symbol); !exists {
+				writeError(w, r, tracker.ErrSymbolNotFound)
+				return
+			}
+		}
+		rule, err := s.alerts.Create(rule)
+		if err != nil {
+			logging.Error("Error saving alert rules:", err)
+			writeProblem(w, r, "Failed to save alert rule", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+
+	case r.Method == http.MethodDelete && id != "":
+		removed, err := s.alerts.Remove(user, id)
+		if err != nil {
+			logging.Error("Error saving alert rules:", err)
+			writeProblem(w, r, "Failed to delete alert rule", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			writeProblem(w, r, "Alert rule not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleAlertHistory lists when a rule triggered, newest first, and what became of each
+// notification
+func (s *Server) handleAlertHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeProblem(w, r, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	id := r.PathValue("id")
+	triggers, exists := s.alerts.Triggers(requestUser(r), id, limit)
+	if !exists {
+		writeProblem(w, r, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"rule_id": id, "triggers": triggers})
+}
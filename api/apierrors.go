@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// WriteError replies with a coded error as an application/problem+json response
+func writeError(w http.ResponseWriter, r *http.Request, err *tracker.APIError) {
+	if err.RetryAfter > 0 {
+		// Retry-After is whole seconds, so round up rather than invite an early retry
+		w.Header().Set("Retry-After", strconv.Itoa(int((err.RetryAfter+time.Second-1)/time.Second)))
+	}
+	writeCodedProblem(w, r, err.Code, err.Detail, err.Status())
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleArbitrage(w http.ResponseWriter, r *http.Request) {
+	threshold := tracker.ArbitrageThreshold()
+	if raw := r.URL.Query().Get("threshold"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			writeProblem(w, r, "Invalid 'threshold' parameter", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+
+	opportunities, scannedAt := s.arbitrage.Above(threshold)
+	if !tracker.RawValues(r) {
+		precision := s.tracker.Precision()
+		for i := range opportunities {
+			opportunity := &opportunities[i]
+			opportunity.ImpliedPrice = precision.PriceIn(opportunity.Asset, opportunity.Quote, opportunity.ImpliedPrice)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threshold_percent": threshold,
+		"fee_rate":          tracker.ArbitrageFeeRate(),
+		"scanned_at":        scannedAt.UnixNano() / int64(time.Millisecond),
+		"opportunities":     opportunities,
+	})
+}
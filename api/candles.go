@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleCandles serves a market's OHLCV candles at any interval from 1m to 1w, optionally
+// limited to a time range in epoch milliseconds; limit defaults to tracker.DefaultCandleLimit. A type
+// of heikin_ashi or renko, with a brick size, derives those from the candles instead.
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	format, ok := responseFormat(w, r)
+	if !ok {
+		return
+	}
+	market := symbolParam(r)
+	if _, exists := s.tracker.MarketInfo(market); !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	duration, _ := tracker.ParseCandleInterval(interval)
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		from = time.UnixMilli(ms)
+	}
+	if value := query.Get("to"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		to = time.UnixMilli(ms)
+	}
+	limit := tracker.DefaultCandleLimit
+	if value := query.Get("limit"); value != "" {
+		limit, _ = strconv.Atoi(value)
+	}
+	candleType := strings.ToLower(query.Get("type"))
+	brick, _ := strconv.ParseFloat(query.Get("brick"), 64)
+	if candleType == "renko" && brick <= 0 {
+		writeProblem(w, r, "Renko candles need a positive 'brick' size", http.StatusBadRequest)
+		return
+	}
+
+	candles, failure := s.tracker.MarketCandles(r.Context(), market, duration, from, to, limit)
+	if failure != nil {
+		writeError(w, r, failure)
+		return
+	}
+	filename := market + "-" + interval
+	switch candleType {
+	case "heikin_ashi":
+		candles, filename = tracker.HeikinAshiCandles(candles), filename+"-heikin-ashi"
+	case "renko":
+		if minimum := tracker.MinRenkoBrick(candles); brick < minimum {
+			writeProblem(w, r, "'brick' must be at least "+FormatFloat(minimum)+", a millionth of the latest close", http.StatusBadRequest)
+			return
+		}
+		candles, filename = tracker.RenkoBricks(candles, brick), filename+"-renko"
+	}
+	if format == "csv" {
+		serveCSV(w, filename+".csv", marketCandleCSVHeader, marketCandleCSVRows(candles))
+		return
+	}
+	writeJSON(w, candles)
+}
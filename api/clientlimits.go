@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// Client tiers, the keys of ClientRateLimits
+const (
+	clientTierAnonymous = "anonymous"
+	clientTierKey       = "key"
+	clientTierAdmin     = "admin"
+)
+
+var clientTiers = []string{clientTierAnonymous, clientTierKey, clientTierAdmin}
+
+// clientBucketPruneInterval is how often buckets of clients gone quiet are dropped
+const clientBucketPruneInterval = time.Minute
+
+// ClientLimiter holds a token bucket for each client the API has seen recently: one per
+// address for anonymous clients, one per named API key and one shared by admins
+type ClientLimiter struct {
+	buckets   map[string]*exchange.TokenBucket
+	lastPrune time.Time
+	mutex     sync.Mutex
+}
+
+func newClientLimiter() *ClientLimiter {
+	return &ClientLimiter{buckets: make(map[string]*exchange.TokenBucket), lastPrune: time.Now()}
+}
+
+// Take takes a token from a client's bucket, creating it on first use
+func (l *ClientLimiter) take(client string, limit config.RateLimit) (bool, exchange.BucketState) {
+	l.mutex.Lock()
+	now := time.Now()
+	if now.Sub(l.lastPrune) >= clientBucketPruneInterval {
+		l.pruneLocked(now)
+	}
+	bucket, exists := l.buckets[client]
+	if !exists {
+		bucket = exchange.NewTokenBucket(limit)
+		l.buckets[client] = bucket
+	}
+	l.mutex.Unlock()
+	return bucket.Take(limit)
+}
+
+// PruneLocked drops the buckets that have refilled, which a returning client would get back anyway
+func (l *ClientLimiter) pruneLocked(now time.Time) {
+	for client, bucket := range l.buckets {
+		if bucket.Idle(now) {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastPrune = now
+}
+
+// PresentedKey returns the API key a request carries in X-API-Key or as a bearer token
+func presentedKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// keyVerifiedKey marks a request whose API key, admin token or OIDC token limitClients
+// resolved to a key or admin client
+type keyVerifiedKey struct{}
+
+// KeyVerified reports whether limitClients accepted the key a request presented as the
+// credential of a key or admin client
+func keyVerified(r *http.Request) bool {
+	verified, _ := r.Context().Value(keyVerifiedKey{}).(bool)
+	return verified
+}
+
+// ClientIdentity finds the tier and bucket of a request. Holders of AdminToken, an admin
+// certificate or an authorized OIDC token share the admin bucket, each named API key has
+// its own and anyone else is limited by address. ok is false for a key that is not
+// configured or a token that fails verification.
+func (s *Server) clientIdentity(r *http.Request, cfg config.Config) (tier, client string, ok bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return clientTierAdmin, clientTierAdmin, true
+	}
+	key := presentedKey(r)
+	if key == "" {
+		return clientTierAnonymous, clientAddr(r, cfg.TrustedProxies).String(), true
+	}
+	if cfg.OIDCIssuer != "" && looksLikeJWT(key) {
+		_, err := s.oidc.verify(r.Context(), key, cfg)
+		if err == errOIDCUnauthorized {
+			// A valid token without admin rights is an ordinary client; requireAdmin refuses it
+			return clientTierAnonymous, clientAddr(r, cfg.TrustedProxies).String(), true
+		}
+		return clientTierAdmin, clientTierAdmin, err == nil
+	}
+	if cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg.AdminToken)) == 1 {
+		return clientTierAdmin, clientTierAdmin, true
+	}
+	// Every key is compared so the time taken does not reveal which one nearly matched
+	name := ""
+	for candidate, candidateKey := range cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidateKey)) == 1 {
+			name = candidate
+		}
+	}
+	if name == "" {
+		return "", "", false
+	}
+	return clientTierKey, clientTierKey + ":" + name, true
+}
+
+// LimitClients applies the ClientRateLimits tier of each request, reporting the state of
+// its bucket in X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset, the seconds
+// until the bucket is full again. Requests with an API key act as its user. Health checks
+// are never limited.
+func (s *Server) limitClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg := config.Current()
+		tier, client, ok := s.clientIdentity(r, cfg)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeProblem(w, r, "Unknown API key", http.StatusUnauthorized)
+			return
+		}
+		if tier != clientTierAnonymous && presentedKey(r) != "" {
+			// Only a key that resolved stands in for Basic auth; a token without admin rights does not
+			r = r.WithContext(context.WithValue(r.Context(), keyVerifiedKey{}, true))
+		}
+		if tier == clientTierKey {
+			// Each named key is a user owning its own resources
+			r = withUser(r, client)
+		}
+		limit, exists := cfg.ClientRateLimits[tier]
+		if !exists || limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		taken, state := s.clients.take(client, limit)
+		header := w.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(state.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(state.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(int((state.UntilFull+time.Second-1)/time.Second)))
+		if !taken {
+			writeError(w, r, &tracker.APIError{Code: tracker.CodeRateLimited, Detail: "Too many requests for the " + tier + " tier", RetryAfter: state.UntilNext})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
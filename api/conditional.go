@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// bootID tells this process's ETags apart from an earlier one's. View generations restart
+// at 1 with every process, so without it a client holding an ETag from before a restart
+// would be told unrelated data is not modified.
+var bootID = newBootID()
+
+func newBootID() string {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+// NotModified sets Last-Modified and reports whether the request's If-Modified-Since is no
+// older than modified, in which case it has written 304 Not Modified. An If-None-Match
+// header takes precedence, as RFC 9110 requires, so If-Modified-Since is then ignored.
+func notModified(w http.ResponseWriter, r *http.Request, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	if r.Method != http.MethodGet && r.Method != http.MethodHead || r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	// HTTP dates only have whole seconds
+	if err != nil || modified.Truncate(time.Second).After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ETagMatches reports whether a request's If-None-Match names etag, by the weak
+// comparison RFC 9110 uses for it, so W/"1" matches "1"
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
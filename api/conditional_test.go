@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotETagMatches(t *testing.T) {
+	etag := snapshotETag(57)
+	if !strings.HasPrefix(etag, `W/"`+bootID+"-") {
+		t.Fatalf("snapshotETag(57) = %s, want a weak ETag carrying the boot ID", etag)
+	}
+	strong := strings.TrimPrefix(etag, "W/")
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{etag, true},
+		{strong, true},
+		{`"other", ` + etag, true},
+		{"*", true},
+		// The same generation of an earlier process
+		{`W/"00000000-57"`, false},
+		{snapshotETag(58), false},
+		{"", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/v1/snapshot", nil)
+		if test.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		if got := etagMatches(r, etag); got != test.want {
+			t.Errorf("If-None-Match %s matches = %v, want %v", test.ifNoneMatch, got, test.want)
+		}
+	}
+}
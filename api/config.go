@@ -0,0 +1,48 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+func init() {
+	config.RegisterValidator(validateConfig)
+}
+
+// validateConfig checks the settings of the unix socket listener, timestamp formatting,
+// Basic auth and client rate limits
+func validateConfig(c config.Config, check func(ok bool, format string, args ...interface{})) {
+	if c.UnixSocket != "" {
+		_, err := parseSocketMode(c.UnixSocketMode)
+		check(err == nil, "UnixSocketMode: %v", err)
+	}
+	if c.UnixSocketGroup != "" {
+		_, err := lookupGroupID(c.UnixSocketGroup)
+		check(err == nil, "UnixSocketGroup: %v", err)
+	}
+	validFormat := c.TimestampFormat == ""
+	for _, format := range timestampFormats {
+		validFormat = validFormat || strings.EqualFold(c.TimestampFormat, format)
+	}
+	check(validFormat, "TimestampFormat must be one of %s, got %q", strings.Join(timestampFormats, ", "), c.TimestampFormat)
+	if c.BasicAuthPasswordHash != "" {
+		_, _, _, err := parseBcryptHash(c.BasicAuthPasswordHash)
+		check(err == nil, "BasicAuthPasswordHash must be a bcrypt hash: %v", err)
+	}
+	tiers := make([]string, 0, len(c.ClientRateLimits))
+	for tier := range c.ClientRateLimits {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+	for _, tier := range tiers {
+		known := false
+		for _, name := range clientTiers {
+			known = known || tier == name
+		}
+		check(known, "ClientRateLimits tier %q must be one of %s", tier, strings.Join(clientTiers, ", "))
+		limit := c.ClientRateLimits[tier]
+		check(limit.Rate >= 0 && limit.Burst >= 0, "ClientRateLimits[%s] must not be negative", tier)
+	}
+}
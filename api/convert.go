@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from := query.Get("from")
+	to := query.Get("to")
+	if from == "" || to == "" {
+		writeProblem(w, r, "Missing 'from' or 'to' parameter", http.StatusBadRequest)
+		return
+	}
+
+	amount := exchange.DecimalFromInt(1)
+	if raw := query.Get("amount"); raw != "" {
+		parsed, ok := exchange.ParseDecimal(raw)
+		if !ok || parsed.Sign() < 0 {
+			writeProblem(w, r, "Invalid 'amount' parameter", http.StatusBadRequest)
+			return
+		}
+		amount = parsed
+	}
+
+	result, err := s.tracker.ConvertCurrency(from, to, amount)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !tracker.RawValues(r) {
+		result = result.Rounded(s.tracker.Precision())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
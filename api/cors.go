@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+var (
+	// corsExposedHeaders are the response headers browsers let scripts read beyond the simple ones
+	corsExposedHeaders = []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+)
+
+// CORSOriginListed matches origin against the allowed list other than "*", supporting
+// single-label wildcards such as https://*.example.com
+func corsOriginListed(origin string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			continue
+		}
+		if strings.EqualFold(pattern, origin) {
+			return true
+		}
+		if prefix, suffix, found := strings.Cut(pattern, "*"); found {
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+				!strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EnableCORS applies the configured CORS policy and answers preflight requests. The policy
+// is read per request so runtime config changes apply immediately.
+func enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Current()
+		wildcard := false
+		for _, pattern := range cfg.CORSAllowedOrigins {
+			wildcard = wildcard || pattern == "*"
+		}
+		origin := r.Header.Get("Origin")
+		listed := origin != "" && corsOriginListed(origin, cfg.CORSAllowedOrigins)
+		allowed := listed || origin != "" && wildcard
+		if origin == "" {
+			allowed = len(cfg.CORSAllowedOrigins) > 0
+		}
+
+		if allowed {
+			// Only origins listed by name may send credentials; "*" never lets a page read
+			// another user's credentialed responses
+			if listed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+				if cfg.CORSAllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			} else if wildcard {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				if len(cfg.CORSAllowedOrigins) > 1 {
+					w.Header().Add("Vary", "Origin")
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.CORSAllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.CORSAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+
+		if r.Method == "OPTIONS" {
+			if !allowed {
+				writeProblem(w, r, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			if cfg.CORSMaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cfg.CORSMaxAge))
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
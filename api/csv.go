@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func FormatFloat(value float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.8f", value), "0"), ".")
+}
+
+var (
+	TickerCSVHeader  = []string{"market", "last_price", "change_24_hour", "high", "low", "volume", "bid", "ask", "timestamp", "last_updated", "stale"}
+	HistoryCSVHeader = []string{"market", "timestamp", "last_price", "bid", "ask", "high", "low", "volume"}
+	candleCSVHeader  = []string{"market", "timestamp", "open", "high", "low", "close", "points"}
+	// marketCandleCSVHeader is the header of the exchange's candles, which carry volume
+	marketCandleCSVHeader = []string{"market", "timestamp", "open", "high", "low", "close", "volume"}
+)
+
+// WriteCSV writes a header row and rows; encoding/csv quotes any field that needs it
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// RawPriceText returns a price upstream sent as a JSON number or string as plain text
+func rawPriceText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+func TickerCSVRows(tickers []tracker.TickerView) [][]string {
+	rows := make([][]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		rows = append(rows, []string{
+			ticker.Market, ticker.LastPrice, ticker.Change24Hour, ticker.High, ticker.Low, ticker.Volume,
+			rawPriceText(ticker.Bid), rawPriceText(ticker.Ask),
+			strconv.FormatInt(ticker.Timestamp, 10), strconv.FormatInt(ticker.LastUpdated, 10), strconv.FormatBool(ticker.Stale),
+		})
+	}
+	return rows
+}
+
+func HistoryCSVRows(points []tracker.HistoryPoint) [][]string {
+	rows := make([][]string, 0, len(points))
+	for _, point := range points {
+		rows = append(rows, []string{
+			point.Market, strconv.FormatInt(point.Timestamp, 10),
+			FormatFloat(point.LastPrice), FormatFloat(point.Bid), FormatFloat(point.Ask),
+			FormatFloat(point.High), FormatFloat(point.Low), FormatFloat(point.Volume),
+		})
+	}
+	return rows
+}
+
+func marketCandleCSVRows(candles []tracker.MarketCandle) [][]string {
+	rows := make([][]string, 0, len(candles))
+	for _, candle := range candles {
+		rows = append(rows, []string{
+			candle.Market, strconv.FormatInt(candle.Timestamp, 10),
+			FormatFloat(candle.Open), FormatFloat(candle.High), FormatFloat(candle.Low), FormatFloat(candle.Close),
+			FormatFloat(candle.Volume),
+		})
+	}
+	return rows
+}
+
+func candleCSVRows(candles []tracker.Candle) [][]string {
+	rows := make([][]string, 0, len(candles))
+	for _, candle := range candles {
+		rows = append(rows, []string{
+			candle.Market, strconv.FormatInt(candle.Timestamp, 10),
+			FormatFloat(candle.Open), FormatFloat(candle.High), FormatFloat(candle.Low), FormatFloat(candle.Close),
+			strconv.Itoa(candle.Points),
+		})
+	}
+	return rows
+}
+
+// ServeCSV sends rows as a CSV download named filename
+func serveCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if err := WriteCSV(w, header, rows); err != nil {
+		logging.Debug("Error writing CSV response:", err)
+	}
+}
+
+// ResponseFormat reads the ?format parameter, which selects json (the default) or csv
+func responseFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json":
+		return "json", true
+	case "csv":
+		return "csv", true
+	}
+	writeProblem(w, r, "Unsupported 'format' parameter", http.StatusBadRequest)
+	return "", false
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleReady reports whether the tracker has market data and fresh tickers to serve
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	markets := len(s.tracker.MarketView().Markets)
+	tickers := tracker.FreshnessOf(s.tracker.TickersUpdated())
+
+	ready := markets > 0 && !(tickers.Stale && config.Current().StaleFailsReadiness)
+	status := "ready"
+	switch {
+	case markets == 0:
+		status = "loading"
+	case tickers.Stale:
+		status = "stale"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":   ready,
+		"status":  status,
+		"markets": markets,
+		"tickers": tickers,
+	})
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleFuturesFunding serves /futures/funding: every perpetual's current funding rate, or
+// with a symbol one contract's current rate and its history, optionally bounded by from
+// and to in epoch milliseconds and limited to the latest limit points
+func (s *Server) handleFuturesFunding(w http.ResponseWriter, r *http.Request) {
+	if !tracker.FuturesEnabled() {
+		writeProblem(w, r, "Futures data is disabled", http.StatusNotFound)
+		return
+	}
+	view := s.tracker.MarketView()
+	query := r.URL.Query()
+	symbol := query.Get("symbol")
+	if symbol == "" {
+		if notModified(w, r, view.FuturesPricesModified) {
+			return
+		}
+		rates := []map[string]interface{}{}
+		for pair, price := range view.FuturesPrices {
+			rates = append(rates, map[string]interface{}{"pair": pair, "funding_rate": price.FundingRate})
+		}
+		sort.Slice(rates, func(i, j int) bool { return rates[i]["pair"].(string) < rates[j]["pair"].(string) })
+		writeJSON(w, map[string]interface{}{
+			"rates":     rates,
+			"freshness": tracker.FreshnessOf(view.FuturesPricesModified),
+		})
+		return
+	}
+
+	pair := s.tracker.ResolveFuturesPair(symbol)
+	price, exists := view.FuturesPrices[pair]
+	if !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		from = time.UnixMilli(ms)
+	}
+	if value := query.Get("to"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		to = time.UnixMilli(ms)
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if notModified(w, r, view.FuturesPricesModified) {
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"pair":         pair,
+		"funding_rate": price.FundingRate,
+		"mark_price":   price.MarkPrice,
+		"freshness":    tracker.FreshnessOf(view.FuturesPricesModified),
+		"history":      s.tracker.Funding().Query(pair, from, to, limit),
+	})
+}
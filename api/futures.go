@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleFuturesInstruments serves /futures/instruments, the active contracts and their
+// margin currencies, and /futures/instruments/{pair}, one contract's specification
+func (s *Server) handleFuturesInstruments(w http.ResponseWriter, r *http.Request) {
+	if !tracker.FuturesEnabled() {
+		writeProblem(w, r, "Futures data is disabled", http.StatusNotFound)
+		return
+	}
+	if pair := strings.ToUpper(r.PathValue("pair")); pair != "" {
+		instrument, failure := s.tracker.FuturesInstrument(r.Context(), pair)
+		if failure != nil {
+			writeError(w, r, failure)
+			return
+		}
+		writeJSON(w, instrument)
+		return
+	}
+
+	view := s.tracker.MarketView()
+	if notModified(w, r, view.FuturesInstrumentsModified) {
+		return
+	}
+	type listedInstrument struct {
+		Pair                    string `json:"pair"`
+		MarginCurrencyShortName string `json:"margin_currency_short_name"`
+	}
+	instruments := []listedInstrument{}
+	for pair, margin := range view.FuturesInstruments {
+		instruments = append(instruments, listedInstrument{Pair: pair, MarginCurrencyShortName: margin})
+	}
+	sort.Slice(instruments, func(i, j int) bool { return instruments[i].Pair < instruments[j].Pair })
+	writeJSON(w, map[string]interface{}{
+		"instruments": instruments,
+		"freshness":   tracker.DatasetFreshness("futures_instruments", view.FuturesInstrumentsModified),
+	})
+}
+
+// HandleFuturesPrices serves /futures/prices, every contract's last, mark and index price,
+// and /futures/prices/{pair}, one contract's
+func (s *Server) handleFuturesPrices(w http.ResponseWriter, r *http.Request) {
+	if !tracker.FuturesEnabled() {
+		writeProblem(w, r, "Futures data is disabled", http.StatusNotFound)
+		return
+	}
+	view := s.tracker.MarketView()
+	freshness := tracker.FreshnessOf(view.FuturesPricesModified)
+	if pair := strings.ToUpper(r.PathValue("pair")); pair != "" {
+		price, exists := view.FuturesPrices[pair]
+		if !exists {
+			writeError(w, r, tracker.ErrSymbolNotFound)
+			return
+		}
+		if notModified(w, r, view.FuturesPricesModified) {
+			return
+		}
+		writeJSON(w, tracker.FuturesPriceView{FuturesPrice: price, Freshness: freshness})
+		return
+	}
+
+	s.responses.serve(w, r, view, "futures_prices", view.FuturesPricesModified, func() interface{} {
+		prices := make([]tracker.FuturesPriceView, 0, len(view.FuturesPrices))
+		for _, price := range view.FuturesPrices {
+			prices = append(prices, tracker.FuturesPriceView{FuturesPrice: price, Freshness: freshness})
+		}
+		sort.Slice(prices, func(i, j int) bool { return prices[i].Pair < prices[j].Pair })
+		return prices
+	})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleHistoryGaps reports the intervals with no stored candle in a market's history,
+// over the last day unless from and to in epoch milliseconds say otherwise
+func (s *Server) handleHistoryGaps(w http.ResponseWriter, r *http.Request) {
+	market := symbolParam(r)
+	if _, exists := s.tracker.MarketInfo(market); !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = tracker.CandleResolutions[0].Name
+	}
+	duration, _ := tracker.ParseCandleInterval(interval)
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		to = time.UnixMilli(ms)
+	}
+	from := to.Add(-tracker.DefaultGapReportWindow)
+	if value := query.Get("from"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		from = time.UnixMilli(ms)
+	}
+	if !from.Before(to) {
+		writeProblem(w, r, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	gaps, err := s.tracker.History().CandleGaps(market, duration, from, to)
+	if err != nil {
+		logging.Error("Error reading history:", err)
+		writeProblem(w, r, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+	missing := 0
+	for _, gap := range gaps {
+		missing += gap.Missing
+	}
+	writeJSON(w, map[string]interface{}{
+		"market":   market,
+		"interval": interval,
+		"from":     from.UnixMilli(),
+		"to":       to.UnixMilli(),
+		"gaps":     gaps,
+		"missing":  missing,
+		// Whether gaps in the last HistoryBackfillWindow hours are filled from upstream
+		"backfill": s.tracker.History().Persistent() && config.Current().HistoryBackfillWindow > 0,
+	})
+}
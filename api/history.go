@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleHistory serves a market's recorded tickers, optionally limited to a time range in
+// epoch milliseconds and to the most recent points. With an interval from 1m to 1w it
+// serves candles at that interval instead.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	format, ok := responseFormat(w, r)
+	if !ok {
+		return
+	}
+	market := symbolParam(r)
+	if market == "" {
+		writeProblem(w, r, "Missing 'symbol' parameter", http.StatusBadRequest)
+		return
+	}
+	if _, exists := s.tracker.MarketInfo(market); !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to time.Time
+	for _, bound := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if value := query.Get(bound.name); value != "" {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				writeProblem(w, r, "Invalid '"+bound.name+"' parameter", http.StatusBadRequest)
+				return
+			}
+			*bound.target = time.Unix(0, ms*int64(time.Millisecond))
+		}
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeProblem(w, r, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if interval := query.Get("interval"); interval != "" {
+		duration, ok := tracker.ParseCandleInterval(interval)
+		if !ok {
+			writeProblem(w, r, "Unsupported 'interval' parameter", http.StatusBadRequest)
+			return
+		}
+		candles, err := s.tracker.History().Candles(market, duration, from, to, limit)
+		if err != nil {
+			logging.Error("Error reading history:", err)
+			writeProblem(w, r, "Failed to read history", http.StatusInternalServerError)
+			return
+		}
+		if format == "csv" {
+			serveCSV(w, market+"-"+interval+".csv", candleCSVHeader, candleCSVRows(candles))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(candles)
+		return
+	}
+
+	points, err := s.tracker.History().Query(market, from, to, limit)
+	if err != nil {
+		logging.Error("Error reading history:", err)
+		writeProblem(w, r, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+	if format == "csv" {
+		serveCSV(w, market+"-history.csv", HistoryCSVHeader, HistoryCSVRows(points))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// InNetworks reports whether addr falls in any of the configured networks. Entries are
+// validated with the config, so unparseable ones are skipped.
+func inNetworks(addr netip.Addr, networks []string) bool {
+	for _, entry := range networks {
+		if prefix, err := config.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr finds the address a request came from. Behind a trusted proxy X-Forwarded-For
+// is walked from the nearest hop, so the client is the first address not in TrustedProxies
+// and one it names itself cannot be forged past a proxy that appends to the header.
+func clientAddr(r *http.Request, trusted []string) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	if !inNetworks(addr, trusted) {
+		return addr
+	}
+
+	hops := []string{}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop cannot be attributed, so stop at the last proxy that vouched for it
+			break
+		}
+		addr = hop.Unmap()
+		if !inNetworks(addr, trusted) {
+			break
+		}
+	}
+	return addr
+}
+
+// FilterClients refuses clients outside AllowedCIDRs or inside DeniedCIDRs before any
+// other handling, authentication included. The lists are read per request so they can
+// be changed at runtime.
+func filterClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Current()
+		if len(cfg.AllowedCIDRs) == 0 && len(cfg.DeniedCIDRs) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		addr := clientAddr(r, cfg.TrustedProxies)
+		allowed := addr.IsValid() && !inNetworks(addr, cfg.DeniedCIDRs)
+		if allowed && len(cfg.AllowedCIDRs) > 0 {
+			allowed = inNetworks(addr, cfg.AllowedCIDRs)
+		}
+		if !allowed {
+			writeProblem(w, r, "Client address not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
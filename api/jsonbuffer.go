@@ -0,0 +1,53 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// maxPooledJSONBuffer bounds the buffers kept for reuse, so one unusually large response
+// does not pin its memory
+const maxPooledJSONBuffer = 1 << 20
+
+// jsonBuffers holds encoding buffers between requests. Ticker listings and order books run
+// to hundreds of kilobytes, and a fresh buffer would grow, and reallocate, towards that
+// size on every request.
+var jsonBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// EncodeJSON encodes value into a pooled buffer. The caller hands it back with
+// releaseJSONBuffer once its bytes have been written or copied.
+func encodeJSON(value interface{}) (*bytes.Buffer, error) {
+	buffer := jsonBuffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	if err := json.NewEncoder(buffer).Encode(value); err != nil {
+		releaseJSONBuffer(buffer)
+		return nil, err
+	}
+	return buffer, nil
+}
+
+func releaseJSONBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledJSONBuffer {
+		jsonBuffers.Put(buffer)
+	}
+}
+
+// WriteJSON writes value as a JSON response in one write with its Content-Length, rather
+// than streaming it through the encoder in chunks
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	buffer, err := encodeJSON(value)
+	if err != nil {
+		logging.Error("Error encoding response:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer releaseJSONBuffer(buffer)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buffer.Len()))
+	w.Write(buffer.Bytes())
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// discardResponseWriter is a ResponseWriter that keeps only its headers, so benchmarks
+// measure encoding rather than a recorder's buffering
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// writeJSONUnpooled is writeJSON encoding into a fresh buffer on every call
+func writeJSONUnpooled(w http.ResponseWriter, value interface{}) {
+	buffer := new(bytes.Buffer)
+	if err := json.NewEncoder(buffer).Encode(value); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buffer.Len()))
+	w.Write(buffer.Bytes())
+}
+
+// benchmarkWriters runs b once per way of writing value as a JSON response
+func benchmarkWriters(b *testing.B, value interface{}) {
+	writers := []struct {
+		name  string
+		write func(http.ResponseWriter, interface{})
+	}{
+		{"pooled", writeJSON},
+		{"unpooled", writeJSONUnpooled},
+	}
+	for _, writer := range writers {
+		b.Run(writer.name, func(b *testing.B) {
+			w := &discardResponseWriter{header: make(http.Header)}
+			b.ReportAllocs()
+			for b.Loop() {
+				writer.write(w, value)
+			}
+		})
+	}
+}
+
+// BenchmarkTickerResponse writes a listing the size of the exchange's, about 500 markets
+func BenchmarkTickerResponse(b *testing.B) {
+	freshness := tracker.FreshnessOf(time.Now())
+	tickers := make([]tracker.TickerView, 500)
+	for i := range tickers {
+		price := 1000 + float64(i)*1.25
+		tickers[i] = tracker.TickerView{
+			TickerDetails: exchange.TickerDetails{
+				Market:       fmt.Sprintf("COIN%dINR", i),
+				Change24Hour: "-1.52",
+				High:         strconv.FormatFloat(price*1.02, 'f', 4, 64),
+				Low:          strconv.FormatFloat(price*0.98, 'f', 4, 64),
+				Volume:       "123456.789",
+				LastPrice:    strconv.FormatFloat(price, 'f', 4, 64),
+				Bid:          json.RawMessage(strconv.FormatFloat(price-0.5, 'f', 4, 64)),
+				Ask:          json.RawMessage(strconv.FormatFloat(price+0.5, 'f', 4, 64)),
+				Timestamp:    time.Now().Unix(),
+			},
+			Freshness: freshness,
+		}
+	}
+	benchmarkWriters(b, tickers)
+}
+
+// BenchmarkOrderBookResponse writes a /livedata response for a book 500 levels deep a side
+func BenchmarkOrderBookResponse(b *testing.B) {
+	book := exchange.OrderBook{Bids: make(map[string]string), Asks: make(map[string]string)}
+	for i := 0; i < 500; i++ {
+		book.Bids[strconv.FormatFloat(5000000-float64(i)*10, 'f', 2, 64)] = "0.0125"
+		book.Asks[strconv.FormatFloat(5000010+float64(i)*10, 'f', 2, 64)] = "0.0250"
+	}
+	freshness := tracker.FreshnessOf(time.Now())
+	response := map[string]interface{}{
+		"pair":         "BTCINR",
+		"order_book":   book,
+		"cached":       true,
+		"age_ms":       250,
+		"last_updated": freshness.LastUpdated,
+		"age_seconds":  freshness.AgeSeconds,
+		"stale":        freshness.Stale,
+	}
+	benchmarkWriters(b, response)
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HandleMetrics serves /metrics for Prometheus to scrape
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var metrics strings.Builder
+	s.tracker.Upstream().Latency.WriteMetrics(&metrics)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(metrics.String()))
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleLendingRates serves /lending-rates: every currency's lending and borrowing rates,
+// best paying first, or with a currency its current rates and their history, optionally
+// bounded by from and to in epoch milliseconds and limited to the latest limit points
+func (s *Server) handleLendingRates(w http.ResponseWriter, r *http.Request) {
+	if !tracker.LendingEnabled() {
+		writeProblem(w, r, "Lending rates are disabled", http.StatusNotFound)
+		return
+	}
+	view := s.tracker.MarketView()
+	query := r.URL.Query()
+	freshness := tracker.DatasetFreshness("lending_rates", view.LendingRatesModified)
+	currency := strings.ToUpper(strings.TrimSpace(query.Get("currency")))
+	if currency == "" {
+		if notModified(w, r, view.LendingRatesModified) {
+			return
+		}
+		rates := make([]tracker.LendingRateView, 0, len(view.LendingRates))
+		for _, rate := range view.LendingRates {
+			rates = append(rates, tracker.LendingRateViewOf(rate))
+		}
+		sort.Slice(rates, func(i, j int) bool {
+			if order := rates[i].LendRate.Cmp(rates[j].LendRate); order != 0 {
+				return order > 0
+			}
+			return rates[i].Currency < rates[j].Currency
+		})
+		writeJSON(w, map[string]interface{}{"rates": rates, "freshness": freshness})
+		return
+	}
+
+	rate, exists := view.LendingRates[currency]
+	if !exists {
+		writeProblem(w, r, "Unknown 'currency'", http.StatusNotFound)
+		return
+	}
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		from = time.UnixMilli(ms)
+	}
+	if value := query.Get("to"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		to = time.UnixMilli(ms)
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if notModified(w, r, view.LendingRatesModified) {
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"rate":      tracker.LendingRateViewOf(rate),
+		"freshness": freshness,
+		"history":   s.tracker.Lending().Query(currency, from, to, limit),
+	})
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	// inheritedListenersEnv names, comma separated, the listeners a process was handed by the
+	// process it upgrades, as files 3 onwards
+	inheritedListenersEnv = "CRYPTOTRACKER_LISTENERS"
+	// upgradeReadyEnv is the file the new process closes once it is serving, telling the old
+	// one it may stop
+	upgradeReadyEnv = "CRYPTOTRACKER_UPGRADE_READY_FD"
+)
+
+// namedListener is a listening socket and the address it was opened for
+type namedListener struct {
+	address  string
+	listener net.Listener
+}
+
+var (
+	// inherited holds the listeners passed down by an upgrade until they are claimed
+	inherited map[string]net.Listener
+	// activated holds the sockets passed by systemd socket activation until they are claimed
+	activated      []net.Listener
+	inheritedMutex sync.Mutex
+)
+
+// InheritListeners takes over the sockets named in CRYPTOTRACKER_LISTENERS and those
+// passed by systemd
+func InheritListeners() {
+	inheritedMutex.Lock()
+	defer inheritedMutex.Unlock()
+	inherited = make(map[string]net.Listener)
+	activated = activatedListeners()
+	names := os.Getenv(inheritedListenersEnv)
+	os.Unsetenv(inheritedListenersEnv)
+	if names == "" {
+		return
+	}
+	for i, address := range strings.Split(names, ",") {
+		file := os.NewFile(uintptr(3+i), address)
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			logging.Error("Error inheriting listener for", address+":", err)
+			continue
+		}
+		inherited[address] = listener
+	}
+}
+
+// Listen opens the listener for address, reusing the socket of the process this one
+// replaced when there is one so no connection is refused during an upgrade, or else one
+// passed by systemd
+func (s *Server) listen(network, address string) (net.Listener, error) {
+	inheritedMutex.Lock()
+	listener, exists := inherited[address]
+	delete(inherited, address)
+	if !exists {
+		listener, exists = takeActivatedLocked(address)
+	}
+	inheritedMutex.Unlock()
+
+	var err error
+	if !exists {
+		if network == "unix" {
+			removeStaleSocket(address)
+		}
+		if listener, err = net.Listen(network, address); err != nil {
+			return nil, err
+		}
+	}
+	s.listeners = append(s.listeners, namedListener{address: address, listener: listener})
+	return listener, nil
+}
+
+// Serve runs server in the background on a TCP listener for its address, with TLS when it
+// has a TLS config
+func (s *Server) serve(server *http.Server, name, certFile, keyFile string) {
+	listener, err := s.listen("tcp", server.Addr)
+	if err != nil {
+		logging.Error(name+" error:", err)
+		return
+	}
+	s.serveListener(server, listener, name, certFile, keyFile)
+}
+
+func (s *Server) serveListener(server *http.Server, listener net.Listener, name, certFile, keyFile string) {
+	s.servers = append(s.servers, server)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ServeTLS(listener, certFile, keyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		// Listeners closed for an upgrade stop serving on purpose
+		if err != nil && err != http.ErrServerClosed && !errors.Is(err, net.ErrClosed) {
+			logging.Error(name+" error:", err)
+		}
+	}()
+}
+
+// CloseListeners stops accepting connections while those already open are served
+func (s *Server) CloseListeners() {
+	for _, named := range s.listeners {
+		// The new process accepts on the same socket file, so it must stay in place
+		if unix, ok := named.listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+		named.listener.Close()
+	}
+}
+
+// DrainStreams waits, for at most UpgradeDrainTimeout, until every SSE and WebSocket client
+// of a replaced process has disconnected, after which the rest are dropped and reconnect to
+// the new process. A signal on interrupt stops waiting early.
+func (s *Server) DrainStreams(interrupt <-chan os.Signal) {
+	timeout := time.Duration(config.Current().UpgradeDrainTimeout) * time.Second
+	deadline := time.Now().Add(timeout)
+	for {
+		_, _, clients := s.tracker.Stats().RefreshStats()
+		connected := 0
+		for _, count := range clients {
+			connected += count
+		}
+		if connected == 0 {
+			return
+		}
+		if !time.Now().Before(deadline) {
+			fmt.Println("Dropping", connected, "streaming clients after", timeout)
+			return
+		}
+		select {
+		case <-interrupt:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
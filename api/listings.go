@@ -0,0 +1,24 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleNewListings serves the markets listed since the tracker started watching, newest
+// first, optionally only those detected at or after since milliseconds
+func (s *Server) handleNewListings(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			writeProblem(w, r, "Invalid 'since' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]tracker.NewListing{"markets": s.listings.Since(since)})
+}
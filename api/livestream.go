@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+	"github.com/namithsaliyan/CryptoTrackerAPI/websocket"
+)
+
+// LiveMarket reads and validates the symbol of a streaming request
+func (s *Server) liveMarket(w http.ResponseWriter, r *http.Request) (string, bool) {
+	market := symbolParam(r)
+	if market == "" {
+		writeProblem(w, r, "Missing 'symbol' parameter", http.StatusBadRequest)
+		return "", false
+	}
+	if _, exists := s.tracker.MarketInfo(market); !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return "", false
+	}
+	return market, true
+}
+
+// HandleLiveDataStream pushes order book updates as server-sent events
+func (s *Server) handleLiveDataStream(w http.ResponseWriter, r *http.Request) {
+	market, ok := s.liveMarket(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, r, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream outlives any server WriteTimeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logging.Debug("Error clearing write deadline:", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	defer s.tracker.Stats().ClientConnected("sse")()
+
+	s.tracker.LiveUpdates(market, r.Context().Done(), func(update tracker.LiveUpdate) error {
+		data, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: orderbook\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+// HandleLiveDataSocket pushes order book updates over a websocket
+func (s *Server) handleLiveDataSocket(w http.ResponseWriter, r *http.Request) {
+	market, ok := s.liveMarket(w, r)
+	if !ok {
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	switch {
+	case errors.Is(err, websocket.ErrNotHandshake):
+		writeProblem(w, r, "Expected a websocket upgrade", http.StatusBadRequest)
+		return
+	case errors.Is(err, websocket.ErrNotHijackable):
+		writeProblem(w, r, "Websocket not supported", http.StatusInternalServerError)
+		return
+	case err != nil:
+		logging.Error("Error upgrading websocket:", err)
+		return
+	}
+	defer conn.Close()
+	defer s.tracker.Stats().ClientConnected("websocket")()
+
+	// Reading is only needed to notice the client going away and to answer pings
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	s.tracker.LiveUpdates(market, done, func(update tracker.LiveUpdate) error {
+		data, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.OpText, data)
+	})
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleMarketStatusChanges serves the market status change log
+func (s *Server) handleMarketStatusChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since int64
+	if value := query.Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			writeProblem(w, r, "Invalid 'since' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]tracker.MarketStatusChange{"changes": s.statuses.Changes(query.Get("symbol"), since)})
+}
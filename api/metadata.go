@@ -0,0 +1,29 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	// /v1/markets/{symbol} returns just that market
+	symbol := r.PathValue("symbol")
+	status := ""
+	if symbol == "" {
+		status = statusFilter(r)
+	}
+	markets := s.tracker.MarketsWithMetadata(symbol, status, s.statuses.ChangedTimes())
+
+	w.Header().Set("Content-Type", "application/json")
+	if symbol != "" {
+		if len(markets) == 0 {
+			writeError(w, r, tracker.ErrSymbolNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(markets[0])
+		return
+	}
+	json.NewEncoder(w).Encode(markets)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// ValidRequestID reports whether a client-supplied ID is safe to reuse in logs and headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder captures the status code and size of a response while still exposing
+// the flushing and hijacking that streaming handlers rely on
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer cannot be hijacked")
+	}
+	conn, buffer, err := hijacker.Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, buffer, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LogRequests assigns every request an ID, reusing a valid incoming X-Request-ID, returns
+// it in the response and logs the request once it completes
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		started := time.Now()
+		// Deferred so requests aborted by a panic are still logged
+		defer func() {
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+			logging.Info(fmt.Sprintf("request_id=%s method=%s path=%s status=%d bytes=%d latency=%s remote=%s",
+				id, r.Method, r.URL.Path, recorder.status, recorder.bytes, time.Since(started).Round(time.Microsecond), r.RemoteAddr))
+		}()
+		next.ServeHTTP(recorder, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// RecoverPanics turns a handler panic into a logged stack trace and a problem 500 response,
+// keeping the server and its other connections running. Responses that already started
+// cannot be replaced, so those connections are just closed.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			id := logging.RequestID(r.Context())
+			logging.Error(fmt.Sprintf("Panic serving request_id=%s %s %s: %v\n%s", id, r.Method, r.URL.Path, recovered, debug.Stack()))
+
+			if recorder, ok := w.(*statusRecorder); ok && recorder.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			writeProblem(w, r, "Internal server error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
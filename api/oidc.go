@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	// oidcKeysTTL is how long an issuer's signing keys are used before they are fetched again
+	oidcKeysTTL = time.Hour
+	// oidcRefetchInterval limits fetches for tokens signed by a key not yet seen, which is how
+	// key rotation shows up, so made-up key IDs cannot turn into a flood of requests
+	oidcRefetchInterval = time.Minute
+	// oidcClockSkew is how far token lifetimes are stretched for clocks that disagree
+	oidcClockSkew = time.Minute
+	// defaultOIDCGroupsClaim is the claim groups are read from when OIDCGroupsClaim is not set
+	defaultOIDCGroupsClaim = "groups"
+)
+
+var (
+	errOIDCUnauthorized = errors.New("token is not authorized for admin endpoints")
+
+	// oidcHashes are the signature algorithms accepted, by JWS name. Symmetric and "none"
+	// algorithms are deliberately absent: the keys come from the issuer, never a shared secret.
+	oidcHashes = map[string]crypto.Hash{
+		"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+		"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	}
+)
+
+// OIDCVerifier checks OpenID Connect tokens against the signing keys OIDCIssuer publishes,
+// discovered from its /.well-known/openid-configuration
+type OIDCVerifier struct {
+	client *http.Client
+
+	issuer      string
+	keys        map[string]crypto.PublicKey
+	fetched     time.Time
+	lastAttempt time.Time
+	mutex       sync.Mutex
+}
+
+func newOIDCVerifier() *OIDCVerifier {
+	return &OIDCVerifier{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// JSONWebKey is one key of an issuer's JWKS
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LooksLikeJWT tells tokens apart from opaque API keys and admin tokens
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Key returns the issuer's key with the given ID, fetching the keys when they are stale,
+// belong to another issuer or lack that ID
+func (v *OIDCVerifier) key(ctx context.Context, issuer, kid string) (crypto.PublicKey, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.issuer != issuer {
+		v.issuer, v.keys, v.fetched, v.lastAttempt = issuer, nil, time.Time{}, time.Time{}
+	}
+	key, exists := v.keys[kid]
+	stale := time.Since(v.fetched) > oidcKeysTTL
+	if (exists && !stale) || time.Since(v.lastAttempt) < oidcRefetchInterval {
+		if !exists {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	}
+
+	v.lastAttempt = time.Now()
+	keys, err := v.fetchKeysLocked(ctx, issuer)
+	if err != nil {
+		// Keys that were good an hour ago are better than refusing every admin
+		if exists {
+			logging.Warn("Failed to refresh OIDC signing keys:", err)
+			return key, nil
+		}
+		return nil, err
+	}
+	v.keys, v.fetched = keys, time.Now()
+	if key, exists = keys[kid]; !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// FetchKeysLocked discovers the issuer's JWKS and parses the signing keys it can use
+func (v *OIDCVerifier) fetchKeysLocked(ctx context.Context, issuer string) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	// The discovery document must be the issuer's own, or tokens would be checked against a stranger's keys
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer %q does not match %q", discovery.Issuer, issuer)
+	}
+	var jwks struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logging.Debug("Skipping OIDC key", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (v *OIDCVerifier) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// PublicKey builds the RSA or elliptic curve key a JWK describes
+func (k JSONWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("unusable RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, exists := curves[k.Crv]
+		if !exists {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("malformed EC point")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// VerifySignature checks a JWS signature over signed with the key of the named algorithm
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	hash := oidcHashes[alg]
+	var digest []byte
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(signed)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(signed)
+		digest = sum[:]
+	default:
+		sum := sha512.Sum512(signed)
+		digest = sum[:]
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			break
+		}
+		// JWS signatures are the two integers side by side rather than ASN.1
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(key, digest, r, s) {
+			return nil
+		}
+		return errors.New("invalid signature")
+	}
+	return fmt.Errorf("key does not match algorithm %s", alg)
+}
+
+// Verify checks a token's signature, issuer, audience and lifetime, then that its claims
+// pass OIDCRequiredClaims and OIDCAdminGroups, returning errOIDCUnauthorized when they
+// do not. The token's claims are returned either way once it is valid.
+func (v *OIDCVerifier) verify(ctx context.Context, token string, cfg config.Config) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if _, supported := oidcHashes[header.Alg]; !supported {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	key, err := v.key(ctx, cfg.OIDCIssuer, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if issuer, _ := claims["iss"].(string); issuer != cfg.OIDCIssuer {
+		return nil, fmt.Errorf("token issued by %q", issuer)
+	}
+	if !claimContains(claims["aud"], cfg.OIDCAudience) {
+		return nil, errors.New("token not issued for this audience")
+	}
+	now := time.Now()
+	expires, ok := claims["exp"].(float64)
+	if !ok || now.Add(-oidcClockSkew).After(time.Unix(int64(expires), 0)) {
+		return nil, errors.New("token expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, errors.New("token not yet valid")
+	}
+
+	for name, want := range cfg.OIDCRequiredClaims {
+		if got, _ := claims[name].(string); got != want {
+			return claims, errOIDCUnauthorized
+		}
+	}
+	if len(cfg.OIDCAdminGroups) > 0 {
+		groupsClaim := cfg.OIDCGroupsClaim
+		if groupsClaim == "" {
+			groupsClaim = defaultOIDCGroupsClaim
+		}
+		member := false
+		for _, group := range cfg.OIDCAdminGroups {
+			member = member || claimContains(claims[groupsClaim], group)
+		}
+		if !member {
+			return claims, errOIDCUnauthorized
+		}
+	}
+	return claims, nil
+}
+
+// DecodeTokenPart decodes a base64url JSON segment of a token
+func decodeTokenPart(part string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// ClaimContains reports whether a claim, a single string or a list of them, holds value
+func claimContains(claim interface{}, value string) bool {
+	switch claim := claim.(type) {
+	case string:
+		return claim == value
+	case []interface{}:
+		for _, item := range claim {
+			if item == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleFuturesOpenInterest serves /futures/open-interest: every contract's current open
+// interest, or with a symbol one contract's and its samples, optionally bounded by from and
+// to in epoch milliseconds and limited to the latest limit samples. A window in seconds
+// adds the percentage change over it.
+func (s *Server) handleFuturesOpenInterest(w http.ResponseWriter, r *http.Request) {
+	if !tracker.FuturesEnabled() {
+		writeProblem(w, r, "Futures data is disabled", http.StatusNotFound)
+		return
+	}
+	view := s.tracker.MarketView()
+	query := r.URL.Query()
+	window, _ := strconv.Atoi(query.Get("window"))
+	change := func(pair string, current exchange.Decimal) interface{} {
+		if window <= 0 {
+			return nil
+		}
+		earliest, exists := s.tracker.OpenInterest().Since(pair, time.Now().Add(-time.Duration(window)*time.Second))
+		if !exists {
+			return nil
+		}
+		if percent, ok := tracker.OpenInterestChange(earliest, current); ok {
+			return percent.RoundTo(4)
+		}
+		return nil
+	}
+
+	symbol := query.Get("symbol")
+	if symbol == "" {
+		if notModified(w, r, view.FuturesPricesModified) {
+			return
+		}
+		interests := []map[string]interface{}{}
+		for pair, price := range view.FuturesPrices {
+			if price.OpenInterest.IsZero() {
+				continue
+			}
+			entry := map[string]interface{}{"pair": pair, "open_interest": price.OpenInterest}
+			if percent := change(pair, price.OpenInterest); percent != nil {
+				entry["change_percent"] = percent
+			}
+			interests = append(interests, entry)
+		}
+		sort.Slice(interests, func(i, j int) bool { return interests[i]["pair"].(string) < interests[j]["pair"].(string) })
+		writeJSON(w, map[string]interface{}{
+			"open_interest": interests,
+			"freshness":     tracker.FreshnessOf(view.FuturesPricesModified),
+		})
+		return
+	}
+
+	pair := s.tracker.ResolveFuturesPair(symbol)
+	price, exists := view.FuturesPrices[pair]
+	if !exists {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		from = time.UnixMilli(ms)
+	}
+	if value := query.Get("to"); value != "" {
+		ms, _ := strconv.ParseInt(value, 10, 64)
+		to = time.UnixMilli(ms)
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if notModified(w, r, view.FuturesPricesModified) {
+		return
+	}
+	response := map[string]interface{}{
+		"pair":          pair,
+		"open_interest": price.OpenInterest,
+		"freshness":     tracker.FreshnessOf(view.FuturesPricesModified),
+		"history":       s.tracker.OpenInterest().Query(pair, from, to, limit),
+	}
+	if percent := change(pair, price.OpenInterest); percent != nil {
+		response["change_percent"] = percent
+	}
+	writeJSON(w, response)
+}
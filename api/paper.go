@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handlePaperAccounts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			writeProblem(w, r, "Missing 'name' parameter", http.StatusBadRequest)
+			return
+		}
+		account, exists := s.paper.Account(name)
+		if !exists {
+			writeProblem(w, r, "Paper account not found", http.StatusNotFound)
+			return
+		}
+		if !tracker.RawValues(r) {
+			account = account.Rounded(s.tracker.Precision())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(account)
+
+	case http.MethodPost:
+		var account tracker.PaperAccount
+		if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+			writeProblem(w, r, "Failed to parse paper account", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(account.Name) == "" {
+			writeProblem(w, r, "Missing 'name'", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.paper.CreateAccount(account))
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePaperOrders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		account, exists := s.paper.Account(r.URL.Query().Get("account"))
+		if !exists {
+			writeProblem(w, r, "Paper account not found", http.StatusNotFound)
+			return
+		}
+		if !tracker.RawValues(r) {
+			account = account.Rounded(s.tracker.Precision())
+		}
+		status := r.URL.Query().Get("status")
+		orders := []*tracker.PaperOrder{}
+		for _, order := range account.Orders {
+			if status == "" || order.Status == status {
+				orders = append(orders, order)
+			}
+		}
+		sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt > orders[j].CreatedAt })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]*tracker.PaperOrder{"orders": orders})
+
+	case http.MethodPost:
+		var request tracker.PaperOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeProblem(w, r, "Failed to parse order", http.StatusBadRequest)
+			return
+		}
+		request.Market = s.tracker.ResolveSymbol(request.Market)
+		order, err := s.paper.PlaceOrder(r.Context(), request)
+		var failure *tracker.APIError
+		if errors.As(err, &failure) {
+			writeError(w, r, failure)
+			return
+		}
+		if err != nil {
+			writeProblem(w, r, "Order rejected: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if !tracker.RawValues(r) {
+			order = order.Rounded(s.tracker.Precision())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+
+	case http.MethodDelete:
+		query := r.URL.Query()
+		order, err := s.paper.CancelOrder(query.Get("account"), query.Get("id"))
+		if err != nil {
+			writeProblem(w, r, "Cancel failed: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if !tracker.RawValues(r) {
+			order = order.Rounded(s.tracker.Precision())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePaperFills(w http.ResponseWriter, r *http.Request) {
+	account, exists := s.paper.Account(r.URL.Query().Get("account"))
+	if !exists {
+		writeProblem(w, r, "Paper account not found", http.StatusNotFound)
+		return
+	}
+	if !tracker.RawValues(r) {
+		account = account.Rounded(s.tracker.Precision())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]tracker.PaperFill{"fills": account.Fills})
+}
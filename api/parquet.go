@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// Parquet physical and converted types, encodings and page types used by the writer, as
+// numbered in the parquet-format Thrift definitions
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain    = 0
+	parquetRLE      = 3
+	parquetDataPage = 0
+	parquetRequired = 0
+)
+
+// Thrift compact protocol type codes
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetMagic starts and ends every Parquet file
+var parquetMagic = []byte("PAR1")
+
+// parquetColumn is one required, flat column of a history Parquet file
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32 // -1 for none
+	write     func(*bytes.Buffer, tracker.HistoryPoint)
+}
+
+var historyParquetColumns = []parquetColumn{
+	{"market", parquetByteArray, parquetUTF8, func(b *bytes.Buffer, p tracker.HistoryPoint) {
+		binary.Write(b, binary.LittleEndian, uint32(len(p.Market)))
+		b.WriteString(p.Market)
+	}},
+	{"timestamp", parquetInt64, parquetTimestampMillis, func(b *bytes.Buffer, p tracker.HistoryPoint) {
+		binary.Write(b, binary.LittleEndian, p.Timestamp)
+	}},
+	{"last_price", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.LastPrice) }},
+	{"bid", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.Bid) }},
+	{"ask", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.Ask) }},
+	{"high", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.High) }},
+	{"low", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.Low) }},
+	{"volume", parquetDouble, -1, func(b *bytes.Buffer, p tracker.HistoryPoint) { writeParquetDouble(b, p.Volume) }},
+}
+
+func writeParquetDouble(b *bytes.Buffer, value float64) {
+	binary.Write(b, binary.LittleEndian, math.Float64bits(value))
+}
+
+// parquetChunk locates a written column chunk for the footer
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+// WriteHistoryParquet writes points as an uncompressed Parquet file with one row group and
+// one plain-encoded data page per column. Every column is required, so pages carry no
+// repetition or definition levels; pandas, DuckDB and Spark all read this layout.
+func WriteHistoryParquet(w io.Writer, points []tracker.HistoryPoint) error {
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	chunks := make([]parquetChunk, len(historyParquetColumns))
+	for i, column := range historyParquetColumns {
+		var data bytes.Buffer
+		for _, point := range points {
+			column.write(&data, point)
+		}
+
+		header := newThriftWriter()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(data.Len()))
+		header.i32(3, int32(data.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(len(points)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+
+		chunks[i] = parquetChunk{offset: int64(file.Len()), size: int64(header.buf.Len() + data.Len())}
+		file.Write(header.buf.Bytes())
+		file.Write(data.Bytes())
+	}
+
+	footer := parquetFooter(int64(len(points)), chunks)
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.Write(parquetMagic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// ParquetFooter encodes the FileMetaData describing the schema and the single row group
+func parquetFooter(rows int64, chunks []parquetChunk) []byte {
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.size
+	}
+
+	meta := newThriftWriter()
+	meta.i32(1, 1)
+	meta.beginList(2, thriftStruct, len(historyParquetColumns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(historyParquetColumns)))
+	meta.endStruct()
+	for _, column := range historyParquetColumns {
+		meta.beginElement()
+		meta.i32(1, column.kind)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, column.name)
+		if column.converted >= 0 {
+			meta.i32(6, column.converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, rows)
+
+	meta.beginList(4, thriftStruct, 1)
+	meta.beginElement()
+	meta.beginList(1, thriftStruct, len(chunks))
+	for i, column := range historyParquetColumns {
+		chunk := chunks[i]
+		meta.beginElement()
+		meta.i64(2, chunk.offset)
+		meta.beginStruct(3)
+		meta.i32(1, column.kind)
+		meta.beginList(2, thriftI32, 1)
+		meta.listI32(parquetPlain)
+		meta.beginList(3, thriftBinary, 1)
+		meta.listBinary(column.name)
+		meta.i32(4, 0) // uncompressed
+		meta.i64(5, rows)
+		meta.i64(6, chunk.size)
+		meta.i64(7, chunk.size)
+		meta.i64(9, chunk.offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, total)
+	meta.i64(3, rows)
+	meta.endStruct()
+
+	meta.binary(6, "cryptotracker")
+	meta.stop()
+	return meta.buf.Bytes()
+}
+
+// thriftWriter encodes structs in the Thrift compact protocol, which Parquet uses for its
+// page headers and footer. Field ids are delta-encoded against the previous field of the
+// enclosing struct, so the writer keeps one last id per nesting level.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) uvarint(value uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, value))
+}
+
+func (t *thriftWriter) zigzag(value int64) {
+	t.uvarint(uint64((value << 1) ^ (value >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+	level := len(t.last) - 1
+	if delta := id - t.last[level]; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.zigzag(int64(id))
+	}
+	t.last[level] = id
+}
+
+func (t *thriftWriter) i32(id int16, value int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(value))
+}
+
+func (t *thriftWriter) i64(id int16, value int64) {
+	t.field(id, thriftI64)
+	t.zigzag(value)
+}
+
+func (t *thriftWriter) binary(id int16, value string) {
+	t.field(id, thriftBinary)
+	t.listBinary(value)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+// BeginElement starts a struct that is a list element and so has no field header
+func (t *thriftWriter) beginElement() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.last[:len(t.last)-1]
+}
+
+// Stop ends the current struct's fields
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) beginList(id int16, elements byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elements)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elements)
+	t.uvarint(uint64(size))
+}
+
+func (t *thriftWriter) listI32(value int32) {
+	t.zigzag(int64(value))
+}
+
+func (t *thriftWriter) listBinary(value string) {
+	t.uvarint(uint64(len(value)))
+	t.buf.WriteString(value)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handlePortfolioPnL(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeProblem(w, r, "Missing 'name' parameter", http.StatusBadRequest)
+		return
+	}
+
+	method := strings.ToLower(r.URL.Query().Get("method"))
+	if method == "" {
+		method = tracker.CostBasisFIFO
+	}
+	if method != tracker.CostBasisFIFO && method != tracker.CostBasisAverage {
+		writeProblem(w, r, "Invalid 'method' parameter, expected 'fifo' or 'average'", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, exists := s.portfolios.Get(requestUser(r), name)
+	if !exists {
+		writeProblem(w, r, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := s.tracker.ComputePnL(portfolio, method)
+	if err != nil {
+		writeProblem(w, r, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if !tracker.RawValues(r) {
+		result = result.Rounded(s.tracker.Precision())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// PortfolioResponse values a portfolio for a response, rounded unless the request asked for raw values
+func (s *Server) portfolioResponse(r *http.Request, portfolio tracker.Portfolio) tracker.PortfolioValuation {
+	valuation := s.tracker.ValuePortfolio(portfolio)
+	if tracker.RawValues(r) {
+		return valuation
+	}
+	return valuation.Rounded(s.tracker.Precision())
+}
+
+// HandlePortfolio manages the portfolios of the user a request acts as
+func (s *Server) handlePortfolio(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	user := requestUser(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string][]string{"portfolios": s.portfolios.Names(user)})
+			return
+		}
+		portfolio, exists := s.portfolios.Get(user, name)
+		if !exists {
+			writeProblem(w, r, "Portfolio not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.portfolioResponse(r, portfolio))
+
+	case http.MethodPost, http.MethodPut:
+		var portfolio tracker.Portfolio
+		if err := json.NewDecoder(r.Body).Decode(&portfolio); err != nil {
+			writeProblem(w, r, "Failed to parse portfolio", http.StatusBadRequest)
+			return
+		}
+		if err := tracker.NormalizePortfolio(&portfolio); err != nil {
+			writeProblem(w, r, "Invalid portfolio: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		portfolio.Owner = user
+		if err := s.portfolios.Put(portfolio); err != nil {
+			logging.Error("Error saving portfolios:", err)
+			writeProblem(w, r, "Failed to save portfolio", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.portfolioResponse(r, portfolio))
+
+	case http.MethodDelete:
+		if name == "" {
+			writeProblem(w, r, "Missing 'name' parameter", http.StatusBadRequest)
+			return
+		}
+		removed, err := s.portfolios.Remove(user, name)
+		if err != nil {
+			logging.Error("Error saving portfolios:", err)
+			writeProblem(w, r, "Failed to delete portfolio", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			writeProblem(w, r, "Portfolio not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	state := s.tracker.Upstream().State
+	upstream := state.Health()
+	probe := s.tracker.Probe().Health()
+
+	status := "ok"
+	switch {
+	case upstream.Throttled:
+		status = "throttled"
+	case upstream.ConsecutiveFailures > 0 || probe.LastError != "":
+		status = "degraded"
+	}
+
+	datasets := make(map[string]tracker.Freshness)
+	for name, updated := range s.tracker.DatasetTimes() {
+		datasets[name] = tracker.DatasetFreshness(name, updated)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tracker.UpstreamStatus{
+		Status:   status,
+		Upstream: upstream,
+		Probe:    probe,
+		Backoff: tracker.BackoffState{
+			Throttled:      upstream.Throttled,
+			ThrottledUntil: upstream.ThrottledUntil,
+			RefreshDelayMs: state.RefreshDelay(tracker.RefreshInterval()).Milliseconds(),
+			BaseIntervalMs: tracker.RefreshInterval().Milliseconds(),
+		},
+		Datasets: datasets,
+	})
+}
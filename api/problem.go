@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypeBlank is the RFC 7807 type of problems described by their status alone
+	problemTypeBlank = "about:blank"
+)
+
+// Problem is an RFC 7807 problem details object, the body of every error response. The
+// request ID matches the X-Request-ID header and the server's request log.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is set for errors from the taxonomy in apierrors.go
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// InvalidParams lists every parameter that failed validation
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// WriteProblem replies to a request with an application/problem+json error, taking the same
+// message and status as http.Error
+func writeProblem(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	writeCodedProblem(w, r, "", detail, status)
+}
+
+// WriteCodedProblem is writeProblem for an error with a code, which also names its problem type
+func writeCodedProblem(w http.ResponseWriter, r *http.Request, code, detail string, status int) {
+	sendProblem(w, newProblem(r, code, detail, status))
+}
+
+// NewProblem describes a failed request; code may be empty
+func newProblem(r *http.Request, code, detail string, status int) Problem {
+	problem := Problem{
+		Type:      problemTypeBlank,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: logging.RequestID(r.Context()),
+	}
+	if code != "" {
+		problem.Type = tracker.ProblemTypePrefix + code
+	}
+	return problem
+}
+
+// SendProblem writes a problem as the response
+func sendProblem(w http.ResponseWriter, problem Problem) {
+	header := w.Header()
+	// A handler may have set headers for the response it meant to send
+	header.Del("Content-Length")
+	header.Set("Content-Type", problemContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
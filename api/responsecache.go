@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+const (
+	// responseCacheMaxAge bounds how long an encoded response is reused within one view, as
+	// responses embed the age of their data
+	responseCacheMaxAge = time.Second
+	// maxCachedResponses bounds the variants, such as status filters, kept per view
+	maxCachedResponses = 32
+)
+
+// cachedResponse is an encoded response body and its ETag
+type cachedResponse struct {
+	body      []byte
+	etag      string
+	encodedAt time.Time
+}
+
+// ResponseCache keeps the encoded JSON of hot listings such as /ticker for the current
+// market view, so every request between refreshes is served the same bytes instead of
+// encoding hundreds of tickers again. A new view empties it.
+type ResponseCache struct {
+	view     *tracker.MarketView
+	entries  map[string]cachedResponse
+	encoding *exchange.FlightGroup
+	mutex    sync.Mutex
+}
+
+func newResponseCache() *ResponseCache {
+	return &ResponseCache{entries: make(map[string]cachedResponse), encoding: exchange.NewFlightGroup()}
+}
+
+// Lookup returns the response for key cached from view while it is recent enough
+func (c *ResponseCache) lookup(view *tracker.MarketView, key string) (cachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.view != view {
+		return cachedResponse{}, false
+	}
+	cached, exists := c.entries[key]
+	return cached, exists && time.Since(cached.encodedAt) < responseCacheMaxAge
+}
+
+// ResponseETag returns the ETag of the response for key built from view. It depends only
+// on the process and the view's generation, not on the body, which embeds data ages that
+// change every second, so re-encoding an unchanged view keeps the ETag. It is weak for the
+// same reason.
+func responseETag(view *tracker.MarketView, key string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return fmt.Sprintf(`W/"%s-%d-%x"`, bootID, view.Generation, hash.Sum64())
+}
+
+// EncodeResponse encodes value as a response body with the given ETag
+func encodeResponse(value interface{}, etag string) cachedResponse {
+	buffer, err := encodeJSON(value)
+	if err != nil {
+		logging.Error("Error encoding response:", err)
+		return cachedResponse{body: []byte("null\n"), etag: `"0"`, encodedAt: time.Now()}
+	}
+	defer releaseJSONBuffer(buffer)
+	// The cached body outlives the pooled buffer, so it is copied out at its exact size
+	body := bytes.Clone(buffer.Bytes())
+	return cachedResponse{body: body, etag: etag, encodedAt: time.Now()}
+}
+
+// Serve writes the JSON response for key, encoding build's result only when no current
+// encoding is cached. Concurrent misses share one encoding. A request already holding
+// the ETag, or one with the data last modified at modified, gets 304 Not Modified.
+func (c *ResponseCache) serve(w http.ResponseWriter, r *http.Request, view *tracker.MarketView, key string, modified time.Time, build func() interface{}) {
+	cached, ok := c.lookup(view, key)
+	if !ok {
+		// Without a deadline the wait lasts until the shared encoding has stored its result
+		c.encoding.Do(context.Background(), key, func(context.Context) error {
+			if cached, ok = c.lookup(view, key); ok {
+				return nil
+			}
+			cached, ok = encodeResponse(build(), responseETag(view, key)), true
+
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			if c.view != view {
+				c.view = view
+				c.entries = make(map[string]cachedResponse)
+			}
+			if _, exists := c.entries[key]; exists || len(c.entries) < maxCachedResponses {
+				c.entries[key] = cached
+			}
+			return nil
+		})
+	}
+	// A request that waited on another's encoding reads what that stored
+	if !ok {
+		if cached, ok = c.lookup(view, key); !ok {
+			cached = encodeResponse(build(), responseETag(view, key))
+		}
+	}
+
+	w.Header().Set("ETag", cached.etag)
+	if etagMatches(r, cached.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if notModified(w, r, modified) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(cached.body)))
+	w.Write(cached.body)
+}
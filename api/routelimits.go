@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	// defaultRouteLimitKey is the RouteLimits entry applying to every route
+	defaultRouteLimitKey = "default"
+	// defaultMaxBodyBytes bounds request bodies when RouteLimits sets no MaxBodyBytes
+	defaultMaxBodyBytes = 1 << 20
+)
+
+// RouteLimitFor merges a route's entry in RouteLimits over the default entry
+func routeLimitFor(limits map[string]config.RouteLimit, path string) config.RouteLimit {
+	limit := limits[defaultRouteLimitKey]
+	limit.Timeout = 0
+	own, exists := limits[path]
+	if !exists {
+		return limit
+	}
+	if own.MaxBodyBytes > 0 {
+		limit.MaxBodyBytes = own.MaxBodyBytes
+	}
+	if own.MaxQueryLength > 0 {
+		limit.MaxQueryLength = own.MaxQueryLength
+	}
+	if own.MaxQueryParams > 0 {
+		limit.MaxQueryParams = own.MaxQueryParams
+	}
+	limit.Timeout = own.Timeout
+	return limit
+}
+
+// LimitRoute enforces the RouteLimits of the route registered as path, read per request so
+// they can be tuned at runtime. Oversized requests are refused before the handler runs.
+func limitRoute(path string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := routeLimitFor(config.Current().RouteLimits, path)
+
+		if limit.MaxQueryLength > 0 && len(r.URL.RawQuery) > limit.MaxQueryLength {
+			writeProblem(w, r, fmt.Sprintf("Query is longer than %d bytes", limit.MaxQueryLength), http.StatusRequestURITooLong)
+			return
+		}
+		if limit.MaxQueryParams > 0 {
+			count := 0
+			for _, values := range r.URL.Query() {
+				count += len(values)
+			}
+			if count > limit.MaxQueryParams {
+				writeProblem(w, r, fmt.Sprintf("Query has more than %d parameters", limit.MaxQueryParams), http.StatusBadRequest)
+				return
+			}
+		}
+
+		maxBody := limit.MaxBodyBytes
+		if maxBody <= 0 {
+			maxBody = defaultMaxBodyBytes
+		}
+		if r.ContentLength > maxBody {
+			writeProblem(w, r, fmt.Sprintf("Request body is larger than %d bytes", maxBody), http.StatusRequestEntityTooLarge)
+			return
+		}
+		// Bodies without a length are cut off where they pass the limit
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+		if limit.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), time.Duration(limit.Timeout)*time.Second)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
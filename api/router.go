@@ -0,0 +1,68 @@
+package api
+
+import "net/http"
+
+// apiVersionPrefix is where the current API lives; unprefixed paths are deprecated aliases
+const apiVersionPrefix = "/v1"
+
+// problemResponses serves requests through mux, answering those it has no route for with
+// problem details instead of ServeMux's plain text errors. A path registered only for other
+// methods is still 405 Method Not Allowed with its Allow header.
+func problemResponses(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		// Unmatched requests get ServeMux's NotFound or MethodNotAllowed handler, and
+		// only its status and Allow header are kept
+		rejection := &headerRecorder{header: make(http.Header)}
+		handler.ServeHTTP(rejection, r)
+		if rejection.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rejection.header.Get("Allow"))
+			writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeProblem(w, r, "Unknown endpoint", http.StatusNotFound)
+	})
+}
+
+// headerRecorder captures a response's header and status, discarding its body
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (h *headerRecorder) Header() http.Header { return h.header }
+
+func (h *headerRecorder) Write(body []byte) (int, error) {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+	return len(body), nil
+}
+
+func (h *headerRecorder) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+}
+
+// Deprecated marks responses from a legacy path with a pointer to its versioned successor
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SymbolParam returns the market named in the path, falling back to the symbol query
+// parameter used by the legacy endpoints
+func symbolParam(r *http.Request) string {
+	if symbol := r.PathValue("symbol"); symbol != "" {
+		return symbol
+	}
+	return r.URL.Query().Get("symbol")
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleSearch serves type-ahead market search: /search?q=sol[&limit=N][&status=active]
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := query.Get("q")
+	if strings.TrimSpace(q) == "" {
+		writeProblem(w, r, "Missing 'q' parameter", http.StatusBadRequest)
+		return
+	}
+	limit := tracker.DefaultSearchLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > tracker.MaxSearchLimit {
+			writeProblem(w, r, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"matches": s.tracker.SearchMarkets(q, statusFilter(r), limit),
+	})
+}
@@ -0,0 +1,421 @@
+// Package api serves the HTTP API over the data the tracker keeps.
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// Server serves API requests
+type Server struct {
+	tracker    tracker.Tracker
+	users      *UserStore
+	portfolios *tracker.PortfolioStore
+	watchlists *tracker.WatchlistStore
+	paper      *tracker.PaperTrader
+	arbitrage  *tracker.ArbitrageScanner
+	triangular *tracker.TriangularScanner
+	webhooks   *tracker.WebhookDispatcher
+	alerts     *tracker.AlertEngine
+	listings   *tracker.ListingDetector
+	statuses   *tracker.MarketStatusTracker
+	// applyConfig pushes runtime config changes to components that copied settings at startup
+	applyConfig  func(config.Config)
+	certificates *ACMEManager
+	// clients holds the rate limit buckets of API clients
+	clients *ClientLimiter
+	oidc    *OIDCVerifier
+	// responses caches the encoded /ticker and /pairs listings
+	responses *ResponseCache
+	servers   []*http.Server
+	// listeners are the sockets the servers accept on, handed to the new process on upgrade
+	listeners []namedListener
+}
+
+// Components are the tracker and the feature components a server answers from
+type Components struct {
+	Tracker    tracker.Tracker
+	Watchlists *tracker.WatchlistStore
+	Paper      *tracker.PaperTrader
+	Arbitrage  *tracker.ArbitrageScanner
+	Triangular *tracker.TriangularScanner
+	Webhooks   *tracker.WebhookDispatcher
+	Alerts     *tracker.AlertEngine
+	Listings   *tracker.ListingDetector
+	Statuses   *tracker.MarketStatusTracker
+	// ApplyConfig pushes runtime config changes to components that copied settings at startup
+	ApplyConfig  func(config.Config)
+	Certificates *ACMEManager
+}
+
+// NewServer returns a server of components keeping users and portfolios in storage
+func NewServer(store storage.Storage, components Components) *Server {
+	return &Server{
+		tracker:      components.Tracker,
+		users:        newUserStore(store),
+		portfolios:   tracker.NewPortfolioStore(store),
+		watchlists:   components.Watchlists,
+		paper:        components.Paper,
+		arbitrage:    components.Arbitrage,
+		triangular:   components.Triangular,
+		webhooks:     components.Webhooks,
+		alerts:       components.Alerts,
+		listings:     components.Listings,
+		statuses:     components.Statuses,
+		applyConfig:  components.ApplyConfig,
+		certificates: components.Certificates,
+		clients:      newClientLimiter(),
+		oidc:         newOIDCVerifier(),
+		responses:    newResponseCache(),
+	}
+}
+
+func (s *Server) Start() {
+	mux := http.NewServeMux()
+
+	// Every API endpoint lives under /v1; the original unversioned paths remain as
+	// deprecated aliases. Paths with a {symbol} are new and have no alias.
+	liveData := validParams(withHandlerTimeout(http.HandlerFunc(s.handleLiveData)), requiredParam("symbol"))
+	unbounded := math.Inf(1)
+	history := validParams(http.HandlerFunc(s.handleHistory),
+		requiredParam("symbol"), intervalParam("interval"), integerParam("limit", 0, unbounded),
+		integerParam("from", 0, unbounded), integerParam("to", 0, unbounded))
+	candles := validParams(withHandlerTimeout(http.HandlerFunc(s.handleCandles)),
+		requiredParam("symbol"), requiredParam("interval"), intervalParam("interval"), integerParam("limit", 1, tracker.MaxCandleLimit),
+		integerParam("from", 0, unbounded), integerParam("to", 0, unbounded),
+		oneOfParam("type", tracker.CandleTypes...), numberParam("brick", 0, unbounded))
+	gaps := validParams(http.HandlerFunc(s.handleHistoryGaps),
+		requiredParam("symbol"), intervalParam("interval"), integerParam("from", 0, unbounded), integerParam("to", 0, unbounded))
+	aggregates := validParams(http.HandlerFunc(s.handleAggregates),
+		requiredParam("symbol"), requiredParam("timeframe"), oneOfParam("timeframe", config.Current().AggregationTimeframes...),
+		integerParam("limit", 0, unbounded))
+	search := validParams(http.HandlerFunc(s.handleSearch), requiredParam("q"), integerParam("limit", 1, tracker.MaxSearchLimit))
+	convert := validParams(http.HandlerFunc(s.handleConvert),
+		requiredParam("from"), requiredParam("to"), numberParam("amount", 0, unbounded))
+	since := integerParam("since", 0, unbounded)
+	threshold := numberParam("threshold", math.Inf(-1), unbounded)
+	endpoints := []struct {
+		path string
+		// methods are the space separated methods the route serves; GET also serves HEAD
+		methods string
+		handler http.Handler
+		legacy  bool
+	}{
+		{"/livedata", "GET POST", liveData, true},
+		{"/livedata/{symbol}", "GET POST", liveData, false},
+		{"/livedata/stream", "GET", http.HandlerFunc(s.handleLiveDataStream), true},
+		{"/livedata/{symbol}/stream", "GET", http.HandlerFunc(s.handleLiveDataStream), false},
+		{"/livedata/ws", "GET", http.HandlerFunc(s.handleLiveDataSocket), true},
+		{"/livedata/{symbol}/ws", "GET", http.HandlerFunc(s.handleLiveDataSocket), false},
+		{"/pairs", "GET", http.HandlerFunc(s.handlePairs), true},
+		{"/ticker", "GET", http.HandlerFunc(s.handleTicker), true},
+		{"/ticker/{symbol}", "GET", http.HandlerFunc(s.handleTicker), false},
+		{"/history", "GET", history, false},
+		{"/history/{symbol}", "GET", history, false},
+		{"/history/gaps", "GET", gaps, false},
+		{"/history/{symbol}/gaps", "GET", gaps, false},
+		{"/candles", "GET", candles, false},
+		{"/candles/{symbol}", "GET", candles, false},
+		{"/aggregates", "GET", aggregates, false},
+		{"/aggregates/{symbol}", "GET", aggregates, false},
+		{"/status", "GET", http.HandlerFunc(s.handleStatus), true},
+		{"/snapshot", "GET", http.HandlerFunc(s.handleSnapshot), true},
+		{"/convert", "GET", convert, true},
+		{"/markets", "GET", http.HandlerFunc(s.handleMarkets), true},
+		{"/markets/{symbol}", "GET", http.HandlerFunc(s.handleMarkets), false},
+		{"/markets/new", "GET", validParams(http.HandlerFunc(s.handleNewListings), since), false},
+		{"/markets/status-changes", "GET", validParams(http.HandlerFunc(s.handleMarketStatusChanges), since), false},
+		{"/search", "GET", search, false},
+		{"/portfolio", "GET POST PUT DELETE", http.HandlerFunc(s.handlePortfolio), true},
+		{"/portfolio/pnl", "GET", validParams(http.HandlerFunc(s.handlePortfolioPnL),
+			requiredParam("name"), oneOfParam("method", tracker.CostBasisFIFO, tracker.CostBasisAverage)), true},
+		{"/watchlists", "GET POST PUT DELETE", http.HandlerFunc(s.handleWatchlists), true},
+		{"/paper/accounts", "GET POST", http.HandlerFunc(s.handlePaperAccounts), true},
+		{"/paper/orders", "GET POST DELETE", withHandlerTimeout(http.HandlerFunc(s.handlePaperOrders)), true},
+		{"/paper/fills", "GET", http.HandlerFunc(s.handlePaperFills), true},
+		{"/arbitrage", "GET", validParams(http.HandlerFunc(s.handleArbitrage), threshold), true},
+		{"/arbitrage/triangular", "GET", validParams(http.HandlerFunc(s.handleTriangularArbitrage), threshold), true},
+		{"/me", "GET", http.HandlerFunc(s.handleMe), false},
+		{"/alerts", "GET POST", http.HandlerFunc(s.handleAlertRules), false},
+		{"/alerts/{id}", "GET DELETE", http.HandlerFunc(s.handleAlertRules), false},
+		{"/alerts/{id}/history", "GET", validParams(http.HandlerFunc(s.handleAlertHistory), integerParam("limit", 0, unbounded)), false},
+		{"/webhooks", "GET POST", http.HandlerFunc(s.handleWebhooks), false},
+		{"/webhooks/{id}", "GET DELETE", http.HandlerFunc(s.handleWebhooks), false},
+		{"/futures/instruments", "GET", http.HandlerFunc(s.handleFuturesInstruments), false},
+		{"/futures/instruments/{pair}", "GET", withHandlerTimeout(http.HandlerFunc(s.handleFuturesInstruments)), false},
+		{"/futures/prices", "GET", http.HandlerFunc(s.handleFuturesPrices), false},
+		{"/futures/prices/{pair}", "GET", http.HandlerFunc(s.handleFuturesPrices), false},
+		{"/futures/funding", "GET", validParams(http.HandlerFunc(s.handleFuturesFunding),
+			integerParam("from", 0, unbounded), integerParam("to", 0, unbounded), integerParam("limit", 0, unbounded)), false},
+		{"/futures/open-interest", "GET", validParams(http.HandlerFunc(s.handleFuturesOpenInterest),
+			integerParam("from", 0, unbounded), integerParam("to", 0, unbounded), integerParam("limit", 0, unbounded),
+			integerParam("window", 0, unbounded)), false},
+		{"/lending-rates", "GET", validParams(http.HandlerFunc(s.handleLendingRates),
+			integerParam("from", 0, unbounded), integerParam("to", 0, unbounded), integerParam("limit", 0, unbounded)), false},
+	}
+	for _, endpoint := range endpoints {
+		handler := limitRoute(endpoint.path, s.resolvedSymbol(endpoint.handler))
+		for _, method := range strings.Fields(endpoint.methods) {
+			mux.Handle(method+" "+apiVersionPrefix+endpoint.path, handler)
+			if endpoint.legacy {
+				mux.Handle(method+" "+endpoint.path, deprecated(apiVersionPrefix+endpoint.path, handler))
+			}
+		}
+	}
+	// Probes stay unversioned so orchestrator configuration never has to change
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+
+	// Operator endpoints move to their own, optionally mutual-TLS, listener when one is configured
+	cfg := config.Current()
+	admin := s.adminRoutes()
+	if cfg.AdminPort > 0 {
+		s.startAdmin(cfg, logRequests(recoverPanics(filterClients(s.limitClients(admin)))))
+	} else {
+		mux.Handle("/admin/", admin)
+		mux.Handle("/debug/", admin)
+	}
+
+	// Wrap with request logging, panic recovery, client filtering, CORS, rate limiting and
+	// authentication middleware. Clients are limited before Basic auth so guesses cost them.
+	handler := logRequests(recoverPanics(filterClients(enableCORS(s.limitClients(requireBasicAuth(s.trackUsers(s.trackTickerInterest(s.canonicalSymbols(formatTimestamps(problemResponses(mux)))))))))))
+
+	if cfg.UnixSocket != "" {
+		s.serveUnixSocket(cfg, handler)
+	}
+	// A unix socket may be the only listener when no TCP port should be exposed
+	if cfg.Port == 0 {
+		return
+	}
+	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	server := newHTTPServer(address, handler)
+
+	if cfg.CertFile == "" && s.certificates == nil {
+		fmt.Println("Server starting on", address)
+		s.serve(server, "Server", "", "")
+		return
+	}
+
+	server.TLSConfig = serverTLSConfig()
+	if s.certificates != nil {
+		server.TLSConfig = s.certificates.tlsConfig()
+	}
+	fmt.Println("Server starting on", address, "(HTTPS)")
+	s.serve(server, "Server", cfg.CertFile, cfg.KeyFile)
+	if cfg.HTTPRedirectPort > 0 {
+		redirect := newHTTPServer(fmt.Sprintf("%s:%d", cfg.Host, cfg.HTTPRedirectPort), redirectToHTTPS(cfg.Port))
+		fmt.Println("Redirecting HTTP on", redirect.Addr, "to HTTPS")
+		s.serve(redirect, "Redirect server", "", "")
+	}
+}
+
+// NewHTTPServer creates a server with the configured connection timeouts and protocols
+func newHTTPServer(address string, handler http.Handler) *http.Server {
+	cfg := config.Current()
+	seconds := func(n int) time.Duration { return time.Duration(n) * time.Second }
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2)
+	protocols.SetUnencryptedHTTP2(cfg.H2C)
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadTimeout:       seconds(cfg.ReadTimeout),
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeout),
+		WriteTimeout:      seconds(cfg.WriteTimeout),
+		IdleTimeout:       seconds(cfg.IdleTimeout),
+		Protocols:         protocols,
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: cfg.HTTP2MaxConcurrentStreams},
+	}
+}
+
+// WithHandlerTimeout bounds handlers that may wait on upstream by cancelling their context
+// once HandlerTimeout passes. Upstream waits then fail with context.DeadlineExceeded, which
+// handlers report as UPSTREAM_TIMEOUT. A Timeout in the route's RouteLimits takes precedence.
+func withHandlerTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := time.Duration(config.Current().HandlerTimeout) * time.Second
+		if _, limited := r.Context().Deadline(); timeout <= 0 || limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Stop gracefully shuts down every listener, waiting for in-flight requests until ctx is done
+func (s *Server) Stop(ctx context.Context) {
+	for _, server := range s.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			logging.Error("Error shutting down server:", err)
+		}
+	}
+}
+
+func (s *Server) handleLiveData(w http.ResponseWriter, r *http.Request) {
+	// Parse form data if the request is POST
+	if r.Method == http.MethodPost {
+		err := r.ParseForm()
+		if err != nil {
+			writeProblem(w, r, "Failed to parse form data", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Check the path, query parameters and form data for the 'symbol' parameter
+	market := symbolParam(r)
+	if market == "" {
+		market = s.tracker.ResolveSymbol(r.FormValue("symbol")) // Check the form data
+	}
+
+	if market == "" {
+		writeProblem(w, r, "Missing 'symbol' parameter", http.StatusBadRequest)
+		return
+	}
+
+	response, failure := s.tracker.HandleDataRequest(r.Context(), market)
+	if failure != nil {
+		writeError(w, r, failure)
+		return
+	}
+
+	// Optionally convert INR order book prices into another fiat currency
+	view := s.tracker.MarketView()
+	if fiat := r.URL.Query().Get("fiat"); fiat != "" && view.IsINRMarket(market) {
+		rate, ok := view.FiatRate(fiat)
+		if !ok {
+			writeProblem(w, r, "Unsupported 'fiat' parameter", http.StatusBadRequest)
+			return
+		}
+		if orderBook, exists := response["order_book"].(exchange.OrderBook); exists {
+			response["order_book"] = tracker.ConvertOrderBookToFiat(orderBook, rate)
+			response["fiat"] = strings.ToUpper(fiat)
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+// StatusFilter returns the market status a listing request is limited to: its status
+// parameter, or MarketStatusFilter when it has none. An empty result, or "all", matches
+// every market.
+func statusFilter(r *http.Request) string {
+	status := config.Current().MarketStatusFilter
+	if values, exists := r.URL.Query()["status"]; exists {
+		status = values[0]
+	}
+	if strings.EqualFold(status, "all") {
+		return ""
+	}
+	return strings.ToLower(status)
+}
+
+func (s *Server) handlePairs(w http.ResponseWriter, r *http.Request) {
+	status := statusFilter(r)
+	view := s.tracker.MarketView()
+	s.responses.serve(w, r, view, "pairs\x00"+status, view.MarketsModified, func() interface{} {
+		pairs := []string{}
+		for name, pair := range view.Pairs {
+			if view.StatusMatches(name, status) {
+				pairs = append(pairs, pair)
+			}
+		}
+		sort.Strings(pairs)
+		return map[string][]string{"pairs": pairs}
+	})
+}
+
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	format, ok := responseFormat(w, r)
+	if !ok {
+		return
+	}
+
+	// Every ticker, market and rate comes from one view, read without locking
+	view := s.tracker.MarketView()
+
+	// Optionally convert INR prices into another fiat currency
+	fiat := r.URL.Query().Get("fiat")
+	rate := 1.0
+	if fiat != "" {
+		var ok bool
+		rate, ok = view.FiatRate(fiat)
+		if !ok {
+			writeProblem(w, r, "Unsupported 'fiat' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// /v1/ticker/{symbol} returns just that market's ticker; the status filter only
+	// narrows listings
+	symbol := r.PathValue("symbol")
+	status := ""
+	if symbol == "" {
+		status = statusFilter(r)
+	}
+	collect := func() []tracker.TickerView {
+		tickers := []tracker.TickerView{}
+		for _, ticker := range view.Tickers {
+			if symbol != "" && ticker.Market != symbol {
+				continue
+			}
+			if !view.StatusMatches(ticker.Market, status) {
+				continue
+			}
+			if fiat != "" && view.IsINRMarket(ticker.Market) {
+				ticker = tracker.ConvertTickerToFiat(ticker, rate)
+			}
+			tickers = append(tickers, tracker.TickerView{
+				TickerDetails: ticker,
+				Freshness:     tracker.FreshnessOf(view.Times[ticker.Market]),
+			})
+		}
+		// Map order is random, and the same view must encode to the same listing
+		sort.Slice(tickers, func(i, j int) bool { return tickers[i].Market < tickers[j].Market })
+		return tickers
+	}
+	// A listing changes with any ticker and with the market statuses it is filtered by, a
+	// single ticker only with its own refresh
+	modified := tracker.LatestOf(view.TickersModified, view.MarketsModified)
+	if symbol != "" {
+		modified = view.Times[symbol]
+	}
+	if fiat != "" {
+		modified = tracker.LatestOf(modified, view.FXModified)
+	}
+	// The full listing is what dashboards poll, so its encoding is shared between requests
+	if symbol == "" && format == "json" {
+		key := "ticker\x00" + status + "\x00" + strings.ToUpper(fiat)
+		s.responses.serve(w, r, view, key, modified, func() interface{} { return collect() })
+		return
+	}
+
+	tickers := collect()
+
+	if symbol != "" && len(tickers) == 0 {
+		writeError(w, r, tracker.ErrSymbolNotFound)
+		return
+	}
+	if notModified(w, r, modified) {
+		return
+	}
+	if format == "csv" {
+		serveCSV(w, "tickers.csv", TickerCSVHeader, TickerCSVRows(tickers))
+		return
+	}
+	writeJSON(w, tickers[0])
+}
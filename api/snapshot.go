@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandleSnapshot serves /snapshot. The generation doubles as an ETag, so a poller that
+// already has the current generation gets 304 Not Modified, as does one whose
+// If-Modified-Since is no older than the latest change to any dataset.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	generation, modified := s.tracker.SnapshotVersion()
+	if etagMatches(r, snapshotETag(generation)) {
+		w.Header().Set("ETag", snapshotETag(generation))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if notModified(w, r, modified) {
+		return
+	}
+
+	snapshot := s.tracker.Snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", snapshotETag(snapshot.Generation))
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// SnapshotETag is the ETag of the snapshot at generation. It is weak, as generated_at and
+// the freshness of each dataset change between requests for the same generation, and
+// carries the boot ID as generations restart with the process.
+func snapshotETag(generation uint64) string {
+	return fmt.Sprintf(`W/"%s-%d"`, bootID, generation)
+}
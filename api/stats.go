@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	trackerStats := s.tracker.Stats()
+	client := s.tracker.Upstream()
+	refresh, cache, clients := trackerStats.RefreshStats()
+
+	stats := tracker.AdminStats{
+		UptimeSeconds:   int64(time.Since(trackerStats.StartedAt).Seconds()),
+		StartedAt:       trackerStats.StartedAt.UnixNano() / int64(time.Millisecond),
+		Refresh:         refresh,
+		Upstream:        client.State.Health(),
+		UpstreamLatency: client.Latency.Stats(),
+		Markets:         len(s.tracker.MarketView().Markets),
+		OrderBooks:      s.tracker.OrderBookCount(),
+		Clients:         clients,
+		Cache:           cache,
+		Goroutines:      runtime.NumGoroutine(),
+	}
+	if config.Current().AdaptiveRefresh {
+		stats.AdaptiveIntervalsMs = make(map[string]int64)
+		for _, symbol := range s.tracker.OrderBookSymbols() {
+			stats.AdaptiveIntervalsMs[symbol] = s.tracker.Volatility().RefreshInterval(symbol, tracker.RefreshInterval()).Milliseconds()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
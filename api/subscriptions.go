@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// TrackTickerInterest marks tickers as wanted whenever an endpoint other than /livedata is used
+func (s *Server) trackTickerInterest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(strings.TrimPrefix(r.URL.Path, apiVersionPrefix), "/livedata") {
+			s.tracker.Subscriptions().Touch(tracker.AllMarketsKey)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
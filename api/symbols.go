@@ -0,0 +1,30 @@
+package api
+
+import "net/http"
+
+// CanonicalSymbols rewrites the symbol query parameter of every request to the market's
+// coindcx_name, so handlers only ever see canonical symbols. Path segments are resolved
+// by resolvedSymbol.
+func (s *Server) canonicalSymbols(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if values, exists := query["symbol"]; exists {
+			for i, value := range values {
+				values[i] = s.tracker.ResolveSymbol(value)
+			}
+			r.URL.RawQuery = query.Encode()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ResolvedSymbol rewrites the {symbol} path parameter, when the route has one, to the
+// market's coindcx_name before next sees it
+func (s *Server) resolvedSymbol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if symbol := r.PathValue("symbol"); symbol != "" {
+			r.SetPathValue("symbol", s.tracker.ResolveSymbol(symbol))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+var timestampFormats = []string{config.TimestampsEpoch, config.TimestampsRFC3339, config.TimestampsBoth}
+
+// timestampFields are the JSON fields holding epoch timestamps. A field holding an object,
+// such as last_refreshed_at, has every numeric value in it converted.
+var timestampFields = map[string]bool{
+	"timestamp": true, "last_updated": true, "created_at": true, "updated_at": true,
+	"detected_at": true, "changed_at": true, "status_changed_at": true, "generated_at": true,
+	"started_at": true, "sent_at": true, "last_attempt": true, "last_success": true,
+	"last_triggered": true, "last_probe_at": true, "last_refreshed_at": true,
+	"last_error_at": true, "last_success_at": true, "throttled_until": true,
+}
+
+// epochSecondsLimit separates epochs in seconds from epochs in milliseconds: as seconds it
+// is the year 5138, as milliseconds 1973
+const epochSecondsLimit = 1e11
+
+// FormatTimestamps rewrites the epoch timestamps of JSON responses as RFC 3339 when the
+// request's timestamps parameter, or TimestampFormat, asks for it. A tz parameter naming
+// an IANA zone, e.g. tz=Asia/Kolkata, renders the times in that zone, and implies both when
+// the format would otherwise be epochs. Other responses, including streams, pass through.
+func formatTimestamps(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := strings.ToLower(config.Current().TimestampFormat)
+		if value := query.Get("timestamps"); value != "" {
+			format = strings.ToLower(value)
+			valid := false
+			for _, known := range timestampFormats {
+				valid = valid || format == known
+			}
+			if !valid {
+				writeProblem(w, r, "Invalid 'timestamps' parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		location := time.UTC
+		if tz := query.Get("tz"); tz != "" {
+			var err error
+			if location, err = time.LoadLocation(tz); err != nil {
+				writeProblem(w, r, "Invalid 'tz' parameter", http.StatusBadRequest)
+				return
+			}
+			if format == config.TimestampsEpoch || format == "" {
+				format = config.TimestampsBoth
+			}
+		}
+		if format == config.TimestampsEpoch || format == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &timestampWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r)
+		if !writer.buffering {
+			return
+		}
+		body := writer.body.Bytes()
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var document interface{}
+		if err := decoder.Decode(&document); err == nil {
+			if converted, err := json.Marshal(convertTimestamps(document, format == config.TimestampsBoth, location)); err == nil {
+				body = append(converted, '\n')
+			}
+		}
+		w.Header().Del("Content-Length")
+		// An ETag names the epoch body, not this rewritten one
+		w.Header().Del("ETag")
+		w.WriteHeader(writer.status)
+		w.Write(body)
+	})
+}
+
+// ConvertTimestamps walks a decoded JSON document replacing epoch timestamp fields
+func convertTimestamps(value interface{}, keepEpochs bool, location *time.Location) interface{} {
+	switch typed := value.(type) {
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = convertTimestamps(item, keepEpochs, location)
+		}
+	case map[string]interface{}:
+		for key, item := range typed {
+			if !timestampFields[key] {
+				typed[key] = convertTimestamps(item, keepEpochs, location)
+				continue
+			}
+			if nested, ok := item.(map[string]interface{}); ok {
+				for name, child := range nested {
+					if formatted, ok := formatEpoch(child, location); ok {
+						nested[name] = formatted
+					}
+				}
+				continue
+			}
+			if formatted, ok := formatEpoch(item, location); ok {
+				typed[key] = formatted
+				if keepEpochs {
+					typed[key+"_epoch"] = item
+				}
+			}
+		}
+	}
+	return value
+}
+
+// FormatEpoch renders a positive epoch in seconds or milliseconds as RFC 3339
+func formatEpoch(value interface{}, location *time.Location) (string, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return "", false
+	}
+	epoch, err := number.Int64()
+	if err != nil || epoch <= 0 {
+		return "", false
+	}
+	at := time.UnixMilli(epoch)
+	if epoch < epochSecondsLimit {
+		at = time.Unix(epoch, 0)
+	}
+	return at.In(location).Format(time.RFC3339Nano), true
+}
+
+// timestampWriter buffers JSON responses so their timestamps can be rewritten, and passes
+// anything else straight through
+type timestampWriter struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (t *timestampWriter) WriteHeader(status int) {
+	if t.status != 0 {
+		return
+	}
+	t.status = status
+	t.buffering = strings.HasPrefix(t.Header().Get("Content-Type"), "application/json")
+	if !t.buffering {
+		t.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (t *timestampWriter) Write(data []byte) (int, error) {
+	if t.status == 0 {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.buffering {
+		return t.body.Write(data)
+	}
+	return t.ResponseWriter.Write(data)
+}
+
+func (t *timestampWriter) Flush() {
+	if flusher, ok := t.ResponseWriter.(http.Flusher); ok && !t.buffering {
+		flusher.Flush()
+	}
+}
+
+func (t *timestampWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := t.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer cannot be hijacked")
+	}
+	return hijacker.Hijack()
+}
+
+func (t *timestampWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
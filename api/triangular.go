@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleTriangularArbitrage(w http.ResponseWriter, r *http.Request) {
+	threshold := tracker.ArbitrageThreshold()
+	if raw := r.URL.Query().Get("threshold"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			writeProblem(w, r, "Invalid 'threshold' parameter", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+
+	cycles, scannedAt := s.triangular.Above(threshold)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threshold_percent": threshold,
+		"fee_rate":          tracker.ArbitrageFeeRate(),
+		"scanned_at":        scannedAt.UnixNano() / int64(time.Millisecond),
+		"cycles":            cycles,
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// RemoveStaleSocket deletes a socket file left behind by a process that did not shut down
+// cleanly, which would otherwise stop the listener from binding. Other files are kept.
+func removeStaleSocket(path string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+// ParseSocketMode reads an octal permission string such as "0660"
+func parseSocketMode(mode string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || bits > 0777 {
+		return 0, fmt.Errorf("%q is not an octal permission mode", mode)
+	}
+	return os.FileMode(bits), nil
+}
+
+// LookupGroupID resolves a group name or numeric ID
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	found, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(found.Gid)
+}
+
+// ServeUnixSocket serves handler as plain HTTP on UnixSocket, for a reverse proxy on the
+// same host, with the socket file given UnixSocketMode and, when set, UnixSocketGroup
+func (s *Server) serveUnixSocket(cfg config.Config, handler http.Handler) {
+	server := newHTTPServer(cfg.UnixSocket, handler)
+	listener, err := s.listen("unix", cfg.UnixSocket)
+	if err != nil {
+		logging.Error("Unix socket server error:", err)
+		return
+	}
+	mode, _ := parseSocketMode(cfg.UnixSocketMode)
+	if err := os.Chmod(cfg.UnixSocket, mode); err != nil {
+		logging.Error("Error setting unix socket permissions:", err)
+	}
+	if cfg.UnixSocketGroup != "" {
+		gid, err := lookupGroupID(cfg.UnixSocketGroup)
+		if err == nil {
+			err = os.Chown(cfg.UnixSocket, -1, gid)
+		}
+		if err != nil {
+			logging.Error("Error setting unix socket group:", err)
+		}
+	}
+	fmt.Println("Server starting on unix socket", cfg.UnixSocket)
+	s.serveListener(server, listener, "Unix socket server", "", "")
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package api
+
+// WatchUpgrades never fires where listening sockets cannot be handed to a child process
+func (s *Server) WatchUpgrades() <-chan struct{} {
+	return nil
+}
+
+// NotifyUpgradeReady has no process to notify without upgrade support
+func NotifyUpgradeReady() {}
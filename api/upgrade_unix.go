@@ -0,0 +1,129 @@
+//go:build unix
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// upgradeReadyTimeout is how long a new process may take to start serving before the
+// upgrade is abandoned and the old process carries on
+const upgradeReadyTimeout = time.Minute
+
+// fileListener is a listener whose socket can be handed to another process
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// WatchUpgrades starts a new copy of the binary on every SIGUSR2, handing it the listening
+// sockets. The returned channel is closed once the new process is serving, when this one
+// should stop accepting, finish its requests and exit.
+func (s *Server) WatchUpgrades() <-chan struct{} {
+	upgraded := make(chan struct{})
+	requests := make(chan os.Signal, 1)
+	signal.Notify(requests, syscall.SIGUSR2)
+	go func() {
+		for range requests {
+			if err := s.upgrade(); err != nil {
+				logging.Error("Upgrade failed, still serving:", err)
+				continue
+			}
+			signal.Stop(requests)
+			close(upgraded)
+			return
+		}
+	}()
+	return upgraded
+}
+
+// Upgrade starts the binary at its current path with this process's arguments and
+// listening sockets, and waits for it to report that it is serving
+func (s *Server) upgrade() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	files := []*os.File{}
+	addresses := []string{}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, named := range s.listeners {
+		listener, ok := named.listener.(fileListener)
+		if !ok {
+			return fmt.Errorf("listener for %s cannot be handed over", named.address)
+		}
+		file, err := listener.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		addresses = append(addresses, named.address)
+	}
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	command := exec.Command(executable, os.Args[1:]...)
+	command.Stdout, command.Stderr = os.Stdout, os.Stderr
+	command.ExtraFiles = append(files, readyWriter)
+	command.Env = append(os.Environ(),
+		inheritedListenersEnv+"="+strings.Join(addresses, ","),
+		upgradeReadyEnv+"="+strconv.Itoa(3+len(files)))
+	err = command.Start()
+	readyWriter.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Upgrading to process", command.Process.Pid)
+
+	// The pipe reaches EOF once the new process closes it when serving, or when it exits
+	signalled := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, ready)
+		signalled <- err
+	}()
+	exited := make(chan error, 1)
+	go func() { exited <- command.Wait() }()
+	select {
+	case err := <-signalled:
+		if err != nil {
+			return err
+		}
+		// Give a process that exited rather than became ready a moment to be reaped
+		select {
+		case err := <-exited:
+			return fmt.Errorf("new process exited: %v", err)
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	case <-time.After(upgradeReadyTimeout):
+		command.Process.Kill()
+		return errors.New("new process did not start serving in time")
+	}
+}
+
+// NotifyUpgradeReady tells the process this one replaces that it is serving
+func NotifyUpgradeReady() {
+	fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnv))
+	os.Unsetenv(upgradeReadyEnv)
+	if err != nil {
+		return
+	}
+	os.NewFile(uintptr(fd), "upgrade-ready").Close()
+}
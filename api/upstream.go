@@ -0,0 +1,23 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	upstream := s.tracker.Upstream().State.Health()
+	status := "ok"
+	switch {
+	case upstream.Throttled:
+		status = "throttled"
+	case upstream.ConsecutiveFailures > 0:
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"upstream": upstream,
+	})
+}
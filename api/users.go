@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const usersStorageKey = "users"
+
+// userKey is the context key under which the user a request acts as is stored
+type userKey struct{}
+
+// User is a client that owns watchlists, alerts and portfolios. Users are named API keys,
+// as key:NAME, and Basic auth usernames, as basic:NAME. Anonymous clients and admins are no
+// user and share the resources every client could see before users existed.
+type User struct {
+	ID        string `json:"id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// WithUser records the user a request acts as
+func withUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+}
+
+// RequestUser returns the user a request acts as, or "" for the shared resources
+func requestUser(r *http.Request) string {
+	user, _ := r.Context().Value(userKey{}).(string)
+	return user
+}
+
+// UserStore records the users that have been seen and persists them to storage
+type UserStore struct {
+	storage storage.Storage
+	users   map[string]User
+	mutex   sync.RWMutex
+}
+
+func newUserStore(backend storage.Storage) *UserStore {
+	store := &UserStore{
+		storage: backend,
+		users:   make(map[string]User),
+	}
+	err := backend.Load(usersStorageKey, &store.users)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading users:", err)
+	}
+	return store
+}
+
+// Touch records a user the first time they make a request
+func (s *UserStore) touch(id string) {
+	s.mutex.RLock()
+	_, exists := s.users[id]
+	s.mutex.RUnlock()
+	if exists {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.users[id]; exists {
+		return
+	}
+	s.users[id] = User{ID: id, CreatedAt: time.Now().UnixNano() / int64(time.Millisecond)}
+	if err := s.storage.Save(usersStorageKey, s.users); err != nil {
+		logging.Error("Error saving users:", err)
+	}
+}
+
+func (s *UserStore) list() []User {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	users := []User{}
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// TrackUsers records the user of each request, once limitClients and requireBasicAuth
+// have established who it is
+func (s *Server) trackUsers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := requestUser(r); user != "" {
+			s.users.touch(user)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]User{"users": s.users.list()})
+}
+
+// HandleMe describes the user a request acts as
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": requestUser(r), "shared": requestUser(r) == ""})
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// Kinds of parameter value
+const (
+	paramText = iota
+	paramInteger
+	paramNumber
+	// paramInterval is a candle interval tracker.ParseCandleInterval accepts
+	paramInterval
+)
+
+// paramRule declares a parameter a route accepts in its path, query or form
+type paramRule struct {
+	name     string
+	required bool
+	// values, when set, are the only values accepted
+	values []string
+	kind   int
+	// min and max bound integers and numbers
+	min, max float64
+}
+
+// InvalidParam is one parameter that failed validation and why
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func requiredParam(name string) paramRule {
+	return paramRule{name: name, required: true}
+}
+
+func oneOfParam(name string, values ...string) paramRule {
+	return paramRule{name: name, values: values}
+}
+
+func integerParam(name string, min, max float64) paramRule {
+	return paramRule{name: name, kind: paramInteger, min: min, max: max}
+}
+
+func numberParam(name string, min, max float64) paramRule {
+	return paramRule{name: name, kind: paramNumber, min: min, max: max}
+}
+
+func intervalParam(name string) paramRule {
+	return paramRule{name: name, kind: paramInterval}
+}
+
+// Check returns why a parameter's value is invalid, or "" when it is valid
+func (p paramRule) check(value string, present bool) string {
+	if !present {
+		if p.required {
+			return "is required"
+		}
+		return ""
+	}
+	if len(p.values) > 0 {
+		for _, allowed := range p.values {
+			if strings.EqualFold(value, allowed) {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(p.values, ", ")
+	}
+	if p.kind == paramText {
+		return ""
+	}
+	if p.kind == paramInterval {
+		if _, ok := tracker.ParseCandleInterval(value); !ok {
+			return "must be an interval from 1m to 1w, such as 5m, 4h or 1d"
+		}
+		return ""
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if p.kind == paramInteger {
+		var integer int64
+		integer, err = strconv.ParseInt(value, 10, 64)
+		number = float64(integer)
+	}
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		if p.kind == paramInteger {
+			return "must be an integer"
+		}
+		return "must be a number"
+	}
+	switch {
+	case !math.IsInf(p.max, 1) && (number < p.min || number > p.max):
+		return fmt.Sprintf("must be between %g and %g", p.min, p.max)
+	case !math.IsInf(p.min, -1) && number < p.min:
+		return fmt.Sprintf("must be at least %g", p.min)
+	}
+	return ""
+}
+
+// ParamValue looks a parameter up in the path, then the query and, for form posts, the
+// form. Blank values count as missing.
+func paramValue(r *http.Request, name string) (string, bool) {
+	value := r.PathValue(name)
+	if value == "" {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			value = r.FormValue(name)
+		} else {
+			value = r.URL.Query().Get(name)
+		}
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+// ValidParams checks a route's parameters before its handler runs, answering 400 with
+// every invalid parameter listed in invalid_params rather than stopping at the first
+func validParams(next http.Handler, rules ...paramRule) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invalid := []InvalidParam{}
+		for _, rule := range rules {
+			value, present := paramValue(r, rule.name)
+			if reason := rule.check(value, present); reason != "" {
+				invalid = append(invalid, InvalidParam{Name: rule.name, Reason: reason})
+			}
+		}
+		if len(invalid) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		names := make([]string, len(invalid))
+		for i, param := range invalid {
+			names[i] = "'" + param.Name + "'"
+		}
+		problem := newProblem(r, tracker.CodeInvalidParameters, "Invalid "+strings.Join(names, ", ")+" parameter", http.StatusBadRequest)
+		if len(invalid) > 1 {
+			problem.Detail += "s"
+		}
+		problem.InvalidParams = invalid
+		sendProblem(w, problem)
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+// HandleWatchlists manages the watchlists of the user a request acts as
+func (s *Server) handleWatchlists(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	user := requestUser(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string][]tracker.Watchlist{"watchlists": s.watchlists.List(user)})
+			return
+		}
+		watchlist, exists := s.watchlists.Get(user, name)
+		if !exists {
+			writeProblem(w, r, "Watchlist not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.tracker.WatchlistView(watchlist))
+
+	case http.MethodPost, http.MethodPut:
+		var watchlist tracker.Watchlist
+		if err := json.NewDecoder(r.Body).Decode(&watchlist); err != nil {
+			writeProblem(w, r, "Failed to parse watchlist", http.StatusBadRequest)
+			return
+		}
+		for i, symbol := range watchlist.Symbols {
+			watchlist.Symbols[i] = s.tracker.ResolveSymbol(symbol)
+		}
+		if err := tracker.NormalizeWatchlist(&watchlist); err != nil {
+			writeProblem(w, r, "Invalid watchlist: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		watchlist.Owner = user
+		if err := s.watchlists.Put(watchlist); err != nil {
+			logging.Error("Error saving watchlists:", err)
+			writeProblem(w, r, "Failed to save watchlist", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.tracker.WatchlistView(watchlist))
+
+	case http.MethodDelete:
+		if name == "" {
+			writeProblem(w, r, "Missing 'name' parameter", http.StatusBadRequest)
+			return
+		}
+		removed, err := s.watchlists.Remove(user, name)
+		if err != nil {
+			logging.Error("Error saving watchlists:", err)
+			writeProblem(w, r, "Failed to delete watchlist", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			writeProblem(w, r, "Watchlist not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]tracker.WebhookSubscription{"webhooks": s.webhooks.List()})
+
+	case r.Method == http.MethodGet:
+		subscription, exists := s.webhooks.Get(id)
+		if !exists {
+			writeProblem(w, r, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(subscription)
+
+	case r.Method == http.MethodPost && id == "":
+		var subscription tracker.WebhookSubscription
+		if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+			writeProblem(w, r, "Failed to parse webhook", http.StatusBadRequest)
+			return
+		}
+		for i, symbol := range subscription.Symbols {
+			subscription.Symbols[i] = s.tracker.ResolveSymbol(symbol)
+		}
+		if err := tracker.NormalizeWebhook(&subscription); err != nil {
+			writeProblem(w, r, "Invalid webhook: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		subscription, err := s.webhooks.Create(subscription)
+		if err != nil {
+			logging.Error("Error saving webhooks:", err)
+			writeProblem(w, r, "Failed to save webhook", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(subscription)
+
+	case r.Method == http.MethodDelete && id != "":
+		removed, err := s.webhooks.Remove(id)
+		if err != nil {
+			logging.Error("Error saving webhooks:", err)
+			writeProblem(w, r, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			writeProblem(w, r, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeProblem(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
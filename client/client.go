@@ -0,0 +1,261 @@
+// Package client is a Go client for the CryptoTracker API. It covers the versioned /v1
+// endpoints with typed methods, retries transient failures and honours context
+// cancellation on every call.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 15 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
+
+// ErrNotFound is returned when the server has no data for the requested market
+var ErrNotFound = errors.New("cryptotracker: not found")
+
+// APIError is a non-success response from the server. Servers describe errors as RFC 7807
+// problems; Message is the problem's detail, or the whole body of any other response.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Type is the problem type URI, about:blank when the status says it all
+	Type string
+	// Code classifies the error, e.g. STALE_DATA or RATE_LIMITED, when the server gives one
+	Code string
+	// RequestID identifies the request in the server's logs
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cryptotracker: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Retryable reports whether the request may succeed if repeated
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client calls a CryptoTracker server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client, e.g. to add TLS settings
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken sends a bearer token with every request
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetries sets how many times a failed request is retried and the initial delay,
+// which doubles after every attempt
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// GetTickers returns the ticker of every market
+func (c *Client) GetTickers(ctx context.Context) ([]Ticker, error) {
+	var tickers []Ticker
+	err := c.getJSON(ctx, "/v1/ticker", nil, &tickers)
+	return tickers, err
+}
+
+// GetTicker returns one market's ticker
+func (c *Client) GetTicker(ctx context.Context, symbol string) (Ticker, error) {
+	var ticker Ticker
+	err := c.getJSON(ctx, "/v1/ticker/"+url.PathEscape(symbol), nil, &ticker)
+	return ticker, err
+}
+
+// GetMarkets returns every market with its metadata
+func (c *Client) GetMarkets(ctx context.Context) ([]Market, error) {
+	var markets []Market
+	err := c.getJSON(ctx, "/v1/markets", nil, &markets)
+	return markets, err
+}
+
+// GetMarket returns one market with its metadata
+func (c *Client) GetMarket(ctx context.Context, symbol string) (Market, error) {
+	var market Market
+	err := c.getJSON(ctx, "/v1/markets/"+url.PathEscape(symbol), nil, &market)
+	return market, err
+}
+
+// GetOrderBook returns a market's order book, optionally converted to a fiat currency
+func (c *Client) GetOrderBook(ctx context.Context, symbol string, fiat string) (OrderBookResponse, error) {
+	query := url.Values{}
+	if fiat != "" {
+		query.Set("fiat", fiat)
+	}
+	var response OrderBookResponse
+	if err := c.getJSON(ctx, "/v1/livedata/"+url.PathEscape(symbol), query, &response); err != nil {
+		return response, err
+	}
+	// The server answers unknown markets with an empty object
+	if response.Pair == "" {
+		return response, ErrNotFound
+	}
+	return response, nil
+}
+
+// GetSnapshot returns one consistent generation of tickers, markets and FX rates
+func (c *Client) GetSnapshot(ctx context.Context) (Snapshot, error) {
+	var snapshot Snapshot
+	err := c.getJSON(ctx, "/v1/snapshot", nil, &snapshot)
+	return snapshot, err
+}
+
+// GetStatus returns upstream health and dataset freshness
+func (c *Client) GetStatus(ctx context.Context) (Status, error) {
+	var status Status
+	err := c.getJSON(ctx, "/v1/status", nil, &status)
+	return status, err
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, target interface{}) error {
+	body, _, err := c.get(ctx, path, query, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, target)
+}
+
+// Get performs a GET with retries, returning the body and response headers. A 304 Not
+// Modified response returns a nil body and no error.
+func (c *Client) get(ctx context.Context, path string, query url.Values, header http.Header) ([]byte, http.Header, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		body, responseHeader, err := c.do(ctx, endpoint, header)
+		if err == nil || attempt >= c.maxRetries || ctx.Err() != nil || !retryable(err) {
+			return body, responseHeader, err
+		}
+		var hinted *retryAfterError
+		if errors.As(err, &hinted) && hinted.after > delay {
+			delay = hinted.after
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// retryAfterError carries a Retry-After hint alongside the API error it wraps
+type retryAfterError struct {
+	*APIError
+	after time.Duration
+}
+
+func (e *retryAfterError) Unwrap() error { return e.APIError }
+
+func (c *Client) do(ctx context.Context, endpoint string, header http.Header) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, resp.Header, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil, ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+			var problem struct {
+				Type      string `json:"type"`
+				Detail    string `json:"detail"`
+				Code      string `json:"code"`
+				RequestID string `json:"request_id"`
+			}
+			if json.Unmarshal(body, &problem) == nil {
+				apiErr.Message, apiErr.Type, apiErr.RequestID = problem.Detail, problem.Type, problem.RequestID
+				apiErr.Code = problem.Code
+			}
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			return nil, nil, &retryAfterError{APIError: apiErr, after: time.Duration(seconds) * time.Second}
+		}
+		return nil, nil, apiErr
+	}
+	return body, resp.Header, nil
+}
+
+// Retryable reports whether an error is worth retrying: network failures and 429 or 5xx
+// responses are, anything the caller caused is not
+func retryable(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for a server answering with handler, retrying quickly
+func newTestClient(t *testing.T, handler http.HandlerFunc, options ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, append([]Option{WithRetries(3, time.Millisecond)}, options...)...)
+}
+
+// failingThen answers the first failures requests with status and later ones with body,
+// counting every request in attempts
+func failingThen(failures int32, status int, header http.Header, body string, attempts *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func TestGetTicker(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/v1/ticker/BTC%2FINR" {
+			t.Errorf("path = %q, want /v1/ticker/BTC%%2FINR", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want Bearer secret", got)
+		}
+		w.Write([]byte(`{"market":"BTC/INR","last_price":"5000000","bid":"4999000","timestamp":1700000000,"stale":true}`))
+	}, WithToken("secret"))
+
+	ticker, err := client.GetTicker(context.Background(), "BTC/INR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Market != "BTC/INR" || ticker.LastPrice != "5000000" || ticker.Timestamp != 1700000000 || !ticker.Stale {
+		t.Errorf("ticker = %+v", ticker)
+	}
+	if string(ticker.Bid) != `"4999000"` {
+		t.Errorf("bid = %s, want \"4999000\"", ticker.Bid)
+	}
+}
+
+func TestGetOrderBook(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/livedata/BTCINR" {
+			t.Errorf("path = %q, want /v1/livedata/BTCINR", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("fiat"); got != "USD" {
+			t.Errorf("fiat = %q, want USD", got)
+		}
+		w.Write([]byte(`{"pair":"BTCINR","order_book":{"bids":{"100":"2"},"asks":{"101":"3"}},"cached":true,"age_ms":250,"fiat":"USD"}`))
+	})
+
+	book, err := client.GetOrderBook(context.Background(), "BTCINR", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Pair != "BTCINR" || !book.Cached || book.AgeMs != 250 || book.Fiat != "USD" {
+		t.Errorf("order book = %+v", book)
+	}
+	if book.OrderBook.Bids["100"] != "2" || book.OrderBook.Asks["101"] != "3" {
+		t.Errorf("levels = %+v", book.OrderBook)
+	}
+}
+
+func TestGetOrderBookNotFound(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"empty object", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{}`)) }},
+		{"404", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				test.handler(w, r)
+			})
+			if _, err := client.GetOrderBook(context.Background(), "NOPE", ""); !errors.Is(err, ErrNotFound) {
+				t.Errorf("error = %v, want ErrNotFound", err)
+			}
+			if got := attempts.Load(); got != 1 {
+				t.Errorf("attempts = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		status       int
+		wantAttempts int32
+		wantStatus   int
+	}{
+		{"recovers from 503", 2, http.StatusServiceUnavailable, 3, 0},
+		{"recovers from 429", 1, http.StatusTooManyRequests, 2, 0},
+		{"gives up after max retries", 10, http.StatusBadGateway, 4, http.StatusBadGateway},
+		{"does not retry 400", 10, http.StatusBadRequest, 1, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			client := newTestClient(t, failingThen(test.failures, test.status, nil, `{"market":"BTCINR"}`, &attempts))
+
+			ticker, err := client.GetTicker(context.Background(), "BTCINR")
+			if got := attempts.Load(); got != test.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, test.wantAttempts)
+			}
+			if test.wantStatus == 0 {
+				if err != nil || ticker.Market != "BTCINR" {
+					t.Errorf("GetTicker = %+v, %v", ticker, err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.wantStatus {
+				t.Errorf("error = %v, want status %d", err, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestProblemResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"type":"urn:cryptotracker:error:STALE_DATA","detail":"Ticker data is stale","code":"STALE_DATA","request_id":"abc123"}`))
+	}, WithRetries(0, time.Millisecond))
+
+	_, err := client.GetTicker(context.Background(), "BTCINR")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want an APIError", err)
+	}
+	if apiErr.Message != "Ticker data is stale" || apiErr.Code != "STALE_DATA" || apiErr.RequestID != "abc123" ||
+		apiErr.Type != "urn:cryptotracker:error:STALE_DATA" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	header := http.Header{"Retry-After": {"1"}}
+	client := newTestClient(t, failingThen(1, http.StatusTooManyRequests, header, `{"pair":"BTCINR"}`, &attempts))
+
+	started := time.Now()
+	if _, err := client.GetOrderBook(context.Background(), "BTCINR", ""); err != nil {
+		t.Fatal(err)
+	}
+	// The retry waits out the hint rather than the millisecond retry delay
+	if elapsed := time.Since(started); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("while waiting to retry", func(t *testing.T) {
+		var attempts atomic.Int32
+		header := http.Header{"Retry-After": {"30"}}
+		client := newTestClient(t, failingThen(10, http.StatusServiceUnavailable, header, "", &attempts))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		if _, err := client.GetTicker(ctx, "BTCINR"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(started); elapsed > 5*time.Second {
+			t.Errorf("returned after %v, want it to stop at the deadline", elapsed)
+		}
+		if got := attempts.Load(); got != 1 {
+			t.Errorf("attempts = %d, want 1", got)
+		}
+	})
+
+	t.Run("during a request", func(t *testing.T) {
+		var attempts atomic.Int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			<-r.Context().Done()
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		if _, err := client.GetOrderBook(ctx, "BTCINR", ""); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if got := attempts.Load(); got != 1 {
+			t.Errorf("attempts = %d, want 1", got)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultPollInterval = 2 * time.Second
+
+// StreamOrderBook calls handle with every new version of a market's order book, read
+// from the server-sent event stream. Dropped connections are re-established with
+// backoff. It returns when ctx is cancelled, the market is unknown or handle fails.
+func (c *Client) StreamOrderBook(ctx context.Context, symbol string, handle func(OrderBookUpdate) error) error {
+	delay := c.retryDelay
+	for {
+		received, err := c.streamOrderBookOnce(ctx, symbol, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, isHandlerErr := err.(handlerError); isHandlerErr || !retryable(err) {
+			return err
+		}
+		// A connection that delivered updates resets the backoff
+		if received {
+			delay = c.retryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// handlerError marks an error returned by the caller's handler, which ends a stream
+type handlerError struct{ error }
+
+func (e handlerError) Unwrap() error { return e.error }
+
+func (c *Client) streamOrderBookOnce(ctx context.Context, symbol string, handle func(OrderBookUpdate) error) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/livedata/"+url.PathEscape(symbol)+"/stream", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// Streams outlive any overall request timeout; ctx bounds them instead
+	streaming := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := streaming.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+
+	received := false
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event == "orderbook" && data != "" {
+				var update OrderBookUpdate
+				if err := json.Unmarshal([]byte(data), &update); err != nil {
+					return received, fmt.Errorf("cryptotracker: decoding stream event: %v", err)
+				}
+				received = true
+				if err := handle(update); err != nil {
+					return received, handlerError{err}
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, fmt.Errorf("cryptotracker: stream closed by server")
+}
+
+// StreamTickers calls handle with the tickers that changed each time the server's data
+// moves to a new generation, polling /v1/snapshot every interval (default 2s). The
+// first call carries every ticker. Unchanged generations cost a 304 response.
+// It returns when ctx is cancelled or handle fails.
+func (c *Client) StreamTickers(ctx context.Context, interval time.Duration, handle func([]Ticker) error) error {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	etag := ""
+	seen := make(map[string]Ticker)
+	for {
+		header := http.Header{}
+		if etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		body, responseHeader, err := c.get(ctx, "/v1/snapshot", nil, header)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !retryable(err) {
+			return err
+		}
+		if err == nil && body != nil {
+			var snapshot Snapshot
+			if err := json.Unmarshal(body, &snapshot); err != nil {
+				return err
+			}
+			etag = responseHeader.Get("ETag")
+
+			changed := []Ticker{}
+			for _, ticker := range snapshot.Tickers {
+				previous, exists := seen[ticker.Market]
+				if !exists || previous.LastPrice != ticker.LastPrice || previous.Timestamp != ticker.Timestamp {
+					changed = append(changed, ticker)
+				}
+				seen[ticker.Market] = ticker
+			}
+			if len(changed) > 0 {
+				if err := handle(changed); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import "encoding/json"
+
+// Freshness tells when data was last refreshed on the server
+type Freshness struct {
+	LastUpdated int64   `json:"last_updated"`
+	AgeSeconds  float64 `json:"age_seconds"`
+	Stale       bool    `json:"stale"`
+}
+
+// Ticker is a market's latest ticker
+type Ticker struct {
+	Market       string          `json:"market"`
+	Change24Hour string          `json:"change_24_hour"`
+	High         string          `json:"high"`
+	Low          string          `json:"low"`
+	Volume       string          `json:"volume"`
+	LastPrice    string          `json:"last_price"`
+	Bid          json.RawMessage `json:"bid"`
+	Ask          json.RawMessage `json:"ask"`
+	Timestamp    int64           `json:"timestamp"`
+	Freshness
+}
+
+// OrderBook maps price levels to quantities, both as decimal strings
+type OrderBook struct {
+	Bids map[string]string `json:"bids"`
+	Asks map[string]string `json:"asks"`
+}
+
+// OrderBookResponse is the order book of one market as returned by /v1/livedata
+type OrderBookResponse struct {
+	Pair      string    `json:"pair"`
+	OrderBook OrderBook `json:"order_book"`
+	Cached    bool      `json:"cached"`
+	AgeMs     int64     `json:"age_ms"`
+	Fiat      string    `json:"fiat,omitempty"`
+	Freshness
+}
+
+// OrderBookUpdate is one pushed order book version from the live stream
+type OrderBookUpdate struct {
+	Pair      string    `json:"pair"`
+	OrderBook OrderBook `json:"order_book"`
+	AgeMs     int64     `json:"age_ms"`
+	Freshness
+}
+
+// CoinMetadata is descriptive coin information
+type CoinMetadata struct {
+	ID                string  `json:"id"`
+	Symbol            string  `json:"symbol"`
+	Name              string  `json:"name"`
+	Image             string  `json:"image"`
+	MarketCap         float64 `json:"market_cap"`
+	CirculatingSupply float64 `json:"circulating_supply"`
+}
+
+// Market is a market's trading rules with optional coin metadata
+type Market struct {
+	CoindcxName             string        `json:"coindcx_name"`
+	BaseCurrencyShortName   string        `json:"base_currency_short_name"`
+	TargetCurrencyShortName string        `json:"target_currency_short_name"`
+	TargetCurrencyName      string        `json:"target_currency_name"`
+	BaseCurrencyName        string        `json:"base_currency_name"`
+	MinQuantity             float64       `json:"min_quantity"`
+	MaxQuantity             float64       `json:"max_quantity"`
+	MinPrice                float64       `json:"min_price"`
+	MaxPrice                float64       `json:"max_price"`
+	MinNotional             float64       `json:"min_notional"`
+	BaseCurrencyPrecision   int           `json:"base_currency_precision"`
+	TargetCurrencyPrecision int           `json:"target_currency_precision"`
+	Step                    float64       `json:"step"`
+	OrderTypes              []string      `json:"order_types"`
+	Symbol                  string        `json:"symbol"`
+	ECode                   string        `json:"ecode"`
+	Pair                    string        `json:"pair"`
+	Status                  string        `json:"status"`
+	Metadata                *CoinMetadata `json:"metadata,omitempty"`
+}
+
+// Snapshot is one consistent generation of the server's data
+type Snapshot struct {
+	Generation  uint64               `json:"generation"`
+	GeneratedAt int64                `json:"generated_at"`
+	Tickers     []Ticker             `json:"tickers"`
+	Markets     []Market             `json:"markets"`
+	FXRates     map[string]float64   `json:"fx_rates"`
+	Datasets    map[string]Freshness `json:"datasets"`
+}
+
+// Status summarizes upstream health and dataset freshness
+type Status struct {
+	Status   string                     `json:"status"`
+	Upstream map[string]json.RawMessage `json:"upstream"`
+	Probe    map[string]json.RawMessage `json:"probe"`
+	Backoff  map[string]json.RawMessage `json:"backoff"`
+	Datasets map[string]Freshness       `json:"datasets"`
+}
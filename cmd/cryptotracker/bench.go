@@ -0,0 +1,344 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/api"
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/websocket"
+)
+
+const defaultBenchMix = "ticker=6,livedata=3,pairs=1"
+
+// benchEndpoints are the endpoints bench can request, by the names -mix weights them with
+var benchEndpoints = map[string]func(symbol string) string{
+	"ticker":   func(string) string { return "/v1/ticker" },
+	"livedata": func(symbol string) string { return "/v1/livedata/" + url.PathEscape(symbol) },
+	"pairs":    func(string) string { return "/v1/pairs" },
+	"snapshot": func(string) string { return "/v1/snapshot" },
+}
+
+// benchStep is an endpoint in the request mix, chosen in proportion to its weight
+type benchStep struct {
+	name   string
+	path   string
+	weight int
+}
+
+// benchResults collects the outcome of every request a bench run sends
+type benchResults struct {
+	durations map[string][]time.Duration
+	errors    map[string]int
+	statuses  map[int]int
+	// skipped counts requests not sent because -concurrency were already in flight
+	skipped int
+	// lastError is the most recent failure to get any response
+	lastError error
+	mutex     sync.Mutex
+}
+
+func (b *benchResults) record(name string, duration time.Duration, status int, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err != nil || status >= http.StatusBadRequest {
+		b.errors[name]++
+		if err != nil {
+			b.lastError = err
+		}
+		if status != 0 {
+			b.statuses[status]++
+		}
+		return
+	}
+	b.durations[name] = append(b.durations[name], duration)
+}
+
+// ParseBenchMix reads -mix, a list of endpoint=weight pairs
+func parseBenchMix(mix, symbol string) ([]benchStep, int, error) {
+	steps := []benchStep{}
+	total := 0
+	for _, entry := range strings.Split(mix, ",") {
+		name, weightText, found := strings.Cut(strings.TrimSpace(entry), "=")
+		weight := 1
+		if found {
+			var err error
+			if weight, err = strconv.Atoi(weightText); err != nil || weight < 0 {
+				return nil, 0, fmt.Errorf("invalid weight %q for %s", weightText, name)
+			}
+		}
+		path, exists := benchEndpoints[name]
+		if !exists {
+			return nil, 0, fmt.Errorf("unknown endpoint %q", name)
+		}
+		if weight > 0 {
+			steps = append(steps, benchStep{name: name, path: path(symbol), weight: weight})
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil, 0, errors.New("-mix selects no endpoint")
+	}
+	return steps, total, nil
+}
+
+// Bench sends a steady rate of requests, and optionally holds WebSocket subscriptions,
+// against a running instance, then reports each endpoint's latency percentiles
+func bench(args []string) {
+	flags := flag.NewFlagSet(config.FlagSetName+" bench", flag.ContinueOnError)
+	target := flags.String("url", "", "base URL of the instance to load (default http://localhost:Port)")
+	rate := flags.Int("rps", 50, "requests per second to send")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send requests for")
+	mix := flags.String("mix", defaultBenchMix, "relative weights of the endpoints requested: ticker, livedata, pairs and snapshot")
+	symbol := flags.String("symbol", "BTCINR", "market requested by livedata and the WebSocket subscriptions")
+	sockets := flags.Int("websockets", 0, "livedata WebSocket subscriptions to hold open during the run")
+	key := flags.String("key", "", "API key to send as X-API-Key")
+	concurrency := flags.Int("concurrency", 256, "most requests in flight; requests due beyond it are skipped")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	loadCommandConfig(flags, args)
+	logging.Output = os.Stderr
+
+	if *target == "" {
+		*target = fmt.Sprintf("http://localhost:%d", config.Current().Port)
+	}
+	base, err := url.Parse(strings.TrimSuffix(*target, "/"))
+	if err == nil && base.Scheme != "http" && base.Scheme != "https" {
+		err = errors.New("-url must be an http or https URL")
+	}
+	if err == nil && (*rate <= 0 || *concurrency <= 0 || *duration <= 0) {
+		err = errors.New("-rps, -concurrency and -duration must be positive")
+	}
+	steps, total, mixErr := parseBenchMix(*mix, *symbol)
+	if err == nil {
+		err = mixErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+	header := http.Header{}
+	if *key != "" {
+		header.Set("X-API-Key", *key)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	results := &benchResults{
+		durations: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		statuses:  make(map[int]int),
+	}
+
+	// Subscriptions are opened first, so the requests measure a server already streaming
+	subscriptions := openBenchSockets(base, *symbol, header, *sockets, *timeout, results)
+	defer subscriptions.close()
+
+	client := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+	}
+	inFlight := make(chan struct{}, *concurrency)
+	var requests sync.WaitGroup
+	fmt.Fprintf(os.Stderr, "Sending %d requests/s to %s for %s...\n", *rate, base, *duration)
+
+	started := time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(*rate))
+	deadline := time.NewTimer(*duration)
+	defer ticker.Stop()
+	defer deadline.Stop()
+	for sending := true; sending; {
+		select {
+		case <-ctx.Done():
+			sending = false
+			continue
+		case <-deadline.C:
+			sending = false
+			continue
+		case <-ticker.C:
+		}
+
+		pick := rand.Intn(total)
+		step := steps[0]
+		for _, candidate := range steps {
+			if pick < candidate.weight {
+				step = candidate
+				break
+			}
+			pick -= candidate.weight
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			results.mutex.Lock()
+			results.skipped++
+			results.mutex.Unlock()
+			continue
+		}
+		requests.Add(1)
+		go func(step benchStep) {
+			defer requests.Done()
+			defer func() { <-inFlight }()
+			status, took, err := benchRequest(client, base.String()+step.path, header)
+			results.record(step.name, took, status, err)
+		}(step)
+	}
+	requests.Wait()
+	elapsed := time.Since(started)
+	subscriptions.close()
+
+	writeBenchReport(results, subscriptions, elapsed)
+	sent := 0
+	for _, durations := range results.durations {
+		sent += len(durations)
+	}
+	if sent == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no request succeeded")
+		os.Exit(1)
+	}
+}
+
+// BenchRequest fetches address and reads the whole body, reporting how long that took
+func benchRequest(client *http.Client, address string, header http.Header) (int, time.Duration, error) {
+	request, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	request.Header = header.Clone()
+	started := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer response.Body.Close()
+	if _, err := io.Copy(io.Discard, response.Body); err != nil {
+		return response.StatusCode, 0, err
+	}
+	return response.StatusCode, time.Since(started), nil
+}
+
+// benchSockets are the WebSocket subscriptions a bench run holds open
+type benchSockets struct {
+	conns    []*websocket.Conn
+	failed   int
+	messages int
+	readers  sync.WaitGroup
+	mutex    sync.Mutex
+	closed   sync.Once
+}
+
+// OpenBenchSockets subscribes count times to symbol's livedata WebSocket, recording each
+// handshake's latency under "websocket"
+func openBenchSockets(base *url.URL, symbol string, header http.Header, count int, timeout time.Duration, results *benchResults) *benchSockets {
+	sockets := &benchSockets{}
+	if count <= 0 {
+		return sockets
+	}
+	target := *base
+	target.Scheme = map[string]string{"http": "ws", "https": "wss"}[base.Scheme]
+	target.Path += "/v1/livedata/" + symbol + "/ws"
+	dial := func(address string) (net.Conn, error) { return net.DialTimeout("tcp", address, timeout) }
+
+	for i := 0; i < count; i++ {
+		started := time.Now()
+		conn, err := websocket.Connect(&target, header, timeout, dial)
+		results.record("websocket", time.Since(started), 0, err)
+		if err != nil {
+			sockets.failed++
+			continue
+		}
+		sockets.conns = append(sockets.conns, conn)
+		sockets.readers.Add(1)
+		go func() {
+			defer sockets.readers.Done()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+				sockets.mutex.Lock()
+				sockets.messages++
+				sockets.mutex.Unlock()
+			}
+		}()
+	}
+	if sockets.failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d WebSocket subscriptions failed\n", sockets.failed, count)
+	}
+	return sockets
+}
+
+func (b *benchSockets) close() {
+	b.closed.Do(func() {
+		for _, conn := range b.conns {
+			conn.Close()
+		}
+		b.readers.Wait()
+	})
+}
+
+// WriteBenchReport prints a table of each endpoint's request count, error count and
+// latency percentiles, followed by what the WebSocket subscriptions received
+func writeBenchReport(results *benchResults, sockets *benchSockets, elapsed time.Duration) {
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+	names := []string{}
+	for name := range results.durations {
+		names = append(names, name)
+	}
+	for name := range results.errors {
+		if _, exists := results.durations[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	table := newTable("ENDPOINT", "OK", "ERRORS", "RPS", "P50 MS", "P90 MS", "P99 MS", "MAX MS")
+	for _, name := range names {
+		durations := results.durations[name]
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		rps := "-"
+		if name != "websocket" {
+			rps = fmt.Sprintf("%.1f", float64(len(durations))/elapsed.Seconds())
+		}
+		table.row(name, strconv.Itoa(len(durations)), strconv.Itoa(results.errors[name]), rps,
+			api.FormatFloat(exchange.DurationMs(exchange.Percentile(durations, 50))),
+			api.FormatFloat(exchange.DurationMs(exchange.Percentile(durations, 90))),
+			api.FormatFloat(exchange.DurationMs(exchange.Percentile(durations, 99))),
+			api.FormatFloat(exchange.DurationMs(exchange.Percentile(durations, 100))))
+	}
+	table.flush()
+
+	if len(results.statuses) > 0 {
+		statuses := []int{}
+		for status := range results.statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Printf("HTTP %d: %d responses\n", status, results.statuses[status])
+		}
+	}
+	if results.lastError != nil {
+		fmt.Println("Last error:", results.lastError)
+	}
+	if results.skipped > 0 {
+		fmt.Printf("Skipped %d requests with -concurrency already in flight\n", results.skipped)
+	}
+	if len(sockets.conns) > 0 {
+		fmt.Printf("WebSocket: %d subscriptions received %d messages (%.1f/s)\n",
+			len(sockets.conns), sockets.messages, float64(sockets.messages)/elapsed.Seconds())
+	}
+}
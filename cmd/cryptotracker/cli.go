@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/api"
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+const (
+	cliUsage = `Usage: cryptotracker [command] [flags] [arguments]
+
+Commands:
+  serve                      run the API server (the default when no command is given)
+  fetch ticker [SYMBOL]      print tickers, or one market's ticker
+  fetch orderbook SYMBOL     print a market's order book
+  fetch markets              print market details
+  watch SYMBOL               show a market's ticker and order book, updating live
+  export ticker [SYMBOL...]  write tickers as CSV
+  export history SYMBOL      write a market's persisted history (see HistoryPersist) as CSV
+  export parquet [SYMBOL...] write persisted history as one Parquet file per market and day
+  tui                        run a live dashboard of every market (also --tui)
+  hash-password              read a password from standard input and print its bcrypt hash
+  bench                      load a running instance and report latency percentiles
+
+Every command accepts the config flags listed by "cryptotracker serve -h".
+`
+	// watchDepth is how many levels of each side of the order book watch shows
+	watchDepth = 10
+)
+
+// RunCommand dispatches to a subcommand. Arguments that start with a flag run the
+// server, so existing invocations keep working, unless --tui asks for the dashboard.
+func runCommand(args []string) {
+	for i, arg := range args {
+		if arg == "-tui" || arg == "--tui" {
+			runTUI(append(append([]string{}, args[:i]...), args[i+1:]...))
+			return
+		}
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		serve(args)
+		return
+	}
+	switch args[0] {
+	case "serve":
+		serve(args[1:])
+	case "fetch":
+		fetch(args[1:])
+	case "watch":
+		watch(args[1:])
+	case "export":
+		export(args[1:])
+	case "tui":
+		runTUI(args[1:])
+	case "hash-password":
+		api.HashPassword(args[1:])
+	case "bench":
+		bench(args[1:])
+	case "help":
+		fmt.Print(cliUsage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], cliUsage)
+		os.Exit(2)
+	}
+}
+
+// LoadCommandConfig builds the config for a command, exiting on -h or invalid settings
+func loadCommandConfig(flags *flag.FlagSet, args []string) {
+	err := config.Configure(flags, args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Println("Failed to load configuration:", err)
+		os.Exit(1)
+	}
+}
+
+// InterruptContext is cancelled on SIGINT or SIGTERM
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// Fetch runs one query against the exchange and prints the result
+func fetch(args []string) {
+	flags := flag.NewFlagSet(config.FlagSetName+" fetch", flag.ContinueOnError)
+	format := flags.String("format", "json", "output format: json or table")
+	loadCommandConfig(flags, args)
+	logging.Output = os.Stderr
+	if *format != "json" && *format != "table" {
+		fmt.Fprintln(os.Stderr, "Unsupported -format", *format)
+		os.Exit(2)
+	}
+	rest := flags.Args()
+	if len(rest) == 0 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	cryptoTracker := newTracker()
+
+	var err error
+	switch rest[0] {
+	case "ticker", "tickers":
+		err = fetchTickers(ctx, cryptoTracker, rest[1:], *format)
+	case "orderbook", "book":
+		if len(rest) < 2 {
+			err = errors.New("fetch orderbook needs a SYMBOL")
+			break
+		}
+		err = fetchOrderBookCommand(ctx, cryptoTracker, rest[1], *format)
+	case "markets":
+		err = fetchMarkets(ctx, cryptoTracker, *format)
+	default:
+		err = fmt.Errorf("unknown dataset %q", rest[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func fetchTickers(ctx context.Context, cryptoTracker *tracker.CryptoTracker, symbols []string, format string) error {
+	cryptoTracker.RefreshTickerData(ctx)
+	current := cryptoTracker.MarketView()
+	tickers := []exchange.TickerDetails{}
+	if len(symbols) == 0 {
+		for _, ticker := range current.Tickers {
+			tickers = append(tickers, ticker)
+		}
+	}
+	for _, symbol := range symbols {
+		ticker, exists := current.Tickers[symbol]
+		if !exists {
+			return fmt.Errorf("no ticker for %s", symbol)
+		}
+		tickers = append(tickers, ticker)
+	}
+	if len(tickers) == 0 {
+		return errors.New("no ticker data received")
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Market < tickers[j].Market })
+
+	if format == "json" {
+		if len(symbols) == 1 {
+			return printJSON(tickers[0])
+		}
+		return printJSON(tickers)
+	}
+	table := newTable("MARKET", "LAST", "CHANGE 24H", "HIGH", "LOW", "VOLUME")
+	for _, ticker := range tickers {
+		table.row(ticker.Market, ticker.LastPrice, ticker.Change24Hour, ticker.High, ticker.Low, ticker.Volume)
+	}
+	return table.flush()
+}
+
+func fetchOrderBookCommand(ctx context.Context, cryptoTracker *tracker.CryptoTracker, symbol string, format string) error {
+	cryptoTracker.RefreshMarketData(ctx)
+	orderBook, _, exists := cryptoTracker.OrderBookFor(ctx, symbol, true)
+	if !exists {
+		return fmt.Errorf("no order book for %s", symbol)
+	}
+	if format == "json" {
+		return printJSON(orderBook)
+	}
+	return writeOrderBookTable(orderBook, 0)
+}
+
+func fetchMarkets(ctx context.Context, cryptoTracker *tracker.CryptoTracker, format string) error {
+	cryptoTracker.RefreshMarketData(ctx)
+	details := cryptoTracker.MarketView().Markets
+	markets := make([]exchange.MarketDetails, 0, len(details))
+	for _, market := range details {
+		markets = append(markets, market)
+	}
+	if len(markets) == 0 {
+		return errors.New("no market data received")
+	}
+	sort.Slice(markets, func(i, j int) bool { return markets[i].CoindcxName < markets[j].CoindcxName })
+
+	if format == "json" {
+		return printJSON(markets)
+	}
+	table := newTable("MARKET", "BASE", "TARGET", "PAIR", "STATUS")
+	for _, market := range markets {
+		table.row(market.CoindcxName, market.BaseCurrencyShortName, market.TargetCurrencyShortName, market.Pair, market.Status)
+	}
+	return table.flush()
+}
+
+// Export writes tickers or stored history as CSV to stdout or a file
+func export(args []string) {
+	flags := flag.NewFlagSet(config.FlagSetName+" export", flag.ContinueOnError)
+	output := flags.String("o", "", "write to this file instead of stdout, or for parquet the output directory (default parquet)")
+	since := flags.Duration("since", 0, "export history from this long ago (default all of it)")
+	limit := flags.Int("limit", 0, "export at most this many of the most recent history points")
+	loadCommandConfig(flags, args)
+	logging.Output = os.Stderr
+	rest := flags.Args()
+	if len(rest) == 0 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	if rest[0] == "parquet" {
+		dir := *output
+		if dir == "" {
+			dir = "parquet"
+		}
+		if err := exportParquet(dir, rest[1:], *since); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var header []string
+	var rows [][]string
+	var err error
+	switch rest[0] {
+	case "ticker", "tickers":
+		header = api.TickerCSVHeader
+		rows, err = exportTickers(ctx, newTracker(), rest[1:])
+	case "history":
+		if len(rest) != 2 {
+			err = errors.New("export history needs one SYMBOL")
+			break
+		}
+		var from time.Time
+		if *since > 0 {
+			from = time.Now().Add(-*since)
+		}
+		var points []tracker.HistoryPoint
+		points, err = tracker.NewHistoryStore(tracker.HistoryDir()).Query(rest[1], from, time.Time{}, *limit)
+		if err == nil && len(points) == 0 {
+			err = fmt.Errorf("no history stored for %s in %s", rest[1], tracker.HistoryDir())
+		}
+		header, rows = api.HistoryCSVHeader, api.HistoryCSVRows(points)
+	default:
+		err = fmt.Errorf("unknown dataset %q", rest[0])
+	}
+	if err == nil {
+		err = writeExport(*output, header, rows)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func exportTickers(ctx context.Context, cryptoTracker *tracker.CryptoTracker, symbols []string) ([][]string, error) {
+	cryptoTracker.RefreshTickerData(ctx)
+	current := cryptoTracker.MarketView()
+	tickers := []tracker.TickerView{}
+	view := func(ticker exchange.TickerDetails) tracker.TickerView {
+		return tracker.TickerView{TickerDetails: ticker, Freshness: tracker.FreshnessOf(current.Times[ticker.Market])}
+	}
+	if len(symbols) == 0 {
+		for _, ticker := range current.Tickers {
+			tickers = append(tickers, view(ticker))
+		}
+	}
+	for _, symbol := range symbols {
+		ticker, exists := current.Tickers[symbol]
+		if !exists {
+			return nil, fmt.Errorf("no ticker for %s", symbol)
+		}
+		tickers = append(tickers, view(ticker))
+	}
+	if len(tickers) == 0 {
+		return nil, errors.New("no ticker data received")
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Market < tickers[j].Market })
+	return api.TickerCSVRows(tickers), nil
+}
+
+// ExportParquet converts persisted history into dir/SYMBOL/YYYY-MM-DD.parquet, one file per
+// market and UTC day, for every market when none are named. Existing files are overwritten
+// so re-running an export picks up points added to the current day since.
+func exportParquet(dir string, markets []string, since time.Duration) error {
+	history := tracker.NewHistoryStore(tracker.HistoryDir())
+	if len(markets) == 0 {
+		var err error
+		if markets, err = history.Markets(); err != nil {
+			return err
+		}
+	}
+	var oldest string
+	if since > 0 {
+		oldest = time.Now().Add(-since).UTC().Format(tracker.HistoryDayLayout)
+	}
+
+	written := 0
+	for _, market := range markets {
+		days, err := history.Days(market)
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			if day < oldest {
+				continue
+			}
+			points, err := history.ReadDay(market, day)
+			if err != nil {
+				return err
+			}
+			if len(points) == 0 {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Join(dir, market), 0755); err != nil {
+				return err
+			}
+			file, err := os.Create(filepath.Join(dir, market, day+".parquet"))
+			if err != nil {
+				return err
+			}
+			if err := api.WriteHistoryParquet(file, points); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			written++
+		}
+	}
+	if written == 0 {
+		return fmt.Errorf("no history stored in %s", tracker.HistoryDir())
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d Parquet files to %s\n", written, dir)
+	return nil
+}
+
+// WriteExport writes CSV to path, or to stdout when path is empty
+func writeExport(path string, header []string, rows [][]string) error {
+	if path == "" {
+		return api.WriteCSV(os.Stdout, header, rows)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := api.WriteCSV(file, header, rows); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Watch redraws a market's ticker and order book every refresh interval until interrupted
+func watch(args []string) {
+	flags := flag.NewFlagSet(config.FlagSetName+" watch", flag.ContinueOnError)
+	interval := flags.Duration("interval", 0, "how often to redraw (default the configured RefreshInterval)")
+	loadCommandConfig(flags, args)
+	logging.Output = os.Stderr
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		os.Exit(2)
+	}
+	symbol := flags.Arg(0)
+	if *interval <= 0 {
+		*interval = tracker.RefreshInterval()
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	cryptoTracker := newTracker()
+	cryptoTracker.RefreshMarketData(ctx)
+	if _, exists := cryptoTracker.MarketInfo(symbol); !exists {
+		fmt.Fprintln(os.Stderr, "Error: unknown market", symbol)
+		os.Exit(1)
+	}
+
+	for ctx.Err() == nil {
+		cryptoTracker.RefreshTickerData(ctx)
+		orderBook, fetchedAt, _ := cryptoTracker.OrderBookFor(ctx, symbol, true)
+		ticker := cryptoTracker.MarketView().Tickers[symbol]
+
+		// Clear the screen and move the cursor home before redrawing
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("%s  last %s  24h %s%%  high %s  low %s  volume %s\n", symbol, ticker.LastPrice, ticker.Change24Hour, ticker.High, ticker.Low, ticker.Volume)
+		if !fetchedAt.IsZero() {
+			fmt.Printf("order book as of %s\n\n", fetchedAt.Format("15:04:05"))
+			writeOrderBookTable(orderBook, watchDepth)
+		}
+		fmt.Printf("\nRefreshing every %s, Ctrl-C to quit\n", *interval)
+		exchange.SleepContext(ctx, *interval)
+	}
+}
+
+// WriteOrderBookTable prints bids and asks side by side, best first; depth 0 prints every level
+func writeOrderBookTable(orderBook exchange.OrderBook, depth int) error {
+	bids := exchange.SortedLevels(orderBook.Bids, true)
+	asks := exchange.SortedLevels(orderBook.Asks, false)
+	rows := len(bids)
+	if len(asks) > rows {
+		rows = len(asks)
+	}
+	if depth > 0 && rows > depth {
+		rows = depth
+	}
+	table := newTable("BID QTY", "BID", "ASK", "ASK QTY")
+	for i := 0; i < rows; i++ {
+		cells := []string{"", "", "", ""}
+		if i < len(bids) {
+			cells[0], cells[1] = bids[i].Quantity.String(), bids[i].Price.String()
+		}
+		if i < len(asks) {
+			cells[2], cells[3] = asks[i].Price.String(), asks[i].Quantity.String()
+		}
+		table.row(cells...)
+	}
+	return table.flush()
+}
+
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// cliTable aligns rows of cells into columns on stdout
+type cliTable struct {
+	writer *tabwriter.Writer
+}
+
+func newTable(headers ...string) *cliTable {
+	table := &cliTable{writer: tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)}
+	table.row(headers...)
+	return table
+}
+
+func (t *cliTable) row(cells ...string) {
+	fmt.Fprintln(t.writer, strings.Join(cells, "\t"))
+}
+
+func (t *cliTable) flush() error {
+	return t.writer.Flush()
+}
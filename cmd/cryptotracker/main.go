@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/api"
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+func main() {
+	runCommand(os.Args[1:])
+}
+
+// NewTracker returns a tracker of the CoinDCX exchange, reached through a new upstream client
+func newTracker() *tracker.CryptoTracker {
+	client := exchange.NewSafeHTTPClient()
+	return tracker.NewCryptoTracker(client, exchange.NewCoinDCX(client))
+}
+
+// Serve runs the API server until interrupted
+func serve(args []string) {
+	loadCommandConfig(flag.NewFlagSet(config.FlagSetName+" serve", flag.ContinueOnError), args)
+
+	cfg := config.Current()
+	if cfg.Demo {
+		fmt.Println("Demo mode: serving synthetic data from the built-in mock exchange")
+	}
+	if cfg.ReplayFile != "" {
+		fmt.Printf("Replaying upstream responses from %s at %gx speed\n", cfg.ReplayFile, cfg.ReplaySpeed)
+	}
+	if cfg.ChaosEnabled() {
+		fmt.Println("Chaos mode: injecting faults into upstream requests")
+	}
+	store := storage.NewFileStorage(cfg.StorageDir)
+	watchlists := tracker.NewWatchlistStore(store)
+
+	client := exchange.NewSafeHTTPClient()
+	cryptoTracker := tracker.NewCryptoTracker(client, exchange.NewCoinDCX(client))
+	cryptoTracker.Events = tracker.NewEventBus(tracker.NewPublishers(cfg))
+	paper := tracker.NewPaperTrader(cryptoTracker, store)
+	cryptoTracker.OnRefresh(paper.MatchOpenOrders)
+	arbitrage := tracker.NewArbitrageScanner(cryptoTracker)
+	cryptoTracker.OnRefresh(arbitrage.Scan)
+	notifier := tracker.NewAlertNotifier(cfg.AlertWebhookURL)
+	notifier.Events = cryptoTracker.Events
+	alerts := tracker.NewAlertEngine(cryptoTracker, notifier, store)
+	cryptoTracker.OnRefresh(alerts.Evaluate)
+	listings := tracker.NewListingDetector(cryptoTracker, notifier, store)
+	cryptoTracker.OnMarketsRefresh(listings.Detect)
+	statuses := tracker.NewMarketStatusTracker(cryptoTracker, notifier, store)
+	cryptoTracker.OnMarketsRefresh(statuses.Detect)
+	cryptoTracker.PrioritySymbols = func() []string {
+		symbols := append(watchlists.Symbols(), paper.OpenMarkets()...)
+		return append(symbols, alerts.BookSymbols()...)
+	}
+	health := tracker.NewHealthMonitor(cryptoTracker, notifier)
+	cryptoTracker.OnRefresh(health.Evaluate)
+	triangular := tracker.NewTriangularScanner(cryptoTracker, notifier)
+	cryptoTracker.OnRefresh(triangular.Scan)
+	webhooks := tracker.NewWebhookDispatcher(cryptoTracker, store)
+	cryptoTracker.OnRefresh(webhooks.Check)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var certificates *api.ACMEManager
+	if len(cfg.ACMEDomains) > 0 {
+		certificates = api.NewACMEManager(store, cfg.ACMEDomains, cfg.ACMEEmail, cfg.ACMEDirectoryURL)
+	}
+	cryptoTracker.RefreshMarketData(ctx)
+	cryptoTracker.StartBackgroundRefresh(ctx)
+	cryptoTracker.Probe().Start(ctx)
+	if cfg.RedisFollow {
+		cryptoTracker.Follower = tracker.NewRedisFollower(cryptoTracker, cfg)
+		cryptoTracker.Follower.Start(ctx)
+	}
+	if cfg.StreamEnabled && cfg.LiveUpstream() {
+		cryptoTracker.Stream = tracker.NewCoinDCXStream(cryptoTracker, cfg.StreamURL)
+		cryptoTracker.Stream.Start()
+	}
+
+	// Settings copied by long-lived components at startup are pushed to them on reload
+	applyConfig := func(cfg config.Config) {
+		client.Limiter.Update(cfg.UpstreamRateLimits)
+		cryptoTracker.Subscriptions().SetWindow(time.Duration(cfg.SubscriptionWindow) * time.Second)
+		notifier.SetWebhookURL(cfg.AlertWebhookURL)
+	}
+	config.WatchReload(applyConfig)
+
+	// Handle graceful shutdown
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	server := api.NewServer(store, api.Components{
+		Tracker:      cryptoTracker,
+		Watchlists:   watchlists,
+		Paper:        paper,
+		Arbitrage:    arbitrage,
+		Triangular:   triangular,
+		Webhooks:     webhooks,
+		Alerts:       alerts,
+		Listings:     listings,
+		Statuses:     statuses,
+		ApplyConfig:  applyConfig,
+		Certificates: certificates,
+	})
+	api.InheritListeners()
+	server.Start()
+	// The CA validates a new order over the HTTPS listener, which must be accepting first
+	if certificates != nil {
+		certificates.Start(ctx)
+	}
+	api.UnusedActivatedListeners()
+	api.NotifyUpgradeReady()
+
+	// After handing its listeners to an upgraded process this one only finishes what it has
+	select {
+	case <-stop:
+	case <-server.WatchUpgrades():
+		fmt.Println("\nUpgraded; draining streaming clients...")
+		server.CloseListeners()
+		server.DrainStreams(stop)
+	}
+	fmt.Println("\nShutting down server...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	server.Stop(shutdownCtx)
+	cancelShutdown()
+	cancel()
+	if cryptoTracker.Stream != nil {
+		cryptoTracker.Stream.Stop()
+	}
+	cryptoTracker.Events.Close()
+	fmt.Println("Server gracefully stopped.")
+}
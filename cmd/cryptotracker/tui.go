@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/api"
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/tracker"
+)
+
+const (
+	defaultTerminalRows = 24
+	defaultTerminalCols = 100
+	tuiRedrawInterval   = time.Second
+	tuiBookDepth        = 8
+	sparklineWidth      = 48
+)
+
+// tuiColumns are the ticker table columns the dashboard can sort by
+var tuiColumns = []string{"market", "last", "change", "volume"}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Dashboard is the state of the terminal dashboard
+type Dashboard struct {
+	tracker    *tracker.CryptoTracker
+	sortColumn int
+	descending bool
+	selected   string
+	cursor     int
+	offset     int
+}
+
+// RunTUI shows a live dashboard of every market until q or Ctrl-C is pressed
+func runTUI(args []string) {
+	loadCommandConfig(flag.NewFlagSet(config.FlagSetName+" tui", flag.ContinueOnError), args)
+	// Log lines would tear through the dashboard; upstream health is shown in the header instead
+	logging.Output = ioutil.Discard
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	cryptoTracker := newTracker()
+	cryptoTracker.RefreshMarketData(ctx)
+	cryptoTracker.StartBackgroundRefresh(ctx)
+	if cfg := config.Current(); cfg.StreamEnabled && cfg.LiveUpstream() {
+		cryptoTracker.Stream = tracker.NewCoinDCXStream(cryptoTracker, cfg.StreamURL)
+		cryptoTracker.Stream.Start()
+		defer cryptoTracker.Stream.Stop()
+	}
+
+	if restore, err := makeRaw(os.Stdin.Fd()); err == nil {
+		defer restore()
+	}
+	// Draw on the alternate screen with the cursor hidden, restoring both on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	dashboard := &Dashboard{tracker: cryptoTracker, sortColumn: 2, descending: true}
+	defer dashboard.selectMarket("")
+	keys := readKeys(ctx)
+	redraw := time.NewTicker(tuiRedrawInterval)
+	defer redraw.Stop()
+	for {
+		dashboard.render()
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-keys:
+			if !dashboard.handleKey(key) {
+				return
+			}
+		case <-redraw.C:
+		}
+	}
+}
+
+// ReadKeys turns stdin into key names such as "up", "down" or single characters
+func readKeys(ctx context.Context) <-chan string {
+	keys := make(chan string)
+	go func() {
+		buffer := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buffer)
+			if err != nil {
+				return
+			}
+			input := string(buffer[:n])
+			for input != "" {
+				key := input[:1]
+				for sequence, name := range map[string]string{"\033[A": "up", "\033[B": "down", "\033[5~": "pgup", "\033[6~": "pgdown"} {
+					if strings.HasPrefix(input, sequence) {
+						key = name
+						input = input[len(sequence)-1:]
+						break
+					}
+				}
+				input = input[1:]
+				select {
+				case keys <- key:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return keys
+}
+
+// HandleKey applies one key press, returning false when the dashboard should exit
+func (d *Dashboard) handleKey(key string) bool {
+	rows, _ := terminalSize()
+	page := d.tableHeight(rows)
+	switch key {
+	case "q", "Q", "\033":
+		return false
+	case "down", "j":
+		d.cursor++
+	case "up", "k":
+		d.cursor--
+	case "pgdown", " ":
+		d.cursor += page
+	case "pgup":
+		d.cursor -= page
+	case "s":
+		d.sortColumn = (d.sortColumn + 1) % len(tuiColumns)
+	case "r":
+		d.descending = !d.descending
+	case "1", "2", "3", "4":
+		d.sortColumn = int(key[0] - '1')
+	}
+	return true
+}
+
+// SelectMarket keeps the selected market's order book refreshed while it is shown
+func (d *Dashboard) selectMarket(market string) {
+	if market == d.selected {
+		return
+	}
+	if d.selected != "" {
+		d.tracker.Subscriptions().Unsubscribe(d.selected)
+	}
+	if market != "" {
+		d.tracker.Subscriptions().Subscribe(market)
+	}
+	d.selected = market
+}
+
+// TableHeight is how many ticker rows fit above the order book panel
+func (d *Dashboard) tableHeight(rows int) int {
+	height := rows - tuiBookDepth - 8
+	if height < 3 {
+		height = 3
+	}
+	return height
+}
+
+// SortedTickers returns every ticker ordered by the current sort column
+func (d *Dashboard) sortedTickers() []exchange.TickerDetails {
+	current := d.tracker.MarketView().Tickers
+	tickers := make([]exchange.TickerDetails, 0, len(current))
+	for _, ticker := range current {
+		tickers = append(tickers, ticker)
+	}
+
+	number := func(value string) float64 {
+		parsed, _ := strconv.ParseFloat(value, 64)
+		return parsed
+	}
+	// Ties fall back to the market name so rows don't shuffle between redraws
+	less := func(a, b exchange.TickerDetails) bool {
+		var x, y float64
+		switch tuiColumns[d.sortColumn] {
+		case "last":
+			x, y = number(a.LastPrice), number(b.LastPrice)
+		case "change":
+			x, y = number(a.Change24Hour), number(b.Change24Hour)
+		case "volume":
+			x, y = number(a.Volume), number(b.Volume)
+		}
+		if x != y {
+			return x < y
+		}
+		return a.Market < b.Market
+	}
+	sort.Slice(tickers, func(i, j int) bool {
+		if d.descending {
+			return less(tickers[j], tickers[i])
+		}
+		return less(tickers[i], tickers[j])
+	})
+	return tickers
+}
+
+// Render redraws the whole screen
+func (d *Dashboard) render() {
+	// Interest in every market keeps tickers refreshing under SelectiveTickerRefresh
+	d.tracker.Subscriptions().Touch(tracker.AllMarketsKey)
+
+	rows, cols := terminalSize()
+	tickers := d.sortedTickers()
+	height := d.tableHeight(rows)
+	if d.cursor >= len(tickers) {
+		d.cursor = len(tickers) - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	}
+	if d.cursor >= d.offset+height {
+		d.offset = d.cursor - height + 1
+	}
+	if len(tickers) > 0 {
+		d.selectMarket(tickers[d.cursor].Market)
+	}
+
+	var screen strings.Builder
+	line := func(format string, args ...interface{}) {
+		text := fmt.Sprintf(format, args...)
+		if len([]rune(text)) > cols {
+			text = string([]rune(text)[:cols])
+		}
+		screen.WriteString(text + "\033[K\r\n")
+	}
+
+	upstream := d.tracker.Upstream().State.Health()
+	status := "ok"
+	switch {
+	case upstream.Throttled:
+		status = "throttled"
+	case upstream.ConsecutiveFailures > 0:
+		status = "degraded: " + upstream.LastError
+	}
+	direction := "asc"
+	if d.descending {
+		direction = "desc"
+	}
+	line("CryptoTracker  %s  upstream %s", time.Now().Format("15:04:05"), status)
+	line("%d markets  sort %s %s  [up/down select, s/1-4 sort, r reverse, q quit]", len(tickers), tuiColumns[d.sortColumn], direction)
+	line("")
+	line("  %-16s %16s %10s %20s", "MARKET", "LAST", "CHANGE %", "VOLUME")
+	for i := d.offset; i < d.offset+height; i++ {
+		if i >= len(tickers) {
+			line("")
+			continue
+		}
+		ticker := tickers[i]
+		row := fmt.Sprintf("  %-16s %16s %10s %20s", ticker.Market, ticker.LastPrice, ticker.Change24Hour, ticker.Volume)
+		if i == d.cursor {
+			row = "\033[7m" + row + "\033[0m"
+		}
+		line("%s", row)
+	}
+	line("")
+	d.renderSelected(line)
+
+	fmt.Print("\033[H" + screen.String() + "\033[J")
+}
+
+// RenderSelected draws the selected market's sparkline and order book
+func (d *Dashboard) renderSelected(line func(string, ...interface{})) {
+	if d.selected == "" {
+		line("Waiting for ticker data...")
+		return
+	}
+	line("%s  %s", d.selected, sparkline(d.tracker.Volatility().History(d.selected), sparklineWidth))
+	orderBook, fetchedAt, exists := d.tracker.OrderBookFor(context.Background(), d.selected, false)
+	if !exists {
+		line("  order book loading...")
+		for i := 0; i < tuiBookDepth; i++ {
+			line("")
+		}
+		return
+	}
+	bids := exchange.SortedLevels(orderBook.Bids, true)
+	asks := exchange.SortedLevels(orderBook.Asks, false)
+	line("  %14s %14s | %-14s %-14s  as of %s", "BID QTY", "BID", "ASK", "ASK QTY", fetchedAt.Format("15:04:05"))
+	for i := 0; i < tuiBookDepth; i++ {
+		var bid, bidQuantity, ask, askQuantity string
+		if i < len(bids) {
+			bid, bidQuantity = bids[i].Price.String(), bids[i].Quantity.String()
+		}
+		if i < len(asks) {
+			ask, askQuantity = asks[i].Price.String(), asks[i].Quantity.String()
+		}
+		line("  %14s %14s | %-14s %-14s", bidQuantity, bid, ask, askQuantity)
+	}
+}
+
+// Sparkline draws the last width prices as block characters scaled between their low and high
+func sparkline(prices []float64, width int) string {
+	if len(prices) > width {
+		prices = prices[len(prices)-width:]
+	}
+	if len(prices) < 2 {
+		return "collecting prices..."
+	}
+	low, high := prices[0], prices[0]
+	for _, price := range prices {
+		if price < low {
+			low = price
+		}
+		if price > high {
+			high = price
+		}
+	}
+	blocks := make([]rune, len(prices))
+	for i, price := range prices {
+		level := 0
+		if high > low {
+			level = int((price - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		blocks[i] = sparkBlocks[level]
+	}
+	return fmt.Sprintf("%s  low %s  high %s", string(blocks), api.FormatFloat(low), api.FormatFloat(high))
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// MakeRaw switches the terminal on fd to unbuffered, unechoed input and returns a
+// function restoring the previous mode
+func makeRaw(fd uintptr) (func(), error) {
+	var original syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&original))); errno != 0 {
+		return nil, errno
+	}
+	raw := original
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&original)))
+	}, nil
+}
+
+// TerminalSize returns the rows and columns of the terminal on stdout
+func terminalSize() (int, int) {
+	var size struct{ rows, cols, x, y uint16 }
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size))); errno != 0 || size.rows == 0 {
+		return defaultTerminalRows, defaultTerminalCols
+	}
+	return int(size.rows), int(size.cols)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// MakeRaw is only implemented on Linux; elsewhere keys take effect after Enter
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+// TerminalSize falls back to a conventional terminal size
+func terminalSize() (int, int) {
+	return defaultTerminalRows, defaultTerminalCols
+}
@@ -0,0 +1,1076 @@
+// Package config holds the application configuration: its defaults, how config.json,
+// environment variables and flags override them, and the validation a config must pass
+// before it takes effect.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// Config handles application configuration
+type Config struct {
+	APIBaseURL         string
+	FXAPIURL           string
+	CoinGeckoAPIURL    string
+	StorageDir         string
+	ArbitrageThreshold float64
+	ArbitrageFeeRate   float64
+	AlertWebhookURL    string
+	StreamEnabled      bool
+	StreamURL          string
+	MaxRetries         int
+	RetryDelay         int
+	LogLevel           string
+	// Port is the TCP port of the API; 0 serves only on UnixSocket
+	Port int
+	Host string
+	// UnixSocket additionally serves the API as plain HTTP on a unix socket at this path, with
+	// the socket file given UnixSocketMode (octal) and, when set, owned by UnixSocketGroup
+	UnixSocket      string
+	UnixSocketMode  string
+	UnixSocketGroup string
+	// FuturesEnabled fetches the futures contracts margined in FuturesMarginCurrencies and
+	// their prices, and serves them under /futures
+	FuturesEnabled          bool
+	FuturesMarginCurrencies []string
+	// FundingRetention is how many seconds of funding rate history are kept per contract
+	FundingRetention int
+	// AggregationTimeframes are the timeframes the aggregation engine builds candles at from
+	// every tick, finest first, each a multiple of the one before. AggregationCandles is how
+	// many candles are kept per market and timeframe, and AggregationLateness how many
+	// seconds after a candle's interval ends a delayed tick still updates it.
+	AggregationTimeframes []string
+	AggregationCandles    int
+	AggregationLateness   int
+	// OpenInterestInterval is how many seconds apart futures open interest is sampled, and
+	// OpenInterestRetention how many seconds of samples are kept per contract
+	OpenInterestInterval  int
+	OpenInterestRetention int
+	// LendingRatesURL, when set, is polled every MarketsRefreshInterval for margin lending
+	// and borrowing rates, served under /lending-rates. It must answer with a JSON array of
+	// {"currency", "lend_rate", "borrow_rate"} objects quoting daily rates as fractions.
+	LendingRatesURL string
+	// LendingRetention is how many seconds of lending rate history are kept per currency
+	LendingRetention int
+
+	// AlertCooldown is the minimum number of seconds between notifications of an alert rule
+	// that does not set its own cooldown
+	AlertCooldown int
+	// ListingAlerts raises an alert for every newly listed market
+	ListingAlerts bool
+	// MarketStatusAlerts raises an alert whenever a market's status changes, e.g. from
+	// active to suspended
+	MarketStatusAlerts bool
+	// HealthAlerts notify through the alert channels when the tracker's own refreshes fail
+	// or its data goes stale, and again when they recover
+	HealthAlerts []HealthAlertRule
+	// MarketStatusFilter, e.g. active, limits /pairs, /ticker and /markets to markets with
+	// that status unless a request asks otherwise with ?status=, where status=all lists
+	// every market
+	MarketStatusFilter string
+	// SymbolAliases maps alternative names to the ones the exchange uses, for whole
+	// markets or single currencies, e.g. {"XBT": "BTC"}. Symbols such as BTC-INR, btc_inr
+	// and BTC/INR are recognised without aliases.
+	SymbolAliases map[string]string
+	// TimestampFormat is how JSON responses give times: epoch, as upstream sends them,
+	// rfc3339, or both, which keeps each epoch under the field name plus _epoch. Requests
+	// may override it with ?timestamps= and choose a zone with ?tz=.
+	TimestampFormat string
+
+	// RefreshInterval is how many seconds pass between ticker and watched order book refreshes
+	RefreshInterval int
+	// MarketsRefreshInterval, FXRefreshInterval and MetadataRefreshInterval are how many
+	// seconds pass between refreshes of market details, fiat rates and coin metadata
+	MarketsRefreshInterval  int
+	FXRefreshInterval       int
+	MetadataRefreshInterval int
+	// OrderBookRefreshInterval is how many milliseconds pass between refreshes of streamed
+	// order books; it defaults to OrderBookCacheTTL
+	OrderBookRefreshInterval int
+	// RefreshJitter randomizes each refresh delay by up to this fraction so instances and
+	// datasets don't hit upstream in lockstep
+	RefreshJitter float64
+	// AdaptiveRefresh scales each order book's refresh interval by how much its price moved
+	// over the last AdaptiveWindow seconds: a move of AdaptiveVolatilityThreshold percent keeps
+	// the normal interval, bigger moves refresh sooner and quiet markets later, bounded by
+	// AdaptiveMinInterval and AdaptiveMaxInterval milliseconds
+	AdaptiveRefresh             bool
+	AdaptiveWindow              int
+	AdaptiveVolatilityThreshold float64
+	AdaptiveMinInterval         int
+	AdaptiveMaxInterval         int
+	// SubscriptionWindow is how many seconds a requested symbol keeps being refreshed
+	SubscriptionWindow     int
+	SelectiveTickerRefresh bool
+	// OrderBookCacheTTL is how many milliseconds a fetched order book is reused
+	OrderBookCacheTTL int
+	// UpstreamConcurrency bounds how many upstream HTTP calls run at once
+	UpstreamConcurrency int
+	// UpstreamTimeout is how many seconds an upstream HTTP call may take
+	UpstreamTimeout int
+	// UpstreamProxy routes exchange requests and the stream through an http, https, socks5 or
+	// socks5h proxy URL, except for hosts, domains and CIDRs in UpstreamNoProxy. Without it
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY apply.
+	UpstreamProxy   string
+	UpstreamNoProxy []string
+	// UpstreamDNSServers resolves upstream hostnames through these servers, such as "1.1.1.1"
+	// or "[2606:4700::1111]:53", rather than the system resolver. With UpstreamDNSCacheTTL
+	// above 0 answers are kept for that many seconds, and kept past it while lookups fail.
+	UpstreamDNSServers  []string
+	UpstreamDNSCacheTTL int
+	// UpstreamUserAgent is the User-Agent of upstream requests and UpstreamHeaders are added to
+	// each of them. UpstreamHostHeaders sets headers, User-Agent included, for one upstream host
+	// such as "api.coingecko.com", taking precedence over both.
+	UpstreamUserAgent   string
+	UpstreamHeaders     map[string]string
+	UpstreamHostHeaders map[string]map[string]string
+	// UpstreamRateLimits overrides the per-endpoint token buckets (markets, ticker, orderbook, default)
+	UpstreamRateLimits map[string]RateLimit
+	// ProbeInterval is how often, in seconds, the exchange is probed for /status
+	ProbeInterval int
+	// UpstreamLatencySLO is how many milliseconds an upstream call may take and still meet the
+	// SLO, which is breached when fewer than UpstreamSLOTarget of recent calls do
+	UpstreamLatencySLO int
+	UpstreamSLOTarget  float64
+
+	// AdminToken is the bearer token required by /admin endpoints, which are disabled without one
+	AdminToken string
+	// OIDCIssuer, when set, accepts tokens from that OpenID Connect issuer as admin bearer
+	// tokens if they were issued to OIDCAudience. OIDCAdminGroups, when set, limits admins to
+	// members of those groups, read from the OIDCGroupsClaim claim (groups by default), and
+	// every OIDCRequiredClaims entry must equal the token's claim of that name.
+	OIDCIssuer         string
+	OIDCAudience       string
+	OIDCGroupsClaim    string
+	OIDCAdminGroups    []string
+	OIDCRequiredClaims map[string]string
+	// BasicAuthUsername and BasicAuthPasswordHash, a bcrypt hash from the hash-password
+	// command, require HTTP Basic credentials of every client without an API key
+	BasicAuthUsername     string
+	BasicAuthPasswordHash string
+	// APIKeys maps names to the keys clients send in X-API-Key, or as a bearer token, to be
+	// rate limited by key rather than by address
+	APIKeys map[string]string
+	// ClientRateLimits are the token buckets of each client tier: anonymous, per address;
+	// key, per API key; and admin, shared by AdminToken holders. Tiers without one are unlimited.
+	ClientRateLimits map[string]RateLimit
+
+	// CertFile and KeyFile enable HTTPS when both are set
+	CertFile string
+	KeyFile  string
+	// HTTPRedirectPort, when set alongside TLS, serves plain HTTP redirects to HTTPS on that port
+	HTTPRedirectPort int
+	// ACMEDomains enables automatic certificates for these domains instead of CertFile/KeyFile.
+	// The CA's tls-alpn-01 validation connects on port 443, so Port must be 443 or have
+	// 443 forwarded to it.
+	ACMEDomains      []string
+	ACMEEmail        string
+	ACMEDirectoryURL string
+	// AdminPort moves /admin endpoints to their own listener. With AdminClientCAFile set it
+	// requires client certificates issued by that CA; AdminCertFile/AdminKeyFile default to
+	// the main server certificate.
+	AdminPort         int
+	AdminClientCAFile string
+	AdminCertFile     string
+	AdminKeyFile      string
+
+	// CORSAllowedOrigins lists origins allowed to call the API; "*" allows any and entries
+	// such as https://*.example.com allow subdomains. CORSAllowCredentials lets the listed
+	// origins make credentialed requests, so it cannot be combined with "*".
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	// CORSMaxAge is how many seconds browsers may cache a preflight response
+	CORSMaxAge int
+
+	// AllowedCIDRs, when set, refuses clients outside these networks; DeniedCIDRs refuses
+	// clients inside them, even if allowed. Entries may be bare addresses.
+	AllowedCIDRs []string
+	DeniedCIDRs  []string
+	// TrustedProxies are the networks whose X-Forwarded-For is believed when finding the client address
+	TrustedProxies []string
+
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout and IdleTimeout are the http.Server
+	// timeouts in seconds; 0 disables one. Streaming endpoints are exempt from WriteTimeout.
+	ReadTimeout       int
+	ReadHeaderTimeout int
+	WriteTimeout      int
+	IdleTimeout       int
+	// HandlerTimeout is how many seconds a request that may wait on upstream can take
+	HandlerTimeout int
+	// HTTP2 negotiates HTTP/2 on TLS listeners; H2C also accepts HTTP/2 with prior knowledge
+	// on plaintext ones, such as behind a local proxy. HTTP2MaxConcurrentStreams bounds the
+	// requests, streams included, one connection may have open; 0 keeps the Go default.
+	HTTP2                     bool
+	H2C                       bool
+	HTTP2MaxConcurrentStreams int
+	// UpgradeDrainTimeout is how many seconds a process replaced by a SIGUSR2 upgrade keeps
+	// serving its SSE and WebSocket clients before dropping them
+	UpgradeDrainTimeout int
+	// RouteLimits caps request bodies, queries and handler time per route, keyed by path
+	// as in /livedata/{symbol}, or default for every route
+	RouteLimits map[string]RouteLimit
+
+	// DebugEndpoints exposes pprof and expvar under /debug on the admin listener
+	DebugEndpoints bool
+
+	// StaleThreshold is how many seconds data may go without refreshing before it is
+	// flagged stale; StaleFailsReadiness makes /readyz return 503 while tickers are stale
+	StaleThreshold      int
+	StaleFailsReadiness bool
+
+	// Demo serves synthetic markets, tickers and order books from a built-in mock
+	// exchange instead of calling CoinDCX, FX and CoinGecko
+	Demo bool
+	// RecordFile saves every upstream response to this file as JSON lines. ReplayFile
+	// serves upstream requests from such a recording instead, with time running at
+	// ReplaySpeed times real time.
+	RecordFile  string
+	ReplayFile  string
+	ReplaySpeed float64
+	// ChaosLatencyRate is the share of upstream HTTP requests delayed by ChaosLatency
+	// milliseconds. Of the rest, ChaosTimeoutRate hang until they time out, ChaosErrorRate
+	// fail with a 5xx and ChaosMalformedRate get truncated JSON. Meant for testing how the
+	// tracker copes with a failing upstream; every rate defaults to 0.
+	ChaosLatencyRate   float64
+	ChaosLatency       int
+	ChaosTimeoutRate   float64
+	ChaosErrorRate     float64
+	ChaosMalformedRate float64
+	// HistoryPersist appends every recorded ticker to StorageDir/history so /history
+	// survives restarts and reaches further back than the in-memory window
+	HistoryPersist bool
+	// HistoryRawRetention, HistoryMinuteRetention and HistoryHourRetention are how many
+	// days persisted raw tickers, 1m candles and 1h candles are kept. Expired raw tickers
+	// are downsampled to 1m candles and expired 1m candles to 1h candles; expired 1h
+	// candles are deleted. 0 keeps that level forever.
+	HistoryRawRetention    int
+	HistoryMinuteRetention int
+	HistoryHourRetention   int
+	// HistoryBackfillWindow is how many hours back gaps in persisted history, from downtime
+	// or upstream outages, are filled from the upstream candles API after each compaction;
+	// 0 disables backfill
+	HistoryBackfillWindow int
+	// SnapshotDumpInterval writes a full ticker and order book snapshot to SnapshotDumpDir
+	// (default StorageDir/snapshots) every this many seconds; 0 disables dumps. Dumps are
+	// gzipped with SnapshotDumpCompress. Only the newest SnapshotDumpKeep dumps and those
+	// younger than SnapshotDumpMaxAge hours are kept; 0 disables either limit.
+	SnapshotDumpInterval int
+	SnapshotDumpDir      string
+	SnapshotDumpCompress bool
+	SnapshotDumpKeep     int
+	SnapshotDumpMaxAge   int
+	// KafkaBrokers enables publishing ticker updates, order book snapshots and alerts to
+	// Kafka, keyed by symbol. An empty topic stops that event type being published.
+	// KafkaAcks is the produce acknowledgement level: 0, 1 or -1 for all replicas.
+	KafkaBrokers        []string
+	KafkaTickerTopic    string
+	KafkaOrderBookTopic string
+	KafkaAlertTopic     string
+	KafkaAcks           int
+	// NATSURL, as nats://[user[:password]@]host[:port] or nats://token@host, enables
+	// publishing events on subjects like ticker.BTCINR, under NATSSubjectPrefix if set.
+	// NATSJetStream waits for a JetStream acknowledgement of every event.
+	NATSURL           string
+	NATSSubjectPrefix string
+	NATSJetStream     bool
+	// MQTTURL, as mqtt://[user[:password]@]host[:port], enables publishing the event types
+	// in MQTTEvents (default only tickers) to MQTTTopic, a template where {type} and
+	// {symbol} are filled in. MQTTRetain keeps the last message of every topic on the
+	// broker for subscribers that connect later.
+	MQTTURL      string
+	MQTTTopic    string
+	MQTTEvents   []string
+	MQTTRetain   bool
+	MQTTClientID string
+	// RedisURL, as redis://[[user]:password@]host[:port][/db], enables publishing every
+	// event on channels like cryptotracker:ticker:BTCINR, with RedisChannelPrefix in place
+	// of cryptotracker. With RedisFollow the tracker instead subscribes to another
+	// tracker's ticker and order book channels and stops polling them itself.
+	RedisURL           string
+	RedisChannelPrefix string
+	RedisFollow        bool
+}
+
+// Load configuration from a JSON, YAML or TOML file, detected by extension
+func loadConfig(filename string, target *Config) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		raw, err = parseYAML(data)
+	case ".toml":
+		raw, err = parseTOML(data)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return decodeConfig(flattenConfig(raw), target)
+}
+
+const (
+	envPrefix = "CRYPTOTRACKER_"
+	// FlagSetName names the program in flag usage output
+	FlagSetName = "cryptotracker"
+)
+
+// defaultConfigFiles are tried in order when no config path is given
+var defaultConfigFiles = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// defaultConfig holds the documented default of every setting a config file, the
+// environment or a flag leaves unset. The first group matches the original C++ server.
+var defaultConfig = Config{
+	APIBaseURL: "https://api.coindcx.com",
+	MaxRetries: 3,
+	RetryDelay: 1000,
+	LogLevel:   "info",
+	Port:       8080,
+	Host:       "localhost",
+
+	UnixSocketMode: defaultUnixSocketMode,
+
+	FXAPIURL:           DefaultFXAPIURL,
+	CoinGeckoAPIURL:    DefaultCoinGeckoAPIURL,
+	StorageDir:         DefaultStorageDir,
+	ArbitrageThreshold: DefaultArbitrageThreshold,
+	ArbitrageFeeRate:   DefaultArbitrageFeeRate,
+	AlertCooldown:      defaultAlertCooldown,
+	ListingAlerts:      true,
+	MarketStatusAlerts: true,
+	TimestampFormat:    TimestampsEpoch,
+	StreamURL:          DefaultStreamURL,
+
+	FuturesMarginCurrencies: []string{"USDT"},
+	FundingRetention:        DefaultFundingRetention,
+	LendingRetention:        DefaultLendingRetention,
+	OpenInterestInterval:    DefaultOpenInterestInterval,
+	AggregationTimeframes:   defaultAggregationTimeframes,
+	AggregationCandles:      DefaultAggregationCandles,
+	AggregationLateness:     defaultAggregationLateness,
+	OpenInterestRetention:   DefaultOpenInterestRetention,
+
+	RefreshInterval:         int(DefaultRefreshInterval / time.Second),
+	MarketsRefreshInterval:  int(DefaultMarketsRefreshInterval / time.Second),
+	FXRefreshInterval:       int(DefaultFXRefreshInterval / time.Second),
+	MetadataRefreshInterval: int(DefaultMetadataRefreshInterval / time.Second),
+	RefreshJitter:           defaultRefreshJitter,
+
+	AdaptiveWindow:              int(DefaultAdaptiveWindow / time.Second),
+	AdaptiveVolatilityThreshold: DefaultAdaptiveVolatilityThreshold,
+	AdaptiveMinInterval:         int(DefaultAdaptiveMinInterval / time.Millisecond),
+	AdaptiveMaxInterval:         int(DefaultAdaptiveMaxInterval / time.Millisecond),
+
+	SubscriptionWindow:  int(DefaultSubscriptionWindow / time.Second),
+	OrderBookCacheTTL:   int(DefaultOrderBookCacheTTL / time.Millisecond),
+	UpstreamConcurrency: DefaultUpstreamConcurrency,
+	UpstreamTimeout:     int(DefaultUpstreamTimeout / time.Second),
+	UpstreamUserAgent:   defaultUpstreamUserAgent,
+	ProbeInterval:       int(DefaultProbeInterval / time.Second),
+	UpstreamLatencySLO:  defaultUpstreamLatencySLO,
+	UpstreamSLOTarget:   defaultUpstreamSLOTarget,
+
+	CORSAllowedOrigins: defaultCORSAllowedOrigins,
+	CORSAllowedMethods: defaultCORSAllowedMethods,
+	CORSAllowedHeaders: defaultCORSAllowedHeaders,
+
+	ReadTimeout:       30,
+	ReadHeaderTimeout: 10,
+	WriteTimeout:      30,
+	IdleTimeout:       120,
+	HandlerTimeout:    20,
+	HTTP2:             true,
+
+	UpgradeDrainTimeout: defaultUpgradeDrainTimeout,
+
+	StaleThreshold: int(DefaultStaleThreshold / time.Second),
+	ReplaySpeed:    1,
+
+	HistoryRawRetention:    defaultHistoryRawRetention,
+	HistoryMinuteRetention: defaultHistoryMinuteRetention,
+	HistoryBackfillWindow:  defaultHistoryBackfillWindow,
+
+	KafkaTickerTopic:    defaultKafkaTickerTopic,
+	KafkaOrderBookTopic: defaultKafkaOrderBookTopic,
+	KafkaAlertTopic:     defaultKafkaAlertTopic,
+	KafkaAcks:           defaultKafkaAcks,
+
+	MQTTTopic:  DefaultMQTTTopic,
+	MQTTEvents: defaultMQTTEvents,
+	MQTTRetain: true,
+
+	RedisChannelPrefix: DefaultRedisChannelPrefix,
+}
+
+var (
+	// active holds the Config in effect; reloads replace it wholesale
+	active atomic.Value
+	// configArgs are the command-line arguments the config was first built from
+	configArgs []string
+	// configWriteMutex serializes reloads and runtime updates so neither loses the other's changes
+	configWriteMutex sync.Mutex
+)
+
+func init() {
+	setActive(defaultConfig)
+}
+
+// ConfigErrors collects every problem found while loading and validating configuration
+type configErrors []string
+
+func (e configErrors) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// configAcronyms are split out of runs of capitals when deriving env and flag names
+var configAcronyms = []string{"API", "URL", "FX", "TTL"}
+
+// configField is a Config field and the names it can be set by outside config.json
+type configField struct {
+	index int
+	env   string
+	flag  string
+}
+
+// ConfigFields lists every Config field, e.g. APIBaseURL becomes
+// CRYPTOTRACKER_API_BASE_URL and -api-base-url
+func configFields() []configField {
+	configType := reflect.TypeOf(Config{})
+	fields := make([]configField, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		words := configWords(configType.Field(i).Name)
+		fields = append(fields, configField{
+			index: i,
+			env:   envPrefix + strings.ToUpper(strings.Join(words, "_")),
+			flag:  strings.ToLower(strings.Join(words, "-")),
+		})
+	}
+	return fields
+}
+
+// ConfigWords splits a CamelCase field name into words, keeping known acronyms whole
+func configWords(name string) []string {
+	words := []string{}
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		boundary := i == len(runes) ||
+			(unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1])) ||
+			(unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))
+		if boundary {
+			words = append(words, splitAcronyms(string(runes[start:i]))...)
+			start = i
+		}
+	}
+	return words
+}
+
+// SplitAcronyms breaks an all-capitals run such as APIURL into API and URL
+func splitAcronyms(word string) []string {
+	if strings.ToUpper(word) != word {
+		return []string{word}
+	}
+	parts := []string{}
+	for word != "" {
+		matched := false
+		for _, acronym := range configAcronyms {
+			if strings.HasPrefix(word, acronym) && len(word) > len(acronym) {
+				parts = append(parts, acronym)
+				word = word[len(acronym):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			parts = append(parts, word)
+			break
+		}
+	}
+	return parts
+}
+
+// SetConfigValue parses raw into a config field. Scalars use their plain text form and
+// string lists may be comma separated; anything else, such as UpstreamRateLimits, is JSON.
+func setConfigValue(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(value)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case reflect.Slice:
+		// Lists such as ACMEDomains may be given comma separated instead of as JSON
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			items := []string{}
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+		return json.Unmarshal([]byte(raw), field.Addr().Interface())
+	default:
+		return json.Unmarshal([]byte(raw), field.Addr().Interface())
+	}
+	return nil
+}
+
+// DecodeConfig sets each flattened setting on target separately, so one bad value does
+// not hide the others. Unknown settings are reported but not fatal.
+func decodeConfig(flat map[string]interface{}, target *Config) error {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	value := reflect.ValueOf(target).Elem()
+	var problems configErrors
+	for _, key := range keys {
+		field, exists := value.Type().FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+		if !exists {
+			fmt.Println("Ignoring unknown config setting:", key)
+			continue
+		}
+		data, err := json.Marshal(flat[key])
+		if err == nil {
+			err = json.Unmarshal(data, value.FieldByIndex(field.Index).Addr().Interface())
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// ValidateConfig checks settings that would otherwise fail later or silently misbehave
+func validateConfig(c Config) configErrors {
+	var problems configErrors
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	checkURL := func(name, value string, schemes ...string) {
+		if value == "" {
+			return
+		}
+		parsed, err := url.Parse(value)
+		valid := err == nil && parsed.Host != ""
+		if valid {
+			valid = false
+			for _, scheme := range schemes {
+				valid = valid || parsed.Scheme == scheme
+			}
+		}
+		check(valid, "%s must be a %s URL, got %q", name, strings.Join(schemes, " or "), value)
+	}
+
+	check(c.APIBaseURL != "", "APIBaseURL must not be empty")
+	checkURL("APIBaseURL", c.APIBaseURL, "http", "https")
+	checkURL("FXAPIURL", c.FXAPIURL, "http", "https")
+	checkURL("CoinGeckoAPIURL", c.CoinGeckoAPIURL, "http", "https")
+	checkURL("AlertWebhookURL", c.AlertWebhookURL, "http", "https")
+	checkURL("StreamURL", c.StreamURL, "ws", "wss")
+	check(!c.FuturesEnabled || len(c.FuturesMarginCurrencies) > 0, "FuturesMarginCurrencies must not be empty when FuturesEnabled is set")
+	for _, margin := range c.FuturesMarginCurrencies {
+		check(margin != "" && !strings.ContainsAny(margin, "&=# "), "FuturesMarginCurrencies entry %q is not a currency", margin)
+	}
+	check(c.FundingRetention >= 0, "FundingRetention must not be negative")
+	check(c.AggregationCandles >= 0, "AggregationCandles must not be negative")
+	check(c.AggregationLateness >= 0 && c.AggregationLateness <= 60, "AggregationLateness must be between 0 and 60 seconds")
+	check(c.OpenInterestInterval >= 0, "OpenInterestInterval must not be negative")
+	check(c.OpenInterestRetention >= 0, "OpenInterestRetention must not be negative")
+	checkURL("LendingRatesURL", c.LendingRatesURL, "http", "https")
+	check(c.LendingRetention >= 0, "LendingRetention must not be negative")
+	checkURL("UpstreamProxy", c.UpstreamProxy, upstreamProxySchemes...)
+	for _, server := range c.UpstreamDNSServers {
+		host, _, err := net.SplitHostPort(server)
+		if err != nil {
+			host = server
+		}
+		_, err = netip.ParseAddr(strings.Trim(host, "[]"))
+		check(err == nil, "UpstreamDNSServers entry %q is not an IP address with an optional port", server)
+	}
+	check(c.UpstreamDNSCacheTTL >= 0, "UpstreamDNSCacheTTL must not be negative, got %d", c.UpstreamDNSCacheTTL)
+	if c.Port != 0 || c.UnixSocket == "" {
+		check(c.Port >= 1 && c.Port <= 65535, "Port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	check(logging.LevelRank(c.LogLevel) >= 0, "LogLevel must be one of %s, got %q", strings.Join(logging.Levels, ", "), c.LogLevel)
+
+	check((c.CertFile == "") == (c.KeyFile == ""), "CertFile and KeyFile must be set together")
+	if c.HTTPRedirectPort != 0 {
+		check(c.HTTPRedirectPort >= 1 && c.HTTPRedirectPort <= 65535, "HTTPRedirectPort must be between 1 and 65535, got %d", c.HTTPRedirectPort)
+		check(c.HTTPRedirectPort != c.Port, "HTTPRedirectPort must differ from Port")
+		check(c.Port != 0, "HTTPRedirectPort requires Port")
+		check(c.CertFile != "" || len(c.ACMEDomains) > 0, "HTTPRedirectPort requires CertFile and KeyFile or ACMEDomains")
+	}
+	check(c.CertFile == "" || len(c.ACMEDomains) == 0, "CertFile and ACMEDomains cannot be used together")
+	for _, domain := range c.ACMEDomains {
+		check(domain != "" && !strings.ContainsAny(domain, "/: *"), "ACMEDomains entry %q is not a domain name", domain)
+	}
+	checkURL("ACMEDirectoryURL", c.ACMEDirectoryURL, "https")
+	if c.AdminPort != 0 {
+		check(c.AdminPort >= 1 && c.AdminPort <= 65535, "AdminPort must be between 1 and 65535, got %d", c.AdminPort)
+		check(c.AdminPort != c.Port && c.AdminPort != c.HTTPRedirectPort, "AdminPort must differ from Port and HTTPRedirectPort")
+	}
+	check((c.AdminCertFile == "") == (c.AdminKeyFile == ""), "AdminCertFile and AdminKeyFile must be set together")
+	if c.AdminClientCAFile != "" {
+		check(c.AdminPort != 0, "AdminClientCAFile requires AdminPort")
+		check(c.AdminCertFile != "" || c.CertFile != "" || len(c.ACMEDomains) > 0, "AdminClientCAFile requires a server certificate")
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		check(origin == "*" || strings.Contains(origin, "://"), "CORSAllowedOrigins entry %q must be \"*\" or include a scheme", origin)
+		check(origin != "*" || !c.CORSAllowCredentials, "CORSAllowCredentials cannot be combined with the \"*\" CORSAllowedOrigins entry")
+	}
+	check(c.ReadTimeout >= 0 && c.ReadHeaderTimeout >= 0 && c.WriteTimeout >= 0 && c.IdleTimeout >= 0 && c.HandlerTimeout >= 0,
+		"ReadTimeout, ReadHeaderTimeout, WriteTimeout, IdleTimeout and HandlerTimeout must not be negative")
+	if c.HandlerTimeout > 0 && c.WriteTimeout > 0 {
+		check(c.HandlerTimeout < c.WriteTimeout, "HandlerTimeout must be shorter than WriteTimeout so timed out requests still get a response")
+	}
+	check(c.HTTP2MaxConcurrentStreams >= 0, "HTTP2MaxConcurrentStreams must not be negative, got %d", c.HTTP2MaxConcurrentStreams)
+	check(c.UpgradeDrainTimeout >= 0, "UpgradeDrainTimeout must not be negative, got %d", c.UpgradeDrainTimeout)
+	check(!c.Demo || c.ReplayFile == "", "Demo and ReplayFile cannot be used together")
+	check(c.RecordFile == "" || c.RecordFile != c.ReplayFile, "RecordFile must differ from ReplayFile")
+	check(c.ReplaySpeed >= 0, "ReplaySpeed must not be negative, got %g", c.ReplaySpeed)
+	check(c.ChaosLatency >= 0 && c.ChaosLatency <= 60000, "ChaosLatency must be between 0 and 60000 milliseconds, got %d", c.ChaosLatency)
+	check(c.ChaosLatencyRate >= 0 && c.ChaosLatencyRate <= 1, "ChaosLatencyRate must be between 0 and 1, got %g", c.ChaosLatencyRate)
+	check(c.ChaosTimeoutRate >= 0 && c.ChaosErrorRate >= 0 && c.ChaosMalformedRate >= 0 &&
+		c.ChaosTimeoutRate+c.ChaosErrorRate+c.ChaosMalformedRate <= 1,
+		"ChaosTimeoutRate, ChaosErrorRate and ChaosMalformedRate must not be negative and must add up to at most 1")
+	check(c.HistoryRawRetention >= 0 && c.HistoryMinuteRetention >= 0 && c.HistoryHourRetention >= 0,
+		"HistoryRawRetention, HistoryMinuteRetention and HistoryHourRetention must not be negative")
+	for _, broker := range c.KafkaBrokers {
+		_, port, err := net.SplitHostPort(broker)
+		check(err == nil && port != "", "KafkaBrokers entry %q must be host:port", broker)
+	}
+	if c.NATSURL != "" {
+		parsed, err := url.Parse(c.NATSURL)
+		check(err == nil && parsed.Scheme == "nats" && parsed.Hostname() != "", "NATSURL must look like nats://host:4222, got %q", c.NATSURL)
+	}
+	if c.MQTTURL != "" {
+		parsed, err := url.Parse(c.MQTTURL)
+		check(err == nil && parsed.Scheme == "mqtt" && parsed.Hostname() != "", "MQTTURL must look like mqtt://host:1883, got %q", c.MQTTURL)
+	}
+	if c.RedisURL != "" {
+		parsed, err := url.Parse(c.RedisURL)
+		check(err == nil && parsed.Scheme == "redis" && parsed.Hostname() != "", "RedisURL must look like redis://host:6379, got %q", c.RedisURL)
+	}
+	check(!c.RedisFollow || c.RedisURL != "", "RedisFollow requires RedisURL")
+	check(!strings.ContainsAny(c.RedisChannelPrefix, "*?[ "), "RedisChannelPrefix must not contain spaces or glob characters")
+	check(!strings.ContainsAny(c.MQTTTopic, "+#"), "MQTTTopic must not contain wildcards")
+	check(!strings.ContainsAny(c.NATSSubjectPrefix, " *>"), "NATSSubjectPrefix must not contain spaces or wildcards")
+	check(c.KafkaAcks >= -1 && c.KafkaAcks <= 1, "KafkaAcks must be 0, 1 or -1, got %d", c.KafkaAcks)
+	check(c.SnapshotDumpInterval >= 0 && c.SnapshotDumpKeep >= 0 && c.SnapshotDumpMaxAge >= 0,
+		"SnapshotDumpInterval, SnapshotDumpKeep and SnapshotDumpMaxAge must not be negative")
+	check(c.StaleThreshold >= 0, "StaleThreshold must not be negative, got %d", c.StaleThreshold)
+	check(c.CORSMaxAge >= 0, "CORSMaxAge must not be negative, got %d", c.CORSMaxAge)
+	checkNetworks := func(name string, entries []string) {
+		for _, entry := range entries {
+			_, err := ParsePrefix(entry)
+			check(err == nil, "%s entry %q must be a CIDR or IP address", name, entry)
+		}
+	}
+	checkNetworks("AllowedCIDRs", c.AllowedCIDRs)
+	checkNetworks("DeniedCIDRs", c.DeniedCIDRs)
+	checkNetworks("TrustedProxies", c.TrustedProxies)
+	check(c.MaxRetries >= 0, "MaxRetries must not be negative, got %d", c.MaxRetries)
+	check(c.RetryDelay >= 0, "RetryDelay must not be negative, got %d", c.RetryDelay)
+	check(c.RefreshInterval >= 1 && c.RefreshInterval <= 3600, "RefreshInterval must be between 1 and 3600 seconds, got %d", c.RefreshInterval)
+	check(c.MarketsRefreshInterval >= 0 && c.FXRefreshInterval >= 0 && c.MetadataRefreshInterval >= 0 && c.OrderBookRefreshInterval >= 0,
+		"MarketsRefreshInterval, FXRefreshInterval, MetadataRefreshInterval and OrderBookRefreshInterval must not be negative")
+	check(c.RefreshJitter >= 0 && c.RefreshJitter < 1, "RefreshJitter must be in [0, 1), got %g", c.RefreshJitter)
+	check(c.AdaptiveWindow >= 0 && c.AdaptiveMinInterval >= 0 && c.AdaptiveMaxInterval >= 0,
+		"AdaptiveWindow, AdaptiveMinInterval and AdaptiveMaxInterval must not be negative")
+	check(c.AdaptiveMaxInterval == 0 || c.AdaptiveMinInterval <= c.AdaptiveMaxInterval, "AdaptiveMinInterval must not exceed AdaptiveMaxInterval")
+	check(c.AdaptiveVolatilityThreshold >= 0, "AdaptiveVolatilityThreshold must not be negative, got %g", c.AdaptiveVolatilityThreshold)
+	check(c.SubscriptionWindow >= 0, "SubscriptionWindow must not be negative, got %d", c.SubscriptionWindow)
+	check(c.OrderBookCacheTTL >= 0 && c.OrderBookCacheTTL <= 60000, "OrderBookCacheTTL must be between 0 and 60000 milliseconds, got %d", c.OrderBookCacheTTL)
+	check(c.UpstreamConcurrency >= 0 && c.UpstreamConcurrency <= 256, "UpstreamConcurrency must be between 0 and 256, got %d", c.UpstreamConcurrency)
+	check(c.UpstreamTimeout >= 0 && c.UpstreamTimeout <= 300, "UpstreamTimeout must be between 0 and 300 seconds, got %d", c.UpstreamTimeout)
+	check(c.ProbeInterval >= 1 && c.ProbeInterval <= 3600, "ProbeInterval must be between 1 and 3600 seconds, got %d", c.ProbeInterval)
+	check(c.UpstreamLatencySLO >= 1, "UpstreamLatencySLO must be at least 1 millisecond, got %d", c.UpstreamLatencySLO)
+	check(c.UpstreamSLOTarget >= 0 && c.UpstreamSLOTarget <= 1, "UpstreamSLOTarget must be between 0 and 1, got %g", c.UpstreamSLOTarget)
+	check(c.ArbitrageThreshold >= 0, "ArbitrageThreshold must not be negative, got %g", c.ArbitrageThreshold)
+	check(c.ArbitrageFeeRate >= 0 && c.ArbitrageFeeRate < 1, "ArbitrageFeeRate must be in [0, 1), got %g", c.ArbitrageFeeRate)
+	check(c.AlertCooldown >= 0, "AlertCooldown must not be negative, got %d", c.AlertCooldown)
+
+	endpoints := make([]string, 0, len(c.UpstreamRateLimits))
+	for endpoint := range c.UpstreamRateLimits {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		limit := c.UpstreamRateLimits[endpoint]
+		check(limit.Rate >= 0 && limit.Burst >= 0, "UpstreamRateLimits[%s] must not be negative", endpoint)
+	}
+	checkHeaders := func(setting string, headers map[string]string) {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			check(validHeaderName(name), "%s contains invalid header name %q", setting, name)
+			check(validHeaderValue(headers[name]), "%s[%s] must not contain line breaks", setting, name)
+		}
+	}
+	check(validHeaderValue(c.UpstreamUserAgent), "UpstreamUserAgent must not contain line breaks")
+	checkHeaders("UpstreamHeaders", c.UpstreamHeaders)
+	hosts := make([]string, 0, len(c.UpstreamHostHeaders))
+	for host := range c.UpstreamHostHeaders {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		check(host != "" && !strings.ContainsAny(host, "/: "), "UpstreamHostHeaders key %q is not a host name", host)
+		checkHeaders("UpstreamHostHeaders["+host+"]", c.UpstreamHostHeaders[host])
+	}
+	if c.OIDCIssuer != "" {
+		check(strings.HasPrefix(c.OIDCIssuer, "https://") || strings.HasPrefix(c.OIDCIssuer, "http://localhost"),
+			"OIDCIssuer must be an https URL, got %q", c.OIDCIssuer)
+		check(c.OIDCAudience != "", "OIDCAudience must be set with OIDCIssuer")
+	}
+	check((c.BasicAuthUsername == "") == (c.BasicAuthPasswordHash == ""), "BasicAuthUsername and BasicAuthPasswordHash must be set together")
+	names := make([]string, 0, len(c.APIKeys))
+	for name := range c.APIKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := make(map[string]string, len(c.APIKeys))
+	for _, name := range names {
+		key := c.APIKeys[name]
+		if key == "" {
+			check(false, "APIKeys[%s] must not be empty", name)
+			continue
+		}
+		check(key != c.AdminToken, "APIKeys[%s] must differ from AdminToken", name)
+		other, duplicate := keys[key]
+		check(!duplicate, "APIKeys[%s] must differ from APIKeys[%s]", name, other)
+		keys[key] = name
+	}
+	routes := make([]string, 0, len(c.RouteLimits))
+	for route := range c.RouteLimits {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		limit := c.RouteLimits[route]
+		check(limit.MaxBodyBytes >= 0 && limit.MaxQueryLength >= 0 && limit.MaxQueryParams >= 0 && limit.Timeout >= 0,
+			"RouteLimits[%s] must not be negative", route)
+		if limit.Timeout > 0 && c.WriteTimeout > 0 {
+			check(limit.Timeout < c.WriteTimeout, "RouteLimits[%s].Timeout must be shorter than WriteTimeout", route)
+		}
+	}
+	for _, validate := range validators {
+		validate(c, check)
+	}
+	return problems
+}
+
+// Validator checks settings whose rules belong with the code reading them, reporting
+// each broken rule through check
+type Validator func(c Config, check func(ok bool, format string, args ...interface{}))
+
+// validators run after validateConfig's own checks; see RegisterValidator
+var validators []Validator
+
+// RegisterValidator adds validate to validateConfig. Packages register theirs from
+// init, so a configuration is checked by all the code that will read it.
+func RegisterValidator(validate Validator) {
+	validators = append(validators, validate)
+}
+
+// configFlag records a command-line value so it can be applied after config.json and the environment
+type configFlag struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *configFlag) String() string { return f.value }
+
+func (f *configFlag) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+func (f *configFlag) IsBoolFlag() bool { return f.isBool }
+
+// Configure builds the active config from args and remembers them for reloads. Config
+// flags are added to flags, which may already define flags of its own command; any
+// positional arguments are left in flags.Args(). Reloads re-parse args with the config
+// flags alone.
+func Configure(flags *flag.FlagSet, args []string) error {
+	cfg, err := buildConfig(flags, args)
+	if err != nil {
+		return err
+	}
+	configArgs = args
+	setActive(cfg)
+	return nil
+}
+
+// ReloadConfig rebuilds the config from the same sources as at startup and makes it
+// active, returning the previous config. An invalid reload keeps the current config.
+func reloadConfig() (Config, Config, error) {
+	configWriteMutex.Lock()
+	defer configWriteMutex.Unlock()
+	previous := Current()
+	cfg, err := buildConfig(flag.NewFlagSet(FlagSetName, flag.ContinueOnError), configArgs)
+	if err != nil {
+		return previous, previous, err
+	}
+	setActive(cfg)
+	return previous, cfg, nil
+}
+
+// restartRequiredSettings are read once at startup, so reloading cannot change them
+var restartRequiredSettings = []string{
+	"Port", "Host", "UnixSocket", "UnixSocketMode", "UnixSocketGroup", "StorageDir", "StreamEnabled", "StreamURL", "UpstreamConcurrency", "UpstreamTimeout",
+	"UpstreamProxy", "UpstreamNoProxy",
+	"CertFile", "KeyFile", "HTTPRedirectPort", "ACMEDomains", "ACMEEmail", "ACMEDirectoryURL",
+	"AdminPort", "AdminClientCAFile", "AdminCertFile", "AdminKeyFile",
+	"ReadTimeout", "ReadHeaderTimeout", "WriteTimeout", "IdleTimeout", "HTTP2", "H2C", "HTTP2MaxConcurrentStreams",
+	"Demo", "RecordFile", "ReplayFile", "ReplaySpeed",
+	"HistoryPersist", "KafkaBrokers", "KafkaTickerTopic", "KafkaOrderBookTopic", "KafkaAlertTopic", "KafkaAcks",
+	"NATSURL", "NATSSubjectPrefix", "NATSJetStream", "MQTTURL", "MQTTTopic", "MQTTEvents", "MQTTRetain", "MQTTClientID",
+	"RedisURL", "RedisChannelPrefix", "RedisFollow", "AggregationTimeframes",
+}
+
+// ChangedSettings returns which of the named settings differ between two configs
+func changedSettings(previous, current Config, names []string) []string {
+	changed := []string{}
+	before := reflect.ValueOf(previous)
+	after := reflect.ValueOf(current)
+	for _, name := range names {
+		if !reflect.DeepEqual(before.FieldByName(name).Interface(), after.FieldByName(name).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// WatchReload reloads the config on every SIGHUP and passes it to apply so running
+// components can pick up settings they copied at startup
+func WatchReload(apply func(Config)) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			previous, current, err := reloadConfig()
+			if err != nil {
+				logging.Error("Error reloading configuration:", err)
+				continue
+			}
+			apply(current)
+			if changed := changedSettings(previous, current, restartRequiredSettings); len(changed) > 0 {
+				logging.Warn("Configuration reloaded; restart to apply changes to", strings.Join(changed, ", "))
+			} else {
+				logging.Info("Configuration reloaded")
+			}
+		}
+	}()
+}
+
+// runtimeTunables are the settings that may be changed through /admin/config
+var runtimeTunables = []string{
+	"LogLevel", "RefreshInterval", "MarketsRefreshInterval", "FXRefreshInterval", "MetadataRefreshInterval",
+	"OrderBookRefreshInterval", "RefreshJitter", "AdaptiveRefresh", "AdaptiveWindow", "AdaptiveVolatilityThreshold",
+	"AdaptiveMinInterval", "AdaptiveMaxInterval", "SubscriptionWindow", "SelectiveTickerRefresh", "OrderBookCacheTTL",
+	"UpstreamRateLimits", "UpstreamDNSServers", "UpstreamDNSCacheTTL", "UpstreamUserAgent", "UpstreamHeaders", "UpstreamHostHeaders", "ArbitrageThreshold", "ArbitrageFeeRate", "AlertWebhookURL", "AlertCooldown", "ListingAlerts", "MarketStatusAlerts", "HealthAlerts", "MarketStatusFilter", "SymbolAliases", "TimestampFormat",
+	"CORSAllowedOrigins", "CORSAllowedMethods", "CORSAllowedHeaders", "CORSAllowCredentials", "CORSMaxAge",
+	"AllowedCIDRs", "DeniedCIDRs", "TrustedProxies", "APIKeys", "ClientRateLimits",
+	"HandlerTimeout", "RouteLimits", "DebugEndpoints", "StaleThreshold", "StaleFailsReadiness", "ProbeInterval",
+	"UpstreamLatencySLO", "UpstreamSLOTarget", "UpgradeDrainTimeout",
+	"ChaosLatencyRate", "ChaosLatency", "ChaosTimeoutRate", "ChaosErrorRate", "ChaosMalformedRate",
+	"FuturesEnabled", "FuturesMarginCurrencies", "FundingRetention", "AggregationCandles", "AggregationLateness", "OpenInterestInterval", "OpenInterestRetention",
+	"LendingRatesURL", "LendingRetention",
+	"HistoryRawRetention", "HistoryMinuteRetention", "HistoryHourRetention", "HistoryBackfillWindow",
+	"SnapshotDumpInterval", "SnapshotDumpDir", "SnapshotDumpCompress", "SnapshotDumpKeep", "SnapshotDumpMaxAge",
+}
+
+// Update applies a partial config to the active one. Keys may be flat or in
+// sections as in a config file, but only runtimeTunables are accepted. Changes last until
+// the next reload or restart.
+func Update(patch map[string]interface{}) (Config, error) {
+	configWriteMutex.Lock()
+	defer configWriteMutex.Unlock()
+
+	flat := flattenConfig(patch)
+	var problems configErrors
+	for key := range flat {
+		allowed := false
+		for _, name := range runtimeTunables {
+			allowed = allowed || strings.EqualFold(name, key)
+		}
+		if !allowed {
+			problems = append(problems, fmt.Sprintf("%s cannot be changed at runtime", key))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return Config{}, problems
+	}
+
+	cfg := Current()
+	// Decoding into a map would merge with the current one, so start from scratch
+	if _, exists := flat["upstreamratelimits"]; exists {
+		cfg.UpstreamRateLimits = nil
+	}
+	if _, exists := flat["upstreamheaders"]; exists {
+		cfg.UpstreamHeaders = nil
+	}
+	if _, exists := flat["upstreamhostheaders"]; exists {
+		cfg.UpstreamHostHeaders = nil
+	}
+	if _, exists := flat["routelimits"]; exists {
+		cfg.RouteLimits = nil
+	}
+	if _, exists := flat["apikeys"]; exists {
+		cfg.APIKeys = nil
+	}
+	if _, exists := flat["clientratelimits"]; exists {
+		cfg.ClientRateLimits = nil
+	}
+	if err := decodeConfig(flat, &cfg); err != nil {
+		return Config{}, err
+	}
+	if problems := validateConfig(cfg); len(problems) > 0 {
+		return Config{}, problems
+	}
+	setActive(cfg)
+	return cfg, nil
+}
+
+// setActive puts cfg in effect, along with its LogLevel
+func setActive(cfg Config) {
+	active.Store(cfg)
+	logging.SetLevel(cfg.LogLevel)
+}
+
+// Current returns the configuration in effect. Callers should read it once per
+// operation rather than holding on to it, so reloads take effect.
+func Current() Config {
+	return active.Load().(Config)
+}
+
+// BuildConfig reads, in increasing precedence, the config file, CRYPTOTRACKER_*
+// environment variables and command-line flags. Without an explicit path the first of
+// defaultConfigFiles that exists is used, and having none is not an error. The config
+// flags are registered on flags before args are parsed.
+func buildConfig(flags *flag.FlagSet, args []string) (Config, error) {
+	configFile := flags.String("config", "", "path to a JSON, YAML or TOML config file (env "+envPrefix+"CONFIG)")
+
+	configType := reflect.TypeOf(Config{})
+	fields := configFields()
+	values := make([]*configFlag, len(fields))
+	for i, field := range fields {
+		values[i] = &configFlag{isBool: configType.Field(field.index).Type.Kind() == reflect.Bool}
+		flags.Var(values[i], field.flag, "sets "+configType.Field(field.index).Name+" (env "+field.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	filename := *configFile
+	if filename == "" {
+		filename = os.Getenv(envPrefix + "CONFIG")
+	}
+	cfg := defaultConfig
+	var problems configErrors
+	addLoadError := func(err error) error {
+		if fieldErrors, isFieldErrors := err.(configErrors); isFieldErrors {
+			problems = append(problems, fieldErrors...)
+			return nil
+		}
+		return err
+	}
+	if filename != "" {
+		if err := loadConfig(filename, &cfg); err != nil && addLoadError(err) != nil {
+			return Config{}, err
+		}
+	} else {
+		for _, candidate := range defaultConfigFiles {
+			err := loadConfig(candidate, &cfg)
+			if err != nil && os.IsNotExist(err) {
+				continue
+			}
+			if err != nil && addLoadError(err) != nil {
+				return Config{}, err
+			}
+			break
+		}
+	}
+
+	target := reflect.ValueOf(&cfg).Elem()
+	for _, field := range fields {
+		if raw, exists := os.LookupEnv(field.env); exists {
+			if err := setConfigValue(target.Field(field.index), raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", field.env, err))
+			}
+		}
+	}
+	for i, field := range fields {
+		if values[i].set {
+			if err := setConfigValue(target.Field(field.index), values[i].value); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: %v", field.flag, err))
+			}
+		}
+	}
+
+	problems = append(problems, validateConfig(cfg)...)
+	if len(problems) > 0 {
+		return Config{}, problems
+	}
+	return cfg, nil
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// configSectionPrefixes maps nested config sections to the field prefix their keys
+// take, e.g. alerting.webhook_url sets AlertWebhookURL. Sections not listed use their
+// own name, so upstream.timeout sets UpstreamTimeout and server.port sets Port.
+var configSectionPrefixes = map[string]string{
+	"alerting": "alert",
+	"alerts":   "alert",
+}
+
+// ConfigKey normalizes a config key such as api_base_url for matching against field names
+func configKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// FlattenConfig lifts the keys of nested sections such as server, upstream, storage
+// and alerting to the top level, naming each after the Config field it sets
+func flattenConfig(raw map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]bool)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		fields[strings.ToLower(configType.Field(i).Name)] = true
+	}
+
+	flat := make(map[string]interface{})
+	for key, value := range raw {
+		name := configKey(key)
+		section, isSection := value.(map[string]interface{})
+		if !isSection || fields[name] {
+			flat[name] = value
+			continue
+		}
+		prefix := name
+		if mapped, exists := configSectionPrefixes[name]; exists {
+			prefix = mapped
+		}
+		for childKey, childValue := range section {
+			child := configKey(childKey)
+			if fields[prefix+child] {
+				child = prefix + child
+			}
+			flat[child] = childValue
+		}
+	}
+	return flat
+}
+
+// yamlLine is a non-blank YAML line with comments removed
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// ParseYAML reads the subset of YAML used for configuration: nested block mappings,
+// block sequences of scalars, flow collections and plain or quoted scalars
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	lines := []yamlLine{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(line) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	parsed, consumed, err := parseYAMLMapping(lines, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if consumed < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[consumed].number)
+	}
+	return parsed, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, indent int) (map[string]interface{}, int, error) {
+	mapping := make(map[string]interface{})
+	i := 0
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, rest, found := splitKeyValue(line.text, ':')
+		if !found {
+			return nil, 0, fmt.Errorf("line %d: expected 'key: value'", line.number)
+		}
+		i++
+
+		if rest != "" {
+			value, err := parseScalar(rest, ':')
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %v", line.number, err)
+			}
+			mapping[key] = value
+			continue
+		}
+
+		// An empty value opens a nested block, or is null if nothing is indented under it
+		if i < len(lines) && lines[i].indent > indent {
+			var value interface{}
+			var consumed int
+			var err error
+			if strings.HasPrefix(lines[i].text, "- ") || lines[i].text == "-" {
+				value, consumed, err = parseYAMLSequence(lines[i:], lines[i].indent)
+			} else {
+				value, consumed, err = parseYAMLMapping(lines[i:], lines[i].indent)
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key] = value
+			i += consumed
+		} else if i < len(lines) && lines[i].indent == indent && strings.HasPrefix(lines[i].text, "- ") {
+			// Sequences may sit at the same indentation as their key
+			value, consumed, err := parseYAMLSequence(lines[i:], indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key] = value
+			i += consumed
+		} else {
+			mapping[key] = nil
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("line %d: unexpected indentation", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+func parseYAMLSequence(lines []yamlLine, indent int) ([]interface{}, int, error) {
+	sequence := []interface{}{}
+	i := 0
+	for i < len(lines) && lines[i].indent == indent && (strings.HasPrefix(lines[i].text, "- ") || lines[i].text == "-") {
+		item := strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-"))
+		value, err := parseScalar(item, ':')
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %v", lines[i].number, err)
+		}
+		sequence = append(sequence, value)
+		i++
+	}
+	return sequence, i, nil
+}
+
+// ParseTOML reads the subset of TOML used for configuration: [tables], dotted keys,
+// strings, numbers, booleans, single-line arrays and inline tables
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+	for i, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(stripComment(line))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if strings.HasPrefix(text, "[[") {
+				return nil, fmt.Errorf("line %d: arrays of tables are not supported", i+1)
+			}
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", i+1)
+			}
+			table, err := tomlTable(root, strings.TrimSpace(text[1:len(text)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			current = table
+			continue
+		}
+
+		key, rest, found := splitKeyValue(text, '=')
+		if !found || rest == "" {
+			return nil, fmt.Errorf("line %d: expected 'key = value'", i+1)
+		}
+		value, err := parseScalar(rest, '=')
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if _, isString := value.(string); isString && !isQuoted(rest) {
+			return nil, fmt.Errorf("line %d: strings must be quoted", i+1)
+		}
+
+		// Dotted keys such as upstream.timeout = 10 create intermediate tables
+		path := strings.Split(key, ".")
+		table, err := tomlTable(current, strings.Join(path[:len(path)-1], "."))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		table[strings.TrimSpace(path[len(path)-1])] = value
+	}
+	return root, nil
+}
+
+// TomlTable returns the table at a dotted path below parent, creating it if needed
+func tomlTable(parent map[string]interface{}, path string) (map[string]interface{}, error) {
+	if path == "" {
+		return parent, nil
+	}
+	table := parent
+	for _, name := range strings.Split(path, ".") {
+		name = strings.Trim(strings.TrimSpace(name), `"`)
+		existing, exists := table[name]
+		if !exists {
+			child := make(map[string]interface{})
+			table[name] = child
+			table = child
+			continue
+		}
+		child, isTable := existing.(map[string]interface{})
+		if !isTable {
+			return nil, fmt.Errorf("%s is not a table", name)
+		}
+		table = child
+	}
+	return table, nil
+}
+
+// StripComment removes a trailing # comment that is not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// SplitKeyValue splits "key: value" or "key = value" at the first separator outside quotes.
+// For ':' the separator must end the line or be followed by a space, as in YAML.
+func splitKeyValue(text string, separator byte) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == separator && (separator != ':' || i+1 == len(text) || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if isQuoted(key) {
+				key = key[1 : len(key)-1]
+			}
+			return key, strings.TrimSpace(text[i+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]
+}
+
+// ParseScalar converts a plain, quoted or flow value; separator is the key/value
+// separator used inside inline tables
+func parseScalar(value string, separator byte) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return nil, nil
+	case value[0] == '"':
+		if !isQuoted(value) {
+			return nil, errors.New("unterminated string")
+		}
+		return strconv.Unquote(value)
+	case value[0] == '\'':
+		if !isQuoted(value) {
+			return nil, errors.New("unterminated string")
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case value[0] == '[':
+		if value[len(value)-1] != ']' {
+			return nil, errors.New("unterminated array")
+		}
+		items := []interface{}{}
+		for _, item := range splitTopLevel(value[1 : len(value)-1]) {
+			parsed, err := parseScalar(item, separator)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, parsed)
+		}
+		return items, nil
+	case value[0] == '{':
+		if value[len(value)-1] != '}' {
+			return nil, errors.New("unterminated inline table")
+		}
+		table := make(map[string]interface{})
+		for _, entry := range splitTopLevel(value[1 : len(value)-1]) {
+			key, rest, found := splitKeyValue(entry, separator)
+			if !found {
+				return nil, fmt.Errorf("invalid inline table entry %q", entry)
+			}
+			parsed, err := parseScalar(rest, separator)
+			if err != nil {
+				return nil, err
+			}
+			table[key] = parsed
+		}
+		return table, nil
+	}
+
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	number := strings.ReplaceAll(value, "_", "")
+	if integer, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return integer, nil
+	}
+	if float, err := strconv.ParseFloat(number, 64); err == nil {
+		return float, nil
+	}
+	return value, nil
+}
+
+// SplitTopLevel splits a flow collection body on commas that are not nested or quoted
+func splitTopLevel(body string) []string {
+	parts := []string{}
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, body[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(body[start:]) != "" {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
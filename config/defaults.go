@@ -0,0 +1,98 @@
+package config
+
+import "time"
+
+// Defaults of the refresh loops and upstream requests
+const (
+	DefaultRefreshInterval         = 5 * time.Second
+	DefaultMarketsRefreshInterval  = time.Hour
+	DefaultMetadataRefreshInterval = time.Hour
+	DefaultFXRefreshInterval       = 10 * time.Minute
+	DefaultProbeInterval           = 30 * time.Second
+	DefaultOrderBookCacheTTL       = 2 * time.Second
+	DefaultSubscriptionWindow      = 2 * time.Minute
+	DefaultStaleThreshold          = time.Minute
+	DefaultUpstreamTimeout         = 15 * time.Second
+	DefaultUpstreamConcurrency     = 4
+	defaultRefreshJitter           = 0.1
+	defaultUpstreamUserAgent       = "CryptoTrackerAPI"
+	defaultUpstreamLatencySLO      = 1000
+	defaultUpstreamSLOTarget       = 0.99
+)
+
+// Defaults of adaptive refreshing
+const (
+	DefaultAdaptiveWindow              = 5 * time.Minute
+	DefaultAdaptiveVolatilityThreshold = 1.0
+	DefaultAdaptiveMinInterval         = 500 * time.Millisecond
+	DefaultAdaptiveMaxInterval         = 30 * time.Second
+)
+
+// Defaults of the upstream sources besides the exchange's REST API
+const (
+	DefaultStreamURL       = "wss://stream.coindcx.com"
+	DefaultFXAPIURL        = "https://api.exchangerate.host/latest?base=INR"
+	DefaultCoinGeckoAPIURL = "https://api.coingecko.com/api/v3"
+)
+
+// Defaults of what is kept and for how long
+const (
+	DefaultStorageDir             = "data"
+	DefaultFundingRetention       = 7 * 24 * 60 * 60
+	DefaultLendingRetention       = 30 * 24 * 60 * 60
+	DefaultOpenInterestInterval   = 60
+	DefaultOpenInterestRetention  = 7 * 24 * 60 * 60
+	defaultHistoryRawRetention    = 7
+	defaultHistoryMinuteRetention = 90
+	defaultHistoryBackfillWindow  = 24
+)
+
+// Defaults of candle aggregation
+const (
+	DefaultAggregationCandles  = 120
+	defaultAggregationLateness = 5
+)
+
+var defaultAggregationTimeframes = []string{"1m", "5m", "1h"}
+
+// Defaults of arbitrage detection and alerting
+const (
+	DefaultArbitrageThreshold = 0.5
+	DefaultArbitrageFeeRate   = 0.001
+	defaultAlertCooldown      = 60
+)
+
+// Defaults of the message brokers updates are published to
+const (
+	DefaultMQTTTopic           = "cryptotracker/{type}/{symbol}"
+	DefaultRedisChannelPrefix  = "cryptotracker"
+	defaultKafkaTickerTopic    = "cryptotracker.tickers"
+	defaultKafkaOrderBookTopic = "cryptotracker.orderbooks"
+	defaultKafkaAlertTopic     = "cryptotracker.alerts"
+	defaultKafkaAcks           = 1
+)
+
+// defaultMQTTEvents publishes only ticker events
+var defaultMQTTEvents = []string{"ticker"}
+
+// Defaults of the HTTP listeners
+const (
+	defaultUnixSocketMode      = "0660"
+	defaultUpgradeDrainTimeout = 300
+)
+
+var (
+	defaultCORSAllowedOrigins = []string{"*"}
+	defaultCORSAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSAllowedHeaders = []string{"Content-Type", "Authorization", "X-API-Key"}
+)
+
+// Timestamp formats of JSON responses
+const (
+	// Epoch milliseconds, or seconds where upstream sends seconds, as the API always has
+	TimestampsEpoch = "epoch"
+	// RFC 3339 strings, in UTC unless the request has a tz parameter
+	TimestampsRFC3339 = "rfc3339"
+	// RFC 3339 strings with the original epoch kept under the field name plus _epoch
+	TimestampsBoth = "both"
+)
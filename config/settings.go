@@ -0,0 +1,50 @@
+package config
+
+import "fmt"
+
+// ChaosEnabled reports whether any upstream fault is being injected
+func (c Config) ChaosEnabled() bool {
+	return c.ChaosLatencyRate > 0 || c.ChaosTimeoutRate > 0 || c.ChaosMalformedRate > 0 || c.ChaosErrorRate > 0
+}
+
+// HealthAlertRule watches the tracker's own health, e.g. {"Condition": "consecutive_failures",
+// "Dataset": "ticker", "Threshold": 5} or {"Condition": "data_age", "Dataset": "ticker",
+// "Threshold": 120}
+type HealthAlertRule struct {
+	Condition string
+	Dataset   string
+	Threshold float64
+}
+
+// Key identifies the rule, so its alert state survives a reload that keeps it
+func (rule HealthAlertRule) Key() string {
+	return fmt.Sprintf("%s/%s/%g", rule.Condition, rule.Dataset, rule.Threshold)
+}
+
+// RateLimit configures a token bucket: Rate tokens are added per second up to Burst
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// LiveUpstream reports whether upstream data comes from the real exchange rather than
+// the demo generator or a recording, which only cover REST responses
+func (c Config) LiveUpstream() bool {
+	return !c.Demo && c.ReplayFile == ""
+}
+
+// RouteLimit caps what a single request to a route may cost. A route's own entry in
+// RouteLimits is keyed by its path as registered, e.g. /livedata/{symbol}, and its zero
+// fields fall back to the default entry.
+type RouteLimit struct {
+	// MaxBodyBytes is the largest request body accepted
+	MaxBodyBytes int64
+	// MaxQueryLength is the longest raw query string accepted
+	MaxQueryLength int
+	// MaxQueryParams is how many query parameter values a request may carry
+	MaxQueryParams int
+	// Timeout is how many seconds the handler may take before its context is cancelled,
+	// overriding HandlerTimeout. It is only read from a route's own entry, so a default
+	// cannot cut off streams.
+	Timeout int
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParsePrefix reads a CIDR, or a bare address as the network holding only that address
+func ParsePrefix(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if !strings.Contains(entry, "/") {
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(entry)
+	return prefix.Masked(), err
+}
+
+// upstreamProxySchemes are the proxy types UpstreamProxy may name. The proxy resolves
+// hostnames for both SOCKS5 schemes, as net/http does.
+var upstreamProxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ValidHeaderName reports whether name is an HTTP header field name that may be configured.
+// Host and the framing headers are set by net/http and cannot be overridden.
+func validHeaderName(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "", "Host", "Content-Length", "Transfer-Encoding", "Connection":
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidHeaderValue reports whether value can be sent without splitting the request
+func validHeaderValue(value string) bool {
+	return !strings.ContainsAny(value, "\r\n\x00")
+}
@@ -0,0 +1,37 @@
+package exchange
+
+import "time"
+
+// UpstreamCandleIntervals are the intervals the exchange serves candles at, finest first
+var UpstreamCandleIntervals = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"2h", 2 * time.Hour},
+	{"4h", 4 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"8h", 8 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"3d", 3 * 24 * time.Hour},
+	{"1w", 7 * 24 * time.Hour},
+}
+
+// weekCandleOffset starts weekly candles on Monday; the Unix epoch fell on a Thursday
+const weekCandleOffset = 4 * 24 * time.Hour
+
+// CandleStart returns the start of the candle of the given resolution holding timestamp,
+// in epoch milliseconds. Candles are aligned to the epoch in UTC, except that those
+// spanning whole weeks start on a Monday.
+func CandleStart(timestamp int64, resolution time.Duration) int64 {
+	step := resolution.Milliseconds()
+	offset := int64(0)
+	if resolution%(7*24*time.Hour) == 0 {
+		offset = weekCandleOffset.Milliseconds()
+	}
+	return timestamp - ((timestamp-offset)%step+step)%step
+}
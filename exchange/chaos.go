@@ -0,0 +1,84 @@
+package exchange
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// chaosErrorStatuses are the server errors ChaosErrorRate answers with
+var chaosErrorStatuses = []int{
+	http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+}
+
+// ChaosTransport injects the upstream faults the Chaos settings ask for into requests
+// made through next, so retries, the circuit breaker and stale data handling can be
+// exercised against a working upstream. Its settings are read on every request, so
+// faults are switched on and off through /admin/config without a restart.
+type ChaosTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip delays the request, then picks at most one of hanging until it times out,
+// failing with a 5xx or truncating the upstream response's JSON
+func (t ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := config.Current()
+	if !cfg.ChaosEnabled() {
+		return t.next.RoundTrip(req)
+	}
+	if rand.Float64() < cfg.ChaosLatencyRate {
+		timer := time.NewTimer(time.Duration(cfg.ChaosLatency) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	roll := rand.Float64()
+	switch {
+	case roll < cfg.ChaosTimeoutRate:
+		logging.Debug("Chaos: holding", req.URL.String(), "until it times out")
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	case roll < cfg.ChaosTimeoutRate+cfg.ChaosErrorRate:
+		status := chaosErrorStatuses[rand.Intn(len(chaosErrorStatuses))]
+		logging.Debug("Chaos: answering", req.URL.String(), "with", status)
+		return chaosResponse(req, status, "text/plain", []byte("chaos: injected upstream error\n")), nil
+	case roll < cfg.ChaosTimeoutRate+cfg.ChaosErrorRate+cfg.ChaosMalformedRate:
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		logging.Debug("Chaos: truncating the response to", req.URL.String())
+		// Cutting the body short leaves an object or array unterminated
+		return chaosResponse(req, resp.StatusCode, resp.Header.Get("Content-Type"), body[:len(body)/2]), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+func chaosResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
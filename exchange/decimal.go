@@ -0,0 +1,154 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimalDivisionPlaces is how many decimal places a quotient keeps. Every other
+// operation is exact, so prices and quantities never pick up binary floating-point
+// artifacts such as 0.30000000000000004.
+const decimalDivisionPlaces = 20
+
+// Decimal is an exact decimal number used for derived prices, amounts and values. The
+// zero value is 0. It is encoded in JSON as a number, so responses keep their shape.
+type Decimal struct {
+	rat *big.Rat
+}
+
+// ParseDecimal reads a decimal as upstream and clients write it, e.g. "5408097.70"
+func ParseDecimal(raw string) (Decimal, bool) {
+	raw = strings.TrimSpace(raw)
+	// big.Rat also accepts fractions such as 1/3, which are not prices
+	if raw == "" || strings.ContainsRune(raw, '/') {
+		return Decimal{}, false
+	}
+	rat, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return Decimal{}, false
+	}
+	return Decimal{rat: rat}, true
+}
+
+// DecimalFromFloat converts a float by its shortest decimal representation, so 0.1
+// becomes exactly 0.1 rather than the nearest binary fraction
+func DecimalFromFloat(value float64) Decimal {
+	decimal, _ := ParseDecimal(strconv.FormatFloat(value, 'f', -1, 64))
+	return decimal
+}
+
+func DecimalFromInt(value int64) Decimal {
+	return Decimal{rat: new(big.Rat).SetInt64(value)}
+}
+
+func (d Decimal) value() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Add(d.value(), other.value())}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Sub(d.value(), other.value())}
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Mul(d.value(), other.value())}
+}
+
+// Div divides, rounding the quotient to decimalDivisionPlaces. Dividing by zero gives zero;
+// callers check the divisor wherever zero has a meaning of its own.
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.Sign() == 0 {
+		return Decimal{}
+	}
+	return Decimal{rat: new(big.Rat).Quo(d.value(), other.value())}.RoundTo(decimalDivisionPlaces)
+}
+
+// RoundTo rounds half away from zero to the given number of decimal places
+func (d Decimal) RoundTo(places int) Decimal {
+	rounded, _ := new(big.Rat).SetString(d.value().FloatString(places))
+	return Decimal{rat: rounded}
+}
+
+// RoundToStep rounds to the nearest multiple of a positive step, e.g. a quantity step of 0.0001
+func (d Decimal) RoundToStep(step Decimal) Decimal {
+	if step.Sign() <= 0 {
+		return d
+	}
+	steps := Decimal{rat: new(big.Rat).Quo(d.value(), step.value())}.RoundTo(0)
+	return steps.Mul(step)
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{rat: new(big.Rat).Neg(d.value())}
+}
+
+// Cmp returns -1, 0 or +1 as d is less than, equal to or greater than other
+func (d Decimal) Cmp(other Decimal) int {
+	return d.value().Cmp(other.value())
+}
+
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+// Min returns the smaller of two decimals
+func (d Decimal) Min(other Decimal) Decimal {
+	if other.Cmp(d) < 0 {
+		return other
+	}
+	return d
+}
+
+// IsZero lets fields tagged omitzero leave out zero decimals
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float returns the nearest float, for comparisons with float thresholds and for display
+func (d Decimal) Float() float64 {
+	value, _ := d.value().Float64()
+	return value
+}
+
+// String formats the decimal without an exponent or trailing zeros
+func (d Decimal) String() string {
+	text := d.value().FloatString(decimalDivisionPlaces)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	if text == "-0" {
+		return "0"
+	}
+	return text
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one, as upstream sends both
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	if number == "" {
+		*d = Decimal{}
+		return nil
+	}
+	parsed, ok := ParseDecimal(string(number))
+	if !ok {
+		return errors.New("invalid decimal " + string(data))
+	}
+	*d = parsed
+	return nil
+}
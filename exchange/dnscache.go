@@ -0,0 +1,142 @@
+package exchange
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const defaultDNSPort = "53"
+
+// dnsEntry is the last answer for a hostname
+type dnsEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+	// err is why the latest lookup failed; addrs then still hold any previous answer
+	err error
+}
+
+// DNSCache resolves upstream hostnames through UpstreamDNSServers and caches the answers
+// for UpstreamDNSCacheTTL, so polling the exchange every second neither waits on the
+// resolver nor fails when it briefly does. Concurrent lookups of one name are collapsed.
+type DNSCache struct {
+	entries map[string]dnsEntry
+	lookups *FlightGroup
+	// next rotates queries across the configured servers
+	next  atomic.Uint32
+	mutex sync.Mutex
+}
+
+// upstreamDNS is shared by every upstream connection, whichever client makes it
+var upstreamDNS = newDNSCache()
+
+func newDNSCache() *DNSCache {
+	return &DNSCache{entries: make(map[string]dnsEntry), lookups: NewFlightGroup()}
+}
+
+// Resolver queries servers in turn, or is the system resolver when none are configured
+func (d *DNSCache) resolver(servers []string) *net.Resolver {
+	if len(servers) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[int(d.next.Add(1))%len(servers)]
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(strings.Trim(server, "[]"), defaultDNSPort)
+			}
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// Lookup returns the addresses of host, from the cache while its answer is fresh. When a
+// lookup fails the previous answer is served until one succeeds.
+func (d *DNSCache) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	cfg := config.Current()
+	resolver := d.resolver(cfg.UpstreamDNSServers)
+	ttl := time.Duration(cfg.UpstreamDNSCacheTTL) * time.Second
+	if ttl <= 0 {
+		return resolver.LookupNetIP(ctx, "ip", host)
+	}
+
+	d.mutex.Lock()
+	entry, exists := d.entries[host]
+	d.mutex.Unlock()
+	if exists && len(entry.addrs) > 0 && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+	d.lookups.Do(ctx, host, func(ctx context.Context) error {
+		addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if err != nil {
+			// Serve the stale answer for another TTL rather than wait on a failing resolver
+			// for every connection
+			previous := d.entries[host]
+			previous.err = err
+			if len(previous.addrs) > 0 {
+				previous.expires = time.Now().Add(ttl)
+				logging.Warn("DNS lookup of", host, "failed, using previous answer:", err)
+			}
+			d.entries[host] = previous
+			return nil
+		}
+		d.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(ttl)}
+		return nil
+	})
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	entry = d.entries[host]
+	if len(entry.addrs) == 0 {
+		return nil, entry.err
+	}
+	return entry.addrs, nil
+}
+
+// Dialer returns a DialContext that resolves through the cache and tries each address in
+// turn. Without UpstreamDNSServers or UpstreamDNSCacheTTL it is dialer's own.
+func (d *DNSCache) dialer(dialer *net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		cfg := config.Current()
+		if len(cfg.UpstreamDNSServers) == 0 && cfg.UpstreamDNSCacheTTL <= 0 {
+			return dialer.DialContext(ctx, network, address)
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if dialer.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+			defer cancel()
+		}
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,305 @@
+// Package exchange talks to CoinDCX and the other upstream sources: the HTTP client every
+// request goes through, with its rate limiting, retries and fault injection, and the
+// types upstream responses decode into.
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// MarketDetails struct to hold market information
+type MarketDetails struct {
+	CoindcxName             string   `json:"coindcx_name"`
+	BaseCurrencyShortName   string   `json:"base_currency_short_name"`
+	TargetCurrencyShortName string   `json:"target_currency_short_name"`
+	TargetCurrencyName      string   `json:"target_currency_name"`
+	BaseCurrencyName        string   `json:"base_currency_name"`
+	MinQuantity             float64  `json:"min_quantity"`
+	MaxQuantity             float64  `json:"max_quantity"`
+	MinPrice                float64  `json:"min_price"`
+	MaxPrice                float64  `json:"max_price"`
+	MinNotional             float64  `json:"min_notional"`
+	BaseCurrencyPrecision   int      `json:"base_currency_precision"`
+	TargetCurrencyPrecision int      `json:"target_currency_precision"`
+	Step                    float64  `json:"step"`
+	OrderTypes              []string `json:"order_types"`
+	Symbol                  string   `json:"symbol"`
+	ECode                   string   `json:"ecode"`
+	Pair                    string   `json:"pair"`
+	Status                  string   `json:"status"`
+}
+
+// TickerDetails struct to hold ticker information
+type TickerDetails struct {
+	Market       string          `json:"market"`
+	Change24Hour string          `json:"change_24_hour"`
+	High         string          `json:"high"`
+	Low          string          `json:"low"`
+	Volume       string          `json:"volume"`
+	LastPrice    string          `json:"last_price"`
+	Bid          json.RawMessage `json:"bid"`
+	Ask          json.RawMessage `json:"ask"`
+	Timestamp    int64           `json:"timestamp"`
+}
+
+// ParseRawPrice reads a price that upstream may send as either a JSON number or string
+func ParseRawPrice(raw json.RawMessage) (float64, bool) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, v > 0
+	case string:
+		price, err := strconv.ParseFloat(v, 64)
+		return price, err == nil && price > 0
+	}
+	return 0, false
+}
+
+// Prices returns the parsed bid, ask and last price of a ticker
+func (t TickerDetails) Prices() (bid, ask, last float64, ok bool) {
+	bid, hasBid := ParseRawPrice(t.Bid)
+	ask, hasAsk := ParseRawPrice(t.Ask)
+	last, err := strconv.ParseFloat(t.LastPrice, 64)
+	return bid, ask, last, hasBid && hasAsk && err == nil && last > 0
+}
+
+// ParseRawDecimal reads a raw price exactly, whether upstream sent a JSON number or string
+func parseRawDecimal(raw json.RawMessage) (Decimal, bool) {
+	var price Decimal
+	if err := json.Unmarshal(raw, &price); err != nil {
+		return Decimal{}, false
+	}
+	return price, price.Sign() > 0
+}
+
+// DecimalPrices returns the bid, ask and last price of a ticker as exact decimals
+func (t TickerDetails) DecimalPrices() (bid, ask, last Decimal, ok bool) {
+	bid, hasBid := parseRawDecimal(t.Bid)
+	ask, hasAsk := parseRawDecimal(t.Ask)
+	last, hasLast := ParseDecimal(t.LastPrice)
+	return bid, ask, last, hasBid && hasAsk && hasLast && last.Sign() > 0
+}
+
+// OrderBook struct to hold order book details
+type OrderBook struct {
+	Bids map[string]string `json:"bids"`
+	Asks map[string]string `json:"asks"`
+}
+
+// Exchange is where the tracker fetches markets, tickers and order books from
+type Exchange interface {
+	Markets(ctx context.Context) ([]MarketDetails, error)
+	Tickers(ctx context.Context) ([]TickerDetails, error)
+	OrderBook(ctx context.Context, pair string) (OrderBook, error)
+}
+
+// CoinDCX is the Exchange behind APIBaseURL, reached through an upstream client
+type CoinDCX struct {
+	client *SafeHTTPClient
+}
+
+func NewCoinDCX(client *SafeHTTPClient) *CoinDCX {
+	return &CoinDCX{client: client}
+}
+
+// Markets fetches the details of every market
+func (e *CoinDCX) Markets(ctx context.Context) ([]MarketDetails, error) {
+	var markets []MarketDetails
+	err := e.fetch(ctx, config.Current().APIBaseURL+"/exchange/v1/markets_details", &markets)
+	return markets, err
+}
+
+// Tickers fetches the ticker of every market
+func (e *CoinDCX) Tickers(ctx context.Context) ([]TickerDetails, error) {
+	var tickers []TickerDetails
+	err := e.fetch(ctx, config.Current().APIBaseURL+"/exchange/ticker", &tickers)
+	return tickers, err
+}
+
+// OrderBook fetches the order book of a pair
+func (e *CoinDCX) OrderBook(ctx context.Context, pair string) (OrderBook, error) {
+	var orderBook OrderBook
+	err := e.fetch(ctx, "https://public.coindcx.com/market_data/orderbook?pair="+pair, &orderBook)
+	return orderBook, err
+}
+
+// Fetch requests url and parses the JSON response into v
+func (e *CoinDCX) fetch(ctx context.Context, url string, v interface{}) error {
+	response, err := e.client.PerformRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(response), v); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// SafeHTTPClient is an upstream HTTP client safe for concurrent use. Connections are pooled
+// and kept alive by the transport, and the worker pool bounds how many requests run at once.
+type SafeHTTPClient struct {
+	client  *http.Client
+	pool    *WorkerPool
+	Limiter *UpstreamLimiter
+	State   *UpstreamState
+	Latency *LatencyTracker
+}
+
+func NewSafeHTTPClient() *SafeHTTPClient {
+	cfg := config.Current()
+	timeout := config.DefaultUpstreamTimeout
+	if cfg.UpstreamTimeout > 0 {
+		timeout = time.Duration(cfg.UpstreamTimeout) * time.Second
+	}
+	concurrency := cfg.UpstreamConcurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultUpstreamConcurrency
+	}
+
+	transport := &http.Transport{
+		Proxy: upstreamProxy(cfg),
+		DialContext: upstreamDNS.dialer(&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   concurrency,
+		MaxConnsPerHost:       concurrency,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	// Demo and replay modes answer every upstream request in process
+	var roundTripper http.RoundTripper = transport
+	switch {
+	case cfg.Demo:
+		roundTripper = newMockExchange(time.Now().UnixNano())
+	case cfg.ReplayFile != "":
+		replay, err := newReplayTransport(cfg.ReplayFile, cfg.ReplaySpeed)
+		if err != nil {
+			logging.Error("Error loading replay file:", err)
+			roundTripper = failingTransport{err}
+		} else {
+			roundTripper = replay
+		}
+	}
+	if cfg.RecordFile != "" {
+		recorder, err := newRecordingTransport(roundTripper, cfg.RecordFile)
+		if err != nil {
+			logging.Error("Error opening record file:", err)
+		} else {
+			roundTripper = recorder
+		}
+	}
+	// Injected faults are left out of recordings
+	roundTripper = ChaosTransport{next: roundTripper}
+
+	return &SafeHTTPClient{
+		client:  &http.Client{Transport: roundTripper, Timeout: timeout},
+		pool:    newWorkerPool(concurrency),
+		Limiter: newUpstreamLimiter(cfg.UpstreamRateLimits),
+		State:   &UpstreamState{},
+		Latency: newLatencyTracker(),
+	}
+}
+
+// PerformRequest waits for the endpoint's rate limit, then runs a GET on one of the upstream workers.
+// Exchange requests fail fast while the exchange has asked us to back off. Cancelling ctx
+// abandons the request wherever it is waiting.
+func (c *SafeHTTPClient) PerformRequest(ctx context.Context, url string) (string, error) {
+	exchange := upstreamEndpoint(url) != ""
+	if exchange && c.State.Throttled() {
+		return "", ErrUpstreamThrottled
+	}
+	if err := c.Limiter.wait(ctx, url); err != nil {
+		return "", err
+	}
+
+	var body string
+	var err error
+	var elapsed time.Duration
+	started := time.Now()
+	if poolErr := c.pool.do(ctx, func() {
+		// Latency is timed from when a worker is free, so it measures the exchange rather than our queue
+		requested := time.Now()
+		body, err = c.get(ctx, url)
+		elapsed = time.Since(requested)
+	}); poolErr != nil {
+		return "", poolErr
+	}
+	if logging.Enabled("debug") {
+		logging.Debug(fmt.Sprintf("upstream url=%s request_id=%s latency=%s error=%v", url, logging.RequestID(ctx), time.Since(started).Round(time.Microsecond), err))
+	}
+	// A caller giving up says nothing about upstream health
+	if exchange && ctx.Err() == nil {
+		if err != nil {
+			c.State.recordFailure(err)
+		} else {
+			c.State.recordSuccess()
+		}
+		c.Latency.record(upstreamEndpoint(url), elapsed, err != nil)
+	}
+	return body, err
+}
+
+func (c *SafeHTTPClient) get(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	setUpstreamHeaders(req.Header, req.URL.Hostname())
+	// Let upstream calls triggered by an API request be correlated with it
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &UpstreamError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// FXRates holds fiat exchange rates relative to INR
+type FXRates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// CoinMetadata holds descriptive coin information sourced from CoinGecko
+type CoinMetadata struct {
+	ID                string  `json:"id"`
+	Symbol            string  `json:"symbol"`
+	Name              string  `json:"name"`
+	Image             string  `json:"image"`
+	MarketCap         float64 `json:"market_cap"`
+	CirculatingSupply float64 `json:"circulating_supply"`
+}
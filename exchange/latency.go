@@ -0,0 +1,198 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	// latencyWindow is how far back the rolling percentiles and SLO compliance look
+	latencyWindow = 5 * time.Minute
+	// maxLatencySamples bounds the samples kept per endpoint for the rolling percentiles
+	maxLatencySamples = 2048
+)
+
+// latencyBuckets are the histogram upper bounds in seconds, as exposed on /metrics
+var latencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// latencySample is one upstream call in the rolling window
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// LatencyHistogram holds every upstream call to one endpoint since startup, bucketed, and
+// the most recent ones individually for percentiles
+type LatencyHistogram struct {
+	// counts holds one count per bucket plus the calls slower than the last bound
+	counts   []int64
+	sum      float64
+	total    int64
+	failures int64
+	samples  []latencySample
+}
+
+// LatencyTracker records upstream call latencies per endpoint
+type LatencyTracker struct {
+	endpoints map[string]*LatencyHistogram
+	mutex     sync.Mutex
+}
+
+func newLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{endpoints: make(map[string]*LatencyHistogram)}
+}
+
+// Record adds a call to an endpoint's histogram and rolling window
+func (t *LatencyTracker) record(endpoint string, duration time.Duration, failed bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	histogram, exists := t.endpoints[endpoint]
+	if !exists {
+		histogram = &LatencyHistogram{counts: make([]int64, len(latencyBuckets)+1)}
+		t.endpoints[endpoint] = histogram
+	}
+	seconds := duration.Seconds()
+	histogram.counts[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	histogram.sum += seconds
+	histogram.total++
+	if failed {
+		histogram.failures++
+	}
+
+	now := time.Now()
+	histogram.samples = append(histogram.samples, latencySample{at: now, duration: duration, failed: failed})
+	start := 0
+	for start < len(histogram.samples) && (now.Sub(histogram.samples[start].at) > latencyWindow || len(histogram.samples)-start > maxLatencySamples) {
+		start++
+	}
+	histogram.samples = histogram.samples[start:]
+}
+
+// LatencyStats is one endpoint's entry in the upstream_latency section of /admin/stats
+type LatencyStats struct {
+	Calls    int64 `json:"calls"`
+	Failures int64 `json:"failures"`
+	// WindowCalls and the fields after it cover only the calls of the last latencyWindow
+	WindowCalls int     `json:"window_calls"`
+	P50Ms       float64 `json:"p50_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	// SLOCompliance is the share of calls that succeeded within UpstreamLatencySLO
+	SLOCompliance float64 `json:"slo_compliance"`
+	// SLOBreached reports compliance below UpstreamSLOTarget
+	SLOBreached bool `json:"slo_breached"`
+}
+
+// Percentile returns the p-th percentile of sorted durations by the nearest-rank method
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Stats summarizes every endpoint's calls, judging the window against the configured SLO
+func (t *LatencyTracker) Stats() map[string]LatencyStats {
+	cfg := config.Current()
+	slo := time.Duration(cfg.UpstreamLatencySLO) * time.Millisecond
+	now := time.Now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := make(map[string]LatencyStats, len(t.endpoints))
+	for endpoint, histogram := range t.endpoints {
+		durations := []time.Duration{}
+		good := 0
+		for _, sample := range histogram.samples {
+			if now.Sub(sample.at) > latencyWindow {
+				continue
+			}
+			durations = append(durations, sample.duration)
+			if !sample.failed && sample.duration <= slo {
+				good++
+			}
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+		endpointStats := LatencyStats{
+			Calls:         histogram.total,
+			Failures:      histogram.failures,
+			WindowCalls:   len(durations),
+			P50Ms:         DurationMs(Percentile(durations, 50)),
+			P95Ms:         DurationMs(Percentile(durations, 95)),
+			P99Ms:         DurationMs(Percentile(durations, 99)),
+			SLOCompliance: 1,
+		}
+		if len(durations) > 0 {
+			endpointStats.SLOCompliance = float64(good) / float64(len(durations))
+		}
+		endpointStats.SLOBreached = endpointStats.SLOCompliance < cfg.UpstreamSLOTarget
+		stats[endpoint] = endpointStats
+	}
+	return stats
+}
+
+func DurationMs(duration time.Duration) float64 {
+	return math.Round(float64(duration)/float64(time.Microsecond)) / 1000
+}
+
+// WriteMetrics writes the histograms, percentiles and SLO compliance in the Prometheus
+// text exposition format
+func (t *LatencyTracker) WriteMetrics(w *strings.Builder) {
+	stats := t.Stats()
+
+	t.mutex.Lock()
+	endpoints := make([]string, 0, len(t.endpoints))
+	for endpoint := range t.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	w.WriteString("# HELP cryptotracker_upstream_request_duration_seconds Latency of upstream exchange requests.\n")
+	w.WriteString("# TYPE cryptotracker_upstream_request_duration_seconds histogram\n")
+	for _, endpoint := range endpoints {
+		histogram := t.endpoints[endpoint]
+		cumulative := int64(0)
+		for i, bound := range latencyBuckets {
+			cumulative += histogram.counts[i]
+			fmt.Fprintf(w, "cryptotracker_upstream_request_duration_seconds_bucket{endpoint=%q,le=\"%g\"} %d\n", endpoint, bound, cumulative)
+		}
+		fmt.Fprintf(w, "cryptotracker_upstream_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", endpoint, histogram.total)
+		fmt.Fprintf(w, "cryptotracker_upstream_request_duration_seconds_sum{endpoint=%q} %g\n", endpoint, histogram.sum)
+		fmt.Fprintf(w, "cryptotracker_upstream_request_duration_seconds_count{endpoint=%q} %d\n", endpoint, histogram.total)
+	}
+	w.WriteString("# HELP cryptotracker_upstream_request_failures_total Upstream exchange requests that failed.\n")
+	w.WriteString("# TYPE cryptotracker_upstream_request_failures_total counter\n")
+	for _, endpoint := range endpoints {
+		fmt.Fprintf(w, "cryptotracker_upstream_request_failures_total{endpoint=%q} %d\n", endpoint, t.endpoints[endpoint].failures)
+	}
+	t.mutex.Unlock()
+
+	w.WriteString("# HELP cryptotracker_upstream_latency_seconds Rolling upstream latency percentiles over the last five minutes.\n")
+	w.WriteString("# TYPE cryptotracker_upstream_latency_seconds gauge\n")
+	for _, endpoint := range endpoints {
+		endpointStats := stats[endpoint]
+		for _, quantile := range []struct {
+			name string
+			ms   float64
+		}{{"0.5", endpointStats.P50Ms}, {"0.95", endpointStats.P95Ms}, {"0.99", endpointStats.P99Ms}} {
+			fmt.Fprintf(w, "cryptotracker_upstream_latency_seconds{endpoint=%q,quantile=%q} %g\n", endpoint, quantile.name, quantile.ms/1000)
+		}
+	}
+	w.WriteString("# HELP cryptotracker_upstream_slo_compliance Share of upstream requests in the last five minutes that succeeded within UpstreamLatencySLO.\n")
+	w.WriteString("# TYPE cryptotracker_upstream_slo_compliance gauge\n")
+	for _, endpoint := range endpoints {
+		fmt.Fprintf(w, "cryptotracker_upstream_slo_compliance{endpoint=%q} %g\n", endpoint, stats[endpoint].SLOCompliance)
+	}
+}
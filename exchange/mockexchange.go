@@ -0,0 +1,470 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	// demoUSDINR is the starting USD to INR rate of the mock exchange
+	demoUSDINR = 83.2
+	// demoBookLevels is how many price levels each side of a mock order book has
+	demoBookLevels = 20
+)
+
+// demoCoin is a coin listed on the mock exchange
+type demoCoin struct {
+	symbol     string
+	name       string
+	priceUSD   float64
+	supply     float64
+	volatility float64 // standard deviation of the log return per second
+	precision  int
+}
+
+var demoCoins = []demoCoin{
+	{"BTC", "Bitcoin", 65000, 19.7e6, 0.0004, 2},
+	{"ETH", "Ethereum", 3200, 120e6, 0.0005, 2},
+	{"SOL", "Solana", 150, 460e6, 0.0008, 3},
+	{"XRP", "XRP", 0.55, 55e9, 0.0007, 4},
+	{"ADA", "Cardano", 0.45, 35e9, 0.0007, 4},
+	{"DOGE", "Dogecoin", 0.12, 144e9, 0.001, 5},
+	{"LTC", "Litecoin", 80, 74e6, 0.0006, 2},
+	{"DOT", "Polkadot", 7, 1.4e9, 0.0007, 3},
+	{"LINK", "Chainlink", 15, 587e6, 0.0007, 3},
+	{"MATIC", "Polygon", 0.7, 9.3e9, 0.0008, 4},
+}
+
+// demoPrice is the evolving state of one coin's USD price
+type demoPrice struct {
+	coin   demoCoin
+	price  float64
+	open   float64
+	high   float64
+	low    float64
+	volume float64
+}
+
+// MockExchange is an in-process stand-in for every upstream the tracker calls. Prices
+// follow independent random walks advanced on each request, so data moves like a real
+// market while staying internally consistent across INR and USDT pairs.
+type MockExchange struct {
+	prices map[string]*demoPrice
+	// openInterests is each futures contract's open interest, which wanders on every quote
+	openInterests map[string]float64
+	usdINR        float64
+	updated       time.Time
+	random        *rand.Rand
+	mutex         sync.Mutex
+}
+
+func newMockExchange(seed int64) *MockExchange {
+	exchange := &MockExchange{
+		prices:        make(map[string]*demoPrice),
+		openInterests: make(map[string]float64),
+		usdINR:        demoUSDINR,
+		updated:       time.Now(),
+		random:        rand.New(rand.NewSource(seed)),
+	}
+	// Start mid-session: a random move since the open and a day's worth of volume
+	for _, coin := range demoCoins {
+		open := coin.priceUSD * math.Exp(0.02*exchange.random.NormFloat64())
+		exchange.prices[coin.symbol] = &demoPrice{
+			coin:   coin,
+			price:  coin.priceUSD,
+			open:   open,
+			high:   math.Max(open, coin.priceUSD) * (1 + 0.01*exchange.random.Float64()),
+			low:    math.Min(open, coin.priceUSD) * (1 - 0.01*exchange.random.Float64()),
+			volume: coin.supply * 0.003 * (0.5 + exchange.random.Float64()),
+		}
+	}
+	return exchange
+}
+
+// RoundTrip answers an upstream request from the generator
+func (m *MockExchange) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	m.advance(time.Now())
+	body, status := m.route(req)
+	m.mutex.Unlock()
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(encoded)),
+		ContentLength: int64(len(encoded)),
+		Request:       req,
+	}, nil
+}
+
+// Advance moves every price along its random walk to now
+func (m *MockExchange) advance(now time.Time) {
+	elapsed := now.Sub(m.updated).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	m.updated = now
+	for _, state := range m.prices {
+		state.price *= math.Exp(state.coin.volatility * math.Sqrt(elapsed) * m.random.NormFloat64())
+		state.high = math.Max(state.high, state.price)
+		state.low = math.Min(state.low, state.price)
+		// Turnover of roughly 0.02% of supply per minute
+		state.volume += state.coin.supply * 0.0002 / 60 * elapsed * (0.5 + m.random.Float64())
+	}
+	m.usdINR *= math.Exp(0.00002 * math.Sqrt(elapsed) * m.random.NormFloat64())
+}
+
+func (m *MockExchange) route(req *http.Request) (interface{}, int) {
+	path := req.URL.Path
+	switch {
+	case config.Current().LendingRatesURL != "" && req.URL.String() == config.Current().LendingRatesURL:
+		// Lending rates have no fixed upstream, so the mock answers wherever they are fetched from
+		return m.lendingRates(), http.StatusOK
+	case strings.HasSuffix(path, "/exchange/v1/markets_details"):
+		return m.marketDetails(), http.StatusOK
+	case strings.HasSuffix(path, "/exchange/v1/markets"):
+		names := []string{}
+		for _, market := range m.marketDetails() {
+			names = append(names, market.CoindcxName)
+		}
+		return names, http.StatusOK
+	case strings.HasSuffix(path, "/exchange/ticker"):
+		return m.tickers(), http.StatusOK
+	case strings.HasSuffix(path, "/market_data/orderbook"):
+		orderBook, exists := m.orderBook(req.URL.Query().Get("pair"))
+		if !exists {
+			return map[string]string{"message": "pair not found"}, http.StatusNotFound
+		}
+		return orderBook, http.StatusOK
+	case strings.HasSuffix(path, "/market_data/candles"):
+		query := req.URL.Query()
+		candles, exists := m.candles(query.Get("pair"), query.Get("interval"), query.Get("startTime"), query.Get("endTime"), query.Get("limit"))
+		if !exists {
+			return map[string]string{"message": "pair or interval not found"}, http.StatusNotFound
+		}
+		return candles, http.StatusOK
+	case strings.HasSuffix(path, "/derivatives/futures/data/active_instruments"):
+		pairs := []string{}
+		for _, market := range m.futuresMarkets() {
+			pairs = append(pairs, market.pair)
+		}
+		return pairs, http.StatusOK
+	case strings.HasSuffix(path, "/derivatives/futures/data/instrument"):
+		instrument, exists := m.futuresInstrument(req.URL.Query().Get("pair"))
+		if !exists {
+			return map[string]string{"message": "instrument not found"}, http.StatusNotFound
+		}
+		return map[string]interface{}{"instrument": instrument}, http.StatusOK
+	case strings.HasSuffix(path, "/current_prices/futures/rt"):
+		return map[string]interface{}{"ts": time.Now().UnixMilli(), "prices": m.futuresPrices()}, http.StatusOK
+	case strings.HasSuffix(path, "/coins/markets"):
+		// Everything fits on the first page
+		if req.URL.Query().Get("page") != "1" {
+			return []CoinMetadata{}, http.StatusOK
+		}
+		return m.coinMetadata(), http.StatusOK
+	case strings.HasSuffix(path, "/latest"):
+		usd := 1 / m.usdINR
+		return FXRates{Base: "INR", Rates: map[string]float64{
+			"INR": 1, "USD": usd, "EUR": usd * 0.92, "GBP": usd * 0.79, "JPY": usd * 151, "AED": usd * 3.67,
+		}}, http.StatusOK
+	}
+	return map[string]string{"message": "not found"}, http.StatusNotFound
+}
+
+// demoMarket is one tradable pair: a coin quoted in INR or USDT
+type demoMarket struct {
+	name  string
+	pair  string
+	coin  string
+	quote string
+}
+
+func (m *MockExchange) markets() []demoMarket {
+	markets := []demoMarket{{name: "USDTINR", pair: "I-USDT_INR", coin: "USDT", quote: "INR"}}
+	for _, coin := range demoCoins {
+		markets = append(markets,
+			demoMarket{name: coin.symbol + "INR", pair: "I-" + coin.symbol + "_INR", coin: coin.symbol, quote: "INR"},
+			demoMarket{name: coin.symbol + "USDT", pair: "B-" + coin.symbol + "_USDT", coin: coin.symbol, quote: "USDT"},
+		)
+	}
+	return markets
+}
+
+// Quote returns a market's last price, session open, high and low in its quote currency,
+// and the coin's traded volume
+func (m *MockExchange) quote(market demoMarket) (float64, float64, float64, float64, float64) {
+	rate := 1.0
+	if market.quote == "INR" {
+		rate = m.usdINR
+	}
+	if market.coin == "USDT" {
+		return rate, demoUSDINR, math.Max(rate, demoUSDINR), math.Min(rate, demoUSDINR), 0
+	}
+	state := m.prices[market.coin]
+	return state.price * rate, state.open * rate, state.high * rate, state.low * rate, state.volume
+}
+
+func (m *MockExchange) precision(coin string) int {
+	if state, exists := m.prices[coin]; exists {
+		return state.coin.precision
+	}
+	return 2
+}
+
+func (m *MockExchange) marketDetails() []MarketDetails {
+	details := []MarketDetails{}
+	for _, market := range m.markets() {
+		precision := m.precision(market.coin)
+		details = append(details, MarketDetails{
+			CoindcxName:             market.name,
+			BaseCurrencyShortName:   market.quote,
+			TargetCurrencyShortName: market.coin,
+			TargetCurrencyName:      market.coin,
+			BaseCurrencyName:        market.quote,
+			MinQuantity:             0.0001,
+			MaxQuantity:             1e9,
+			MinPrice:                math.Pow(10, -float64(precision)),
+			MaxPrice:                1e9,
+			MinNotional:             100,
+			BaseCurrencyPrecision:   precision,
+			TargetCurrencyPrecision: 4,
+			Step:                    0.0001,
+			OrderTypes:              []string{"limit_order", "market_order"},
+			Symbol:                  market.coin + market.quote,
+			ECode:                   "I",
+			Pair:                    market.pair,
+			Status:                  "active",
+		})
+	}
+	return details
+}
+
+func (m *MockExchange) tickers() []TickerDetails {
+	now := time.Now().Unix()
+	tickers := []TickerDetails{}
+	for _, market := range m.markets() {
+		last, open, high, low, volume := m.quote(market)
+		precision := m.precision(market.coin)
+		spread := last * 0.0005
+		tickers = append(tickers, TickerDetails{
+			Market:       market.name,
+			Change24Hour: strconv.FormatFloat((last-open)/open*100, 'f', 3, 64),
+			High:         strconv.FormatFloat(high, 'f', precision, 64),
+			Low:          strconv.FormatFloat(low, 'f', precision, 64),
+			Volume:       strconv.FormatFloat(volume, 'f', 2, 64),
+			LastPrice:    strconv.FormatFloat(last, 'f', precision, 64),
+			Bid:          json.RawMessage(strconv.FormatFloat(last-spread, 'f', precision, 64)),
+			Ask:          json.RawMessage(strconv.FormatFloat(last+spread, 'f', precision, 64)),
+			Timestamp:    now,
+		})
+	}
+	return tickers
+}
+
+// FuturesMarkets are the perpetual contracts of the mock exchange, one per USDT market
+func (m *MockExchange) futuresMarkets() []demoMarket {
+	markets := []demoMarket{}
+	for _, market := range m.markets() {
+		if market.quote == "USDT" {
+			markets = append(markets, market)
+		}
+	}
+	return markets
+}
+
+func (m *MockExchange) futuresInstrument(pair string) (map[string]interface{}, bool) {
+	for _, market := range m.futuresMarkets() {
+		if market.pair != pair {
+			continue
+		}
+		increment := math.Pow(10, -float64(m.precision(market.coin)))
+		return map[string]interface{}{
+			"pair":                           market.pair,
+			"kind":                           "perpetual",
+			"status":                         "active",
+			"underlying_currency_short_name": market.coin,
+			"quote_currency_short_name":      "USDT",
+			"settle_currency_short_name":     "USDT",
+			"margin_currency_short_name":     []string{"USDT"},
+			"max_leverage_long":              20,
+			"max_leverage_short":             20,
+			"price_increment":                increment,
+			"quantity_increment":             0.001,
+			"min_quantity":                   0.001,
+			"max_quantity":                   1000000,
+			"min_notional":                   5,
+			"funding_frequency":              8,
+		}, true
+	}
+	return nil, false
+}
+
+// FuturesPrices quotes every contract a little off its spot market, as a perpetual
+// trades around its index
+func (m *MockExchange) futuresPrices() map[string]map[string]interface{} {
+	prices := make(map[string]map[string]interface{})
+	for _, market := range m.futuresMarkets() {
+		index, open, high, low, volume := m.quote(market)
+		precision := m.precision(market.coin)
+		last := index * (1 + 0.0005*m.random.NormFloat64())
+		mark := (last + index) / 2
+		round := func(value float64) string { return strconv.FormatFloat(value, 'f', precision, 64) }
+		prices[market.pair] = map[string]interface{}{
+			"ls":  round(last),
+			"mp":  round(mark),
+			"ip":  round(index),
+			"fr":  math.Round((last-index)/index*1e6) / 1e6,
+			"h":   round(high),
+			"l":   round(low),
+			"v":   strconv.FormatFloat(volume*2, 'f', 2, 64),
+			"pc":  strconv.FormatFloat((last-open)/open*100, 'f', 3, 64),
+			"oi":  strconv.FormatFloat(m.openInterest(market, volume), 'f', 3, 64),
+			"mkt": strings.NewReplacer("B-", "", "_", "").Replace(market.pair),
+		}
+	}
+	return prices
+}
+
+// LendingRates quotes daily margin rates for every coin and USDT, borrowing costing a
+// spread over what lending pays
+func (m *MockExchange) lendingRates() []map[string]interface{} {
+	currencies := []string{"USDT"}
+	for _, coin := range demoCoins {
+		currencies = append(currencies, coin.symbol)
+	}
+	rates := []map[string]interface{}{}
+	for i, currency := range currencies {
+		// Each currency's rate drifts slowly around its own base, changing every few minutes
+		base := 0.0001 * float64(1+i%5)
+		period := float64(m.updated.Unix()/300 + int64(i))
+		lend := base * (1 + 0.2*math.Sin(period))
+		rates = append(rates, map[string]interface{}{
+			"currency":    currency,
+			"lend_rate":   strconv.FormatFloat(lend, 'f', 6, 64),
+			"borrow_rate": strconv.FormatFloat(lend*1.5, 'f', 6, 64),
+		})
+	}
+	return rates
+}
+
+// OpenInterest moves a contract's open interest along a random walk, starting it at a
+// third of the day's volume
+func (m *MockExchange) openInterest(market demoMarket, volume float64) float64 {
+	interest, exists := m.openInterests[market.pair]
+	if !exists {
+		interest = volume / 3
+	}
+	interest *= math.Exp(0.01 * m.random.NormFloat64())
+	m.openInterests[market.pair] = interest
+	return interest
+}
+
+// Candles returns up to limit candles of a pair, newest first as upstream orders them,
+// ending at endTime. Prices follow a deterministic wave around the current price, so
+// candles of one period agree however often they are asked for.
+func (m *MockExchange) candles(pair, interval, startText, endText, limitText string) ([]map[string]interface{}, bool) {
+	var duration time.Duration
+	for _, candidate := range UpstreamCandleIntervals {
+		if candidate.Name == interval {
+			duration = candidate.Duration
+		}
+	}
+	var market demoMarket
+	for _, candidate := range m.markets() {
+		if candidate.pair == pair {
+			market = candidate
+		}
+	}
+	if duration == 0 || market.pair == "" {
+		return nil, false
+	}
+	start, _ := strconv.ParseInt(startText, 10, 64)
+	end, err := strconv.ParseInt(endText, 10, 64)
+	if err != nil {
+		end = time.Now().UnixMilli()
+	}
+	limit, err := strconv.Atoi(limitText)
+	if err != nil || limit <= 0 {
+		limit = 500
+	}
+	price, _, _, _, volume := m.quote(market)
+	priceAt := func(ms int64) float64 {
+		hours := float64(ms) / float64(time.Hour/time.Millisecond)
+		return price * (1 + 0.03*math.Sin(hours/17) + 0.01*math.Sin(hours*1.3))
+	}
+	step := duration.Milliseconds()
+	candles := []map[string]interface{}{}
+	for at := CandleStart(end, duration); at >= start && len(candles) < limit; at -= step {
+		open, close := priceAt(at), priceAt(at+step)
+		middle := priceAt(at + step/2)
+		candles = append(candles, map[string]interface{}{
+			"open":   open,
+			"high":   math.Max(math.Max(open, close), middle),
+			"low":    math.Min(math.Min(open, close), middle),
+			"close":  close,
+			"volume": volume * duration.Hours() / 24 * (1 + 0.5*math.Sin(float64(at)/float64(step))),
+			"time":   at,
+		})
+	}
+	return candles, true
+}
+
+// OrderBook builds a fresh book around the current price with thinning liquidity away from the touch
+func (m *MockExchange) orderBook(pair string) (OrderBook, bool) {
+	for _, market := range m.markets() {
+		if market.pair != pair {
+			continue
+		}
+		last, _, _, _, _ := m.quote(market)
+		precision := m.precision(market.coin)
+		orderBook := OrderBook{Bids: make(map[string]string), Asks: make(map[string]string)}
+		for level := 0; level < demoBookLevels; level++ {
+			offset := last * (0.00025 + 0.0002*float64(level))
+			quantity := func() string {
+				return strconv.FormatFloat((10000/last)*(1+float64(level)/4)*(0.2+m.random.Float64()), 'f', 4, 64)
+			}
+			orderBook.Bids[strconv.FormatFloat(last-offset, 'f', precision, 64)] = quantity()
+			orderBook.Asks[strconv.FormatFloat(last+offset, 'f', precision, 64)] = quantity()
+		}
+		return orderBook, true
+	}
+	return OrderBook{}, false
+}
+
+func (m *MockExchange) coinMetadata() []CoinMetadata {
+	coins := []CoinMetadata{}
+	for _, coin := range demoCoins {
+		state := m.prices[coin.symbol]
+		coins = append(coins, CoinMetadata{
+			ID:                strings.ToLower(coin.name),
+			Symbol:            strings.ToLower(coin.symbol),
+			Name:              coin.name,
+			MarketCap:         state.price * coin.supply,
+			CirculatingSupply: coin.supply,
+		})
+	}
+	return coins
+}
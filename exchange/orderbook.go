@@ -0,0 +1,50 @@
+package exchange
+
+import "sort"
+
+// BookLevel is a single parsed price level of an order book
+type BookLevel struct {
+	Price    Decimal `json:"price"`
+	Quantity Decimal `json:"quantity"`
+}
+
+// SortedLevels parses order book levels and sorts them best-first
+func SortedLevels(levels map[string]string, descending bool) []BookLevel {
+	parsed := make([]BookLevel, 0, len(levels))
+	for rawPrice, rawQuantity := range levels {
+		price, ok := ParseDecimal(rawPrice)
+		if !ok || price.Sign() <= 0 {
+			continue
+		}
+		quantity, ok := ParseDecimal(rawQuantity)
+		if !ok || quantity.Sign() <= 0 {
+			continue
+		}
+		parsed = append(parsed, BookLevel{Price: price, Quantity: quantity})
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		if descending {
+			return parsed[i].Price.Cmp(parsed[j].Price) > 0
+		}
+		return parsed[i].Price.Cmp(parsed[j].Price) < 0
+	})
+	return parsed
+}
+
+// BestBid returns the highest bid of an order book
+func (o OrderBook) bestBid() (BookLevel, bool) {
+	levels := SortedLevels(o.Bids, true)
+	if len(levels) == 0 {
+		return BookLevel{}, false
+	}
+	return levels[0], true
+}
+
+// BestAsk returns the lowest ask of an order book
+func (o OrderBook) bestAsk() (BookLevel, bool) {
+	levels := SortedLevels(o.Asks, false)
+	if len(levels) == 0 {
+		return BookLevel{}, false
+	}
+	return levels[0], true
+}
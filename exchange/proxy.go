@@ -0,0 +1,238 @@
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// UpstreamProxy picks the proxy for an upstream request: UpstreamProxy unless the host is
+// in UpstreamNoProxy, or else the one named by HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+// Loopback hosts are never proxied.
+func upstreamProxy(cfg config.Config) func(*http.Request) (*url.URL, error) {
+	if cfg.UpstreamProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+	proxyURL, err := url.Parse(cfg.UpstreamProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if err != nil {
+			return nil, err
+		}
+		if bypassProxy(req.URL.Hostname(), cfg.UpstreamNoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+}
+
+// BypassProxy reports whether host is loopback or matches a NO_PROXY style entry: "*", a
+// domain, which covers its subdomains, or an address or CIDR
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	addr, addrErr := netip.ParseAddr(host)
+	if host == "localhost" || addrErr == nil && addr.IsLoopback() {
+		return true
+	}
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimPrefix(entry, "."))
+		if entry == "*" || host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+		if prefix, err := config.ParsePrefix(entry); err == nil && addrErr == nil && prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialUpstream opens a TCP connection to address for a request to target, tunnelled
+// through the proxy upstreamProxy picks for it. It serves connections net/http does not
+// make itself, such as the exchange WebSocket stream.
+func dialUpstream(target *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	// Proxy environment variables are chosen by the HTTP scheme a WebSocket URL upgrades from
+	probe := *target
+	switch probe.Scheme {
+	case "wss":
+		probe.Scheme = "https"
+	case "ws":
+		probe.Scheme = "http"
+	}
+	proxyURL, err := upstreamProxy(config.Current())(&http.Request{URL: &probe})
+	dial := upstreamDNS.dialer(&net.Dialer{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return dial(context.Background(), "tcp", address)
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := map[string]string{"http": "80", "https": "443"}[proxyURL.Scheme]
+		if port == "" {
+			port = "1080"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := dial(context.Background(), "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", proxyAddress, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxyURL.User, address)
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		conn, err = tlsConn, tlsConn.Handshake()
+		if err == nil {
+			err = httpConnect(conn, proxyURL.User, address)
+		}
+	default:
+		err = httpConnect(conn, proxyURL.User, address)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyAddress, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// HTTPConnect asks an HTTP proxy to tunnel conn to address
+func httpConnect(conn net.Conn, user *url.Userinfo, address string) error {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := request.Write(conn); err != nil {
+		return err
+	}
+	// The target only speaks once the client has, so nothing past the response is buffered
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("CONNECT %s: %s", address, response.Status)
+	}
+	return nil
+}
+
+// Socks5Connect asks a SOCKS5 proxy (RFC 1928) to connect conn to address, authenticating
+// with a username and password (RFC 1929) when user is set
+func socks5Connect(conn net.Conn, user *url.Userinfo, address string) error {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	const noAuth, passwordAuth = 0x00, 0x02
+	greeting := []byte{0x05, 1, noAuth}
+	if user != nil {
+		greeting = []byte{0x05, 2, noAuth, passwordAuth}
+	}
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	switch {
+	case reply[0] != 0x05:
+		return errors.New("not a SOCKS5 proxy")
+	case reply[1] == passwordAuth && user != nil:
+		password, _ := user.Password()
+		if len(user.Username()) > 255 || len(password) > 255 {
+			return errors.New("SOCKS5 credentials too long")
+		}
+		login := []byte{0x01, byte(len(user.Username()))}
+		login = append(login, user.Username()...)
+		login = append(login, byte(len(password)))
+		login = append(login, password...)
+		if _, err := conn.Write(login); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errors.New("SOCKS5 authentication failed")
+		}
+	case reply[1] != noAuth:
+		return errors.New("SOCKS5 proxy requires an unsupported authentication method")
+	}
+
+	request := []byte{0x05, 0x01, 0x00}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Is4() {
+			request = append(request, 0x01)
+		} else {
+			request = append(request, 0x04)
+		}
+		request = append(request, addr.AsSlice()...)
+	} else {
+		if len(host) > 255 {
+			return errors.New("SOCKS5 host name too long")
+		}
+		request = append(request, 0x03, byte(len(host)))
+		request = append(request, host...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", address, header[1])
+	}
+	// Skip the bound address and port
+	var skip int
+	switch header[3] {
+	case 0x01:
+		skip = 4 + 2
+	case 0x04:
+		skip = 16 + 2
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0]) + 2
+	default:
+		return errors.New("SOCKS5 reply has an unknown address type")
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
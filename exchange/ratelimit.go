@@ -0,0 +1,199 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// defaultUpstreamRateLimits are conservative per-endpoint limits for the CoinDCX public API
+var defaultUpstreamRateLimits = map[string]config.RateLimit{
+	"markets":   {Rate: 0.5, Burst: 2},
+	"ticker":    {Rate: 1, Burst: 2},
+	"orderbook": {Rate: 8, Burst: 16},
+	"default":   {Rate: 4, Burst: 8},
+}
+
+// TokenBucket is a blocking token-bucket rate limiter
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func NewTokenBucket(limit config.RateLimit) *TokenBucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &TokenBucket{
+		rate:   limit.Rate,
+		burst:  float64(limit.Burst),
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+}
+
+// SetLimit changes the rate and burst, keeping the tokens already accrued
+func (b *TokenBucket) setLimit(limit config.RateLimit) {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rate = limit.Rate
+	b.burst = float64(limit.Burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Reserve takes a token and returns how long the caller must wait before using it
+func (b *TokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// Tokens may go negative; the deficit is the queue of callers already waiting
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// BucketState is what is left of a bucket after a take
+type BucketState struct {
+	Limit     int
+	Remaining int
+	// UntilNext is how long until another token is available, UntilFull until the bucket is full
+	UntilNext time.Duration
+	UntilFull time.Duration
+}
+
+// Take applies limit, then takes a token if one is available without waiting
+func (b *TokenBucket) Take(limit config.RateLimit) (bool, BucketState) {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.rate = limit.Rate
+	b.burst = float64(limit.Burst)
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	taken := b.tokens >= 1
+	if taken {
+		b.tokens--
+	}
+	state := BucketState{Limit: limit.Burst, Remaining: int(b.tokens)}
+	if b.rate > 0 {
+		if b.tokens < 1 {
+			state.UntilNext = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		state.UntilFull = time.Duration((b.burst - b.tokens) / b.rate * float64(time.Second))
+	}
+	return taken, state
+}
+
+// Idle reports whether a bucket would be full by now, so dropping it loses nothing
+func (b *TokenBucket) Idle(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.rate > 0 && b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// Wait blocks until a token is available or ctx is cancelled
+func (b *TokenBucket) wait(ctx context.Context) error {
+	if delay := b.reserve(); delay > 0 && !SleepContext(ctx, delay) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// UpstreamLimiter applies a separate token bucket to each class of upstream endpoint
+type UpstreamLimiter struct {
+	buckets map[string]*TokenBucket
+	mutex   sync.RWMutex
+}
+
+func newUpstreamLimiter(overrides map[string]config.RateLimit) *UpstreamLimiter {
+	limiter := &UpstreamLimiter{buckets: make(map[string]*TokenBucket)}
+	limiter.Update(overrides)
+	return limiter
+}
+
+// Update applies new per-endpoint overrides on top of the defaults. Existing buckets are
+// adjusted in place so callers already waiting keep their place.
+func (l *UpstreamLimiter) Update(overrides map[string]config.RateLimit) {
+	limits := make(map[string]config.RateLimit)
+	for endpoint, limit := range defaultUpstreamRateLimits {
+		limits[endpoint] = limit
+	}
+	for endpoint, limit := range overrides {
+		limits[endpoint] = limit
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for endpoint, limit := range limits {
+		if bucket, exists := l.buckets[endpoint]; exists {
+			bucket.setLimit(limit)
+		} else {
+			l.buckets[endpoint] = NewTokenBucket(limit)
+		}
+	}
+	for endpoint := range l.buckets {
+		if _, exists := limits[endpoint]; !exists {
+			delete(l.buckets, endpoint)
+		}
+	}
+}
+
+// UpstreamEndpoint classifies an exchange URL into a rate limit bucket
+func upstreamEndpoint(url string) string {
+	baseURL := config.Current().APIBaseURL
+	switch {
+	case strings.Contains(url, "/market_data/orderbook"):
+		return "orderbook"
+	case strings.Contains(url, "/exchange/ticker"):
+		return "ticker"
+	case strings.Contains(url, "/exchange/v1/markets_details"):
+		return "markets"
+	case baseURL != "" && strings.HasPrefix(url, baseURL),
+		strings.Contains(url, "coindcx.com"):
+		return "default"
+	}
+	// Other hosts such as FX and metadata providers are not subject to exchange limits
+	return ""
+}
+
+// Wait blocks until the bucket for url has a token or ctx is cancelled
+func (l *UpstreamLimiter) wait(ctx context.Context, url string) error {
+	l.mutex.RLock()
+	bucket, exists := l.buckets[upstreamEndpoint(url)]
+	l.mutex.RUnlock()
+	if exists {
+		return bucket.wait(ctx)
+	}
+	return nil
+}
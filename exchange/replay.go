@@ -0,0 +1,173 @@
+package exchange
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// recordedResponse is one upstream response in a recording, stored as a JSON line
+type recordedResponse struct {
+	OffsetMs   int64  `json:"offset_ms"`
+	URL        string `json:"url"`
+	Status     int    `json:"status"`
+	RetryAfter string `json:"retry_after,omitempty"`
+	Body       string `json:"body"`
+}
+
+// RecordingTransport passes requests through and appends every response to a file
+type RecordingTransport struct {
+	next    http.RoundTripper
+	file    *os.File
+	started time.Time
+	mutex   sync.Mutex
+}
+
+func newRecordingTransport(next http.RoundTripper, path string) (*RecordingTransport, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordingTransport{next: next, file: file, started: time.Now()}, nil
+}
+
+// RoundTrip records the response; failed requests are not recorded, matching what
+// replay can reproduce
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	line, err := json.Marshal(recordedResponse{
+		OffsetMs:   time.Since(t.started).Milliseconds(),
+		URL:        req.URL.String(),
+		Status:     resp.StatusCode,
+		RetryAfter: resp.Header.Get("Retry-After"),
+		Body:       string(body),
+	})
+	if err == nil {
+		t.mutex.Lock()
+		_, err = t.file.Write(append(line, '\n'))
+		t.mutex.Unlock()
+	}
+	if err != nil {
+		logging.Error("Error recording upstream response:", err)
+	}
+	return resp, nil
+}
+
+// ReplayTransport answers requests from a recording. Time in the recording advances at
+// speed times real time from the moment replay starts; each request gets the latest
+// response recorded for its URL at that point, or the first one if none is due yet.
+type ReplayTransport struct {
+	responses map[string][]recordedResponse
+	duration  time.Duration
+	speed     float64
+	started   time.Time
+	finished  sync.Once
+}
+
+func newReplayTransport(path string, speed float64) (*ReplayTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if speed <= 0 {
+		speed = 1
+	}
+	t := &ReplayTransport{responses: make(map[string][]recordedResponse), speed: speed, started: time.Now()}
+	scanner := bufio.NewScanner(file)
+	// Ticker responses for every market easily exceed the default line limit
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var response recordedResponse
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, line, err)
+		}
+		t.responses[response.URL] = append(t.responses[response.URL], response)
+		if offset := time.Duration(response.OffsetMs) * time.Millisecond; offset > t.duration {
+			t.duration = offset
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, responses := range t.responses {
+		sort.SliceStable(responses, func(i, j int) bool { return responses[i].OffsetMs < responses[j].OffsetMs })
+	}
+	return t, nil
+}
+
+// Position returns how far into the recording replay has progressed
+func (t *ReplayTransport) position() time.Duration {
+	return time.Duration(float64(time.Since(t.started)) * t.speed)
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	position := t.position()
+	if position > t.duration {
+		t.finished.Do(func() { logging.Info("Replay reached the end of the recording; serving its final responses") })
+	}
+
+	responses := t.responses[req.URL.String()]
+	if len(responses) == 0 {
+		return replayResponse(req, http.StatusNotFound, "", "not in recording"), nil
+	}
+	due := sort.Search(len(responses), func(i int) bool {
+		return time.Duration(responses[i].OffsetMs)*time.Millisecond > position
+	})
+	if due > 0 {
+		due--
+	}
+	response := responses[due]
+	return replayResponse(req, response.Status, response.RetryAfter, response.Body), nil
+}
+
+func replayResponse(req *http.Request, status int, retryAfter string, body string) *http.Response {
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// failingTransport fails every request, standing in for an upstream that could not be set up
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, t.err
+}
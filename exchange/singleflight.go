@@ -0,0 +1,62 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// flightCall is an in-flight or completed call shared by concurrent callers
+type flightCall struct {
+	done chan struct{}
+	err  error
+}
+
+// FlightGroup collapses concurrent calls with the same key into a single execution
+type FlightGroup struct {
+	calls map[string]*flightCall
+	mutex sync.Mutex
+}
+
+func NewFlightGroup() *FlightGroup {
+	return &FlightGroup{calls: make(map[string]*flightCall)}
+}
+
+// Do runs fn for key unless a call for key is already running, in which case it waits
+// for that call instead, and returns the call's error. It reports whether the result was
+// shared with another caller. The call runs detached from every caller's cancellation, so
+// one caller giving up does not fail it for the others; fn must bound itself, as upstream
+// requests do with UpstreamTimeout. Each caller only waits while its own ctx is live and
+// otherwise returns ctx's error.
+func (g *FlightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error) {
+	g.mutex.Lock()
+	call, shared := g.calls[key]
+	if !shared {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go func() {
+			defer func() {
+				// No request's recovery covers this goroutine, so a panic fails the call instead
+				if recovered := recover(); recovered != nil {
+					logging.Error("Shared call for", key, "panicked:", recovered)
+					call.err = fmt.Errorf("shared call panicked: %v", recovered)
+				}
+				g.mutex.Lock()
+				delete(g.calls, key)
+				g.mutex.Unlock()
+				close(call.done)
+			}()
+			call.err = fn(context.WithoutCancel(ctx))
+		}()
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return shared, call.err
+	case <-ctx.Done():
+		return shared, ctx.Err()
+	}
+}
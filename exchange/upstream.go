@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxRefreshBackoff      = 2 * time.Minute
+	defaultThrottleBackoff = 30 * time.Second
+)
+
+var ErrUpstreamThrottled = errors.New("upstream is throttling requests")
+
+// UpstreamError is a non-success HTTP response from the exchange
+type UpstreamError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("upstream returned %d %s (retry after %s)", e.StatusCode, http.StatusText(e.StatusCode), e.RetryAfter)
+	}
+	return fmt.Sprintf("upstream returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether the request may succeed if repeated later
+func (e *UpstreamError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ParseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// UpstreamState tracks throttling and failures reported by the exchange
+type UpstreamState struct {
+	throttledUntil      time.Time
+	consecutiveFailures int
+	totalRequests       int64
+	totalFailures       int64
+	lastError           string
+	lastErrorAt         time.Time
+	lastSuccessAt       time.Time
+	mutex               sync.Mutex
+}
+
+// UpstreamHealth is the externally visible view of UpstreamState
+type UpstreamHealth struct {
+	Throttled           bool   `json:"throttled"`
+	ThrottledUntil      int64  `json:"throttled_until,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	TotalRequests       int64  `json:"total_requests"`
+	TotalFailures       int64  `json:"total_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	LastErrorAt         int64  `json:"last_error_at,omitempty"`
+	LastSuccessAt       int64  `json:"last_success_at,omitempty"`
+}
+
+// Throttled reports whether requests should be held back because upstream asked us to slow down
+func (s *UpstreamState) Throttled() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Now().Before(s.throttledUntil)
+}
+
+// RetryAfter returns how long the exchange has asked us to back off for, if at all
+func (s *UpstreamState) RetryAfter() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Until(s.throttledUntil)
+}
+
+func (s *UpstreamState) recordSuccess() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.consecutiveFailures = 0
+	s.totalRequests++
+	s.lastSuccessAt = time.Now()
+}
+
+func (s *UpstreamState) recordFailure(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.totalRequests++
+	s.totalFailures++
+
+	var upstreamErr *UpstreamError
+	isUpstreamErr := errors.As(err, &upstreamErr)
+	if isUpstreamErr && !upstreamErr.retryable() {
+		// Client errors such as an unknown pair say nothing about upstream health
+		return
+	}
+	s.consecutiveFailures++
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+
+	if isUpstreamErr && upstreamErr.StatusCode == http.StatusTooManyRequests {
+		delay := upstreamErr.RetryAfter
+		if delay <= 0 {
+			delay = defaultThrottleBackoff
+		}
+		if until := time.Now().Add(delay); until.After(s.throttledUntil) {
+			s.throttledUntil = until
+		}
+	}
+}
+
+// RefreshDelay returns how long the refresh loop should sleep, growing exponentially
+// with consecutive failures and never ending before a Retry-After window
+func (s *UpstreamState) RefreshDelay(base time.Duration) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delay := base
+	for i := 0; i < s.consecutiveFailures && delay < maxRefreshBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRefreshBackoff {
+		delay = maxRefreshBackoff
+	}
+	if wait := time.Until(s.throttledUntil); wait > delay {
+		delay = wait
+	}
+	return delay
+}
+
+func (s *UpstreamState) Health() UpstreamHealth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	health := UpstreamHealth{
+		Throttled:           time.Now().Before(s.throttledUntil),
+		ConsecutiveFailures: s.consecutiveFailures,
+		TotalRequests:       s.totalRequests,
+		TotalFailures:       s.totalFailures,
+		LastError:           s.lastError,
+	}
+	if health.Throttled {
+		health.ThrottledUntil = s.throttledUntil.UnixNano() / int64(time.Millisecond)
+	}
+	if !s.lastErrorAt.IsZero() {
+		health.LastErrorAt = s.lastErrorAt.UnixNano() / int64(time.Millisecond)
+	}
+	if !s.lastSuccessAt.IsZero() {
+		health.LastSuccessAt = s.lastSuccessAt.UnixNano() / int64(time.Millisecond)
+	}
+	return health
+}
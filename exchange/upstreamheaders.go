@@ -0,0 +1,28 @@
+package exchange
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// SetUpstreamHeaders applies UpstreamUserAgent, UpstreamHeaders and then the
+// UpstreamHostHeaders for host to an outgoing upstream request
+func setUpstreamHeaders(header http.Header, host string) {
+	cfg := config.Current()
+	if cfg.UpstreamUserAgent != "" {
+		header.Set("User-Agent", cfg.UpstreamUserAgent)
+	}
+	for name, value := range cfg.UpstreamHeaders {
+		header.Set(name, value)
+	}
+	for configured, headers := range cfg.UpstreamHostHeaders {
+		if !strings.EqualFold(configured, host) {
+			continue
+		}
+		for name, value := range headers {
+			header.Set(name, value)
+		}
+	}
+}
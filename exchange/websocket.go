@@ -0,0 +1,24 @@
+package exchange
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/websocket"
+)
+
+// DialWebSocket opens a client connection to an upstream ws:// or wss:// URL, through the
+// upstream proxy and with the upstream headers
+func DialWebSocket(rawURL string, timeout time.Duration) (*websocket.Conn, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	setUpstreamHeaders(header, target.Hostname())
+	return websocket.Connect(target, header, timeout, func(address string) (net.Conn, error) {
+		return dialUpstream(target, address, timeout)
+	})
+}
@@ -0,0 +1,72 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// WorkerPool runs tasks on a fixed number of goroutines, bounding concurrent upstream calls
+type WorkerPool struct {
+	tasks chan func()
+}
+
+func newWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = config.DefaultUpstreamConcurrency
+	}
+	pool := &WorkerPool{tasks: make(chan func())}
+	for i := 0; i < size; i++ {
+		go func() {
+			for task := range pool.tasks {
+				task()
+			}
+		}()
+	}
+	return pool
+}
+
+// Do runs fn on a pool worker and waits for it to finish. It gives up without running fn
+// if ctx is cancelled while waiting for a free worker.
+func (p *WorkerPool) do(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	task := func() {
+		defer close(done)
+		fn()
+	}
+	select {
+	case p.tasks <- task:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return nil
+}
+
+// Parallel runs every fn concurrently and waits for all of them. Upstream work inside
+// each fn is still bounded by the worker pool.
+func Parallel(fns ...func()) {
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for _, fn := range fns {
+		go func(fn func()) {
+			defer wg.Done()
+			fn()
+		}(fn)
+	}
+	wg.Wait()
+}
+
+// SleepContext waits for d or until ctx is cancelled, reporting whether the full delay elapsed
+func SleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/namithsaliyan/CryptoTrackerAPI
+
+go 1.25
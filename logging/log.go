@@ -0,0 +1,69 @@
+// Package logging writes leveled log lines and carries request IDs through contexts.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Output receives log lines; CLI commands move it to stderr so stdout stays parseable
+var Output io.Writer = os.Stdout
+
+// Levels are the accepted values of LogLevel, from most to least verbose
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// LevelRank returns the position of level in Levels, or -1 if it is unknown
+func LevelRank(level string) int {
+	for i, known := range Levels {
+		if strings.EqualFold(level, known) {
+			return i
+		}
+	}
+	return -1
+}
+
+// currentLevel is the LogLevel in effect; see SetLevel
+var currentLevel atomic.Value
+
+// SetLevel makes level the threshold log messages must pass. The configuration sets it
+// whenever it changes, so reloads take effect immediately.
+func SetLevel(level string) {
+	currentLevel.Store(level)
+}
+
+// Enabled reports whether messages at level pass the LogLevel in effect
+func Enabled(level string) bool {
+	current, _ := currentLevel.Load().(string)
+	configured := LevelRank(current)
+	if configured < 0 {
+		configured = LevelRank("info")
+	}
+	return LevelRank(level) >= configured
+}
+
+func Debug(args ...interface{}) {
+	if Enabled("debug") {
+		fmt.Fprintln(Output, args...)
+	}
+}
+
+func Info(args ...interface{}) {
+	if Enabled("info") {
+		fmt.Fprintln(Output, args...)
+	}
+}
+
+func Warn(args ...interface{}) {
+	if Enabled("warn") {
+		fmt.Fprintln(Output, args...)
+	}
+}
+
+func Error(args ...interface{}) {
+	if Enabled("error") {
+		fmt.Fprintln(Output, args...)
+	}
+}
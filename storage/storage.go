@@ -0,0 +1,74 @@
+// Package storage persists JSON documents by key for the components that outlive a
+// restart.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Storage persists named JSON documents
+type Storage interface {
+	Load(key string, v interface{}) error
+	Save(key string, v interface{}) error
+}
+
+// FileStorage stores each document as a JSON file in a directory
+type FileStorage struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func NewFileStorage(dir string) *FileStorage {
+	if dir == "" {
+		dir = config.DefaultStorageDir
+	}
+	return &FileStorage{dir: dir}
+}
+
+func (s *FileStorage) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Load reads the document stored under key into v, returning ErrNotFound if it does not exist
+func (s *FileStorage) Load(key string, v interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Save writes v under key, replacing the previous document atomically
+func (s *FileStorage) Save(key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	tmp := s.path(key) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(key))
+}
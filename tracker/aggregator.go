@@ -0,0 +1,255 @@
+package tracker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// AggregateCandle is a candle of the aggregation engine. Closed reports whether its
+// interval and the lateness allowance after it have passed, so no tick can change it.
+type AggregateCandle struct {
+	Candle
+	Closed bool `json:"closed"`
+}
+
+// aggregateCandle is a candle with the tick times of its open and close, so ticks
+// arriving out of order still leave the earliest one as the open and the latest as the
+// close
+type aggregateCandle struct {
+	Candle
+	first, last int64
+}
+
+// aggregateTick is the last tick ingested for a market, to skip refreshes that repeat it
+type aggregateTick struct {
+	timestamp int64
+	price     float64
+}
+
+// CandleAggregator maintains candles at every AggregationTimeframes timeframe from one
+// stream of ticks. Ticks build the finest timeframe and each coarser one is rolled up
+// from the one before it (ticks to 1m to 5m to 1h by default), so the timeframes always
+// agree and align. The latest candle of each timeframe is partial until it closes. A tick
+// held up upstream still lands in its own candle while that candle is within
+// AggregationLateness seconds of closing, and the coarser candles holding it are rolled up
+// again; later ticks are dropped and counted.
+type CandleAggregator struct {
+	names      []string
+	timeframes []time.Duration
+	// series holds each market's candles per timeframe, oldest first
+	series   map[string][][]aggregateCandle
+	lastTick map[string]aggregateTick
+	late     map[string]int
+	mutex    sync.Mutex
+}
+
+// NewCandleAggregator builds candles at the given timeframes, finest first; each must be
+// a multiple of the one before it
+func newCandleAggregator(names []string) *CandleAggregator {
+	aggregator := &CandleAggregator{
+		series:   make(map[string][][]aggregateCandle),
+		lastTick: make(map[string]aggregateTick),
+		late:     make(map[string]int),
+	}
+	for _, name := range names {
+		if duration, ok := ParseCandleInterval(name); ok {
+			aggregator.names = append(aggregator.names, name)
+			aggregator.timeframes = append(aggregator.timeframes, duration)
+		}
+	}
+	return aggregator
+}
+
+// AggregationLateness returns how long after a candle's interval ends ticks for it are
+// still accepted
+func aggregationLateness() time.Duration {
+	return time.Duration(config.Current().AggregationLateness) * time.Second
+}
+
+// AggregationCandles returns how many candles are kept per market and timeframe
+func aggregationCandles() int {
+	if candles := config.Current().AggregationCandles; candles > 0 {
+		return candles
+	}
+	return config.DefaultAggregationCandles
+}
+
+// TickTime returns when upstream says a ticker last traded, which it sends in seconds
+// from the ticker endpoint and milliseconds from the stream, or received when it says
+// nothing
+func tickTime(timestamp int64, received time.Time) time.Time {
+	switch {
+	case timestamp <= 0:
+		return received
+	case timestamp < 1e12:
+		return time.Unix(timestamp, 0)
+	}
+	return time.UnixMilli(timestamp)
+}
+
+// Ingest adds a market's tick at price to the candle of every timeframe holding at
+func (a *CandleAggregator) ingest(market string, price float64, at, now time.Time) {
+	if price <= 0 || len(a.timeframes) == 0 {
+		return
+	}
+	timestamp := at.UnixMilli()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	tick := aggregateTick{timestamp: timestamp, price: price}
+	if a.lastTick[market] == tick {
+		return
+	}
+	a.lastTick[market] = tick
+
+	lateness := aggregationLateness().Milliseconds()
+	finest := a.timeframes[0]
+	start := exchange.CandleStart(timestamp, finest)
+	if now.UnixMilli() >= start+finest.Milliseconds()+lateness || timestamp > now.UnixMilli()+lateness {
+		a.late[market]++
+		return
+	}
+
+	levels := a.series[market]
+	if levels == nil {
+		levels = make([][]aggregateCandle, len(a.timeframes))
+	}
+	levels[0] = addTick(levels[0], market, start, price, timestamp)
+	for i := 1; i < len(levels); i++ {
+		levels[i] = rollUp(levels[i], levels[i-1], exchange.CandleStart(timestamp, a.timeframes[i]), a.timeframes[i])
+	}
+	limit := aggregationCandles()
+	for i, candles := range levels {
+		if len(candles) > limit {
+			levels[i] = candles[len(candles)-limit:]
+		}
+	}
+	a.series[market] = levels
+}
+
+// CandleIndex finds the candle starting at start, or where to insert it, searching from
+// the latest since ticks are nearly always for the current candle
+func candleIndex(candles []aggregateCandle, start int64) (int, bool) {
+	i := len(candles)
+	for i > 0 && candles[i-1].Timestamp >= start {
+		i--
+	}
+	return i, i < len(candles) && candles[i].Timestamp == start
+}
+
+func addTick(candles []aggregateCandle, market string, start int64, price float64, timestamp int64) []aggregateCandle {
+	i, exists := candleIndex(candles, start)
+	if !exists {
+		candle := aggregateCandle{
+			Candle: Candle{Market: market, Timestamp: start, Open: price, High: price, Low: price, Close: price, Points: 1},
+			first:  timestamp,
+			last:   timestamp,
+		}
+		candles = append(candles, aggregateCandle{})
+		copy(candles[i+1:], candles[i:])
+		candles[i] = candle
+		return candles
+	}
+	candle := &candles[i]
+	if price > candle.High {
+		candle.High = price
+	}
+	if price < candle.Low {
+		candle.Low = price
+	}
+	if timestamp < candle.first {
+		candle.Open, candle.first = price, timestamp
+	}
+	if timestamp >= candle.last {
+		candle.Close, candle.last = price, timestamp
+	}
+	candle.Points++
+	return candles
+}
+
+// RollUp rebuilds the candle of a coarser timeframe starting at start from the finer
+// candles within it, which replaces any earlier roll up of a tick that arrived late
+func rollUp(coarse, finer []aggregateCandle, start int64, duration time.Duration) []aggregateCandle {
+	first, _ := candleIndex(finer, start)
+	end := start + duration.Milliseconds()
+	var merged aggregateCandle
+	for _, candle := range finer[first:] {
+		if candle.Timestamp >= end {
+			break
+		}
+		if merged.Points == 0 {
+			merged = candle
+			merged.Timestamp = start
+			continue
+		}
+		if candle.High > merged.High {
+			merged.High = candle.High
+		}
+		if candle.Low < merged.Low {
+			merged.Low = candle.Low
+		}
+		merged.Close, merged.last = candle.Close, candle.last
+		merged.Points += candle.Points
+	}
+	if merged.Points == 0 {
+		return coarse
+	}
+	i, exists := candleIndex(coarse, start)
+	if exists {
+		coarse[i] = merged
+		return coarse
+	}
+	coarse = append(coarse, aggregateCandle{})
+	copy(coarse[i+1:], coarse[i:])
+	coarse[i] = merged
+	return coarse
+}
+
+// Candles returns a market's latest limit candles at a timeframe, oldest first, or all
+// that are kept when limit is 0
+func (a *CandleAggregator) Candles(market, timeframe string, limit int, now time.Time) ([]AggregateCandle, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	level := -1
+	for i, name := range a.names {
+		if strings.EqualFold(name, timeframe) {
+			level = i
+		}
+	}
+	if level < 0 {
+		return nil, false
+	}
+	var kept []aggregateCandle
+	if levels := a.series[market]; levels != nil {
+		kept = levels[level]
+	}
+	if limit > 0 && len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	closedBefore := now.Add(-aggregationLateness()).UnixMilli()
+	candles := make([]AggregateCandle, len(kept))
+	for i, candle := range kept {
+		candles[i] = AggregateCandle{
+			Candle: candle.Candle,
+			Closed: candle.Timestamp+a.timeframes[level].Milliseconds() <= closedBefore,
+		}
+	}
+	return candles, true
+}
+
+// LateTicks returns how many of a market's ticks arrived too late to be aggregated
+func (a *CandleAggregator) LateTicks(market string) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.late[market]
+}
+
+// ObservePrice records a market's latest price for adaptive refresh and candle
+// aggregation; timestamp is upstream's, in seconds or milliseconds
+func (c *CryptoTracker) observePrice(market string, price float64, timestamp int64, now time.Time) {
+	c.volatility.observe(market, price, now)
+	c.aggregates.ingest(market, price, tickTime(timestamp, now), now)
+}
@@ -0,0 +1,591 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	alertRulesStorageKey      = "alert_rules"
+	alertHistoryStorageKey    = "alert_history"
+	maxAlertTriggers          = 500
+	defaultAlertRuleWindow    = 300
+	maxAlertRuleWindow        = 86400
+	defaultBookImbalanceDepth = 10
+)
+
+// Alert rule conditions
+const (
+	// Last price at or above, or at or below, the threshold
+	conditionPriceAbove = "price_above"
+	conditionPriceBelow = "price_below"
+	// Percentage change of the last price over the window; a negative threshold watches falls
+	conditionChangePercent = "change_percent"
+	// 24h volume as a multiple of its average over the window
+	conditionVolumeSpike = "volume_spike"
+	// Bid/ask spread as a percentage of the mid price
+	conditionSpreadPercent = "spread_percent"
+	// (bid - ask) / (bid + ask) quantity over the top Depth order book levels, from -1 to 1;
+	// a negative threshold watches ask-heavy books
+	conditionBookImbalance = "book_imbalance"
+	// Funding rate of a perpetual futures contract as a percentage; a negative threshold
+	// watches rates at or below it
+	conditionFundingRate = "funding_rate"
+	// Percentage change of a futures contract's open interest over the window; a negative
+	// threshold watches falls
+	conditionOpenInterestChange = "open_interest_change"
+)
+
+var alertConditions = []string{
+	conditionPriceAbove, conditionPriceBelow, conditionChangePercent,
+	conditionVolumeSpike, conditionSpreadPercent, conditionBookImbalance, conditionFundingRate,
+	conditionOpenInterestChange,
+}
+
+// FuturesConditions are the conditions that watch a futures contract rather than a spot market
+var FuturesConditions = map[string]bool{conditionFundingRate: true, conditionOpenInterestChange: true}
+
+// AlertRule is a user-defined condition on one market, evaluated after every refresh. It
+// notifies when the condition starts to hold, not on every refresh it keeps holding, and
+// then stays quiet until the value has receded Hysteresis past the threshold. Crossings
+// within Cooldown seconds of the last notification are suppressed; if the condition still
+// holds once the cooldown ends, that one is notified then.
+type AlertRule struct {
+	ID string `json:"id"`
+	// Owner is the user the rule belongs to, empty for a shared one
+	Owner     string  `json:"owner,omitempty"`
+	Symbol    string  `json:"symbol"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	// Window is the lookback in seconds of change_percent, volume_spike and
+	// open_interest_change
+	Window int `json:"window,omitempty"`
+	// Depth is the number of levels per side book_imbalance sums
+	Depth int `json:"depth,omitempty"`
+	// Cooldown is the minimum number of seconds between notifications; 0 uses AlertCooldown
+	Cooldown int `json:"cooldown,omitempty"`
+	// Hysteresis, in the threshold's units, is how far the value must recede past the
+	// threshold before the rule re-arms
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	CreatedAt  int64   `json:"created_at"`
+	// Active reports whether the rule has triggered and not yet re-armed
+	Active        bool     `json:"active"`
+	LastValue     *float64 `json:"last_value,omitempty"`
+	LastTriggered int64    `json:"last_triggered,omitempty"`
+	// Suppressed counts the crossings the cooldown kept from notifying
+	Suppressed int `json:"suppressed"`
+	// Deferred marks a suppressed crossing that is notified when the cooldown ends if the
+	// condition still holds
+	Deferred bool `json:"deferred,omitempty"`
+}
+
+// Outcomes of an alert trigger's notification
+const (
+	// Crossed during the rule's cooldown, so nothing was sent
+	alertOutcomeSuppressed = "suppressed"
+	// Logged and published with no webhook configured
+	alertOutcomeLogged = "logged"
+	// Webhook delivery in progress
+	alertOutcomePending   = "pending"
+	alertOutcomeDelivered = "delivered"
+	alertOutcomeFailed    = "failed"
+)
+
+// AlertTrigger records one time a rule's condition started to hold and what became of
+// the notification
+type AlertTrigger struct {
+	RuleID    string  `json:"rule_id"`
+	Symbol    string  `json:"symbol"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+	Outcome   string  `json:"outcome"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// alertSample is one refresh's price and volume of a market, kept for windowed conditions
+type alertSample struct {
+	at     time.Time
+	price  float64
+	volume float64
+}
+
+// AlertEngine evaluates alert rules against the tracker's data after every refresh
+type AlertEngine struct {
+	tracker  *CryptoTracker
+	notifier *AlertNotifier
+	storage  storage.Storage
+	rules    map[string]*AlertRule
+	// history holds each rule's most recent triggers, oldest first
+	history map[string][]*AlertTrigger
+	samples map[string][]alertSample
+	mutex   sync.Mutex
+}
+
+func NewAlertEngine(tracker *CryptoTracker, notifier *AlertNotifier, backend storage.Storage) *AlertEngine {
+	engine := &AlertEngine{
+		tracker:  tracker,
+		notifier: notifier,
+		storage:  backend,
+		rules:    make(map[string]*AlertRule),
+		history:  make(map[string][]*AlertTrigger),
+		samples:  make(map[string][]alertSample),
+	}
+	err := backend.Load(alertRulesStorageKey, &engine.rules)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading alert rules:", err)
+	}
+	err = backend.Load(alertHistoryStorageKey, &engine.history)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading alert history:", err)
+	}
+	return engine
+}
+
+// Save persists all rules. The caller must hold the mutex.
+func (e *AlertEngine) save() error {
+	return e.storage.Save(alertRulesStorageKey, e.rules)
+}
+
+// NormalizeAlertRule validates a submitted rule and fills in default windows and depths
+func NormalizeAlertRule(rule *AlertRule) error {
+	rule.Symbol = strings.ToUpper(strings.TrimSpace(rule.Symbol))
+	if rule.Symbol == "" {
+		return errors.New("missing 'symbol'")
+	}
+	known := false
+	for _, condition := range alertConditions {
+		known = known || rule.Condition == condition
+	}
+	if !known {
+		return fmt.Errorf("'condition' must be one of %s", strings.Join(alertConditions, ", "))
+	}
+	if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+		return errors.New("'threshold' must be a number")
+	}
+	switch rule.Condition {
+	case conditionPriceAbove, conditionPriceBelow, conditionVolumeSpike, conditionSpreadPercent:
+		if rule.Threshold <= 0 {
+			return errors.New("'threshold' must be positive")
+		}
+	case conditionChangePercent, conditionFundingRate, conditionOpenInterestChange:
+		if rule.Threshold == 0 {
+			return errors.New("'threshold' must not be zero")
+		}
+	case conditionBookImbalance:
+		if rule.Threshold == 0 || math.Abs(rule.Threshold) > 1 {
+			return errors.New("'threshold' must be between -1 and 1 and not zero")
+		}
+	}
+
+	switch rule.Condition {
+	case conditionChangePercent, conditionVolumeSpike, conditionOpenInterestChange:
+		if rule.Window == 0 {
+			rule.Window = defaultAlertRuleWindow
+		}
+		if rule.Window < 0 || rule.Window > maxAlertRuleWindow {
+			return fmt.Errorf("'window' must be between 1 and %d seconds", maxAlertRuleWindow)
+		}
+	default:
+		rule.Window = 0
+	}
+	if rule.Cooldown < 0 {
+		return errors.New("'cooldown' must not be negative")
+	}
+	if rule.Hysteresis < 0 || math.IsNaN(rule.Hysteresis) || math.IsInf(rule.Hysteresis, 0) {
+		return errors.New("'hysteresis' must not be negative")
+	}
+	if rule.Condition == conditionBookImbalance {
+		if rule.Depth <= 0 {
+			rule.Depth = defaultBookImbalanceDepth
+		}
+	} else {
+		rule.Depth = 0
+	}
+	return nil
+}
+
+func (e *AlertEngine) Create(rule AlertRule) (AlertRule, error) {
+	rule.ID = logging.NewRequestID()
+	rule.CreatedAt = time.Now().UnixNano() / int64(time.Millisecond)
+	rule.Active, rule.LastValue, rule.LastTriggered = false, nil, 0
+	rule.Suppressed, rule.Deferred = 0, false
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.rules[rule.ID] = &rule
+	return rule, e.save()
+}
+
+// Get returns one of a user's rules
+func (e *AlertEngine) Get(user, id string) (AlertRule, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	rule, exists := e.rules[id]
+	if !exists || rule.Owner != user {
+		return AlertRule{}, false
+	}
+	return *rule, true
+}
+
+func (e *AlertEngine) List(user string) []AlertRule {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	rules := []AlertRule{}
+	for _, rule := range e.rules {
+		if rule.Owner == user {
+			rules = append(rules, *rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt < rules[j].CreatedAt })
+	return rules
+}
+
+func (e *AlertEngine) Remove(user, id string) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if rule, exists := e.rules[id]; !exists || rule.Owner != user {
+		return false, nil
+	}
+	delete(e.rules, id)
+	delete(e.history, id)
+	if err := e.saveHistory(); err != nil {
+		logging.Error("Error saving alert history:", err)
+	}
+	return true, e.save()
+}
+
+// SaveHistory persists the trigger history. The caller must hold the mutex.
+func (e *AlertEngine) saveHistory() error {
+	return e.storage.Save(alertHistoryStorageKey, e.history)
+}
+
+// RecordTriggerLocked appends a trigger to its rule's history, keeping the most recent
+// maxAlertTriggers. The caller must hold the mutex.
+func (e *AlertEngine) recordTriggerLocked(trigger *AlertTrigger) {
+	triggers := append(e.history[trigger.RuleID], trigger)
+	if len(triggers) > maxAlertTriggers {
+		triggers = triggers[len(triggers)-maxAlertTriggers:]
+	}
+	e.history[trigger.RuleID] = triggers
+	if err := e.saveHistory(); err != nil {
+		logging.Error("Error saving alert history:", err)
+	}
+}
+
+// Triggers returns a rule's triggers, newest first, at most limit of them when limit is
+// positive. It reports false if the user has no such rule.
+func (e *AlertEngine) Triggers(user, id string, limit int) ([]AlertTrigger, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if rule, exists := e.rules[id]; !exists || rule.Owner != user {
+		return nil, false
+	}
+	history := e.history[id]
+	triggers := make([]AlertTrigger, 0, len(history))
+	for i := len(history) - 1; i >= 0 && (limit <= 0 || len(triggers) < limit); i-- {
+		triggers = append(triggers, *history[i])
+	}
+	return triggers, true
+}
+
+// BookSymbols returns the markets whose order books imbalance rules need kept fresh
+func (e *AlertEngine) BookSymbols() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	seen := make(map[string]bool)
+	symbols := []string{}
+	for _, rule := range e.rules {
+		if rule.Condition == conditionBookImbalance && !seen[rule.Symbol] {
+			seen[rule.Symbol] = true
+			symbols = append(symbols, rule.Symbol)
+		}
+	}
+	return symbols
+}
+
+// Evaluate checks every rule against the latest tickers and order books and notifies the
+// rules whose condition has just started to hold
+func (e *AlertEngine) Evaluate() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.rules) == 0 {
+		return
+	}
+
+	c := e.tracker
+	tickers := make(map[string]exchange.TickerDetails)
+	books := make(map[string]exchange.OrderBook)
+	futures := make(map[string]FuturesPrice)
+	current := c.MarketView()
+	c.mutex.RLock()
+	for _, rule := range e.rules {
+		if ticker, exists := current.Tickers[rule.Symbol]; exists {
+			tickers[rule.Symbol] = ticker
+		}
+		if orderBook, exists := c.orderBooks[c.marketPairs[rule.Symbol]]; exists {
+			books[rule.Symbol] = orderBook
+		}
+		if price, exists := current.FuturesPrices[rule.Symbol]; exists {
+			futures[rule.Symbol] = price
+		}
+	}
+	c.mutex.RUnlock()
+
+	now := time.Now()
+	e.recordSamplesLocked(tickers, now)
+
+	changed := false
+	for _, rule := range e.rules {
+		value, ok := e.valueLocked(rule, tickers[rule.Symbol], books[rule.Symbol], futures[rule.Symbol], now)
+		if !ok {
+			continue
+		}
+		rule.LastValue = &value
+		holds := ruleHolds(rule, value)
+		if rule.Active && ruleRearmed(rule, value) {
+			rule.Active = false
+			changed = true
+		}
+		if !holds && rule.Deferred {
+			rule.Deferred = false
+			changed = true
+		}
+
+		crossed := holds && !rule.Active
+		if !crossed && !(holds && rule.Deferred) {
+			continue
+		}
+		rule.Active = true
+		trigger := &AlertTrigger{
+			RuleID:    rule.ID,
+			Symbol:    rule.Symbol,
+			Condition: rule.Condition,
+			Threshold: rule.Threshold,
+			Value:     value,
+			Timestamp: now.UnixNano() / int64(time.Millisecond),
+		}
+		cooldown := time.Duration(alertRuleCooldown(rule)) * time.Second
+		if rule.LastTriggered != 0 && now.Sub(time.Unix(0, rule.LastTriggered*int64(time.Millisecond))) < cooldown {
+			if crossed {
+				rule.Suppressed++
+				rule.Deferred = true
+				changed = true
+				trigger.Outcome = alertOutcomeSuppressed
+				e.recordTriggerLocked(trigger)
+			}
+			continue
+		}
+		rule.Deferred = false
+		changed = true
+		rule.LastTriggered = trigger.Timestamp
+		alert := Alert{
+			Type:      rule.Condition,
+			Symbol:    rule.Symbol,
+			RuleID:    rule.ID,
+			Message:   describeAlertRule(rule, value),
+			Value:     value,
+			Timestamp: trigger.Timestamp,
+		}
+		trigger.Outcome = alertOutcomeLogged
+		if e.notifier.notifyWithOutcome(alert, func(err error) { e.delivered(trigger, err) }) {
+			trigger.Outcome = alertOutcomePending
+		}
+		e.recordTriggerLocked(trigger)
+	}
+	if changed {
+		if err := e.save(); err != nil {
+			logging.Error("Error saving alert rules:", err)
+		}
+	}
+}
+
+// Delivered records the result of a trigger's webhook delivery
+func (e *AlertEngine) delivered(trigger *AlertTrigger, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	trigger.Outcome = alertOutcomeDelivered
+	if err != nil {
+		trigger.Outcome, trigger.Error = alertOutcomeFailed, err.Error()
+	}
+	if err := e.saveHistory(); err != nil {
+		logging.Error("Error saving alert history:", err)
+	}
+}
+
+// RecordSamplesLocked appends this refresh's prices for the markets windowed rules watch
+// and drops samples older than the longest window. The caller must hold the mutex.
+func (e *AlertEngine) recordSamplesLocked(tickers map[string]exchange.TickerDetails, now time.Time) {
+	windows := make(map[string]int)
+	for _, rule := range e.rules {
+		if rule.Window > windows[rule.Symbol] {
+			windows[rule.Symbol] = rule.Window
+		}
+	}
+	for symbol := range e.samples {
+		if windows[symbol] == 0 {
+			delete(e.samples, symbol)
+		}
+	}
+	for symbol, window := range windows {
+		samples := e.samples[symbol]
+		start := 0
+		for start < len(samples) && now.Sub(samples[start].at) > time.Duration(window)*time.Second {
+			start++
+		}
+		samples = samples[start:]
+		ticker, exists := tickers[symbol]
+		price, priceErr := strconv.ParseFloat(ticker.LastPrice, 64)
+		volume, volumeErr := strconv.ParseFloat(ticker.Volume, 64)
+		if exists && priceErr == nil && volumeErr == nil && price > 0 {
+			samples = append(samples, alertSample{at: now, price: price, volume: volume})
+		}
+		e.samples[symbol] = samples
+	}
+}
+
+// ValueLocked computes the value a rule's condition compares with its threshold. It
+// reports false while there is not enough data. The caller must hold the mutex.
+func (e *AlertEngine) valueLocked(rule *AlertRule, ticker exchange.TickerDetails, orderBook exchange.OrderBook, future FuturesPrice, now time.Time) (float64, bool) {
+	switch rule.Condition {
+	case conditionPriceAbove, conditionPriceBelow:
+		price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+		return price, err == nil && price > 0
+
+	case conditionSpreadPercent:
+		bid, ask, _, ok := ticker.DecimalPrices()
+		if !ok || ask.Cmp(bid) < 0 {
+			return 0, false
+		}
+		mid := ask.Add(bid).Div(exchange.DecimalFromInt(2))
+		return ask.Sub(bid).Div(mid).Mul(exchange.DecimalFromInt(100)).Float(), true
+
+	case conditionChangePercent, conditionVolumeSpike:
+		var window []alertSample
+		for _, sample := range e.samples[rule.Symbol] {
+			if now.Sub(sample.at) <= time.Duration(rule.Window)*time.Second {
+				window = append(window, sample)
+			}
+		}
+		if len(window) < 2 {
+			return 0, false
+		}
+		latest := window[len(window)-1]
+		if rule.Condition == conditionChangePercent {
+			return (latest.price - window[0].price) / window[0].price * 100, true
+		}
+		// The trailing average leaves out the latest sample it is compared with
+		total := 0.0
+		for _, sample := range window[:len(window)-1] {
+			total += sample.volume
+		}
+		average := total / float64(len(window)-1)
+		if average <= 0 {
+			return 0, false
+		}
+		return latest.volume / average, true
+
+	case conditionBookImbalance:
+		bids, asks := exchange.SortedLevels(orderBook.Bids, true), exchange.SortedLevels(orderBook.Asks, false)
+		if len(bids) == 0 || len(asks) == 0 {
+			return 0, false
+		}
+		sum := func(levels []exchange.BookLevel) exchange.Decimal {
+			total := exchange.Decimal{}
+			for i := 0; i < len(levels) && i < rule.Depth; i++ {
+				total = total.Add(levels[i].Quantity)
+			}
+			return total
+		}
+		bidQuantity, askQuantity := sum(bids), sum(asks)
+		return bidQuantity.Sub(askQuantity).Div(bidQuantity.Add(askQuantity)).Float(), true
+
+	case conditionFundingRate:
+		if future.Pair == "" {
+			return 0, false
+		}
+		return future.FundingRate.Mul(exchange.DecimalFromInt(100)).Float(), true
+
+	case conditionOpenInterestChange:
+		earliest, exists := e.tracker.openInterest.Since(rule.Symbol, now.Add(-time.Duration(rule.Window)*time.Second))
+		if !exists {
+			return 0, false
+		}
+		change, ok := OpenInterestChange(earliest, future.OpenInterest)
+		return change.Float(), ok
+	}
+	return 0, false
+}
+
+// RuleHolds reports whether value satisfies a rule's condition. Thresholds of conditions
+// that can move either way are signed: negative thresholds match values at or below them.
+func ruleHolds(rule *AlertRule, value float64) bool {
+	switch rule.Condition {
+	case conditionPriceBelow:
+		return value <= rule.Threshold
+	case conditionChangePercent, conditionBookImbalance, conditionFundingRate, conditionOpenInterestChange:
+		if rule.Threshold < 0 {
+			return value <= rule.Threshold
+		}
+	}
+	return value >= rule.Threshold
+}
+
+// RuleRearmed reports whether value has receded far enough past a triggered rule's
+// threshold for the rule to notify again on its next crossing
+func ruleRearmed(rule *AlertRule, value float64) bool {
+	above := rule.Threshold - rule.Hysteresis
+	below := rule.Threshold + rule.Hysteresis
+	switch rule.Condition {
+	case conditionPriceBelow:
+		return value > below
+	case conditionChangePercent, conditionBookImbalance, conditionFundingRate, conditionOpenInterestChange:
+		if rule.Threshold < 0 {
+			return value > below
+		}
+	}
+	return value < above
+}
+
+// AlertRuleCooldown returns a rule's cooldown in seconds, falling back to AlertCooldown
+func alertRuleCooldown(rule *AlertRule) int {
+	if rule.Cooldown > 0 {
+		return rule.Cooldown
+	}
+	return config.Current().AlertCooldown
+}
+
+// DescribeAlertRule renders the message of a triggered rule
+func describeAlertRule(rule *AlertRule, value float64) string {
+	switch rule.Condition {
+	case conditionPriceAbove:
+		return fmt.Sprintf("price %g is at or above %g", value, rule.Threshold)
+	case conditionPriceBelow:
+		return fmt.Sprintf("price %g is at or below %g", value, rule.Threshold)
+	case conditionChangePercent:
+		return fmt.Sprintf("price moved %.3f%% in %ds (threshold %g%%)", value, rule.Window, rule.Threshold)
+	case conditionVolumeSpike:
+		return fmt.Sprintf("volume is %.2fx its %ds average (threshold %gx)", value, rule.Window, rule.Threshold)
+	case conditionSpreadPercent:
+		return fmt.Sprintf("spread widened to %.3f%% (threshold %g%%)", value, rule.Threshold)
+	case conditionBookImbalance:
+		return fmt.Sprintf("order book imbalance over %d levels is %.3f (threshold %g)", rule.Depth, value, rule.Threshold)
+	case conditionFundingRate:
+		return fmt.Sprintf("funding rate is %.4f%% (threshold %g%%)", value, rule.Threshold)
+	case conditionOpenInterestChange:
+		return fmt.Sprintf("open interest moved %.3f%% in %ds (threshold %g%%)", value, rule.Window, rule.Threshold)
+	}
+	return fmt.Sprintf("%s is %g", rule.Condition, value)
+}
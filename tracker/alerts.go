@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// Alert is a notification raised by one of the tracker's detectors
+type Alert struct {
+	Type   string `json:"type"`
+	Symbol string `json:"symbol"`
+	// RuleID names the alert rule that raised the alert, if any
+	RuleID    string  `json:"rule_id,omitempty"`
+	Message   string  `json:"message"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// AlertNotifier delivers alerts to the log and, when configured, a webhook
+type AlertNotifier struct {
+	webhookURL string
+	client     *http.Client
+	// Events also publishes alerts to message brokers when set
+	Events *EventBus
+	mutex  sync.RWMutex
+}
+
+func NewAlertNotifier(webhookURL string) *AlertNotifier {
+	return &AlertNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetWebhookURL changes where alerts are delivered; an empty URL only logs them
+func (n *AlertNotifier) SetWebhookURL(webhookURL string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.webhookURL = webhookURL
+}
+
+// Notify records an alert and delivers it asynchronously
+func (n *AlertNotifier) notify(alert Alert) {
+	n.notifyWithOutcome(alert, nil)
+}
+
+// NotifyWithOutcome is notify for callers that track deliveries. It reports whether a
+// webhook delivery was started, in which case outcome, if set, is later called with its
+// result from another goroutine.
+func (n *AlertNotifier) notifyWithOutcome(alert Alert, outcome func(error)) bool {
+	if alert.Timestamp == 0 {
+		alert.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	logging.Info(fmt.Sprintf("Alert [%s] %s: %s", alert.Type, alert.Symbol, alert.Message))
+	n.Events.publish(eventAlert, alert.Symbol, alert)
+
+	n.mutex.RLock()
+	webhookURL := n.webhookURL
+	n.mutex.RUnlock()
+	if webhookURL == "" {
+		return false
+	}
+	go func() {
+		err := n.deliver(webhookURL, alert)
+		if err != nil {
+			logging.Error("Error delivering alert:", err)
+		}
+		if outcome != nil {
+			outcome(err)
+		}
+	}()
+	return true
+}
+
+func (n *AlertNotifier) deliver(webhookURL string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
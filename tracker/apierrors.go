@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// Error codes clients can branch on. Each maps to one HTTP status, and to a problem type
+// of ProblemTypePrefix plus the code.
+const (
+	codeSymbolNotFound  = "SYMBOL_NOT_FOUND"
+	codeUpstreamTimeout = "UPSTREAM_TIMEOUT"
+	codeUpstreamError   = "UPSTREAM_ERROR"
+	codeStaleData       = "STALE_DATA"
+	CodeRateLimited     = "RATE_LIMITED"
+	// Parameters failing a route's declared rules, listed in the problem's invalid_params
+	CodeInvalidParameters = "INVALID_PARAMETERS"
+)
+
+// ProblemTypePrefix names the problem types of coded errors, e.g. urn:cryptotracker:error:STALE_DATA
+const ProblemTypePrefix = "urn:cryptotracker:error:"
+
+var errorStatuses = map[string]int{
+	codeSymbolNotFound: http.StatusNotFound,
+	// The upstream exchange is the gateway these statuses describe
+	codeUpstreamTimeout:   http.StatusGatewayTimeout,
+	codeUpstreamError:     http.StatusBadGateway,
+	codeStaleData:         http.StatusServiceUnavailable,
+	CodeRateLimited:       http.StatusTooManyRequests,
+	CodeInvalidParameters: http.StatusBadRequest,
+}
+
+// APIError is a failure from the error taxonomy, written as a problem with its code
+type APIError struct {
+	Code   string
+	Detail string
+	// RetryAfter is sent as a Retry-After header when set
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Detail
+}
+
+func (e *APIError) Status() int {
+	if status, exists := errorStatuses[e.Code]; exists {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+var ErrSymbolNotFound = &APIError{Code: codeSymbolNotFound, Detail: "Unknown 'symbol'"}
+
+// UpstreamFailure classifies an error from an upstream request: throttling by the exchange,
+// a timeout, including the request's own deadline, or any other failure of the exchange
+func (c *CryptoTracker) upstreamFailure(err error) *APIError {
+	var upstreamErr *exchange.UpstreamError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, exchange.ErrUpstreamThrottled):
+		return &APIError{Code: CodeRateLimited, Detail: err.Error(), RetryAfter: c.httpClient.State.RetryAfter()}
+	case errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusTooManyRequests:
+		return &APIError{Code: CodeRateLimited, Detail: err.Error(), RetryAfter: upstreamErr.RetryAfter}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &APIError{Code: codeUpstreamTimeout, Detail: "Timed out waiting for upstream"}
+	}
+	return &APIError{Code: codeUpstreamError, Detail: err.Error()}
+}
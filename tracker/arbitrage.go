@@ -0,0 +1,140 @@
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// ArbitrageOpportunity compares buying an asset directly against buying it through a second quote market
+type ArbitrageOpportunity struct {
+	Asset            string           `json:"asset"`
+	Quote            string           `json:"quote"`
+	Bridge           string           `json:"bridge"`
+	Route            []string         `json:"route"`
+	DirectPrice      exchange.Decimal `json:"direct_price"`
+	ImpliedPrice     exchange.Decimal `json:"implied_price"`
+	SpreadPercent    float64          `json:"spread_percent"`
+	NetReturnPercent float64          `json:"net_return_percent"`
+}
+
+// ArbitrageScanner looks for cross-quote price discrepancies after every ticker refresh
+type ArbitrageScanner struct {
+	tracker       *CryptoTracker
+	opportunities []ArbitrageOpportunity
+	scannedAt     time.Time
+	mutex         sync.RWMutex
+}
+
+func NewArbitrageScanner(tracker *CryptoTracker) *ArbitrageScanner {
+	return &ArbitrageScanner{tracker: tracker}
+}
+
+func ArbitrageFeeRate() float64 {
+	if feeRate := config.Current().ArbitrageFeeRate; feeRate > 0 {
+		return feeRate
+	}
+	return config.DefaultArbitrageFeeRate
+}
+
+func ArbitrageThreshold() float64 {
+	if threshold := config.Current().ArbitrageThreshold; threshold > 0 {
+		return threshold
+	}
+	return config.DefaultArbitrageThreshold
+}
+
+// Scan evaluates every asset quoted in two currencies that are themselves tradable against each other
+func (a *ArbitrageScanner) Scan() {
+	keep := 1 - ArbitrageFeeRate()
+	legs := keep * keep * keep
+	opportunities := []ArbitrageOpportunity{}
+
+	view := a.tracker.MarketView()
+	// markets[asset][quote] holds the market pricing asset in quote
+	markets := make(map[string]map[string]exchange.TickerDetails)
+	for name, market := range view.Markets {
+		ticker, exists := view.Tickers[name]
+		if !exists {
+			continue
+		}
+		if _, _, _, ok := ticker.Prices(); !ok {
+			continue
+		}
+		asset := market.TargetCurrencyShortName
+		if markets[asset] == nil {
+			markets[asset] = make(map[string]exchange.TickerDetails)
+		}
+		markets[asset][market.BaseCurrencyShortName] = ticker
+	}
+
+	for asset, quotes := range markets {
+		for quote, direct := range quotes {
+			for bridge, viaBridge := range quotes {
+				if bridge == quote {
+					continue
+				}
+				conversion, exists := markets[bridge][quote]
+				if !exists {
+					continue
+				}
+
+				directBid, directAsk, _, _ := direct.Prices()
+				bridgeBid, bridgeAsk, _, _ := viaBridge.Prices()
+				conversionBid, conversionAsk, _, _ := conversion.Prices()
+
+				// Last prices are compared exactly, the fee-adjusted returns below are estimates
+				directLast, _ := exchange.ParseDecimal(direct.LastPrice)
+				bridgeLast, _ := exchange.ParseDecimal(viaBridge.LastPrice)
+				conversionLast, _ := exchange.ParseDecimal(conversion.LastPrice)
+				implied := bridgeLast.Mul(conversionLast)
+				opportunity := ArbitrageOpportunity{
+					Asset:         asset,
+					Quote:         quote,
+					Bridge:        bridge,
+					DirectPrice:   directLast,
+					ImpliedPrice:  implied,
+					SpreadPercent: implied.Div(directLast).Sub(exchange.DecimalFromInt(1)).Mul(exchange.DecimalFromInt(100)).Float(),
+				}
+
+				// Buy directly, sell through the bridge currency back into the quote
+				forward := bridgeBid*conversionBid/directAsk*legs - 1
+				// Buy the bridge currency, buy the asset with it, sell directly
+				reverse := directBid/(conversionAsk*bridgeAsk)*legs - 1
+				if forward >= reverse {
+					opportunity.Route = []string{direct.Market, viaBridge.Market, conversion.Market}
+					opportunity.NetReturnPercent = forward * 100
+				} else {
+					opportunity.Route = []string{conversion.Market, viaBridge.Market, direct.Market}
+					opportunity.NetReturnPercent = reverse * 100
+				}
+				opportunities = append(opportunities, opportunity)
+			}
+		}
+	}
+
+	sort.Slice(opportunities, func(i, j int) bool {
+		return opportunities[i].NetReturnPercent > opportunities[j].NetReturnPercent
+	})
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.opportunities = opportunities
+	a.scannedAt = time.Now()
+}
+
+// Above returns the opportunities from the last scan whose net return exceeds threshold percent
+func (a *ArbitrageScanner) Above(threshold float64) ([]ArbitrageOpportunity, time.Time) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	result := []ArbitrageOpportunity{}
+	for _, opportunity := range a.opportunities {
+		if opportunity.NetReturnPercent > threshold {
+			result = append(result, opportunity)
+		}
+	}
+	return result, a.scannedAt
+}
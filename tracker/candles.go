@@ -0,0 +1,294 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	candlesURL         = "https://public.coindcx.com/market_data/candles"
+	DefaultCandleLimit = 500
+	MaxCandleLimit     = 1000
+	// maxUpstreamCandles is the most candles upstream returns per request, and
+	// maxCandlePages how many requests one resampled interval may make
+	maxUpstreamCandles = 1000
+	maxCandlePages     = 10
+	// minRenkoBrickRatio is the smallest Renko brick as a fraction of the price
+	minRenkoBrickRatio = 1e-6
+
+	minCandleInterval = time.Minute
+	maxCandleInterval = 7 * 24 * time.Hour
+)
+
+// CandleTypes are the kinds of candle the candles endpoint derives from the exchange's
+var CandleTypes = []string{"candles", "heikin_ashi", "renko"}
+
+// MarketCandle is a market's traded prices and volume over one interval starting at
+// Timestamp, as the exchange reports them
+type MarketCandle struct {
+	Market    string  `json:"market"`
+	Timestamp int64   `json:"timestamp"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	Volume    float64 `json:"volume"`
+}
+
+// ParseCandleInterval reads an interval such as 1m, 15m, 4h, 1d or 1w, from one minute to
+// one week
+func ParseCandleInterval(name string) (time.Duration, bool) {
+	if len(name) < 2 {
+		return 0, false
+	}
+	count, err := strconv.Atoi(name[:len(name)-1])
+	if err != nil || count <= 0 || name[0] == '+' {
+		return 0, false
+	}
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	unit, exists := units[name[len(name)-1]]
+	if !exists || count > int(maxCandleInterval/unit) {
+		return 0, false
+	}
+	duration := time.Duration(count) * unit
+	return duration, duration >= minCandleInterval
+}
+
+// SourceCandleInterval returns the coarsest upstream interval that evenly divides
+// duration, which is duration itself when upstream serves it
+func sourceCandleInterval(duration time.Duration) (string, time.Duration) {
+	name, source := exchange.UpstreamCandleIntervals[0].Name, exchange.UpstreamCandleIntervals[0].Duration
+	for _, interval := range exchange.UpstreamCandleIntervals {
+		if interval.Duration <= duration && duration%interval.Duration == 0 {
+			name, source = interval.Name, interval.Duration
+		}
+	}
+	return name, source
+}
+
+// ResampleCandles merges time-ordered candles into candles of the given resolution: each
+// keeps the open of its first candle, the close of its last, the extremes of all of them
+// and the sum of their volumes
+func resampleCandles(candles []MarketCandle, resolution time.Duration) []MarketCandle {
+	merged := []MarketCandle{}
+	for _, candle := range candles {
+		start := exchange.CandleStart(candle.Timestamp, resolution)
+		if n := len(merged); n > 0 && merged[n-1].Timestamp == start {
+			last := &merged[n-1]
+			if candle.High > last.High {
+				last.High = candle.High
+			}
+			if candle.Low < last.Low {
+				last.Low = candle.Low
+			}
+			last.Close = candle.Close
+			last.Volume += candle.Volume
+			continue
+		}
+		candle.Timestamp = start
+		merged = append(merged, candle)
+	}
+	return merged
+}
+
+// HeikinAshiCandles derives Heikin-Ashi candles from time-ordered candles: each closes at
+// the mean of its candle's prices and opens midway through the Heikin-Ashi candle before
+// it, so the first ones depend on where the range starts
+func HeikinAshiCandles(candles []MarketCandle) []MarketCandle {
+	derived := make([]MarketCandle, 0, len(candles))
+	for i, candle := range candles {
+		smoothed := candle
+		smoothed.Close = (candle.Open + candle.High + candle.Low + candle.Close) / 4
+		smoothed.Open = (candle.Open + candle.Close) / 2
+		if i > 0 {
+			smoothed.Open = (derived[i-1].Open + derived[i-1].Close) / 2
+		}
+		smoothed.High = math.Max(candle.High, math.Max(smoothed.Open, smoothed.Close))
+		smoothed.Low = math.Min(candle.Low, math.Min(smoothed.Open, smoothed.Close))
+		derived = append(derived, smoothed)
+	}
+	return derived
+}
+
+// MinRenkoBrick returns the smallest brick RenkoBricks is asked for over candles, a
+// millionth of the latest close. Smaller bricks would only enumerate price ticks.
+func MinRenkoBrick(candles []MarketCandle) float64 {
+	if len(candles) == 0 {
+		return 0
+	}
+	return candles[len(candles)-1].Close * minRenkoBrickRatio
+}
+
+// RenkoBricks derives Renko bricks of size brick from the closes of time-ordered candles,
+// starting from the first close. A brick is added each time the close moves a brick beyond
+// the last one, or two bricks against it to reverse. Each brick has the timestamp of the
+// candle that completed it and the volume traded since the brick before. Only the latest
+// MaxCandleLimit bricks are kept.
+func RenkoBricks(candles []MarketCandle, brick float64) []MarketCandle {
+	bricks := []MarketCandle{}
+	if len(candles) == 0 || brick <= 0 {
+		return bricks
+	}
+	top, bottom := candles[0].Close, candles[0].Close
+	volume := 0.0
+	for _, candle := range candles {
+		volume += candle.Volume
+		// Bricks that would be trimmed anyway are skipped rather than built one by one, leaving
+		// one spare for the rounding of the skipped distance
+		if moves := math.Floor((candle.Close - top) / brick); moves > MaxCandleLimit {
+			top += (moves - MaxCandleLimit - 1) * brick
+			bottom = top - brick
+		} else if moves := math.Floor((bottom - candle.Close) / brick); moves > MaxCandleLimit {
+			bottom -= (moves - MaxCandleLimit - 1) * brick
+			top = bottom + brick
+		}
+		for candle.Close >= top+brick || candle.Close <= bottom-brick {
+			// A brick below the float64 precision of the price would never move it
+			if top+brick == top || bottom-brick == bottom {
+				break
+			}
+			open, close := bottom, bottom-brick
+			if candle.Close >= top+brick {
+				open, close = top, top+brick
+			}
+			top, bottom = math.Max(open, close), math.Min(open, close)
+			bricks = append(bricks, MarketCandle{
+				Market: candle.Market, Timestamp: candle.Timestamp,
+				Open: open, High: top, Low: bottom, Close: close, Volume: volume,
+			})
+			volume = 0
+		}
+		if len(bricks) > 2*MaxCandleLimit {
+			bricks = append(bricks[:0], bricks[len(bricks)-MaxCandleLimit:]...)
+		}
+	}
+	if len(bricks) > MaxCandleLimit {
+		bricks = bricks[len(bricks)-MaxCandleLimit:]
+	}
+	return bricks
+}
+
+// MarketCandles returns a market's candles at duration between from and to, oldest
+// first. Intervals upstream does not serve are resampled from the coarsest one that
+// divides them, and refused when that needs more than maxCandlePages of them. Without
+// from, enough candles are fetched to fill limit.
+func (c *CryptoTracker) MarketCandles(ctx context.Context, market string, duration time.Duration, from, to time.Time, limit int) ([]MarketCandle, *APIError) {
+	details, exists := c.MarketInfo(market)
+	if !exists {
+		return nil, ErrSymbolNotFound
+	}
+	sourceName, source := sourceCandleInterval(duration)
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-time.Duration(limit) * duration)
+	}
+	// Fetching from the start of the first interval keeps it from being resampled partially
+	start := exchange.CandleStart(from.UnixMilli(), duration)
+	if source != duration {
+		span := to.UnixMilli() - start
+		if limit > 0 {
+			span = min(span, int64(limit)*duration.Milliseconds())
+		}
+		if span/source.Milliseconds() > maxCandlePages*maxUpstreamCandles {
+			most := maxCandlePages * maxUpstreamCandles * source / duration
+			return nil, &APIError{Code: CodeInvalidParameters, Detail: fmt.Sprintf(
+				"At most %d candles of this interval can be resampled from upstream's %s candles; narrow 'from' or 'limit'", most, sourceName)}
+		}
+	}
+
+	byTime := make(map[int64]MarketCandle)
+	end := to.UnixMilli()
+	page := 0
+	for ; page < maxCandlePages && end >= start; page++ {
+		fetched, err := c.fetchCandles(ctx, details.Pair, sourceName, start, end)
+		if err != nil {
+			return nil, c.upstreamFailure(err)
+		}
+		earliest := end
+		for _, candle := range fetched {
+			candle.Market = market
+			byTime[candle.Timestamp] = candle
+			if candle.Timestamp < earliest {
+				earliest = candle.Timestamp
+			}
+		}
+		if len(fetched) < maxUpstreamCandles {
+			break
+		}
+		// Upstream returns the latest candles of the range, so page backwards
+		end = earliest - 1
+	}
+	// Paging that ran out before reaching start leaves the earliest interval partly fetched
+	complete := start
+	if page == maxCandlePages && end >= start {
+		complete = end + 1
+	}
+
+	candles := make([]MarketCandle, 0, len(byTime))
+	for _, candle := range byTime {
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Timestamp < candles[j].Timestamp })
+	if source != duration {
+		candles = resampleCandles(candles, duration)
+	}
+	inRange := candles[:0]
+	for _, candle := range candles {
+		if candle.Timestamp >= complete && candle.Timestamp+duration.Milliseconds() > from.UnixMilli() && candle.Timestamp <= to.UnixMilli() {
+			inRange = append(inRange, candle)
+		}
+	}
+	if limit > 0 && len(inRange) > limit {
+		inRange = inRange[len(inRange)-limit:]
+	}
+	return inRange, nil
+}
+
+// FetchCandles fetches up to maxUpstreamCandles of pair's candles at an upstream interval
+// from the latest before end back towards start
+func (c *CryptoTracker) fetchCandles(ctx context.Context, pair, interval string, start, end int64) ([]MarketCandle, error) {
+	query := url.Values{}
+	query.Set("pair", pair)
+	query.Set("interval", interval)
+	query.Set("startTime", strconv.FormatInt(start, 10))
+	query.Set("endTime", strconv.FormatInt(end, 10))
+	query.Set("limit", strconv.Itoa(maxUpstreamCandles))
+	response, err := c.httpClient.PerformRequest(ctx, candlesURL+"?"+query.Encode())
+	if err != nil {
+		logging.Error("Error fetching candles:", err)
+		return nil, err
+	}
+	var entries []struct {
+		Open   float64 `json:"open"`
+		High   float64 `json:"high"`
+		Low    float64 `json:"low"`
+		Close  float64 `json:"close"`
+		Volume float64 `json:"volume"`
+		Time   int64   `json:"time"`
+	}
+	if err := json.Unmarshal([]byte(response), &entries); err != nil {
+		logging.Error("Error parsing candles:", err)
+		return nil, err
+	}
+	candles := make([]MarketCandle, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Time < start || entry.Time > end {
+			continue
+		}
+		candles = append(candles, MarketCandle{
+			Timestamp: entry.Time, Open: entry.Open, High: entry.High, Low: entry.Low, Close: entry.Close, Volume: entry.Volume,
+		})
+	}
+	return candles, nil
+}
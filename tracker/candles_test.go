@@ -0,0 +1,74 @@
+package tracker
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// closes returns one-minute candles closing at each of prices
+func closes(prices ...float64) []MarketCandle {
+	candles := make([]MarketCandle, len(prices))
+	for i, price := range prices {
+		candles[i] = MarketCandle{Market: "BTCINR", Timestamp: int64(i) * time.Minute.Milliseconds(), Close: price, Volume: 1}
+	}
+	return candles
+}
+
+func TestRenkoBricks(t *testing.T) {
+	bricks := RenkoBricks(closes(100, 103, 101, 98), 1)
+	want := [][2]float64{{100, 101}, {101, 102}, {102, 103}, {102, 101}, {101, 100}, {100, 99}, {99, 98}}
+	if len(bricks) != len(want) {
+		t.Fatalf("got %d bricks, want %d: %+v", len(bricks), len(want), bricks)
+	}
+	for i, brick := range bricks {
+		if brick.Open != want[i][0] || brick.Close != want[i][1] {
+			t.Errorf("brick %d = %v to %v, want %v to %v", i, brick.Open, brick.Close, want[i][0], want[i][1])
+		}
+	}
+	// The volume of a candle completing several bricks goes to the first of them
+	if bricks[0].Volume != 2 || bricks[1].Volume != 0 {
+		t.Errorf("volumes = %v, %v, want 2, 0", bricks[0].Volume, bricks[1].Volume)
+	}
+}
+
+func TestRenkoBricksBounded(t *testing.T) {
+	tests := []struct {
+		name      string
+		candles   []MarketCandle
+		brick     float64
+		wantClose float64
+	}{
+		// A brick this small does not move a price of this size at all
+		{"below float64 precision", closes(5000000, 5000100), 1e-300, 0},
+		{"100 INR in 0.001 bricks", closes(5000000, 5000100), 0.001, 5000100},
+		{"falling 100 INR in 0.001 bricks", closes(5000000, 4999900), 0.001, 4999900},
+		{"many small moves", closes(5000000, 5000010, 5000020, 5000030, 5000040), 0.001, 5000040},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bricks := RenkoBricks(test.candles, test.brick)
+			if len(bricks) > MaxCandleLimit {
+				t.Fatalf("got %d bricks, want at most %d", len(bricks), MaxCandleLimit)
+			}
+			if test.wantClose == 0 {
+				return
+			}
+			if len(bricks) != MaxCandleLimit {
+				t.Fatalf("got %d bricks, want the latest %d", len(bricks), MaxCandleLimit)
+			}
+			if last := bricks[len(bricks)-1].Close; math.Abs(last-test.wantClose) > test.brick {
+				t.Errorf("last brick closes at %v, want %v", last, test.wantClose)
+			}
+		})
+	}
+}
+
+func TestMinRenkoBrick(t *testing.T) {
+	if got := MinRenkoBrick(closes(4000000, 5000000)); got != 5 {
+		t.Errorf("MinRenkoBrick = %v, want 5", got)
+	}
+	if got := MinRenkoBrick(nil); got != 0 {
+		t.Errorf("MinRenkoBrick(nil) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,52 @@
+package tracker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+func init() {
+	config.RegisterValidator(validateConfig)
+}
+
+// validateConfig checks the settings of candle aggregation, history backfills,
+// published events and health alerts
+func validateConfig(c config.Config, check func(ok bool, format string, args ...interface{})) {
+	check(len(c.AggregationTimeframes) > 0, "AggregationTimeframes must not be empty")
+	var previousTimeframe time.Duration
+	for _, name := range c.AggregationTimeframes {
+		duration, ok := ParseCandleInterval(name)
+		check(ok, "AggregationTimeframes entry %q must be an interval from 1m to 1w", name)
+		if ok && previousTimeframe > 0 {
+			check(duration > previousTimeframe && duration%previousTimeframe == 0,
+				"AggregationTimeframes entry %q must be a multiple of the timeframe before it", name)
+			// A coarser candle is rolled up from the finer candles still kept
+			check(c.AggregationCandles <= 0 || c.AggregationCandles >= int(duration/previousTimeframe),
+				"AggregationCandles must be at least %d to roll up %s candles", int(duration/previousTimeframe), name)
+		}
+		if ok {
+			previousTimeframe = duration
+		}
+	}
+	// A backfill fetches at most maxCandlePages pages of 1m candles per market
+	check(c.HistoryBackfillWindow >= 0 && c.HistoryBackfillWindow <= maxCandlePages*maxUpstreamCandles/60,
+		"HistoryBackfillWindow must be between 0 and %d hours", maxCandlePages*maxUpstreamCandles/60)
+	for _, event := range c.MQTTEvents {
+		check(event == eventTicker || event == eventOrderBook || event == eventAlert, "MQTTEvents entry %q must be ticker, orderbook or alert", event)
+	}
+	for i, rule := range c.HealthAlerts {
+		known := false
+		for _, condition := range healthConditions {
+			known = known || rule.Condition == condition
+		}
+		check(known, "HealthAlerts[%d].Condition must be one of %s, got %q", i, strings.Join(healthConditions, ", "), rule.Condition)
+		known = false
+		for _, dataset := range healthDatasets {
+			known = known || rule.Dataset == dataset
+		}
+		check(known, "HealthAlerts[%d].Dataset must be one of %s, got %q", i, strings.Join(healthDatasets, ", "), rule.Dataset)
+		check(rule.Threshold > 0, "HealthAlerts[%d].Threshold must be positive, got %g", i, rule.Threshold)
+	}
+}
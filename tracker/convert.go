@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// conversionBridges lists currencies tried as intermediates when no direct market exists
+var conversionBridges = []string{"USDT"}
+
+// ConversionStep describes one market hop used in a conversion
+type ConversionStep struct {
+	Market  string           `json:"market"`
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Price   exchange.Decimal `json:"price"`
+	Rate    exchange.Decimal `json:"rate"`
+	Inverse bool             `json:"inverse"`
+}
+
+// ConversionResult holds the outcome of a currency conversion
+type ConversionResult struct {
+	From   string           `json:"from"`
+	To     string           `json:"to"`
+	Amount exchange.Decimal `json:"amount"`
+	Result exchange.Decimal `json:"result"`
+	Path   []ConversionStep `json:"path"`
+}
+
+// FindConversionStep finds a direct market between two currencies
+func (v *MarketView) findConversionStep(from, to string) (ConversionStep, bool) {
+	for name, market := range v.Markets {
+		ticker, exists := v.Tickers[name]
+		if !exists {
+			continue
+		}
+		price, ok := exchange.ParseDecimal(ticker.LastPrice)
+		if !ok || price.Sign() <= 0 {
+			continue
+		}
+
+		// On CoinDCX the target currency is priced in units of the base currency
+		if market.TargetCurrencyShortName == from && market.BaseCurrencyShortName == to {
+			return ConversionStep{Market: name, From: from, To: to, Price: price, Rate: price}, true
+		}
+		if market.BaseCurrencyShortName == from && market.TargetCurrencyShortName == to {
+			return ConversionStep{Market: name, From: from, To: to, Price: price, Rate: exchange.DecimalFromInt(1).Div(price), Inverse: true}, true
+		}
+	}
+	return ConversionStep{}, false
+}
+
+// Apply converts an amount along the step. Inverse steps divide by the market price rather
+// than multiplying by the rounded rate, so no precision is lost to the reciprocal.
+func (s ConversionStep) apply(amount exchange.Decimal) exchange.Decimal {
+	if s.Inverse {
+		return amount.Div(s.Price)
+	}
+	return amount.Mul(s.Price)
+}
+
+// ConvertCurrency converts an amount between currencies directly or through a bridge currency
+func (c *CryptoTracker) ConvertCurrency(from, to string, amount exchange.Decimal) (ConversionResult, error) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	result := ConversionResult{From: from, To: to, Amount: amount}
+
+	if from == to {
+		result.Result = amount
+		result.Path = []ConversionStep{}
+		return result, nil
+	}
+
+	// Every leg is priced from the same view
+	view := c.MarketView()
+	if step, ok := view.findConversionStep(from, to); ok {
+		result.Result = step.apply(amount)
+		result.Path = []ConversionStep{step}
+		return result, nil
+	}
+
+	for _, bridge := range conversionBridges {
+		if bridge == from || bridge == to {
+			continue
+		}
+		first, ok := view.findConversionStep(from, bridge)
+		if !ok {
+			continue
+		}
+		second, ok := view.findConversionStep(bridge, to)
+		if !ok {
+			continue
+		}
+		result.Result = second.apply(first.apply(amount))
+		result.Path = []ConversionStep{first, second}
+		return result, nil
+	}
+
+	return result, fmt.Errorf("no conversion path from %s to %s", from, to)
+}
+
+// Rounded rounds the converted amount to the precision of the currency it is in
+func (r ConversionResult) Rounded(p *Precision) ConversionResult {
+	r.Result = p.amount(r.To, r.Result)
+	return r
+}
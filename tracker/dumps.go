@@ -0,0 +1,150 @@
+package tracker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	snapshotDumpPrefix = "snapshot-"
+	snapshotDumpLayout = "20060102T150405Z"
+	// snapshotDumpPoll is how often a disabled dump job checks whether it was enabled
+	snapshotDumpPoll = time.Minute
+)
+
+// SnapshotDump is a snapshot plus every cached order book, keyed by pair, as written to disk
+type SnapshotDump struct {
+	Snapshot
+	OrderBooks map[string]exchange.OrderBook `json:"order_books"`
+}
+
+// SnapshotDumpInterval returns how often snapshots are dumped, or 0 when dumps are off
+func snapshotDumpInterval() time.Duration {
+	return time.Duration(config.Current().SnapshotDumpInterval) * time.Second
+}
+
+// SnapshotDumpDir returns where dumps are written, under the storage directory unless set
+func snapshotDumpDir() string {
+	cfg := config.Current()
+	if cfg.SnapshotDumpDir != "" {
+		return cfg.SnapshotDumpDir
+	}
+	dir := cfg.StorageDir
+	if dir == "" {
+		dir = config.DefaultStorageDir
+	}
+	return filepath.Join(dir, "snapshots")
+}
+
+// SnapshotDumpCadence is the dump job's interval, polling slowly while dumps are off
+func snapshotDumpCadence() time.Duration {
+	if interval := snapshotDumpInterval(); interval > 0 {
+		return interval
+	}
+	return snapshotDumpPoll
+}
+
+// DumpSnapshot writes the current snapshot and order books to a timestamped file and then
+// prunes old dumps
+func (c *CryptoTracker) dumpSnapshot(ctx context.Context) {
+	if snapshotDumpInterval() <= 0 {
+		return
+	}
+	cfg := config.Current()
+	dump := SnapshotDump{Snapshot: c.Snapshot(), OrderBooks: make(map[string]exchange.OrderBook)}
+	c.mutex.RLock()
+	for pair, orderBook := range c.orderBooks {
+		dump.OrderBooks[pair] = orderBook
+	}
+	c.mutex.RUnlock()
+
+	dir := snapshotDumpDir()
+	name := snapshotDumpPrefix + time.Now().UTC().Format(snapshotDumpLayout) + ".json"
+	if cfg.SnapshotDumpCompress {
+		name += ".gz"
+	}
+	if err := writeSnapshotDump(filepath.Join(dir, name), dump, cfg.SnapshotDumpCompress); err != nil {
+		logging.Error("Error writing snapshot dump:", err)
+		return
+	}
+	logging.Debug("Wrote snapshot dump", name)
+	if err := pruneSnapshotDumps(dir, cfg.SnapshotDumpKeep, time.Duration(cfg.SnapshotDumpMaxAge)*time.Hour); err != nil {
+		logging.Error("Error pruning snapshot dumps:", err)
+	}
+}
+
+// WriteSnapshotDump writes through a temporary file so readers never see a partial dump
+func writeSnapshotDump(path string, dump SnapshotDump, compress bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = file
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(file)
+		w = zw
+	}
+	err = json.NewEncoder(w).Encode(dump)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// PruneSnapshotDumps deletes dumps beyond the newest keep and any older than maxAge; zero
+// disables either limit
+func pruneSnapshotDumps(dir string, keep int, maxAge time.Duration) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	// Names embed the UTC time, so sorting them sorts dumps oldest first
+	dumps := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, snapshotDumpPrefix) && !strings.HasSuffix(name, ".tmp") {
+			dumps = append(dumps, name)
+		}
+	}
+	sort.Strings(dumps)
+
+	for i, name := range dumps {
+		expired := keep > 0 && i < len(dumps)-keep
+		if !expired && maxAge > 0 {
+			stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, snapshotDumpPrefix), ".gz"), ".json")
+			if written, err := time.Parse(snapshotDumpLayout, stamp); err == nil {
+				expired = time.Since(written) > maxAge
+			}
+		}
+		if expired {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package tracker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	eventTicker    = "ticker"
+	eventOrderBook = "orderbook"
+	eventAlert     = "alert"
+	// eventQueueSize bounds how many events wait for slow brokers before new ones are dropped
+	eventQueueSize = 10000
+)
+
+// MarketEvent is a ticker update, order book snapshot or alert sent to message brokers
+type MarketEvent struct {
+	Type      string      `json:"type"`
+	Symbol    string      `json:"symbol"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Publisher delivers market events to a message broker
+type Publisher interface {
+	Publish(event MarketEvent) error
+	Close() error
+}
+
+// EventBus queues events and hands them to every publisher from one goroutine, so a slow or
+// unreachable broker never holds up a refresh. A nil bus discards events.
+type EventBus struct {
+	publishers []Publisher
+	queue      chan MarketEvent
+	done       chan struct{}
+	dropped    uint64
+	closed     bool
+	mutex      sync.RWMutex
+}
+
+// NewPublishers creates a publisher for every broker configured
+func NewPublishers(cfg config.Config) []Publisher {
+	publishers := []Publisher{}
+	if len(cfg.KafkaBrokers) > 0 {
+		publishers = append(publishers, newKafkaProducer(cfg))
+	}
+	if cfg.NATSURL != "" {
+		if publisher, err := newNATSPublisher(cfg); err != nil {
+			logging.Error("Error configuring NATS:", err)
+		} else {
+			publishers = append(publishers, publisher)
+		}
+	}
+	// Followers only consume; republishing what they receive would loop between replicas
+	if cfg.RedisURL != "" && !cfg.RedisFollow {
+		publishers = append(publishers, newRedisPublisher(cfg))
+	}
+	if cfg.MQTTURL != "" {
+		if publisher, err := newMQTTPublisher(cfg); err != nil {
+			logging.Error("Error configuring MQTT:", err)
+		} else {
+			publishers = append(publishers, publisher)
+		}
+	}
+	return publishers
+}
+
+// NewEventBus starts delivering to publishers, returning nil when there are none
+func NewEventBus(publishers []Publisher) *EventBus {
+	if len(publishers) == 0 {
+		return nil
+	}
+	bus := &EventBus{
+		publishers: publishers,
+		queue:      make(chan MarketEvent, eventQueueSize),
+		done:       make(chan struct{}),
+	}
+	go bus.run()
+	return bus
+}
+
+func (b *EventBus) run() {
+	defer close(b.done)
+	for event := range b.queue {
+		for _, publisher := range b.publishers {
+			if err := publisher.Publish(event); err != nil {
+				logging.Error("Error publishing event:", err)
+			}
+		}
+	}
+}
+
+// Publish queues an event stamped with the current time
+func (b *EventBus) publish(eventType, symbol string, data interface{}) {
+	if b == nil {
+		return
+	}
+	event := MarketEvent{Type: eventType, Symbol: symbol, Timestamp: time.Now().UnixNano() / int64(time.Millisecond), Data: data}
+	// Refreshes still finishing during shutdown may publish after close
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.closed {
+		return
+	}
+	select {
+	case b.queue <- event:
+	default:
+		if atomic.AddUint64(&b.dropped, 1)%1000 == 1 {
+			logging.Warn("Event queue full, dropping events")
+		}
+	}
+}
+
+// Close delivers the events already queued and closes every publisher
+func (b *EventBus) Close() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	b.closed = true
+	close(b.queue)
+	b.mutex.Unlock()
+	<-b.done
+	for _, publisher := range b.publishers {
+		if err := publisher.Close(); err != nil {
+			logging.Error("Error closing publisher:", err)
+		}
+	}
+}
+
+// MarketForPairLocked returns the market name of an order book pair; the caller holds the mutex
+func (c *CryptoTracker) marketForPairLocked(pair string) string {
+	for market, marketPair := range c.marketPairs {
+		if marketPair == pair {
+			return market
+		}
+	}
+	return pair
+}
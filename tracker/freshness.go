@@ -0,0 +1,70 @@
+package tracker
+
+import (
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// LatestOf returns the latest of times, the modification time of a response built from
+// several datasets
+func LatestOf(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// Freshness tells clients when cached data was last refreshed so they can detect stale values
+type Freshness struct {
+	LastUpdated int64   `json:"last_updated"`
+	AgeSeconds  float64 `json:"age_seconds"`
+	Stale       bool    `json:"stale"`
+}
+
+// StaleThreshold returns how old data may get before it is flagged stale
+func staleThreshold() time.Duration {
+	if threshold := config.Current().StaleThreshold; threshold > 0 {
+		return time.Duration(threshold) * time.Second
+	}
+	return config.DefaultStaleThreshold
+}
+
+// FreshnessOf describes data refreshed at updated; data never loaded reports zeros and is stale
+func FreshnessOf(updated time.Time) Freshness {
+	return freshnessWithin(updated, staleThreshold())
+}
+
+// FreshnessWithin is FreshnessOf for data expected to refresh at least every threshold
+func freshnessWithin(updated time.Time, threshold time.Duration) Freshness {
+	if updated.IsZero() {
+		return Freshness{Stale: true}
+	}
+	age := time.Since(updated)
+	return Freshness{
+		LastUpdated: updated.UnixNano() / int64(time.Millisecond),
+		AgeSeconds:  age.Round(time.Millisecond).Seconds(),
+		Stale:       age > threshold,
+	}
+}
+
+// TickerView is a ticker as returned by /ticker, with its freshness
+type TickerView struct {
+	exchange.TickerDetails
+	Freshness
+}
+
+// TickersUpdated returns when ticker data last changed, from either the REST refresh or the stream
+func (c *CryptoTracker) TickersUpdated() time.Time {
+	var latest time.Time
+	for _, updated := range c.MarketView().Times {
+		if updated.After(latest) {
+			latest = updated
+		}
+	}
+	return latest
+}
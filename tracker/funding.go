@@ -0,0 +1,83 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+const (
+	// maxFundingPoints bounds the rates kept per contract whatever the retention
+	maxFundingPoints = 10000
+)
+
+// FundingPoint is a perpetual contract's funding rate from when it was first seen
+type FundingPoint struct {
+	Timestamp   int64            `json:"timestamp"`
+	FundingRate exchange.Decimal `json:"funding_rate"`
+}
+
+// FundingHistory keeps each perpetual contract's funding rates for FundingRetention
+// seconds. A rate is stored when it changes, so a contract whose rate holds still between
+// funding intervals costs one point rather than one per refresh.
+type FundingHistory struct {
+	points map[string][]FundingPoint
+	mutex  sync.Mutex
+}
+
+func newFundingHistory() *FundingHistory {
+	return &FundingHistory{points: make(map[string][]FundingPoint)}
+}
+
+// FundingRetention returns how long funding rates are kept
+func fundingRetention() time.Duration {
+	if retention := config.Current().FundingRetention; retention > 0 {
+		return time.Duration(retention) * time.Second
+	}
+	return config.DefaultFundingRetention * time.Second
+}
+
+// Record stores the funding rates of a futures price refresh and forgets those older
+// than the retention
+func (h *FundingHistory) record(prices map[string]FuturesPrice, now time.Time) {
+	cutoff := now.Add(-fundingRetention()).UnixMilli()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for pair, price := range prices {
+		points := h.points[pair]
+		if len(points) == 0 || points[len(points)-1].FundingRate.Cmp(price.FundingRate) != 0 {
+			points = append(points, FundingPoint{Timestamp: now.UnixMilli(), FundingRate: price.FundingRate})
+		}
+		start := 0
+		for start < len(points)-1 && points[start].Timestamp < cutoff {
+			start++
+		}
+		if len(points)-start > maxFundingPoints {
+			start = len(points) - maxFundingPoints
+		}
+		h.points[pair] = points[start:]
+	}
+}
+
+// Query returns a contract's funding rates between from and to, oldest first; zero bounds
+// are open. A positive limit keeps only the most recent points.
+func (h *FundingHistory) Query(pair string, from, to time.Time, limit int) []FundingPoint {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	points := []FundingPoint{}
+	for _, point := range h.points[pair] {
+		if !from.IsZero() && point.Timestamp < from.UnixMilli() {
+			continue
+		}
+		if !to.IsZero() && point.Timestamp > to.UnixMilli() {
+			continue
+		}
+		points = append(points, point)
+	}
+	if limit > 0 && len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points
+}
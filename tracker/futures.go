@@ -0,0 +1,238 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const futuresPricesURL = "https://public.coindcx.com/market_data/v3/current_prices/futures/rt"
+
+// FuturesInstrument is the specification of a futures contract, such as B-BTC_USDT
+type FuturesInstrument struct {
+	Pair                        string           `json:"pair"`
+	Kind                        string           `json:"kind"`
+	Status                      string           `json:"status"`
+	UnderlyingCurrencyShortName string           `json:"underlying_currency_short_name"`
+	QuoteCurrencyShortName      string           `json:"quote_currency_short_name"`
+	SettleCurrencyShortName     string           `json:"settle_currency_short_name"`
+	MarginCurrencyShortName     string           `json:"margin_currency_short_name"`
+	MaxLeverageLong             exchange.Decimal `json:"max_leverage_long"`
+	MaxLeverageShort            exchange.Decimal `json:"max_leverage_short"`
+	PriceIncrement              exchange.Decimal `json:"price_increment"`
+	QuantityIncrement           exchange.Decimal `json:"quantity_increment"`
+	MinQuantity                 exchange.Decimal `json:"min_quantity"`
+	MaxQuantity                 exchange.Decimal `json:"max_quantity"`
+	MinNotional                 exchange.Decimal `json:"min_notional"`
+	FundingFrequency            int              `json:"funding_frequency"`
+}
+
+// FuturesPrice is a contract's latest prices. The mark price values positions and
+// triggers liquidations; the index price, when upstream sends one, is the spot reference
+// the contract tracks.
+type FuturesPrice struct {
+	Pair         string           `json:"pair"`
+	LastPrice    exchange.Decimal `json:"last_price"`
+	MarkPrice    exchange.Decimal `json:"mark_price"`
+	IndexPrice   exchange.Decimal `json:"index_price,omitzero"`
+	FundingRate  exchange.Decimal `json:"funding_rate"`
+	High         exchange.Decimal `json:"high"`
+	Low          exchange.Decimal `json:"low"`
+	Volume       exchange.Decimal `json:"volume"`
+	Change24Hour exchange.Decimal `json:"change_24_hour"`
+	// OpenInterest is the contracts held open, when upstream sends it
+	OpenInterest exchange.Decimal `json:"open_interest,omitzero"`
+}
+
+// futuresPriceEntry is a contract's entry in the upstream price feed, which abbreviates
+// its field names
+type futuresPriceEntry struct {
+	LastPrice    exchange.Decimal `json:"ls"`
+	MarkPrice    exchange.Decimal `json:"mp"`
+	IndexPrice   exchange.Decimal `json:"ip"`
+	FundingRate  exchange.Decimal `json:"fr"`
+	High         exchange.Decimal `json:"h"`
+	Low          exchange.Decimal `json:"l"`
+	Volume       exchange.Decimal `json:"v"`
+	Change24Hour exchange.Decimal `json:"pc"`
+	OpenInterest exchange.Decimal `json:"oi"`
+}
+
+// FuturesPriceView is a contract's prices with how fresh they are
+type FuturesPriceView struct {
+	FuturesPrice
+	Freshness
+}
+
+// FuturesEnabled reports whether futures data is fetched and served
+func FuturesEnabled() bool {
+	return config.Current().FuturesEnabled
+}
+
+// RefreshFuturesInstruments fetches which futures contracts are active for each of
+// FuturesMarginCurrencies
+func (c *CryptoTracker) refreshFuturesInstruments(ctx context.Context) {
+	if !FuturesEnabled() {
+		return
+	}
+	cfg := config.Current()
+	instruments := make(map[string]string)
+	for _, margin := range cfg.FuturesMarginCurrencies {
+		address := cfg.APIBaseURL + "/exchange/v1/derivatives/futures/data/active_instruments?margin_currency_short_name[]=" + url.QueryEscape(margin)
+		response, err := c.httpClient.PerformRequest(ctx, address)
+		if err != nil {
+			logging.Error("Error fetching futures instruments:", err)
+			c.stats.recordRefreshResult("futures_instruments", err)
+			return
+		}
+		var pairs []string
+		err = json.Unmarshal([]byte(response), &pairs)
+		if err != nil {
+			logging.Error("Error parsing futures instruments:", err)
+			c.stats.recordRefreshResult("futures_instruments", err)
+			return
+		}
+		for _, pair := range pairs {
+			instruments[pair] = strings.ToUpper(margin)
+		}
+	}
+	c.stats.recordRefreshResult("futures_instruments", nil)
+
+	now := time.Now()
+	c.mutex.Lock()
+	c.futuresUpdated = now
+	c.mutex.Unlock()
+	c.publish(func(next *MarketView) { next.FuturesInstruments, next.FuturesInstrumentsModified = instruments, now })
+}
+
+// RefreshFuturesPrices fetches the last, mark and index price of every futures contract
+func (c *CryptoTracker) refreshFuturesPrices(ctx context.Context) {
+	if !FuturesEnabled() {
+		return
+	}
+	response, err := c.httpClient.PerformRequest(ctx, futuresPricesURL)
+	if err != nil {
+		logging.Error("Error fetching futures prices:", err)
+		c.stats.recordRefreshResult("futures_prices", err)
+		return
+	}
+
+	var feed struct {
+		Prices map[string]futuresPriceEntry `json:"prices"`
+	}
+	err = json.Unmarshal([]byte(response), &feed)
+	c.stats.recordRefreshResult("futures_prices", err)
+	if err != nil {
+		logging.Error("Error parsing futures prices:", err)
+		return
+	}
+	prices := make(map[string]FuturesPrice, len(feed.Prices))
+	for pair, entry := range feed.Prices {
+		prices[pair] = FuturesPrice{
+			Pair:         pair,
+			LastPrice:    entry.LastPrice,
+			MarkPrice:    entry.MarkPrice,
+			IndexPrice:   entry.IndexPrice,
+			FundingRate:  entry.FundingRate,
+			High:         entry.High,
+			Low:          entry.Low,
+			Volume:       entry.Volume,
+			Change24Hour: entry.Change24Hour,
+			OpenInterest: entry.OpenInterest,
+		}
+	}
+	now := time.Now()
+	c.funding.record(prices, now)
+	c.openInterest.record(prices, now)
+	c.publish(func(next *MarketView) { next.FuturesPrices, next.FuturesPricesModified = prices, now })
+}
+
+// FuturesInstrument returns an active contract's specification, fetching it when it is
+// not cached or older than MarketsRefreshInterval. Concurrent fetches of one contract
+// share a single upstream call.
+func (c *CryptoTracker) FuturesInstrument(ctx context.Context, pair string) (FuturesInstrument, *APIError) {
+	margin, exists := c.MarketView().FuturesInstruments[pair]
+	if !exists {
+		return FuturesInstrument{}, ErrSymbolNotFound
+	}
+	c.mutex.RLock()
+	fetchedAt := c.futuresInstrumentTimes[pair]
+	c.mutex.RUnlock()
+
+	var failure error
+	if time.Since(fetchedAt) >= marketsRefreshInterval() {
+		_, failure = c.futuresCalls.Do(ctx, pair, func(ctx context.Context) error {
+			return c.fetchFuturesInstrument(ctx, pair, margin)
+		})
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	instrument, exists := c.futuresInstrumentDetails[pair]
+	// A contract fetched before still has its specification; one never fetched has nothing
+	if !exists {
+		if failure == nil {
+			failure = errors.New("futures instrument fetch failed")
+		}
+		return FuturesInstrument{}, c.upstreamFailure(failure)
+	}
+	return instrument, nil
+}
+
+// FetchFuturesInstrument fetches a contract's specification
+func (c *CryptoTracker) fetchFuturesInstrument(ctx context.Context, pair, margin string) error {
+	address := config.Current().APIBaseURL + "/exchange/v1/derivatives/futures/data/instrument?pair=" +
+		url.QueryEscape(pair) + "&margin_currency_short_name=" + url.QueryEscape(margin)
+	response, err := c.httpClient.PerformRequest(ctx, address)
+	if err != nil {
+		logging.Error("Error fetching futures instrument:", err)
+		return err
+	}
+	var details struct {
+		Instrument struct {
+			FuturesInstrument
+			// Upstream may list several margin currencies; the one asked for is kept instead
+			MarginCurrencyShortName json.RawMessage `json:"margin_currency_short_name"`
+		} `json:"instrument"`
+	}
+	if err := json.Unmarshal([]byte(response), &details); err != nil {
+		logging.Error("Error parsing futures instrument:", err)
+		return err
+	}
+	instrument := details.Instrument.FuturesInstrument
+	instrument.MarginCurrencyShortName = margin
+	c.mutex.Lock()
+	c.futuresInstrumentDetails[pair] = instrument
+	c.futuresInstrumentTimes[pair] = time.Now()
+	c.mutex.Unlock()
+	return nil
+}
+
+// ResolveFuturesPair returns the futures contract that raw names. Spot symbols rewritten by
+// canonicalSymbols, such as BTCUSDT for B-BTC_USDT, resolve to the contract trading the
+// same currencies.
+func (c *CryptoTracker) ResolveFuturesPair(raw string) string {
+	symbol := strings.ToUpper(strings.TrimSpace(raw))
+	prices := c.MarketView().FuturesPrices
+	if _, exists := prices[symbol]; exists {
+		return symbol
+	}
+	squash := func(value string) string {
+		return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(symbolSeparators, r) }), "")
+	}
+	for pair := range prices {
+		// Contracts are named after the exchange they are priced on, as in B-BTC_USDT
+		_, currencies, _ := strings.Cut(pair, "-")
+		if squash(pair) == squash(symbol) || squash(currencies) == squash(symbol) {
+			return pair
+		}
+	}
+	return symbol
+}
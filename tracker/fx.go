@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// RefreshFXRates fetches INR fiat exchange rates
+func (c *CryptoTracker) refreshFXRates(ctx context.Context) {
+	url := config.Current().FXAPIURL
+	if url == "" {
+		url = config.DefaultFXAPIURL
+	}
+	response, err := c.httpClient.PerformRequest(ctx, url)
+	if err != nil {
+		logging.Error("Error fetching FX rates:", err)
+		return
+	}
+
+	var rates exchange.FXRates
+	err = json.Unmarshal([]byte(response), &rates)
+	if err != nil {
+		logging.Error("Error parsing FX rates:", err)
+		return
+	}
+	if len(rates.Rates) == 0 {
+		logging.Error("Error parsing FX rates: no rates in response")
+		return
+	}
+
+	now := time.Now()
+	c.mutex.Lock()
+	c.fxUpdated = now
+	c.mutex.Unlock()
+	// The decoded map is never modified, so the view can keep it
+	c.publish(func(next *MarketView) { next.fxRates, next.FXModified = rates.Rates, now })
+}
+
+// FXRefreshInterval returns how often fiat rates are refetched
+func fxRefreshInterval() time.Duration {
+	if interval := config.Current().FXRefreshInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultFXRefreshInterval
+}
+
+// ScalePrice multiplies a decimal price string by rate, leaving unparseable values untouched
+func scalePrice(price string, rate float64) string {
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return price
+	}
+	return strconv.FormatFloat(value*rate, 'f', -1, 64)
+}
+
+// ScaleRawPrice scales a price that upstream may send as either a JSON number or string
+func scaleRawPrice(raw json.RawMessage, rate float64) json.RawMessage {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return raw
+	}
+
+	var scaled interface{}
+	switch v := value.(type) {
+	case float64:
+		scaled = v * rate
+	case string:
+		scaled = scalePrice(v, rate)
+	default:
+		return raw
+	}
+
+	encoded, err := json.Marshal(scaled)
+	if err != nil {
+		return raw
+	}
+	return encoded
+}
+
+// ConvertTickerToFiat converts the price fields of an INR ticker using rate
+func ConvertTickerToFiat(ticker exchange.TickerDetails, rate float64) exchange.TickerDetails {
+	ticker.High = scalePrice(ticker.High, rate)
+	ticker.Low = scalePrice(ticker.Low, rate)
+	ticker.LastPrice = scalePrice(ticker.LastPrice, rate)
+	ticker.Bid = scaleRawPrice(ticker.Bid, rate)
+	ticker.Ask = scaleRawPrice(ticker.Ask, rate)
+	return ticker
+}
+
+// ConvertOrderBookToFiat converts the price levels of an INR order book using rate
+func ConvertOrderBookToFiat(orderBook exchange.OrderBook, rate float64) exchange.OrderBook {
+	converted := exchange.OrderBook{
+		Bids: make(map[string]string, len(orderBook.Bids)),
+		Asks: make(map[string]string, len(orderBook.Asks)),
+	}
+	for price, quantity := range orderBook.Bids {
+		converted.Bids[scalePrice(price, rate)] = quantity
+	}
+	for price, quantity := range orderBook.Asks {
+		converted.Asks[scalePrice(price, rate)] = quantity
+	}
+	return converted
+}
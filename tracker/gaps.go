@@ -0,0 +1,210 @@
+package tracker
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	DefaultGapReportWindow = 24 * time.Hour
+	// backfillSettle keeps the latest minutes out of backfill, since their raw tickers may
+	// still be arriving
+	backfillSettle = 2 * time.Minute
+)
+
+// CandleGap is a run of intervals with no stored candle, from From up to To in epoch
+// milliseconds
+type CandleGap struct {
+	From    int64 `json:"from"`
+	To      int64 `json:"to"`
+	Missing int   `json:"missing"`
+}
+
+// FindCandleGaps returns the runs of duration intervals between from and to that no
+// candle covers. The interval holding to may still be filling, so it is left out.
+func findCandleGaps(candles []Candle, duration time.Duration, from, to time.Time) []CandleGap {
+	step := duration.Milliseconds()
+	cursor, end := exchange.CandleStart(from.UnixMilli(), duration), exchange.CandleStart(to.UnixMilli(), duration)
+	gaps := []CandleGap{}
+	add := func(start, stop int64) {
+		if stop > start {
+			gaps = append(gaps, CandleGap{From: start, To: stop, Missing: int((stop - start) / step)})
+		}
+	}
+	for _, candle := range candles {
+		if candle.Timestamp < cursor {
+			continue
+		}
+		if candle.Timestamp >= end {
+			break
+		}
+		add(cursor, candle.Timestamp)
+		cursor = candle.Timestamp + step
+	}
+	add(cursor, end)
+	return gaps
+}
+
+// CandleGaps returns the gaps in a market's stored candles at duration between from and to
+func (h *HistoryStore) CandleGaps(market string, duration time.Duration, from, to time.Time) ([]CandleGap, error) {
+	candles, err := h.Candles(market, duration, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	return findCandleGaps(candles, duration, from, to), nil
+}
+
+// FirstDay returns the earliest UTC day a market has stored history for at any level, or
+// "" when it has none
+func (h *HistoryStore) firstDay(market string) (string, error) {
+	first, err := h.Days(market)
+	if err != nil {
+		return "", err
+	}
+	for _, resolution := range CandleResolutions {
+		days, err := h.candleDays(market, resolution.Name)
+		if err != nil {
+			return "", err
+		}
+		first = append(first, days...)
+	}
+	if len(first) == 0 {
+		return "", nil
+	}
+	sort.Strings(first)
+	return first[0], nil
+}
+
+// StoreBackfill adds candles fetched from upstream to a market's stored 1m candles,
+// keeping any already stored for the same minute. It returns how many were added.
+func (h *HistoryStore) storeBackfill(market string, candles []Candle) (int, error) {
+	byDay := make(map[string][]Candle)
+	for _, candle := range candles {
+		day := time.UnixMilli(candle.Timestamp).UTC().Format(HistoryDayLayout)
+		byDay[day] = append(byDay[day], candle)
+	}
+	resolution := CandleResolutions[0].Name
+	added := 0
+	for day, fetched := range byDay {
+		stored, err := h.readCandleDay(market, resolution, day)
+		if err != nil {
+			return added, err
+		}
+		present := make(map[int64]bool, len(stored))
+		for _, candle := range stored {
+			present[candle.Timestamp] = true
+		}
+		merged := stored
+		for _, candle := range fetched {
+			if !present[candle.Timestamp] {
+				merged = append(merged, candle)
+				present[candle.Timestamp] = true
+				added++
+			}
+		}
+		if len(merged) == len(stored) {
+			continue
+		}
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp < merged[j].Timestamp })
+		if err := h.writeCandleDay(market, resolution, day, merged); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// BackfillHistory fills the gaps of the last HistoryBackfillWindow hours in every market's
+// persisted 1m candles from the upstream candles API. Gaps before a market's history
+// began are left alone, and each gap is only tried once, so markets that did not trade
+// do not refetch the same empty minutes every run. Backfilled candles have 0 points. It
+// runs after compaction, which also rewrites candle files.
+func (c *CryptoTracker) backfillHistory(ctx context.Context) {
+	window := config.Current().HistoryBackfillWindow
+	if c.history.dir == "" || window <= 0 {
+		return
+	}
+	now := time.Now()
+	since := now.Add(-time.Duration(window) * time.Hour)
+	until := now.Add(-backfillSettle)
+	minute := CandleResolutions[0].duration
+
+	markets := make([]string, 0, len(c.MarketView().Markets))
+	for market := range c.MarketView().Markets {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	filled := 0
+	for _, market := range markets {
+		if ctx.Err() != nil {
+			return
+		}
+		first, err := c.history.firstDay(market)
+		if err != nil || first == "" {
+			continue
+		}
+		from := since
+		if startedAt, _ := time.Parse(HistoryDayLayout, first); startedAt.After(since) {
+			// History began inside the window; the start of its first day is no gap
+			candles, err := c.history.Candles(market, minute, startedAt, until, 0)
+			if err != nil || len(candles) == 0 {
+				continue
+			}
+			from = time.UnixMilli(candles[0].Timestamp)
+		}
+		c.history.mutex.Lock()
+		if tried := c.history.backfilled[market]; tried > from.UnixMilli() {
+			from = time.UnixMilli(tried)
+		}
+		c.history.mutex.Unlock()
+
+		gaps, err := c.history.CandleGaps(market, minute, from, until)
+		if err != nil {
+			logging.Error("Error reading history:", err)
+			continue
+		}
+		if len(gaps) == 0 {
+			continue
+		}
+		added, err := c.backfillGaps(ctx, market, gaps)
+		if err != nil {
+			logging.Warn("Error backfilling", market, "history:", err)
+			continue
+		}
+		filled += added
+		c.history.mutex.Lock()
+		c.history.backfilled[market] = gaps[len(gaps)-1].To
+		c.history.mutex.Unlock()
+	}
+	if filled > 0 {
+		logging.Info("Backfilled", filled, "minutes of history from upstream")
+	}
+}
+
+// BackfillGaps fetches the 1m candles spanning gaps from upstream and stores those that fall
+// inside them
+func (c *CryptoTracker) backfillGaps(ctx context.Context, market string, gaps []CandleGap) (int, error) {
+	from, to := time.UnixMilli(gaps[0].From), time.UnixMilli(gaps[len(gaps)-1].To-1)
+	fetched, failure := c.MarketCandles(ctx, market, CandleResolutions[0].duration, from, to, 0)
+	if failure != nil {
+		return 0, failure
+	}
+	candles := []Candle{}
+	for _, candle := range fetched {
+		inGap := false
+		for _, gap := range gaps {
+			inGap = inGap || candle.Timestamp >= gap.From && candle.Timestamp < gap.To
+		}
+		if inGap {
+			candles = append(candles, Candle{
+				Market: market, Timestamp: candle.Timestamp,
+				Open: candle.Open, High: candle.High, Low: candle.Low, Close: candle.Close,
+			})
+		}
+	}
+	return c.history.storeBackfill(market, candles)
+}
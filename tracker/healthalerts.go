@@ -0,0 +1,96 @@
+package tracker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// Health alert conditions
+const (
+	// healthConsecutiveFailures holds once a dataset's last Threshold refreshes have all failed
+	healthConsecutiveFailures = "consecutive_failures"
+	// healthDataAge holds once a dataset was last refreshed more than Threshold seconds ago
+	healthDataAge = "data_age"
+)
+
+var (
+	healthConditions = []string{healthConsecutiveFailures, healthDataAge}
+	// healthDatasets are the refreshes whose failures and age are tracked
+	healthDatasets = []string{"ticker", "markets"}
+)
+
+// HealthMonitor raises alerts through the notifier when a HealthAlerts rule starts holding
+// and again when it clears, so an outage is reported once rather than every cycle
+type HealthMonitor struct {
+	tracker  *CryptoTracker
+	notifier *AlertNotifier
+	// firing holds the rules currently holding, by key
+	firing map[string]bool
+	mutex  sync.Mutex
+}
+
+func NewHealthMonitor(tracker *CryptoTracker, notifier *AlertNotifier) *HealthMonitor {
+	return &HealthMonitor{tracker: tracker, notifier: notifier, firing: make(map[string]bool)}
+}
+
+// DatasetUpdated returns when a dataset last refreshed successfully
+func (c *CryptoTracker) datasetUpdated(dataset string) time.Time {
+	if dataset == "ticker" {
+		return c.TickersUpdated()
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.marketsUpdated
+}
+
+// Evaluate checks every HealthAlerts rule after a refresh cycle
+func (m *HealthMonitor) Evaluate() {
+	rules := config.Current().HealthAlerts
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	configured := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		key := rule.Key()
+		configured[key] = true
+
+		var holds bool
+		var value float64
+		var message string
+		switch rule.Condition {
+		case healthConsecutiveFailures:
+			value = float64(m.tracker.stats.consecutiveFailures(rule.Dataset))
+			holds = value >= rule.Threshold
+			message = fmt.Sprintf("%g consecutive %s refreshes failed", value, rule.Dataset)
+		case healthDataAge:
+			updated := m.tracker.datasetUpdated(rule.Dataset)
+			// Data that has never loaded is as old as the tracker
+			if updated.IsZero() {
+				updated = m.tracker.stats.StartedAt
+			}
+			value = time.Since(updated).Round(time.Second).Seconds()
+			holds = value > rule.Threshold
+			message = fmt.Sprintf("%s data is %gs old (threshold %gs)", rule.Dataset, value, rule.Threshold)
+		}
+
+		if holds == m.firing[key] {
+			continue
+		}
+		m.firing[key] = holds
+		alert := Alert{Type: "health_" + rule.Condition, Symbol: rule.Dataset, Message: message, Value: value}
+		if !holds {
+			alert.Type += "_resolved"
+			alert.Message = fmt.Sprintf("%s %s recovered", rule.Dataset, rule.Condition)
+		}
+		m.notifier.notify(alert)
+	}
+	// Forget rules removed by a config change so re-adding one reports it afresh
+	for key := range m.firing {
+		if !configured[key] {
+			delete(m.firing, key)
+		}
+	}
+}
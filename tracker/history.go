@@ -0,0 +1,273 @@
+package tracker
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	// maxHistoryPoints bounds the in-memory history per market, an hour at the default refresh interval
+	maxHistoryPoints = 720
+	HistoryDayLayout = "2006-01-02"
+)
+
+// HistoryPoint is one recorded ticker of a market
+type HistoryPoint struct {
+	Market    string  `json:"market"`
+	Timestamp int64   `json:"timestamp"`
+	LastPrice float64 `json:"last_price"`
+	Bid       float64 `json:"bid"`
+	Ask       float64 `json:"ask"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Volume    float64 `json:"volume"`
+}
+
+// HistoryStore keeps the tickers seen on every refresh. Recent points stay in memory; with a
+// directory set, every point is also appended to one JSON lines file per market and day so
+// history survives restarts.
+type HistoryStore struct {
+	dir      string
+	points   map[string][]HistoryPoint
+	upstream map[string]int64
+	// backfilled is, per market, the end of the latest gap backfill has tried to fill
+	backfilled map[string]int64
+	mutex      sync.Mutex
+}
+
+func NewHistoryStore(dir string) *HistoryStore {
+	return &HistoryStore{
+		dir:        dir,
+		points:     make(map[string][]HistoryPoint),
+		upstream:   make(map[string]int64),
+		backfilled: make(map[string]int64),
+	}
+}
+
+// Persistent reports whether history is written to disk, and so outlives the process
+func (h *HistoryStore) Persistent() bool {
+	return h.dir != ""
+}
+
+// HistoryDir returns where persisted history lives under the storage directory
+func HistoryDir() string {
+	dir := config.Current().StorageDir
+	if dir == "" {
+		dir = config.DefaultStorageDir
+	}
+	return filepath.Join(dir, "history")
+}
+
+// HistoryPointOf converts a ticker observed at a time into a history point
+func historyPointOf(ticker exchange.TickerDetails, at time.Time) HistoryPoint {
+	number := func(value string) float64 {
+		parsed, _ := strconv.ParseFloat(value, 64)
+		return parsed
+	}
+	bid, _ := exchange.ParseRawPrice(ticker.Bid)
+	ask, _ := exchange.ParseRawPrice(ticker.Ask)
+	return HistoryPoint{
+		Market:    ticker.Market,
+		Timestamp: at.UnixNano() / int64(time.Millisecond),
+		LastPrice: number(ticker.LastPrice),
+		Bid:       bid,
+		Ask:       ask,
+		High:      number(ticker.High),
+		Low:       number(ticker.Low),
+		Volume:    number(ticker.Volume),
+	}
+}
+
+// Record adds the tickers of one refresh, skipping markets whose upstream timestamp has not
+// moved since their last recorded point
+func (h *HistoryStore) record(tickers []exchange.TickerDetails, at time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, ticker := range tickers {
+		if previous, seen := h.upstream[ticker.Market]; seen && ticker.Timestamp != 0 && previous == ticker.Timestamp {
+			continue
+		}
+		h.upstream[ticker.Market] = ticker.Timestamp
+
+		point := historyPointOf(ticker, at)
+		points := h.points[ticker.Market]
+		if len(points) >= maxHistoryPoints {
+			points = points[len(points)-maxHistoryPoints+1:]
+		}
+		h.points[ticker.Market] = append(points, point)
+		if h.dir != "" {
+			if err := h.appendFile(point, at); err != nil {
+				logging.Error("Error persisting history:", err)
+			}
+		}
+	}
+}
+
+// MarketDir returns the directory of a market's history files, refusing names that would
+// escape the history directory
+func (h *HistoryStore) marketDir(market string) (string, error) {
+	if market == "" || market == "." || market == ".." || strings.ContainsAny(market, `/\`) {
+		return "", errors.New("invalid market name " + strconv.Quote(market))
+	}
+	return filepath.Join(h.dir, market), nil
+}
+
+func (h *HistoryStore) appendFile(point HistoryPoint, at time.Time) error {
+	dir, err := h.marketDir(point.Market)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, at.UTC().Format(HistoryDayLayout)+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Query returns a market's points between from and to, oldest first; zero times leave that
+// end open. A positive limit keeps only the most recent points. Persisted history is read
+// from disk so it covers more than the in-memory window.
+func (h *HistoryStore) Query(market string, from, to time.Time, limit int) ([]HistoryPoint, error) {
+	inRange := func(point HistoryPoint) bool {
+		at := time.Unix(0, point.Timestamp*int64(time.Millisecond))
+		return (from.IsZero() || !at.Before(from)) && (to.IsZero() || !at.After(to))
+	}
+
+	points := []HistoryPoint{}
+	if h.dir == "" {
+		h.mutex.Lock()
+		for _, point := range h.points[market] {
+			if inRange(point) {
+				points = append(points, point)
+			}
+		}
+		h.mutex.Unlock()
+	} else {
+		days, err := h.Days(market)
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range days {
+			// A day file only holds points from that UTC day
+			start, _ := time.Parse(HistoryDayLayout, day)
+			if (!to.IsZero() && start.After(to)) || (!from.IsZero() && start.Add(24*time.Hour).Before(from)) {
+				continue
+			}
+			dayPoints, err := h.ReadDay(market, day)
+			if err != nil {
+				return nil, err
+			}
+			for _, point := range dayPoints {
+				if inRange(point) {
+					points = append(points, point)
+				}
+			}
+		}
+	}
+	if limit > 0 && len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points, nil
+}
+
+// Days lists the UTC days a market has persisted history for, oldest first
+func (h *HistoryStore) Days(market string) ([]string, error) {
+	dir, err := h.marketDir(market)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	days := []string{}
+	for _, entry := range entries {
+		day := strings.TrimSuffix(entry.Name(), ".jsonl")
+		if _, err := time.Parse(HistoryDayLayout, day); err == nil && !entry.IsDir() {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// ReadDay reads the points a market recorded on one UTC day, as written
+func (h *HistoryStore) ReadDay(market, day string) ([]HistoryPoint, error) {
+	dir, err := h.marketDir(market)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(dir, day+".jsonl"))
+	// Compaction may remove a day between listing and reading it
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	points := []HistoryPoint{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var point HistoryPoint
+		// A crash mid-write can leave a partial last line; skip it rather than fail the day
+		if err := json.Unmarshal(scanner.Bytes(), &point); err == nil {
+			points = append(points, point)
+		}
+	}
+	return points, scanner.Err()
+}
+
+// Markets lists the markets that have any history
+func (h *HistoryStore) Markets() ([]string, error) {
+	markets := []string{}
+	if h.dir == "" {
+		h.mutex.Lock()
+		for market := range h.points {
+			markets = append(markets, market)
+		}
+		h.mutex.Unlock()
+	} else {
+		entries, err := ioutil.ReadDir(h.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				markets = append(markets, entry.Name())
+			}
+		}
+	}
+	sort.Strings(markets)
+	return markets, nil
+}
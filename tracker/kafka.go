@@ -0,0 +1,472 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	kafkaClientID = "cryptotracker"
+	kafkaTimeout  = 10 * time.Second
+	// kafkaRetryBackoff is how long events are dropped after a failure instead of each one
+	// waiting on the unreachable broker
+	kafkaRetryBackoff   = 5 * time.Second
+	kafkaProduceKey     = 0
+	kafkaMetadataKey    = 3
+	kafkaProduceVersion = 3 // the first version with record batches, accepted by Kafka 0.11 through 4.x
+	kafkaMetadataVer    = 4
+)
+
+var kafkaCastagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// KafkaProducer publishes events to Kafka, one topic per event type, keyed by symbol. It
+// speaks the Kafka protocol directly: it looks up partition leaders with Metadata
+// requests and sends each event as a one-record batch to the leader of the partition
+// its key hashes to, using the same murmur2 hash as the Java client so consumers see the
+// same partitioning as from any other producer.
+type KafkaProducer struct {
+	brokers []string
+	topics  map[string]string
+	acks    int16
+	// leaders maps each topic to the broker leading each of its partitions
+	leaders     map[string][]int32
+	addresses   map[int32]string
+	conns       map[int32]*kafkaConn
+	correlation int32
+	retryAt     time.Time
+	mutex       sync.Mutex
+}
+
+func newKafkaProducer(cfg config.Config) *KafkaProducer {
+	topics := map[string]string{
+		eventTicker:    cfg.KafkaTickerTopic,
+		eventOrderBook: cfg.KafkaOrderBookTopic,
+		eventAlert:     cfg.KafkaAlertTopic,
+	}
+	return &KafkaProducer{
+		brokers:   cfg.KafkaBrokers,
+		topics:    topics,
+		acks:      int16(cfg.KafkaAcks),
+		leaders:   make(map[string][]int32),
+		addresses: make(map[int32]string),
+		conns:     make(map[int32]*kafkaConn),
+	}
+}
+
+// Publish sends an event to its type's topic; event types without a topic are skipped
+func (p *KafkaProducer) Publish(event MarketEvent) error {
+	topic := p.topics[event.Type]
+	if topic == "" {
+		return nil
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if time.Now().Before(p.retryAt) {
+		return nil
+	}
+	leaders, err := p.partitionLeaders(topic)
+	if err != nil {
+		p.retryAt = time.Now().Add(kafkaRetryBackoff)
+		return fmt.Errorf("kafka topic %s: %v", topic, err)
+	}
+	partition := int32(kafkaMurmur2([]byte(event.Symbol))&0x7fffffff) % int32(len(leaders))
+	leader := leaders[partition]
+	conn, err := p.connection(leader)
+	if err == nil {
+		err = p.produce(conn, topic, partition, []byte(event.Symbol), value)
+	}
+	if err != nil {
+		// Leadership may have moved; look it up again on the next publish
+		p.dropConnection(leader)
+		delete(p.leaders, topic)
+		p.retryAt = time.Now().Add(kafkaRetryBackoff)
+		return fmt.Errorf("kafka topic %s partition %d: %v", topic, partition, err)
+	}
+	return nil
+}
+
+// Close closes every broker connection
+func (p *KafkaProducer) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for id := range p.conns {
+		p.dropConnection(id)
+	}
+	return nil
+}
+
+// PartitionLeaders returns the leader of every partition of topic, asking a bootstrap broker
+// when it is not known yet
+func (p *KafkaProducer) partitionLeaders(topic string) ([]int32, error) {
+	if leaders, exists := p.leaders[topic]; exists {
+		return leaders, nil
+	}
+	var lastErr error
+	for _, broker := range p.brokers {
+		conn, err := dialKafka(broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		leaders, err := p.fetchMetadata(conn, topic)
+		conn.close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		p.leaders[topic] = leaders
+		return leaders, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no brokers configured")
+	}
+	return nil, lastErr
+}
+
+func (p *KafkaProducer) fetchMetadata(conn *kafkaConn, topic string) ([]int32, error) {
+	var body kafkaEncoder
+	body.int32(1)
+	body.string(topic)
+	body.int8(1) // allow auto topic creation
+	response, err := conn.roundTrip(kafkaMetadataKey, kafkaMetadataVer, p.nextCorrelation(), body.Bytes(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	d := kafkaDecoder{data: response}
+	d.int32() // throttle time
+	addresses := make(map[int32]string)
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		addresses[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster id
+	d.int32()  // controller id
+	var leaders []int32
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // internal
+		count := d.int32()
+		if count < 0 || int(count) > len(d.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		partitions := make([]int32, count)
+		for range partitions {
+			d.int16() // partition error code
+			index := d.int32()
+			leader := d.int32()
+			d.int32Array() // replicas
+			d.int32Array() // in-sync replicas
+			if index >= 0 && int(index) < len(partitions) {
+				partitions[index] = leader
+			}
+		}
+		if name != topic {
+			continue
+		}
+		if code != 0 {
+			return nil, fmt.Errorf("metadata error code %d for topic %s", code, topic)
+		}
+		leaders = partitions
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(leaders) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	for _, leader := range leaders {
+		if leader < 0 {
+			return nil, fmt.Errorf("topic %s has a partition without a leader", topic)
+		}
+	}
+	for id, address := range addresses {
+		p.addresses[id] = address
+	}
+	return leaders, nil
+}
+
+// Connection returns the open connection to a broker, dialling it if needed
+func (p *KafkaProducer) connection(id int32) (*kafkaConn, error) {
+	if conn, exists := p.conns[id]; exists {
+		return conn, nil
+	}
+	address, exists := p.addresses[id]
+	if !exists {
+		return nil, fmt.Errorf("unknown broker %d", id)
+	}
+	conn, err := dialKafka(address)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[id] = conn
+	return conn, nil
+}
+
+func (p *KafkaProducer) dropConnection(id int32) {
+	if conn, exists := p.conns[id]; exists {
+		conn.close()
+		delete(p.conns, id)
+	}
+}
+
+func (p *KafkaProducer) nextCorrelation() int32 {
+	p.correlation++
+	return p.correlation
+}
+
+func (p *KafkaProducer) produce(conn *kafkaConn, topic string, partition int32, key, value []byte) error {
+	batch := kafkaRecordBatch(key, value, time.Now())
+
+	var body kafkaEncoder
+	body.int16(-1) // no transactional id
+	body.int16(p.acks)
+	body.int32(int32(kafkaTimeout / time.Millisecond))
+	body.int32(1)
+	body.string(topic)
+	body.int32(1)
+	body.int32(partition)
+	body.int32(int32(len(batch)))
+	body.Write(batch)
+
+	// With acks=0 the broker sends no response at all
+	response, err := conn.roundTrip(kafkaProduceKey, kafkaProduceVersion, p.nextCorrelation(), body.Bytes(), p.acks != 0)
+	if err != nil || p.acks == 0 {
+		return err
+	}
+	d := kafkaDecoder{data: response}
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		d.string()
+		for j := d.int32(); j > 0 && d.err == nil; j-- {
+			d.int32() // partition
+			if code := d.int16(); code != 0 && d.err == nil {
+				return fmt.Errorf("produce error code %d", code)
+			}
+			d.int64() // base offset
+			d.int64() // log append time
+		}
+	}
+	return d.err
+}
+
+// KafkaRecordBatch encodes a single record as a v2 record batch
+func kafkaRecordBatch(key, value []byte, at time.Time) []byte {
+	timestamp := at.UnixNano() / int64(time.Millisecond)
+
+	var record kafkaEncoder
+	record.int8(0)   // attributes
+	record.varint(0) // timestamp delta
+	record.varint(0) // offset delta
+	record.varint(int64(len(key)))
+	record.Write(key)
+	record.varint(int64(len(value)))
+	record.Write(value)
+	record.varint(0) // headers
+
+	// Everything after the CRC field, which the CRC covers
+	var tail kafkaEncoder
+	tail.int16(0) // attributes: no compression, create time
+	tail.int32(0) // last offset delta
+	tail.int64(timestamp)
+	tail.int64(timestamp)
+	tail.int64(-1) // producer id
+	tail.int16(-1) // producer epoch
+	tail.int32(-1) // base sequence
+	tail.int32(1)  // record count
+	tail.varint(int64(record.Len()))
+	tail.Write(record.Bytes())
+
+	var batch kafkaEncoder
+	batch.int64(0)                             // base offset
+	batch.int32(int32(4 + 1 + 4 + tail.Len())) // batch length: leader epoch, magic, crc and tail
+	batch.int32(-1)                            // partition leader epoch
+	batch.int8(2)                              // magic
+	batch.int32(int32(crc32.Checksum(tail.Bytes(), kafkaCastagnoli)))
+	batch.Write(tail.Bytes())
+	return batch.Bytes()
+}
+
+// KafkaMurmur2 is the murmur2 hash the Java client's default partitioner applies to keys
+func kafkaMurmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	length := len(data)
+	h := uint32(seed) ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// kafkaConn is a connection to one broker carrying one request at a time
+type kafkaConn struct {
+	conn net.Conn
+}
+
+func dialKafka(address string) (*kafkaConn, error) {
+	conn, err := net.DialTimeout("tcp", address, kafkaTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaConn{conn: conn}, nil
+}
+
+func (c *kafkaConn) close() {
+	c.conn.Close()
+}
+
+// RoundTrip sends a request with a v1 header and, if expectResponse, returns the response
+// body after its correlation id
+func (c *kafkaConn) roundTrip(apiKey, version int16, correlation int32, body []byte, expectResponse bool) ([]byte, error) {
+	var request kafkaEncoder
+	request.int16(apiKey)
+	request.int16(version)
+	request.int32(correlation)
+	request.string(kafkaClientID)
+	request.Write(body)
+
+	var frame kafkaEncoder
+	frame.int32(int32(request.Len()))
+	frame.Write(request.Bytes())
+
+	c.conn.SetDeadline(time.Now().Add(kafkaTimeout))
+	if _, err := c.conn.Write(frame.Bytes()); err != nil {
+		return nil, err
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+	var size int32
+	if err := binary.Read(c.conn, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 4 {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	response := make([]byte, size)
+	if _, err := io.ReadFull(c.conn, response); err != nil {
+		return nil, err
+	}
+	if got := int32(binary.BigEndian.Uint32(response)); got != correlation {
+		return nil, fmt.Errorf("response correlation id %d, expected %d", got, correlation)
+	}
+	return response[4:], nil
+}
+
+// kafkaEncoder writes big-endian Kafka protocol primitives
+type kafkaEncoder struct {
+	bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8) { e.WriteByte(byte(v)) }
+
+func (e *kafkaEncoder) int16(v int16) { binary.Write(e, binary.BigEndian, v) }
+
+func (e *kafkaEncoder) int32(v int32) { binary.Write(e, binary.BigEndian, v) }
+
+func (e *kafkaEncoder) int64(v int64) { binary.Write(e, binary.BigEndian, v) }
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.WriteString(v)
+}
+
+// Varint writes a zigzag varint as used inside record batches
+func (e *kafkaEncoder) varint(v int64) {
+	e.Write(binary.AppendVarint(nil, v))
+}
+
+// kafkaDecoder reads big-endian Kafka protocol primitives, remembering the first error
+type kafkaDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || len(d.data) < n {
+		d.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	taken := d.data[:n]
+	d.data = d.data[n:]
+	return taken
+}
+
+func (d *kafkaDecoder) int8() int8 { return int8(d.take(1)[0]) }
+
+func (d *kafkaDecoder) int16() int16 { return int16(binary.BigEndian.Uint16(d.take(2))) }
+
+func (d *kafkaDecoder) int32() int32 { return int32(binary.BigEndian.Uint32(d.take(4))) }
+
+func (d *kafkaDecoder) int64() int64 { return int64(binary.BigEndian.Uint64(d.take(8))) }
+
+// String reads a string, returning "" for a null one
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 || d.err != nil {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *kafkaDecoder) int32Array() []int32 {
+	n := d.int32()
+	if n < 0 || d.err != nil {
+		return nil
+	}
+	if int(n) > len(d.data)/4 {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	values := make([]int32, n)
+	for i := range values {
+		values[i] = d.int32()
+	}
+	return values
+}
@@ -0,0 +1,152 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	// maxLendingPoints bounds the rates kept per currency whatever the retention
+	maxLendingPoints = 10000
+	// lendingDaysPerYear annualizes daily rates without compounding
+	lendingDaysPerYear = 365
+)
+
+// LendingRate is what margin lending pays, and margin borrowing costs, in one currency.
+// Upstream quotes daily rates as fractions, so 0.0005 is 0.05% a day.
+type LendingRate struct {
+	Currency   string           `json:"currency"`
+	LendRate   exchange.Decimal `json:"lend_rate"`
+	BorrowRate exchange.Decimal `json:"borrow_rate"`
+}
+
+// LendingRateView is a currency's rates with their simple annual percentage, for comparing
+// yields with other opportunities
+type LendingRateView struct {
+	LendingRate
+	LendAPR   exchange.Decimal `json:"lend_apr"`
+	BorrowAPR exchange.Decimal `json:"borrow_apr"`
+}
+
+// LendingPoint is a currency's rates from when they were first seen
+type LendingPoint struct {
+	Timestamp  int64            `json:"timestamp"`
+	LendRate   exchange.Decimal `json:"lend_rate"`
+	BorrowRate exchange.Decimal `json:"borrow_rate"`
+}
+
+// LendingHistory keeps each currency's lending and borrowing rates for LendingRetention
+// seconds, storing them only when either changes
+type LendingHistory struct {
+	points map[string][]LendingPoint
+	mutex  sync.Mutex
+}
+
+func newLendingHistory() *LendingHistory {
+	return &LendingHistory{points: make(map[string][]LendingPoint)}
+}
+
+// LendingEnabled reports whether lending rates are fetched and served
+func LendingEnabled() bool {
+	return config.Current().LendingRatesURL != ""
+}
+
+// LendingRetention returns how long lending rates are kept
+func lendingRetention() time.Duration {
+	if retention := config.Current().LendingRetention; retention > 0 {
+		return time.Duration(retention) * time.Second
+	}
+	return config.DefaultLendingRetention * time.Second
+}
+
+// Record stores the rates of a lending refresh and forgets those older than the retention
+func (h *LendingHistory) record(rates map[string]LendingRate, now time.Time) {
+	cutoff := now.Add(-lendingRetention()).UnixMilli()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for currency, rate := range rates {
+		points := h.points[currency]
+		if len(points) == 0 || points[len(points)-1].LendRate.Cmp(rate.LendRate) != 0 ||
+			points[len(points)-1].BorrowRate.Cmp(rate.BorrowRate) != 0 {
+			points = append(points, LendingPoint{Timestamp: now.UnixMilli(), LendRate: rate.LendRate, BorrowRate: rate.BorrowRate})
+		}
+		start := 0
+		for start < len(points)-1 && points[start].Timestamp < cutoff {
+			start++
+		}
+		if len(points)-start > maxLendingPoints {
+			start = len(points) - maxLendingPoints
+		}
+		h.points[currency] = points[start:]
+	}
+}
+
+// Query returns a currency's rates between from and to, oldest first; zero bounds are
+// open. A positive limit keeps only the most recent points.
+func (h *LendingHistory) Query(currency string, from, to time.Time, limit int) []LendingPoint {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	points := []LendingPoint{}
+	for _, point := range h.points[currency] {
+		if !from.IsZero() && point.Timestamp < from.UnixMilli() {
+			continue
+		}
+		if !to.IsZero() && point.Timestamp > to.UnixMilli() {
+			continue
+		}
+		points = append(points, point)
+	}
+	if limit > 0 && len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points
+}
+
+// RefreshLendingRates fetches every currency's margin lending and borrowing rates from
+// LendingRatesURL
+func (c *CryptoTracker) refreshLendingRates(ctx context.Context) {
+	if !LendingEnabled() {
+		return
+	}
+	response, err := c.httpClient.PerformRequest(ctx, config.Current().LendingRatesURL)
+	if err != nil {
+		logging.Error("Error fetching lending rates:", err)
+		c.stats.recordRefreshResult("lending_rates", err)
+		return
+	}
+	var entries []LendingRate
+	err = json.Unmarshal([]byte(response), &entries)
+	c.stats.recordRefreshResult("lending_rates", err)
+	if err != nil {
+		logging.Error("Error parsing lending rates:", err)
+		return
+	}
+	rates := make(map[string]LendingRate, len(entries))
+	for _, entry := range entries {
+		entry.Currency = strings.ToUpper(entry.Currency)
+		if entry.Currency != "" {
+			rates[entry.Currency] = entry
+		}
+	}
+
+	now := time.Now()
+	c.lending.record(rates, now)
+	c.mutex.Lock()
+	c.lendingUpdated = now
+	c.mutex.Unlock()
+	c.publish(func(next *MarketView) { next.LendingRates, next.LendingRatesModified = rates, now })
+}
+
+func LendingRateViewOf(rate LendingRate) LendingRateView {
+	annualize := func(daily exchange.Decimal) exchange.Decimal {
+		return daily.Mul(exchange.DecimalFromInt(lendingDaysPerYear * 100)).RoundTo(4)
+	}
+	return LendingRateView{LendingRate: rate, LendAPR: annualize(rate.LendRate), BorrowAPR: annualize(rate.BorrowRate)}
+}
@@ -0,0 +1,116 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	knownMarketsStorageKey = "known_markets"
+	maxNewListings         = 200
+)
+
+// NewListing is a market that appeared in a markets refresh after the first
+type NewListing struct {
+	exchange.MarketDetails
+	DetectedAt int64 `json:"detected_at"`
+}
+
+// ListingDetector compares successive market refreshes to spot newly listed markets. The
+// markets already seen are persisted, so a listing made while the tracker was down is still
+// reported when it comes back up; the very first refresh only seeds them.
+type ListingDetector struct {
+	tracker  *CryptoTracker
+	notifier *AlertNotifier
+	storage  storage.Storage
+	// known maps every market seen to when it was first seen, in milliseconds
+	known    map[string]int64
+	listings []NewListing
+	mutex    sync.Mutex
+}
+
+func NewListingDetector(tracker *CryptoTracker, notifier *AlertNotifier, backend storage.Storage) *ListingDetector {
+	detector := &ListingDetector{
+		tracker:  tracker,
+		notifier: notifier,
+		storage:  backend,
+		known:    make(map[string]int64),
+	}
+	err := backend.Load(knownMarketsStorageKey, &detector.known)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading known markets:", err)
+	}
+	return detector
+}
+
+// Detect records the markets in the latest refresh that have not been seen before and
+// notifies them when ListingAlerts is on
+func (d *ListingDetector) Detect() {
+	c := d.tracker
+	c.mutex.RLock()
+	markets := make([]exchange.MarketDetails, 0, len(c.marketDetails))
+	for _, market := range c.marketDetails {
+		markets = append(markets, market)
+	}
+	c.mutex.RUnlock()
+	sort.Slice(markets, func(i, j int) bool { return markets[i].CoindcxName < markets[j].CoindcxName })
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	seeding := len(d.known) == 0
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	found := []NewListing{}
+	for _, market := range markets {
+		if _, exists := d.known[market.CoindcxName]; exists || market.CoindcxName == "" {
+			continue
+		}
+		d.known[market.CoindcxName] = now
+		if !seeding {
+			found = append(found, NewListing{MarketDetails: market, DetectedAt: now})
+		}
+	}
+	if seeding && len(d.known) > 0 {
+		logging.Info(fmt.Sprintf("Recorded %d known markets for listing detection", len(d.known)))
+	}
+	if len(found) == 0 && !seeding {
+		return
+	}
+	if err := d.storage.Save(knownMarketsStorageKey, d.known); err != nil {
+		logging.Error("Error saving known markets:", err)
+	}
+
+	d.listings = append(d.listings, found...)
+	if len(d.listings) > maxNewListings {
+		d.listings = d.listings[len(d.listings)-maxNewListings:]
+	}
+	notify := config.Current().ListingAlerts
+	for _, listing := range found {
+		logging.Info("New market listed:", listing.CoindcxName)
+		if notify {
+			d.notifier.notify(Alert{
+				Type:    "new_listing",
+				Symbol:  listing.CoindcxName,
+				Message: fmt.Sprintf("%s (%s/%s) is newly listed", listing.CoindcxName, listing.TargetCurrencyShortName, listing.BaseCurrencyShortName),
+			})
+		}
+	}
+}
+
+// Since returns the listings detected at or after since, newest first
+func (d *ListingDetector) Since(since int64) []NewListing {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	listings := []NewListing{}
+	for i := len(d.listings) - 1; i >= 0 && d.listings[i].DetectedAt >= since; i-- {
+		listings = append(listings, d.listings[i])
+	}
+	return listings
+}
@@ -0,0 +1,59 @@
+package tracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+const livePushInterval = 500 * time.Millisecond
+
+// LiveUpdate is the payload pushed to streaming livedata subscribers
+type LiveUpdate struct {
+	Pair      string             `json:"pair"`
+	OrderBook exchange.OrderBook `json:"order_book"`
+	AgeMs     int64              `json:"age_ms"`
+	Freshness
+}
+
+// RefreshSubscribedOrderBooks keeps order books of streamed symbols fresh so handlers never wait on upstream
+func (c *CryptoTracker) refreshSubscribedOrderBooks(ctx context.Context) {
+	if c.orderBooksPushed() {
+		return
+	}
+	c.refreshOrderBooks(ctx, c.subscriptions.subscribed(), orderBookCacheTTL(), orderBookRefreshInterval())
+}
+
+// LiveUpdates polls the cached order book of a market and sends every new version to push
+// until done is closed or push fails
+func (c *CryptoTracker) LiveUpdates(marketName string, done <-chan struct{}, push func(LiveUpdate) error) {
+	c.subscriptions.Subscribe(marketName)
+	defer c.subscriptions.Unsubscribe(marketName)
+
+	ticker := time.NewTicker(livePushInterval)
+	defer ticker.Stop()
+
+	var lastSent time.Time
+	for {
+		orderBook, fetchedAt, exists := c.OrderBookFor(context.Background(), marketName, false)
+		if exists && fetchedAt.After(lastSent) {
+			update := LiveUpdate{
+				Pair:      marketName,
+				OrderBook: orderBook,
+				AgeMs:     time.Since(fetchedAt).Milliseconds(),
+				Freshness: FreshnessOf(fetchedAt),
+			}
+			if err := push(update); err != nil {
+				return
+			}
+			lastSent = fetchedAt
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	marketStatusStorageKey  = "market_statuses"
+	maxMarketStatusChanges  = 500
+	marketStatusAlertPrefix = "market_status"
+)
+
+// MarketStatusChange is one flip of a market's status between two refreshes
+type MarketStatusChange struct {
+	Market    string `json:"market"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	ChangedAt int64  `json:"changed_at"`
+}
+
+// marketStatus is the last status seen for a market and when it last changed
+type marketStatus struct {
+	Status    string `json:"status"`
+	ChangedAt int64  `json:"changed_at,omitempty"`
+}
+
+// MarketStatusTracker keeps the status of every market across refreshes and a log of the
+// changes, so consumers can notice a market being suspended or delisted. Both are
+// persisted so changes made while the tracker was down are still caught.
+type MarketStatusTracker struct {
+	tracker  *CryptoTracker
+	notifier *AlertNotifier
+	storage  storage.Storage
+	state    struct {
+		Statuses map[string]marketStatus `json:"statuses"`
+		Changes  []MarketStatusChange    `json:"changes"`
+	}
+	mutex sync.Mutex
+}
+
+func NewMarketStatusTracker(tracker *CryptoTracker, notifier *AlertNotifier, backend storage.Storage) *MarketStatusTracker {
+	t := &MarketStatusTracker{tracker: tracker, notifier: notifier, storage: backend}
+	err := backend.Load(marketStatusStorageKey, &t.state)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading market statuses:", err)
+	}
+	if t.state.Statuses == nil {
+		t.state.Statuses = make(map[string]marketStatus)
+	}
+	if t.state.Changes == nil {
+		t.state.Changes = []MarketStatusChange{}
+	}
+	return t
+}
+
+// Detect compares the statuses in the latest markets refresh with the previous ones,
+// logging and, when MarketStatusAlerts is on, notifying every change
+func (t *MarketStatusTracker) Detect() {
+	c := t.tracker
+	c.mutex.RLock()
+	statuses := make(map[string]string, len(c.marketDetails))
+	for name, market := range c.marketDetails {
+		statuses[name] = market.Status
+	}
+	c.mutex.RUnlock()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	changes := []MarketStatusChange{}
+	seeded := false
+	for name, status := range statuses {
+		previous, exists := t.state.Statuses[name]
+		if !exists {
+			t.state.Statuses[name] = marketStatus{Status: status}
+			seeded = true
+			continue
+		}
+		if previous.Status == status {
+			continue
+		}
+		t.state.Statuses[name] = marketStatus{Status: status, ChangedAt: now}
+		changes = append(changes, MarketStatusChange{Market: name, From: previous.Status, To: status, ChangedAt: now})
+	}
+	if len(changes) == 0 && !seeded {
+		return
+	}
+
+	t.state.Changes = append(t.state.Changes, changes...)
+	if len(t.state.Changes) > maxMarketStatusChanges {
+		t.state.Changes = t.state.Changes[len(t.state.Changes)-maxMarketStatusChanges:]
+	}
+	if err := t.storage.Save(marketStatusStorageKey, t.state); err != nil {
+		logging.Error("Error saving market statuses:", err)
+	}
+	notify := config.Current().MarketStatusAlerts
+	for _, change := range changes {
+		message := fmt.Sprintf("status changed from %q to %q", change.From, change.To)
+		logging.Warn("Market", change.Market, message)
+		if notify {
+			t.notifier.notify(Alert{
+				Type:    marketStatusAlertPrefix + "_" + change.To,
+				Symbol:  change.Market,
+				Message: message,
+			})
+		}
+	}
+}
+
+// ChangedTimes returns when each market whose status has changed last changed, in milliseconds
+func (t *MarketStatusTracker) ChangedTimes() map[string]int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	times := make(map[string]int64)
+	for name, status := range t.state.Statuses {
+		if status.ChangedAt != 0 {
+			times[name] = status.ChangedAt
+		}
+	}
+	return times
+}
+
+// Changes returns the logged status changes, newest first, optionally only one market's
+// and only those at or after since milliseconds
+func (t *MarketStatusTracker) Changes(market string, since int64) []MarketStatusChange {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	changes := []MarketStatusChange{}
+	for i := len(t.state.Changes) - 1; i >= 0 && t.state.Changes[i].ChangedAt >= since; i-- {
+		if market == "" || t.state.Changes[i].Market == market {
+			changes = append(changes, t.state.Changes[i])
+		}
+	}
+	return changes
+}
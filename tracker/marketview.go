@@ -0,0 +1,191 @@
+package tracker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// MarketView is an immutable copy of what the hot read paths serve: every market's ticker
+// and when it was refreshed, the market list and the FX rates, with when each dataset last
+// changed. Writers publish a new view
+// instead of changing the current one, so handlers load it without any lock and everything
+// they read from one view was current at the same moment.
+type MarketView struct {
+	Tickers map[string]exchange.TickerDetails
+	Times   map[string]time.Time
+	Markets map[string]exchange.MarketDetails
+	Pairs   map[string]string
+	fxRates map[string]float64
+
+	// TickersModified, MarketsModified and FXModified are when each dataset was last
+	// published, served as Last-Modified
+	TickersModified time.Time
+	MarketsModified time.Time
+	FXModified      time.Time
+	// Generation is the tracker generation the view was published at, so two views with
+	// the same generation hold the same data
+	Generation uint64
+
+	// FuturesInstruments maps each active futures contract to its margin currency
+	FuturesInstruments         map[string]string
+	FuturesPrices              map[string]FuturesPrice
+	FuturesInstrumentsModified time.Time
+	FuturesPricesModified      time.Time
+
+	// LendingRates holds each currency's margin lending and borrowing rates
+	LendingRates         map[string]LendingRate
+	LendingRatesModified time.Time
+}
+
+func newMarketView() *MarketView {
+	return &MarketView{
+		Tickers: make(map[string]exchange.TickerDetails),
+		Times:   make(map[string]time.Time),
+		Markets: make(map[string]exchange.MarketDetails),
+		Pairs:   make(map[string]string),
+		fxRates: make(map[string]float64),
+
+		FuturesInstruments: make(map[string]string),
+		FuturesPrices:      make(map[string]FuturesPrice),
+		LendingRates:       make(map[string]LendingRate),
+	}
+}
+
+// MarketView returns the current view; it must not be modified
+func (c *CryptoTracker) MarketView() *MarketView {
+	return c.view.Load()
+}
+
+// Publish replaces the current view with a copy that apply has changed. The copy shares the
+// current view's maps, so apply must replace rather than modify those it changes. Writers
+// are serialized, so apply sees every earlier update, and the view is swapped under the
+// tracker lock so that a reader holding the lock sees the view matching generation.
+func (c *CryptoTracker) publish(apply func(next *MarketView)) {
+	c.viewWrites.Lock()
+	defer c.viewWrites.Unlock()
+	next := *c.view.Load()
+	apply(&next)
+
+	c.mutex.Lock()
+	c.generation++
+	next.Generation = c.generation
+	c.view.Store(&next)
+	c.mutex.Unlock()
+}
+
+// tickerPublishInterval is how long streamed ticker updates are collected before they are
+// published together, so a busy feed replaces the view a few times a second rather than on
+// every frame
+const tickerPublishInterval = 250 * time.Millisecond
+
+// tickerBatch holds ticker updates waiting to be published together
+type tickerBatch struct {
+	// view is the latest published view, which the batch's updates apply to
+	view    *MarketView
+	tickers map[string]exchange.TickerDetails
+	times   map[string]time.Time
+}
+
+// Ticker returns a market's latest ticker, pending in the batch or published
+func (b *tickerBatch) ticker(market string) (exchange.TickerDetails, bool) {
+	if ticker, exists := b.tickers[market]; exists {
+		return ticker, true
+	}
+	ticker, exists := b.view.Tickers[market]
+	return ticker, exists
+}
+
+// Set updates a market's ticker as of at
+func (b *tickerBatch) set(market string, ticker exchange.TickerDetails, at time.Time) {
+	b.tickers[market] = ticker
+	b.times[market] = at
+}
+
+// UpdateTickers lets apply update tickers in the pending batch, which is published within
+// tickerPublishInterval along with every other update made meanwhile
+func (c *CryptoTracker) updateTickers(apply func(batch *tickerBatch)) {
+	c.pendingWrites.Lock()
+	defer c.pendingWrites.Unlock()
+	if c.pendingTickers == nil {
+		c.pendingTickers = &tickerBatch{tickers: make(map[string]exchange.TickerDetails), times: make(map[string]time.Time)}
+		time.AfterFunc(tickerPublishInterval, c.publishTickers)
+	}
+	c.pendingTickers.view = c.MarketView()
+	apply(c.pendingTickers)
+}
+
+// PublishTickers publishes the pending batch as one view with a single copy of the ticker
+// maps. An update older than the published ticker, such as one queued before a complete
+// refresh, is dropped.
+func (c *CryptoTracker) publishTickers() {
+	// Holding the batch until it is published keeps later updates reading its tickers
+	c.pendingWrites.Lock()
+	defer c.pendingWrites.Unlock()
+	batch := c.pendingTickers
+	c.pendingTickers = nil
+	if batch == nil || len(batch.tickers) == 0 {
+		return
+	}
+	c.publish(func(next *MarketView) {
+		tickers := make(map[string]exchange.TickerDetails, len(next.Tickers))
+		times := make(map[string]time.Time, len(next.Times))
+		for market, ticker := range next.Tickers {
+			tickers[market] = ticker
+		}
+		for market, updated := range next.Times {
+			times[market] = updated
+		}
+		for market, ticker := range batch.tickers {
+			if at := batch.times[market]; !at.Before(times[market]) {
+				tickers[market], times[market] = ticker, at
+			}
+		}
+		next.Tickers, next.Times = tickers, times
+		next.TickersModified = time.Now()
+	})
+}
+
+// ReplaceTickers publishes a complete refresh as the new ticker map, so markets upstream
+// stopped listing drop out. Changed tickers are passed to changed before publication.
+func (c *CryptoTracker) replaceTickers(refreshed []exchange.TickerDetails, now time.Time, changed func(previous, ticker exchange.TickerDetails)) {
+	c.publish(func(next *MarketView) {
+		tickers := make(map[string]exchange.TickerDetails, len(refreshed))
+		times := make(map[string]time.Time, len(refreshed))
+		for _, ticker := range refreshed {
+			changed(next.Tickers[ticker.Market], ticker)
+			tickers[ticker.Market] = ticker
+			times[ticker.Market] = now
+		}
+		next.Tickers, next.Times = tickers, times
+		next.TickersModified = now
+	})
+}
+
+// StatusMatches reports whether a market has the status a request filters on, if any
+func (v *MarketView) StatusMatches(name, status string) bool {
+	if status == "" {
+		return true
+	}
+	market, exists := v.Markets[name]
+	return exists && strings.EqualFold(market.Status, status)
+}
+
+// IsINRMarket reports whether a market is priced in INR
+func (v *MarketView) IsINRMarket(marketName string) bool {
+	if market, exists := v.Markets[marketName]; exists {
+		return market.BaseCurrencyShortName == "INR"
+	}
+	return strings.HasSuffix(marketName, "INR")
+}
+
+// FiatRate returns how many units of fiat one INR buys
+func (v *MarketView) FiatRate(fiat string) (float64, bool) {
+	fiat = strings.ToUpper(fiat)
+	if fiat == "INR" {
+		return 1, true
+	}
+	rate, exists := v.fxRates[fiat]
+	return rate, exists && rate > 0
+}
@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	metadataPages = 4
+)
+
+// MarketWithMetadata joins exchange market details with coin metadata
+type MarketWithMetadata struct {
+	exchange.MarketDetails
+	// StatusChangedAt is when the market's status last changed, if it has been seen to
+	StatusChangedAt int64                  `json:"status_changed_at,omitempty"`
+	Metadata        *exchange.CoinMetadata `json:"metadata,omitempty"`
+}
+
+// RefreshCoinMetadata fetches coin metadata from CoinGecko, keyed by upper-case symbol
+func (c *CryptoTracker) refreshCoinMetadata(ctx context.Context) {
+	baseURL := config.Current().CoinGeckoAPIURL
+	if baseURL == "" {
+		baseURL = config.DefaultCoinGeckoAPIURL
+	}
+
+	metadata := make(map[string]exchange.CoinMetadata)
+	for page := 1; page <= metadataPages; page++ {
+		url := fmt.Sprintf("%s/coins/markets?vs_currency=usd&order=market_cap_desc&per_page=250&page=%d", baseURL, page)
+		response, err := c.httpClient.PerformRequest(ctx, url)
+		if err != nil {
+			logging.Error("Error fetching coin metadata:", err)
+			return
+		}
+
+		var coins []exchange.CoinMetadata
+		err = json.Unmarshal([]byte(response), &coins)
+		if err != nil {
+			logging.Error("Error parsing coin metadata:", err)
+			return
+		}
+
+		// Results are ordered by market cap, so the first coin seen for a symbol wins
+		for _, coin := range coins {
+			symbol := strings.ToUpper(coin.Symbol)
+			if _, exists := metadata[symbol]; !exists {
+				metadata[symbol] = coin
+			}
+		}
+		if len(coins) < 250 {
+			break
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.coinMetadata = metadata
+	c.metadataUpdated = time.Now()
+	c.generation++
+}
+
+// MetadataRefreshInterval returns how often coin metadata is refetched
+func metadataRefreshInterval() time.Duration {
+	if interval := config.Current().MetadataRefreshInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultMetadataRefreshInterval
+}
+
+// MarketsWithMetadata returns the markets named symbol, or every market when symbol is
+// empty, whose status matches status, with their coin metadata and when each status last
+// changed according to changedAt
+func (c *CryptoTracker) MarketsWithMetadata(symbol, status string, changedAt map[string]int64) []MarketWithMetadata {
+	markets := []MarketWithMetadata{}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, market := range c.marketDetails {
+		if symbol != "" && market.CoindcxName != symbol {
+			continue
+		}
+		if !c.marketStatusMatchesLocked(market.CoindcxName, status) {
+			continue
+		}
+		entry := MarketWithMetadata{MarketDetails: market, StatusChangedAt: changedAt[market.CoindcxName]}
+		if metadata, exists := c.coinMetadata[strings.ToUpper(market.TargetCurrencyShortName)]; exists {
+			entry.Metadata = &metadata
+		}
+		markets = append(markets, entry)
+	}
+	return markets
+}
+
+// MarketStatusMatchesLocked reports whether a market passes a status filter; markets
+// without known details only pass an empty filter. The caller must hold the tracker read lock.
+func (c *CryptoTracker) marketStatusMatchesLocked(name, status string) bool {
+	if status == "" {
+		return true
+	}
+	market, exists := c.marketDetails[name]
+	return exists && strings.EqualFold(market.Status, status)
+}
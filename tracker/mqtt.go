@@ -0,0 +1,266 @@
+package tracker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	mqttDefaultPort   = "1883"
+	mqttKeepAlive     = 60 * time.Second
+	mqttTimeout       = 10 * time.Second
+	mqttRetryBackoff  = 5 * time.Second
+	mqttConnect       = 0x10
+	mqttConnack       = 0x20
+	mqttPublish       = 0x30
+	mqttPingreq       = 0xc0
+	mqttDisconnect    = 0xe0
+	mqttRetainFlag    = 0x01
+	mqttCleanSession  = 0x02
+	mqttPasswordFlag  = 0x40
+	mqttUsernameFlag  = 0x80
+	mqttProtocolLevel = 4 // MQTT 3.1.1
+)
+
+// MQTTPublisher publishes events to an MQTT 3.1.1 broker at QoS 0. Messages are retained
+// by default, so a display that connects later immediately gets the last price of every
+// symbol it subscribes to.
+type MQTTPublisher struct {
+	url      *url.URL
+	topic    string
+	events   map[string]bool
+	retain   bool
+	clientID string
+	conn     net.Conn
+	done     chan struct{}
+	retryAt  time.Time
+	mutex    sync.Mutex
+}
+
+func newMQTTPublisher(cfg config.Config) (*MQTTPublisher, error) {
+	parsed, err := url.Parse(cfg.MQTTURL)
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]bool)
+	for _, event := range cfg.MQTTEvents {
+		events[event] = true
+	}
+	clientID := cfg.MQTTClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("cryptotracker-%d", os.Getpid())
+	}
+	return &MQTTPublisher{
+		url:      parsed,
+		topic:    cfg.MQTTTopic,
+		events:   events,
+		retain:   cfg.MQTTRetain,
+		clientID: clientID,
+	}, nil
+}
+
+// MQTTTopic fills in the {type} and {symbol} placeholders of a topic template, keeping the
+// symbol a single topic level
+func mqttTopic(template string, event MarketEvent) string {
+	if template == "" {
+		template = config.DefaultMQTTTopic
+	}
+	symbol := strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(event.Symbol)
+	return strings.NewReplacer("{type}", event.Type, "{symbol}", symbol).Replace(template)
+}
+
+func (p *MQTTPublisher) Publish(event MarketEvent) error {
+	if !p.events[event.Type] {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var packet bytes.Buffer
+	mqttString(&packet, mqttTopic(p.topic, event))
+	packet.Write(payload)
+	header := byte(mqttPublish)
+	if p.retain {
+		header |= mqttRetainFlag
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if time.Now().Before(p.retryAt) {
+		return nil
+	}
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			p.retryAt = time.Now().Add(mqttRetryBackoff)
+			return fmt.Errorf("mqtt: %v", err)
+		}
+	}
+	if err := p.send(header, packet.Bytes()); err != nil {
+		p.disconnect()
+		p.retryAt = time.Now().Add(mqttRetryBackoff)
+		return fmt.Errorf("mqtt: %v", err)
+	}
+	return nil
+}
+
+// Send writes one packet; the caller holds the mutex
+func (p *MQTTPublisher) send(header byte, body []byte) error {
+	var packet bytes.Buffer
+	packet.WriteByte(header)
+	// Remaining length: seven bits per byte, least significant first
+	for length := len(body); ; {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet.WriteByte(digit)
+		if length == 0 {
+			break
+		}
+	}
+	packet.Write(body)
+	p.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	_, err := p.conn.Write(packet.Bytes())
+	return err
+}
+
+// Connect opens a clean session and waits for the broker to accept it; the caller holds
+// the mutex
+func (p *MQTTPublisher) connect() error {
+	address := p.url.Host
+	if p.url.Port() == "" {
+		address = net.JoinHostPort(p.url.Hostname(), mqttDefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", address, mqttTimeout)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	mqttString(&body, "MQTT")
+	body.WriteByte(mqttProtocolLevel)
+	flags := byte(mqttCleanSession)
+	var username, password string
+	if user := p.url.User; user != nil {
+		username = user.Username()
+		flags |= mqttUsernameFlag
+		if pass, ok := user.Password(); ok {
+			password = pass
+			flags |= mqttPasswordFlag
+		}
+	}
+	body.WriteByte(flags)
+	body.WriteByte(byte(mqttKeepAlive / time.Second >> 8))
+	body.WriteByte(byte(mqttKeepAlive / time.Second))
+	mqttString(&body, p.clientID)
+	if flags&mqttUsernameFlag != 0 {
+		mqttString(&body, username)
+	}
+	if flags&mqttPasswordFlag != 0 {
+		mqttString(&body, password)
+	}
+
+	p.conn = conn
+	if err := p.send(mqttConnect, body.Bytes()); err != nil {
+		p.disconnect()
+		return err
+	}
+	conn.SetReadDeadline(time.Now().Add(mqttTimeout))
+	reader := bufio.NewReader(conn)
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(reader, connack); err != nil {
+		p.disconnect()
+		return err
+	}
+	if connack[0] != mqttConnack || connack[1] != 2 {
+		p.disconnect()
+		return fmt.Errorf("unexpected reply % x to CONNECT", connack)
+	}
+	if code := connack[3]; code != 0 {
+		p.disconnect()
+		return fmt.Errorf("broker refused connection with code %d", code)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	p.done = make(chan struct{})
+	go p.keepAlive(conn, reader, p.done)
+	return nil
+}
+
+// KeepAlive pings the broker while the connection is idle and drains what the broker sends,
+// which at QoS 0 is only ping responses, until the connection closes
+func (p *MQTTPublisher) keepAlive(conn net.Conn, reader *bufio.Reader, done chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(mqttKeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.mutex.Lock()
+				if p.conn == conn {
+					p.send(mqttPingreq, nil)
+				}
+				p.mutex.Unlock()
+			}
+		}
+	}()
+
+	_, err := io.Copy(ioutil.Discard, reader)
+	if err == nil {
+		err = io.EOF
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn == conn {
+		logging.Warn("MQTT connection lost:", err)
+		p.disconnect()
+	}
+}
+
+// Disconnect drops the connection so the next publish reconnects; the caller holds the mutex
+func (p *MQTTPublisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+}
+
+func (p *MQTTPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.send(mqttDisconnect, nil)
+	p.disconnect()
+	return err
+}
+
+// MQTTString writes a length-prefixed UTF-8 string
+func mqttString(b *bytes.Buffer, value string) {
+	b.WriteByte(byte(len(value) >> 8))
+	b.WriteByte(byte(len(value)))
+	b.WriteString(value)
+}
@@ -0,0 +1,280 @@
+package tracker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	natsTimeout      = 10 * time.Second
+	natsRetryBackoff = 5 * time.Second
+	natsDefaultPort  = "4222"
+)
+
+// natsAck is a JetStream publish acknowledgement
+type natsAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// NATSPublisher publishes events on subjects such as ticker.BTCINR over the NATS text
+// protocol. Plain publishes are at-most-once. With JetStream each publish waits for the
+// stream's acknowledgement, so the event is persisted before the next one is sent; the
+// stream itself, capturing these subjects, is set up by the operator.
+type NATSPublisher struct {
+	url       *url.URL
+	prefix    string
+	jetStream bool
+	conn      net.Conn
+	writer    *bufio.Writer
+	inbox     string
+	nextReply int
+	acks      map[string]chan []byte
+	retryAt   time.Time
+	mutex     sync.Mutex
+}
+
+func newNATSPublisher(cfg config.Config) (*NATSPublisher, error) {
+	parsed, err := url.Parse(cfg.NATSURL)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{
+		url:       parsed,
+		prefix:    cfg.NATSSubjectPrefix,
+		jetStream: cfg.NATSJetStream,
+		acks:      make(map[string]chan []byte),
+	}, nil
+}
+
+// NATSSubject builds the subject of an event; characters NATS treats specially are replaced
+// so every symbol stays a single subject token
+func natsSubject(prefix string, event MarketEvent) string {
+	token := strings.Map(func(r rune) rune {
+		if r == '.' || r == '*' || r == '>' || r <= ' ' {
+			return '_'
+		}
+		return r
+	}, event.Symbol)
+	if token == "" {
+		token = "_"
+	}
+	subject := event.Type + "." + token
+	if prefix != "" {
+		subject = strings.TrimSuffix(prefix, ".") + "." + subject
+	}
+	return subject
+}
+
+func (p *NATSPublisher) Publish(event MarketEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := natsSubject(p.prefix, event)
+
+	p.mutex.Lock()
+	if time.Now().Before(p.retryAt) {
+		p.mutex.Unlock()
+		return nil
+	}
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			p.retryAt = time.Now().Add(natsRetryBackoff)
+			p.mutex.Unlock()
+			return fmt.Errorf("nats: %v", err)
+		}
+	}
+	var reply string
+	var ack chan []byte
+	if p.jetStream {
+		p.nextReply++
+		reply = p.inbox + "." + strconv.Itoa(p.nextReply)
+		ack = make(chan []byte, 1)
+		p.acks[reply] = ack
+	}
+	err = p.write(subject, reply, payload)
+	p.mutex.Unlock()
+	if err != nil || ack == nil {
+		return err
+	}
+
+	defer func() {
+		p.mutex.Lock()
+		delete(p.acks, reply)
+		p.mutex.Unlock()
+	}()
+	select {
+	case data := <-ack:
+		var parsed natsAck
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return fmt.Errorf("nats: invalid JetStream ack %q", data)
+		}
+		if parsed.Error != nil {
+			return fmt.Errorf("nats: JetStream error %d: %s", parsed.Error.Code, parsed.Error.Description)
+		}
+		return nil
+	case <-time.After(natsTimeout):
+		return fmt.Errorf("nats: no JetStream ack for %s; is a stream capturing it?", subject)
+	}
+}
+
+// Write sends one PUB; the caller holds the mutex
+func (p *NATSPublisher) write(subject, reply string, payload []byte) error {
+	command := "PUB " + subject
+	if reply != "" {
+		command += " " + reply
+	}
+	p.conn.SetWriteDeadline(time.Now().Add(natsTimeout))
+	fmt.Fprintf(p.writer, "%s %d\r\n", command, len(payload))
+	p.writer.Write(payload)
+	p.writer.WriteString("\r\n")
+	if err := p.writer.Flush(); err != nil {
+		p.disconnect()
+		p.retryAt = time.Now().Add(natsRetryBackoff)
+		return fmt.Errorf("nats: %v", err)
+	}
+	return nil
+}
+
+// Connect dials the server and completes the INFO, CONNECT, PING, PONG handshake; the
+// caller holds the mutex
+func (p *NATSPublisher) connect() error {
+	address := p.url.Host
+	if p.url.Port() == "" {
+		address = net.JoinHostPort(p.url.Hostname(), natsDefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", address, natsTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "cryptotracker", "lang": "go", "protocol": 1}
+	if user := p.url.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	encoded, _ := json.Marshal(options)
+	writer := bufio.NewWriter(conn)
+	fmt.Fprintf(writer, "CONNECT %s\r\nPING\r\n", encoded)
+	p.inbox = fmt.Sprintf("_INBOX.cryptotracker.%d", time.Now().UnixNano())
+	if p.jetStream {
+		fmt.Fprintf(writer, "SUB %s.* 1\r\n", p.inbox)
+	}
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	if line, err = reader.ReadString('\n'); err != nil {
+		conn.Close()
+		return err
+	}
+	if strings.TrimSpace(line) != "PONG" {
+		conn.Close()
+		return fmt.Errorf("connect rejected: %s", strings.TrimSpace(line))
+	}
+	conn.SetDeadline(time.Time{})
+
+	p.conn, p.writer = conn, writer
+	go p.read(conn, reader)
+	return nil
+}
+
+// Read answers server PINGs and routes JetStream acks until the connection fails
+func (p *NATSPublisher) read(conn net.Conn, reader *bufio.Reader) {
+	err := func() error {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "PING":
+				p.mutex.Lock()
+				if p.conn == conn {
+					p.writer.WriteString("PONG\r\n")
+					p.writer.Flush()
+				}
+				p.mutex.Unlock()
+			case strings.HasPrefix(line, "MSG "):
+				// MSG <subject> <sid> [reply-to] <#bytes>
+				fields := strings.Fields(line)
+				size, err := strconv.Atoi(fields[len(fields)-1])
+				if err != nil || len(fields) < 4 {
+					return fmt.Errorf("malformed %q", line)
+				}
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(reader, payload); err != nil {
+					return err
+				}
+				p.mutex.Lock()
+				if ack, exists := p.acks[fields[1]]; exists {
+					select {
+					case ack <- payload[:size]:
+					default:
+					}
+				}
+				p.mutex.Unlock()
+			case strings.HasPrefix(line, "-ERR"):
+				// Most server errors close the connection, which the next read reports
+				logging.Error("NATS server error:", strings.TrimPrefix(line, "-ERR "))
+			}
+		}
+	}()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn == conn {
+		logging.Warn("NATS connection lost:", err)
+		p.disconnect()
+	}
+}
+
+// Disconnect drops the connection so the next publish reconnects; the caller holds the mutex
+func (p *NATSPublisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.writer = nil, nil
+	}
+}
+
+func (p *NATSPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.writer.Flush()
+	p.disconnect()
+	return err
+}
@@ -0,0 +1,116 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+const (
+	// maxOpenInterestPoints bounds the samples kept per contract whatever the retention
+	maxOpenInterestPoints = 20000
+)
+
+// OpenInterestPoint is a contract's open interest at one moment
+type OpenInterestPoint struct {
+	Timestamp    int64            `json:"timestamp"`
+	OpenInterest exchange.Decimal `json:"open_interest"`
+}
+
+// OpenInterestHistory samples each futures contract's open interest every
+// OpenInterestInterval seconds and keeps the samples for OpenInterestRetention seconds
+type OpenInterestHistory struct {
+	points map[string][]OpenInterestPoint
+	mutex  sync.Mutex
+}
+
+func newOpenInterestHistory() *OpenInterestHistory {
+	return &OpenInterestHistory{points: make(map[string][]OpenInterestPoint)}
+}
+
+// OpenInterestInterval returns how often open interest is sampled
+func openInterestInterval() time.Duration {
+	if interval := config.Current().OpenInterestInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultOpenInterestInterval * time.Second
+}
+
+// OpenInterestRetention returns how long open interest samples are kept
+func openInterestRetention() time.Duration {
+	if retention := config.Current().OpenInterestRetention; retention > 0 {
+		return time.Duration(retention) * time.Second
+	}
+	return config.DefaultOpenInterestRetention * time.Second
+}
+
+// Record samples the open interest of a futures price refresh for the contracts last
+// sampled an interval ago or more, and forgets samples older than the retention
+func (h *OpenInterestHistory) record(prices map[string]FuturesPrice, now time.Time) {
+	interval := openInterestInterval().Milliseconds()
+	cutoff := now.Add(-openInterestRetention()).UnixMilli()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for pair, price := range prices {
+		if price.OpenInterest.IsZero() {
+			continue
+		}
+		points := h.points[pair]
+		if len(points) > 0 && now.UnixMilli()-points[len(points)-1].Timestamp < interval {
+			continue
+		}
+		points = append(points, OpenInterestPoint{Timestamp: now.UnixMilli(), OpenInterest: price.OpenInterest})
+		start := 0
+		for start < len(points)-1 && points[start].Timestamp < cutoff {
+			start++
+		}
+		if len(points)-start > maxOpenInterestPoints {
+			start = len(points) - maxOpenInterestPoints
+		}
+		h.points[pair] = points[start:]
+	}
+}
+
+// Query returns a contract's open interest samples between from and to, oldest first;
+// zero bounds are open. A positive limit keeps only the most recent samples.
+func (h *OpenInterestHistory) Query(pair string, from, to time.Time, limit int) []OpenInterestPoint {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	points := []OpenInterestPoint{}
+	for _, point := range h.points[pair] {
+		if !from.IsZero() && point.Timestamp < from.UnixMilli() {
+			continue
+		}
+		if !to.IsZero() && point.Timestamp > to.UnixMilli() {
+			continue
+		}
+		points = append(points, point)
+	}
+	if limit > 0 && len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points
+}
+
+// Since returns a contract's earliest sample no older than since
+func (h *OpenInterestHistory) Since(pair string, since time.Time) (OpenInterestPoint, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, point := range h.points[pair] {
+		if point.Timestamp >= since.UnixMilli() {
+			return point, true
+		}
+	}
+	return OpenInterestPoint{}, false
+}
+
+// OpenInterestChange returns the percentage change of open interest from the earliest
+// sample in the window to current
+func OpenInterestChange(earliest OpenInterestPoint, current exchange.Decimal) (exchange.Decimal, bool) {
+	if earliest.OpenInterest.Sign() <= 0 || current.IsZero() {
+		return exchange.Decimal{}, false
+	}
+	return current.Sub(earliest.OpenInterest).Div(earliest.OpenInterest).Mul(exchange.DecimalFromInt(100)), true
+}
@@ -0,0 +1,512 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	paperStorageKey     = "paper_accounts"
+	defaultPaperFeeRate = "0.001"
+)
+
+// Paper order statuses
+const (
+	paperStatusOpen      = "open"
+	paperStatusFilled    = "filled"
+	paperStatusCancelled = "cancelled"
+)
+
+// PaperOrder is a simulated order placed against live order books
+type PaperOrder struct {
+	ID             string           `json:"id"`
+	Market         string           `json:"market"`
+	Side           string           `json:"side"`
+	Type           string           `json:"type"`
+	Quantity       exchange.Decimal `json:"quantity"`
+	Price          exchange.Decimal `json:"price,omitzero"`
+	FilledQuantity exchange.Decimal `json:"filled_quantity"`
+	AveragePrice   exchange.Decimal `json:"average_price"`
+	Status         string           `json:"status"`
+	CreatedAt      int64            `json:"created_at"`
+	UpdatedAt      int64            `json:"updated_at"`
+}
+
+// PaperFill is a simulated execution of part of a paper order
+type PaperFill struct {
+	OrderID     string           `json:"order_id"`
+	Market      string           `json:"market"`
+	Side        string           `json:"side"`
+	Quantity    exchange.Decimal `json:"quantity"`
+	Price       exchange.Decimal `json:"price"`
+	Fee         exchange.Decimal `json:"fee"`
+	FeeCurrency string           `json:"fee_currency"`
+	Timestamp   int64            `json:"timestamp"`
+}
+
+// PaperAccount holds the virtual balances, orders and fills of a simulated trader
+type PaperAccount struct {
+	Name        string                      `json:"name"`
+	FeeRate     exchange.Decimal            `json:"fee_rate"`
+	Balances    map[string]exchange.Decimal `json:"balances"`
+	Reserved    map[string]exchange.Decimal `json:"reserved"`
+	Orders      []*PaperOrder               `json:"orders"`
+	Fills       []PaperFill                 `json:"fills"`
+	NextOrderID int                         `json:"next_order_id"`
+}
+
+// PaperOrderRequest is the body accepted when placing a paper order
+type PaperOrderRequest struct {
+	Account  string           `json:"account"`
+	Market   string           `json:"market"`
+	Side     string           `json:"side"`
+	Type     string           `json:"type"`
+	Quantity exchange.Decimal `json:"quantity"`
+	Price    exchange.Decimal `json:"price"`
+}
+
+// PaperTrader simulates order execution against the tracker's live order books
+type PaperTrader struct {
+	tracker  *CryptoTracker
+	storage  storage.Storage
+	accounts map[string]*PaperAccount
+	// liquidity holds what fills have taken from each market's latest order book, and
+	// matched when the book resting orders were last matched against was fetched
+	liquidity map[string]*bookLiquidity
+	matched   map[string]time.Time
+	mutex     sync.Mutex
+}
+
+// bookLiquidity is what paper fills have taken from the levels of one order book, so
+// liquidity it shows is only filled once however many orders and cycles match against it
+type bookLiquidity struct {
+	fetchedAt time.Time
+	// taken is keyed by the side of the taking order and the level's price
+	taken map[string]exchange.Decimal
+}
+
+func NewPaperTrader(tracker *CryptoTracker, backend storage.Storage) *PaperTrader {
+	trader := &PaperTrader{
+		tracker:   tracker,
+		storage:   backend,
+		accounts:  make(map[string]*PaperAccount),
+		liquidity: make(map[string]*bookLiquidity),
+		matched:   make(map[string]time.Time),
+	}
+	err := backend.Load(paperStorageKey, &trader.accounts)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading paper accounts:", err)
+	}
+	return trader
+}
+
+// Save persists all paper accounts. The caller must hold the mutex.
+func (p *PaperTrader) save() {
+	if err := p.storage.Save(paperStorageKey, p.accounts); err != nil {
+		logging.Error("Error saving paper accounts:", err)
+	}
+}
+
+// CreateAccount creates a paper account, resetting any existing account with the same name
+func (p *PaperTrader) CreateAccount(account PaperAccount) PaperAccount {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.FeeRate.Sign() <= 0 {
+		account.FeeRate, _ = exchange.ParseDecimal(defaultPaperFeeRate)
+	}
+	balances := make(map[string]exchange.Decimal)
+	for currency, amount := range account.Balances {
+		balances[strings.ToUpper(currency)] = amount
+	}
+	account.Balances = balances
+	account.Reserved = make(map[string]exchange.Decimal)
+	account.Orders = []*PaperOrder{}
+	account.Fills = []PaperFill{}
+	account.NextOrderID = 1
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.accounts[account.Name] = &account
+	p.save()
+	return account
+}
+
+func (p *PaperTrader) Account(name string) (PaperAccount, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	account, exists := p.accounts[name]
+	if !exists {
+		return PaperAccount{}, false
+	}
+	return copyPaperAccount(account), true
+}
+
+// CopyPaperAccount makes a deep copy so callers can encode it outside the lock
+func copyPaperAccount(account *PaperAccount) PaperAccount {
+	copied := *account
+	copied.Balances = make(map[string]exchange.Decimal, len(account.Balances))
+	for currency, amount := range account.Balances {
+		copied.Balances[currency] = amount
+	}
+	copied.Reserved = make(map[string]exchange.Decimal, len(account.Reserved))
+	for currency, amount := range account.Reserved {
+		copied.Reserved[currency] = amount
+	}
+	copied.Orders = make([]*PaperOrder, len(account.Orders))
+	for i, order := range account.Orders {
+		orderCopy := *order
+		copied.Orders[i] = &orderCopy
+	}
+	copied.Fills = append([]PaperFill{}, account.Fills...)
+	return copied
+}
+
+// Available returns the unreserved balance of a currency
+func (a *PaperAccount) available(currency string) exchange.Decimal {
+	return a.Balances[currency].Sub(a.Reserved[currency])
+}
+
+// WithFee returns a notional plus the account's trading fee on it
+func (a *PaperAccount) withFee(notional exchange.Decimal) exchange.Decimal {
+	return notional.Mul(exchange.DecimalFromInt(1).Add(a.FeeRate))
+}
+
+// BookLiquidity returns what has been taken from a market's order book fetched at
+// fetchedAt, starting afresh for a newer book. The caller must hold the mutex.
+func (p *PaperTrader) bookLiquidity(market string, fetchedAt time.Time) *bookLiquidity {
+	liquidity, exists := p.liquidity[market]
+	if !exists || fetchedAt.After(liquidity.fetchedAt) {
+		liquidity = &bookLiquidity{fetchedAt: fetchedAt, taken: make(map[string]exchange.Decimal)}
+		p.liquidity[market] = liquidity
+	}
+	return liquidity
+}
+
+// Available returns how much of a level is left for an order on side
+func (l *bookLiquidity) available(side string, level exchange.BookLevel) exchange.Decimal {
+	return level.Quantity.Sub(l.taken[side+" "+level.Price.String()])
+}
+
+func (l *bookLiquidity) take(side string, level exchange.BookLevel, quantity exchange.Decimal) {
+	key := side + " " + level.Price.String()
+	l.taken[key] = l.taken[key].Add(quantity)
+}
+
+// OpenMarkets returns the markets with resting limit orders, which need fresh order books
+func (p *PaperTrader) OpenMarkets() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	seen := make(map[string]bool)
+	markets := []string{}
+	for _, account := range p.accounts {
+		for _, order := range account.Orders {
+			if order.Status == paperStatusOpen && !seen[order.Market] {
+				seen[order.Market] = true
+				markets = append(markets, order.Market)
+			}
+		}
+	}
+	return markets
+}
+
+// PlaceOrder validates and executes or rests a paper order
+func (p *PaperTrader) PlaceOrder(ctx context.Context, request PaperOrderRequest) (PaperOrder, error) {
+	request.Market = strings.ToUpper(strings.TrimSpace(request.Market))
+	request.Side = strings.ToLower(request.Side)
+	request.Type = strings.ToLower(request.Type)
+	if request.Side != "buy" && request.Side != "sell" {
+		return PaperOrder{}, errors.New("'side' must be 'buy' or 'sell'")
+	}
+	if request.Type == "" {
+		request.Type = "market"
+	}
+	if request.Type != "market" && request.Type != "limit" {
+		return PaperOrder{}, errors.New("'type' must be 'market' or 'limit'")
+	}
+	if request.Quantity.Sign() <= 0 {
+		return PaperOrder{}, errors.New("'quantity' must be positive")
+	}
+	if request.Type == "limit" && request.Price.Sign() <= 0 {
+		return PaperOrder{}, errors.New("limit orders require a positive 'price'")
+	}
+
+	market, exists := p.tracker.MarketInfo(request.Market)
+	if !exists {
+		return PaperOrder{}, &APIError{Code: codeSymbolNotFound, Detail: "Unknown market " + request.Market}
+	}
+	orderBook, fetchedAt, exists := p.tracker.OrderBookFor(ctx, request.Market, true)
+	if !exists {
+		if failure, failing := p.tracker.orderBookFailure(request.Market); failing {
+			return PaperOrder{}, p.tracker.upstreamFailure(failure.err)
+		}
+		return PaperOrder{}, &APIError{Code: codeUpstreamError, Detail: "Order book unavailable for " + request.Market}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	account, exists := p.accounts[request.Account]
+	if !exists {
+		return PaperOrder{}, errors.New("paper account not found")
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	order := &PaperOrder{
+		ID:        strconv.Itoa(account.NextOrderID),
+		Market:    request.Market,
+		Side:      request.Side,
+		Type:      request.Type,
+		Quantity:  request.Quantity,
+		Price:     request.Price,
+		Status:    paperStatusOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	quote := market.BaseCurrencyShortName
+	asset := market.TargetCurrencyShortName
+	liquidity := p.bookLiquidity(order.Market, fetchedAt)
+
+	if order.Type == "market" {
+		// Check the full cost up front so a market order is either affordable or rejected
+		if order.Side == "buy" {
+			cost := exchange.Decimal{}
+			remaining := order.Quantity
+			for _, level := range exchange.SortedLevels(orderBook.Asks, false) {
+				take := remaining.Min(liquidity.available(order.Side, level))
+				if take.Sign() <= 0 {
+					continue
+				}
+				cost = cost.Add(take.Mul(level.Price))
+				remaining = remaining.Sub(take)
+				if remaining.Sign() <= 0 {
+					break
+				}
+			}
+			if account.withFee(cost).Cmp(account.available(quote)) > 0 {
+				return PaperOrder{}, fmt.Errorf("insufficient %s balance", quote)
+			}
+		} else if order.Quantity.Cmp(account.available(asset)) > 0 {
+			return PaperOrder{}, fmt.Errorf("insufficient %s balance", asset)
+		}
+	} else {
+		if order.Side == "buy" {
+			reserve := account.withFee(order.Quantity.Mul(order.Price))
+			if reserve.Cmp(account.available(quote)) > 0 {
+				return PaperOrder{}, fmt.Errorf("insufficient %s balance", quote)
+			}
+			account.Reserved[quote] = account.Reserved[quote].Add(reserve)
+		} else {
+			if order.Quantity.Cmp(account.available(asset)) > 0 {
+				return PaperOrder{}, fmt.Errorf("insufficient %s balance", asset)
+			}
+			account.Reserved[asset] = account.Reserved[asset].Add(order.Quantity)
+		}
+	}
+
+	account.NextOrderID++
+	account.Orders = append(account.Orders, order)
+	p.matchOrder(account, order, market, orderBook, liquidity)
+	if order.Type == "market" && order.Status == paperStatusOpen {
+		// Whatever the book could not fill is cancelled rather than left resting
+		order.Status = paperStatusCancelled
+	}
+	p.save()
+	return *order, nil
+}
+
+// MatchOrder fills as much of an open order as the liquidity left in the order book
+// allows. The caller must hold the mutex.
+func (p *PaperTrader) matchOrder(account *PaperAccount, order *PaperOrder, market exchange.MarketDetails, orderBook exchange.OrderBook, liquidity *bookLiquidity) {
+	quote := market.BaseCurrencyShortName
+	asset := market.TargetCurrencyShortName
+
+	var levels []exchange.BookLevel
+	if order.Side == "buy" {
+		levels = exchange.SortedLevels(orderBook.Asks, false)
+	} else {
+		levels = exchange.SortedLevels(orderBook.Bids, true)
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, level := range levels {
+		remaining := order.Quantity.Sub(order.FilledQuantity)
+		if remaining.Sign() <= 0 {
+			break
+		}
+		if order.Type == "limit" {
+			if order.Side == "buy" && level.Price.Cmp(order.Price) > 0 {
+				break
+			}
+			if order.Side == "sell" && level.Price.Cmp(order.Price) < 0 {
+				break
+			}
+		}
+
+		take := remaining.Min(liquidity.available(order.Side, level))
+		if take.Sign() <= 0 {
+			continue
+		}
+		liquidity.take(order.Side, level, take)
+		notional := take.Mul(level.Price)
+		fee := notional.Mul(account.FeeRate)
+		if order.Side == "buy" {
+			if order.Type == "limit" {
+				account.Reserved[quote] = account.Reserved[quote].Sub(account.withFee(take.Mul(order.Price)))
+			}
+			account.Balances[quote] = account.Balances[quote].Sub(notional.Add(fee))
+			account.Balances[asset] = account.Balances[asset].Add(take)
+		} else {
+			if order.Type == "limit" {
+				account.Reserved[asset] = account.Reserved[asset].Sub(take)
+			}
+			account.Balances[asset] = account.Balances[asset].Sub(take)
+			account.Balances[quote] = account.Balances[quote].Add(notional.Sub(fee))
+		}
+
+		// The average is the volume-weighted price of every fill so far
+		filledNotional := order.AveragePrice.Mul(order.FilledQuantity).Add(notional)
+		order.FilledQuantity = order.FilledQuantity.Add(take)
+		order.AveragePrice = filledNotional.Div(order.FilledQuantity)
+		order.UpdatedAt = now
+		account.Fills = append(account.Fills, PaperFill{
+			OrderID:     order.ID,
+			Market:      order.Market,
+			Side:        order.Side,
+			Quantity:    take,
+			Price:       level.Price,
+			Fee:         fee,
+			FeeCurrency: quote,
+			Timestamp:   now,
+		})
+	}
+
+	if order.Quantity.Cmp(order.FilledQuantity) <= 0 {
+		order.Status = paperStatusFilled
+	}
+}
+
+// MatchOpenOrders re-evaluates resting limit orders against the order books fetched since
+// they were last matched, oldest order first, so a book unchanged since then fills nothing
+// again and orders share rather than each take the liquidity of a level
+func (p *PaperTrader) MatchOpenOrders() {
+	books := make(map[string]exchange.OrderBook)
+	fetched := make(map[string]time.Time)
+	markets := make(map[string]exchange.MarketDetails)
+	for _, name := range p.OpenMarkets() {
+		orderBook, fetchedAt, hasBook := p.tracker.OrderBookFor(context.Background(), name, false)
+		market, hasMarket := p.tracker.MarketInfo(name)
+		if hasBook && hasMarket {
+			books[name] = orderBook
+			fetched[name] = fetchedAt
+			markets[name] = market
+		}
+	}
+	if len(books) == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for name, fetchedAt := range fetched {
+		if !fetchedAt.After(p.matched[name]) {
+			delete(books, name)
+			continue
+		}
+		p.matched[name] = fetchedAt
+	}
+	type restingOrder struct {
+		account *PaperAccount
+		order   *PaperOrder
+	}
+	resting := []restingOrder{}
+	for _, account := range p.accounts {
+		for _, order := range account.Orders {
+			if _, exists := books[order.Market]; exists && order.Status == paperStatusOpen {
+				resting = append(resting, restingOrder{account, order})
+			}
+		}
+	}
+	// Earlier orders are ahead in the queue for the liquidity they compete for
+	sort.SliceStable(resting, func(i, j int) bool { return resting[i].order.CreatedAt < resting[j].order.CreatedAt })
+
+	changed := false
+	for _, entry := range resting {
+		order := entry.order
+		filled := order.FilledQuantity
+		liquidity := p.bookLiquidity(order.Market, fetched[order.Market])
+		p.matchOrder(entry.account, order, markets[order.Market], books[order.Market], liquidity)
+		changed = changed || order.FilledQuantity.Cmp(filled) != 0
+	}
+	if changed {
+		p.save()
+	}
+}
+
+// CancelOrder cancels an open order and releases its reserved balance
+func (p *PaperTrader) CancelOrder(accountName, orderID string) (PaperOrder, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	account, exists := p.accounts[accountName]
+	if !exists {
+		return PaperOrder{}, errors.New("paper account not found")
+	}
+	for _, order := range account.Orders {
+		if order.ID != orderID {
+			continue
+		}
+		if order.Status != paperStatusOpen {
+			return *order, fmt.Errorf("order %s is already %s", orderID, order.Status)
+		}
+
+		market, _ := p.tracker.MarketInfo(order.Market)
+		remaining := order.Quantity.Sub(order.FilledQuantity)
+		quote, asset := market.BaseCurrencyShortName, market.TargetCurrencyShortName
+		if order.Side == "buy" {
+			account.Reserved[quote] = account.Reserved[quote].Sub(account.withFee(remaining.Mul(order.Price)))
+		} else {
+			account.Reserved[asset] = account.Reserved[asset].Sub(remaining)
+		}
+		order.Status = paperStatusCancelled
+		order.UpdatedAt = time.Now().UnixNano() / int64(time.Millisecond)
+		p.save()
+		return *order, nil
+	}
+	return PaperOrder{}, errors.New("order not found")
+}
+
+// Rounded rounds the volume-weighted average price to the market's price precision
+func (o PaperOrder) Rounded(p *Precision) PaperOrder {
+	o.AveragePrice = p.price(o.Market, o.AveragePrice)
+	return o
+}
+
+// Rounded rounds the fee to the precision of the currency it was charged in
+func (f PaperFill) rounded(p *Precision) PaperFill {
+	f.Fee = p.amount(f.FeeCurrency, f.Fee)
+	return f
+}
+
+// Rounded rounds the derived values of an account's orders and fills. Balances are kept
+// exact, so they always match the fills that produced them.
+func (a PaperAccount) Rounded(p *Precision) PaperAccount {
+	for i, order := range a.Orders {
+		rounded := order.Rounded(p)
+		a.Orders[i] = &rounded
+	}
+	for i, fill := range a.Fills {
+		a.Fills[i] = fill.rounded(p)
+	}
+	return a
+}
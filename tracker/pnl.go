@@ -0,0 +1,220 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+const (
+	CostBasisFIFO    = "fifo"
+	CostBasisAverage = "average"
+)
+
+// Transaction is a buy or sell recorded against a portfolio, priced in the portfolio currency
+type Transaction struct {
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"`
+	Quantity  float64 `json:"quantity"`
+	Price     float64 `json:"price"`
+	Fee       float64 `json:"fee,omitempty"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// SymbolPnL is the realized and unrealized profit and loss for one asset
+type SymbolPnL struct {
+	Symbol        string           `json:"symbol"`
+	Quantity      exchange.Decimal `json:"quantity"`
+	CostBasis     exchange.Decimal `json:"cost_basis"`
+	AverageCost   exchange.Decimal `json:"average_cost"`
+	Price         exchange.Decimal `json:"price"`
+	MarketValue   exchange.Decimal `json:"market_value"`
+	RealizedPnL   exchange.Decimal `json:"realized_pnl"`
+	UnrealizedPnL exchange.Decimal `json:"unrealized_pnl"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// PortfolioPnL is the profit and loss for a whole portfolio
+type PortfolioPnL struct {
+	Name          string           `json:"name"`
+	Currency      string           `json:"currency"`
+	Method        string           `json:"method"`
+	RealizedPnL   exchange.Decimal `json:"realized_pnl"`
+	UnrealizedPnL exchange.Decimal `json:"unrealized_pnl"`
+	TotalPnL      exchange.Decimal `json:"total_pnl"`
+	Symbols       []SymbolPnL      `json:"symbols"`
+}
+
+// costLot is an open purchase lot used by FIFO accounting. Lots keep their total cost
+// rather than a unit cost, so selling a whole lot releases exactly what it cost.
+type costLot struct {
+	quantity exchange.Decimal
+	cost     exchange.Decimal
+}
+
+// positionTracker accumulates transactions for a single asset
+type positionTracker struct {
+	method   string
+	lots     []costLot
+	quantity exchange.Decimal
+	cost     exchange.Decimal
+	realized exchange.Decimal
+}
+
+func (p *positionTracker) buy(quantity, price, fee exchange.Decimal) {
+	total := quantity.Mul(price).Add(fee)
+	p.quantity = p.quantity.Add(quantity)
+	p.cost = p.cost.Add(total)
+	if p.method == CostBasisFIFO && quantity.Sign() > 0 {
+		p.lots = append(p.lots, costLot{quantity: quantity, cost: total})
+	}
+}
+
+func (p *positionTracker) sell(quantity, price, fee exchange.Decimal) error {
+	if quantity.Cmp(p.quantity) > 0 {
+		return fmt.Errorf("sell of %s exceeds open quantity %s", quantity, p.quantity)
+	}
+
+	var released exchange.Decimal
+	switch p.method {
+	case CostBasisFIFO:
+		remaining := quantity
+		for remaining.Sign() > 0 && len(p.lots) > 0 {
+			lot := &p.lots[0]
+			used := remaining.Min(lot.quantity)
+			portion := lot.cost
+			if used.Cmp(lot.quantity) < 0 {
+				portion = lot.cost.Mul(used).Div(lot.quantity)
+			}
+			released = released.Add(portion)
+			lot.quantity = lot.quantity.Sub(used)
+			lot.cost = lot.cost.Sub(portion)
+			remaining = remaining.Sub(used)
+			if lot.quantity.Sign() <= 0 {
+				p.lots = p.lots[1:]
+			}
+		}
+	default:
+		if p.quantity.Sign() > 0 {
+			released = p.cost.Mul(quantity).Div(p.quantity)
+		}
+	}
+
+	p.realized = p.realized.Add(quantity.Mul(price).Sub(fee).Sub(released))
+	p.quantity = p.quantity.Sub(quantity)
+	p.cost = p.cost.Sub(released)
+	if p.quantity.Sign() <= 0 {
+		p.quantity = exchange.Decimal{}
+		p.cost = exchange.Decimal{}
+	}
+	return nil
+}
+
+// ComputePnL replays a portfolio's transactions and values open positions at live prices
+func (c *CryptoTracker) ComputePnL(portfolio Portfolio, method string) (PortfolioPnL, error) {
+	result := PortfolioPnL{
+		Name:     portfolio.Name,
+		Currency: portfolio.Currency,
+		Method:   method,
+		Symbols:  []SymbolPnL{},
+	}
+
+	transactions := make([]Transaction, len(portfolio.Transactions))
+	copy(transactions, portfolio.Transactions)
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Timestamp < transactions[j].Timestamp
+	})
+
+	positions := make(map[string]*positionTracker)
+	symbols := []string{}
+	for i, transaction := range transactions {
+		position, exists := positions[transaction.Symbol]
+		if !exists {
+			position = &positionTracker{method: method}
+			positions[transaction.Symbol] = position
+			symbols = append(symbols, transaction.Symbol)
+		}
+		quantity, price, fee := exchange.DecimalFromFloat(transaction.Quantity), exchange.DecimalFromFloat(transaction.Price), exchange.DecimalFromFloat(transaction.Fee)
+		switch transaction.Side {
+		case "buy":
+			position.buy(quantity, price, fee)
+		case "sell":
+			if err := position.sell(quantity, price, fee); err != nil {
+				return result, fmt.Errorf("transaction %d (%s): %v", i, transaction.Symbol, err)
+			}
+		}
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		position := positions[symbol]
+		entry := SymbolPnL{
+			Symbol:      symbol,
+			Quantity:    position.quantity,
+			CostBasis:   position.cost,
+			RealizedPnL: position.realized,
+		}
+		if position.quantity.Sign() > 0 {
+			entry.AverageCost = position.cost.Div(position.quantity)
+			conversion, err := c.ConvertCurrency(symbol, portfolio.Currency, position.quantity)
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.Price = conversion.Result.Div(position.quantity)
+				entry.MarketValue = conversion.Result
+				entry.UnrealizedPnL = entry.MarketValue.Sub(position.cost)
+			}
+		}
+
+		result.RealizedPnL = result.RealizedPnL.Add(entry.RealizedPnL)
+		result.UnrealizedPnL = result.UnrealizedPnL.Add(entry.UnrealizedPnL)
+		result.Symbols = append(result.Symbols, entry)
+	}
+	result.TotalPnL = result.RealizedPnL.Add(result.UnrealizedPnL)
+	return result, nil
+}
+
+// Rounded rounds quantities to the precision of each asset, values to the precision of the
+// portfolio currency and unit costs and prices to the market pricing the asset in it
+func (p PortfolioPnL) Rounded(precision *Precision) PortfolioPnL {
+	p.RealizedPnL = precision.amount(p.Currency, p.RealizedPnL)
+	p.UnrealizedPnL = precision.amount(p.Currency, p.UnrealizedPnL)
+	p.TotalPnL = precision.amount(p.Currency, p.TotalPnL)
+	symbols := make([]SymbolPnL, len(p.Symbols))
+	for i, entry := range p.Symbols {
+		entry.Quantity = precision.amount(entry.Symbol, entry.Quantity)
+		entry.CostBasis = precision.amount(p.Currency, entry.CostBasis)
+		entry.AverageCost = precision.PriceIn(entry.Symbol, p.Currency, entry.AverageCost)
+		entry.Price = precision.PriceIn(entry.Symbol, p.Currency, entry.Price)
+		entry.MarketValue = precision.amount(p.Currency, entry.MarketValue)
+		entry.RealizedPnL = precision.amount(p.Currency, entry.RealizedPnL)
+		entry.UnrealizedPnL = precision.amount(p.Currency, entry.UnrealizedPnL)
+		symbols[i] = entry
+	}
+	p.Symbols = symbols
+	return p
+}
+
+// NormalizeTransactions validates submitted transactions
+func normalizeTransactions(transactions []Transaction) error {
+	for i := range transactions {
+		transaction := &transactions[i]
+		transaction.Symbol = strings.ToUpper(strings.TrimSpace(transaction.Symbol))
+		transaction.Side = strings.ToLower(strings.TrimSpace(transaction.Side))
+		if transaction.Symbol == "" {
+			return fmt.Errorf("transaction %d: missing 'symbol'", i)
+		}
+		if transaction.Side != "buy" && transaction.Side != "sell" {
+			return fmt.Errorf("transaction %d: 'side' must be 'buy' or 'sell'", i)
+		}
+		if transaction.Quantity <= 0 {
+			return fmt.Errorf("transaction %d: 'quantity' must be positive", i)
+		}
+		if transaction.Price < 0 || transaction.Fee < 0 {
+			return fmt.Errorf("transaction %d: 'price' and 'fee' must not be negative", i)
+		}
+	}
+	return nil
+}
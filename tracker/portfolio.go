@@ -0,0 +1,253 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	portfolioStorageKey      = "portfolios"
+	defaultPortfolioCurrency = "INR"
+)
+
+// Holding is a quantity of an asset held in a portfolio
+type Holding struct {
+	Symbol    string   `json:"symbol"`
+	Quantity  float64  `json:"quantity"`
+	CostBasis *float64 `json:"cost_basis,omitempty"`
+}
+
+// Portfolio is a named set of holdings valued in a single currency
+type Portfolio struct {
+	Name string `json:"name"`
+	// Owner is the user the portfolio belongs to, empty for a shared one
+	Owner        string        `json:"owner,omitempty"`
+	Currency     string        `json:"currency"`
+	Holdings     []Holding     `json:"holdings"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+}
+
+// HoldingValuation is the live valuation of one holding
+type HoldingValuation struct {
+	Holding
+	Price            exchange.Decimal `json:"price"`
+	Value            exchange.Decimal `json:"value"`
+	Allocation       float64          `json:"allocation_percent"`
+	Change24hPercent float64          `json:"change_24h_percent"`
+	Change24hValue   exchange.Decimal `json:"change_24h_value"`
+	Path             []ConversionStep `json:"path,omitempty"`
+	Error            string           `json:"error,omitempty"`
+}
+
+// PortfolioValuation is the live valuation of a portfolio
+type PortfolioValuation struct {
+	Name             string             `json:"name"`
+	Currency         string             `json:"currency"`
+	TotalValue       exchange.Decimal   `json:"total_value"`
+	Change24hValue   exchange.Decimal   `json:"change_24h_value"`
+	Change24hPercent float64            `json:"change_24h_percent"`
+	Holdings         []HoldingValuation `json:"holdings"`
+}
+
+// PortfolioStore keeps portfolios in memory and persists them to storage
+type PortfolioStore struct {
+	storage    storage.Storage
+	portfolios map[string]Portfolio
+	mutex      sync.RWMutex
+}
+
+func NewPortfolioStore(backend storage.Storage) *PortfolioStore {
+	store := &PortfolioStore{
+		storage:    backend,
+		portfolios: make(map[string]Portfolio),
+	}
+	err := backend.Load(portfolioStorageKey, &store.portfolios)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading portfolios:", err)
+	}
+	return store
+}
+
+func (s *PortfolioStore) Get(user, name string) (Portfolio, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	portfolio, exists := s.portfolios[ownedKey(user, name)]
+	return portfolio, exists && portfolio.Owner == user
+}
+
+func (s *PortfolioStore) Names(user string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := []string{}
+	for _, portfolio := range s.portfolios {
+		if portfolio.Owner == user {
+			names = append(names, portfolio.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *PortfolioStore) Put(portfolio Portfolio) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	portfolios := s.copy()
+	portfolios[ownedKey(portfolio.Owner, portfolio.Name)] = portfolio
+	return s.save(portfolios)
+}
+
+func (s *PortfolioStore) Remove(user, name string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := ownedKey(user, name)
+	if portfolio, exists := s.portfolios[key]; !exists || portfolio.Owner != user {
+		return false, nil
+	}
+	portfolios := s.copy()
+	delete(portfolios, key)
+	return true, s.save(portfolios)
+}
+
+// Copy returns a copy of the portfolios to change and save. The caller must hold the mutex.
+func (s *PortfolioStore) copy() map[string]Portfolio {
+	portfolios := make(map[string]Portfolio, len(s.portfolios))
+	for key, portfolio := range s.portfolios {
+		portfolios[key] = portfolio
+	}
+	return portfolios
+}
+
+// Save persists portfolios and only then makes them current, so a failed save changes
+// nothing. The caller must hold the mutex.
+func (s *PortfolioStore) save(portfolios map[string]Portfolio) error {
+	if err := s.storage.Save(portfolioStorageKey, portfolios); err != nil {
+		return err
+	}
+	s.portfolios = portfolios
+	return nil
+}
+
+// PathChange24h returns the 24h price multiplier implied by a conversion path
+func (c *CryptoTracker) pathChange24h(path []ConversionStep) exchange.Decimal {
+	tickers := c.MarketView().Tickers
+	one, hundred := exchange.DecimalFromInt(1), exchange.DecimalFromInt(100)
+	factor := one
+	for _, step := range path {
+		ticker, exists := tickers[step.Market]
+		if !exists {
+			continue
+		}
+		percent, ok := exchange.ParseDecimal(ticker.Change24Hour)
+		if !ok || percent.Cmp(hundred.Neg()) <= 0 {
+			continue
+		}
+		change := one.Add(percent.Div(hundred))
+		if step.Inverse {
+			change = one.Div(change)
+		}
+		factor = factor.Mul(change)
+	}
+	return factor
+}
+
+// ValuePortfolio values every holding of a portfolio using the cached tickers
+func (c *CryptoTracker) ValuePortfolio(portfolio Portfolio) PortfolioValuation {
+	valuation := PortfolioValuation{
+		Name:     portfolio.Name,
+		Currency: portfolio.Currency,
+		Holdings: []HoldingValuation{},
+	}
+
+	hundred := exchange.DecimalFromInt(100)
+	previousTotal := exchange.Decimal{}
+	for _, holding := range portfolio.Holdings {
+		entry := HoldingValuation{Holding: holding}
+		quantity := exchange.DecimalFromFloat(holding.Quantity)
+		conversion, err := c.ConvertCurrency(holding.Symbol, portfolio.Currency, quantity)
+		if err != nil {
+			entry.Error = err.Error()
+			valuation.Holdings = append(valuation.Holdings, entry)
+			continue
+		}
+
+		entry.Value = conversion.Result
+		entry.Path = conversion.Path
+		if quantity.Sign() != 0 {
+			entry.Price = conversion.Result.Div(quantity)
+		}
+		factor := c.pathChange24h(conversion.Path)
+		previous := entry.Value.Div(factor)
+		entry.Change24hValue = entry.Value.Sub(previous)
+		entry.Change24hPercent = factor.Sub(exchange.DecimalFromInt(1)).Mul(hundred).Float()
+
+		valuation.TotalValue = valuation.TotalValue.Add(entry.Value)
+		previousTotal = previousTotal.Add(previous)
+		valuation.Holdings = append(valuation.Holdings, entry)
+	}
+
+	if valuation.TotalValue.Sign() > 0 {
+		for i := range valuation.Holdings {
+			valuation.Holdings[i].Allocation = valuation.Holdings[i].Value.Div(valuation.TotalValue).Mul(hundred).Float()
+		}
+	}
+	valuation.Change24hValue = valuation.TotalValue.Sub(previousTotal)
+	if previousTotal.Sign() > 0 {
+		valuation.Change24hPercent = valuation.Change24hValue.Div(previousTotal).Mul(hundred).Float()
+	}
+	return valuation
+}
+
+// Rounded rounds values to the precision of the portfolio currency and unit prices to the
+// precision of the market pricing each asset in it
+func (v PortfolioValuation) Rounded(p *Precision) PortfolioValuation {
+	v.TotalValue = p.amount(v.Currency, v.TotalValue)
+	v.Change24hValue = p.amount(v.Currency, v.Change24hValue)
+	holdings := make([]HoldingValuation, len(v.Holdings))
+	for i, holding := range v.Holdings {
+		holding.Price = p.PriceIn(holding.Symbol, v.Currency, holding.Price)
+		holding.Value = p.amount(v.Currency, holding.Value)
+		holding.Change24hValue = p.amount(v.Currency, holding.Change24hValue)
+		holdings[i] = holding
+	}
+	v.Holdings = holdings
+	return v
+}
+
+// NormalizePortfolio validates a submitted portfolio and fills in defaults
+func NormalizePortfolio(portfolio *Portfolio) error {
+	portfolio.Name = strings.TrimSpace(portfolio.Name)
+	if portfolio.Name == "" {
+		return errors.New("missing 'name'")
+	}
+	if err := validResourceName(portfolio.Name); err != nil {
+		return err
+	}
+	portfolio.Currency = strings.ToUpper(strings.TrimSpace(portfolio.Currency))
+	if portfolio.Currency == "" {
+		portfolio.Currency = defaultPortfolioCurrency
+	}
+	if portfolio.Holdings == nil {
+		portfolio.Holdings = []Holding{}
+	}
+	for i := range portfolio.Holdings {
+		holding := &portfolio.Holdings[i]
+		holding.Symbol = strings.ToUpper(strings.TrimSpace(holding.Symbol))
+		if holding.Symbol == "" {
+			return fmt.Errorf("holding %d: missing 'symbol'", i)
+		}
+		if holding.Quantity < 0 {
+			return fmt.Errorf("holding %d: 'quantity' must not be negative", i)
+		}
+		if holding.CostBasis != nil && *holding.CostBasis < 0 {
+			return fmt.Errorf("holding %d: 'cost_basis' must not be negative", i)
+		}
+	}
+	return normalizeTransactions(portfolio.Transactions)
+}
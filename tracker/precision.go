@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// Precision rounds derived values to the decimal places and quantity steps markets trade
+// in. It is a snapshot of the market details, so responses are rounded without holding
+// the tracker lock.
+type Precision struct {
+	markets map[string]exchange.MarketDetails
+	// pairs finds the market pricing a target currency in a base currency
+	pairs map[[2]string]string
+	// currencies holds the decimal places amounts of each currency are given to
+	currencies map[string]int
+}
+
+// Precision snapshots the precision of every known market. A currency traded as a target
+// takes the finest quantity precision of its markets; one only ever quoted takes the coarsest
+// price precision of the markets quoted in it, e.g. two places for INR.
+func (c *CryptoTracker) Precision() *Precision {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	p := &Precision{
+		markets:    make(map[string]exchange.MarketDetails, len(c.marketDetails)),
+		pairs:      make(map[[2]string]string, len(c.marketDetails)),
+		currencies: make(map[string]int),
+	}
+	traded := make(map[string]bool)
+	for name, market := range c.marketDetails {
+		p.markets[name] = market
+		p.pairs[[2]string{market.TargetCurrencyShortName, market.BaseCurrencyShortName}] = name
+		target := market.TargetCurrencyShortName
+		if places, exists := p.currencies[target]; !exists || market.TargetCurrencyPrecision > places {
+			p.currencies[target] = market.TargetCurrencyPrecision
+		}
+		traded[target] = true
+	}
+	for _, market := range c.marketDetails {
+		base := market.BaseCurrencyShortName
+		if traded[base] {
+			continue
+		}
+		if places, exists := p.currencies[base]; !exists || market.BaseCurrencyPrecision < places {
+			p.currencies[base] = market.BaseCurrencyPrecision
+		}
+	}
+	return p
+}
+
+// Price rounds a price in a market's base currency to the market's price precision
+func (p *Precision) price(market string, value exchange.Decimal) exchange.Decimal {
+	details, exists := p.markets[market]
+	if !exists {
+		return value
+	}
+	return value.RoundTo(details.BaseCurrencyPrecision)
+}
+
+// Quantity rounds a quantity of a market's target currency to its step, or to its
+// quantity precision when the market has no step
+func (p *Precision) quantity(market string, value exchange.Decimal) exchange.Decimal {
+	details, exists := p.markets[market]
+	if !exists {
+		return value
+	}
+	if details.Step > 0 {
+		return value.RoundToStep(exchange.DecimalFromFloat(details.Step))
+	}
+	return value.RoundTo(details.TargetCurrencyPrecision)
+}
+
+// Amount rounds an amount of a currency
+func (p *Precision) amount(currency string, value exchange.Decimal) exchange.Decimal {
+	places, exists := p.currencies[currency]
+	if !exists {
+		return value
+	}
+	return value.RoundTo(places)
+}
+
+// PriceIn rounds the price of one unit of asset in currency, using the market between
+// them when there is one and the currency's amount precision otherwise
+func (p *Precision) PriceIn(asset, currency string, value exchange.Decimal) exchange.Decimal {
+	if market, exists := p.pairs[[2]string{asset, currency}]; exists {
+		return p.price(market, value)
+	}
+	return p.amount(currency, value)
+}
+
+// RawValues reports whether a request asked for derived values unrounded with raw=true
+func RawValues(r *http.Request) bool {
+	raw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+	return raw
+}
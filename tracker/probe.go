@@ -0,0 +1,199 @@
+package tracker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	probeWindow = 100
+)
+
+// probeSample is the outcome of one health probe
+type probeSample struct {
+	at      time.Time
+	latency time.Duration
+	err     error
+}
+
+// UpstreamProbe periodically calls a cheap exchange endpoint and keeps a rolling window
+// of outcomes, so /status reflects upstream health even while the refresh loop is idle
+type UpstreamProbe struct {
+	client  *exchange.SafeHTTPClient
+	samples []probeSample
+	next    int
+	total   int64
+	failed  int64
+	mutex   sync.Mutex
+}
+
+func newUpstreamProbe(client *exchange.SafeHTTPClient) *UpstreamProbe {
+	return &UpstreamProbe{client: client}
+}
+
+// ProbeInterval returns how often the exchange is probed
+func probeInterval() time.Duration {
+	if interval := config.Current().ProbeInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultProbeInterval
+}
+
+// Start probes the exchange until ctx is cancelled
+func (p *UpstreamProbe) Start(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			p.probe(ctx)
+			exchange.SleepContext(ctx, probeInterval())
+		}
+	}()
+}
+
+// Probe makes one request to the exchange's market list and records its outcome.
+// Probes are skipped while upstream has asked us to back off.
+func (p *UpstreamProbe) probe(ctx context.Context) {
+	if p.client.State.Throttled() {
+		return
+	}
+	started := time.Now()
+	_, err := p.client.PerformRequest(ctx, config.Current().APIBaseURL+"/exchange/v1/markets")
+	if ctx.Err() != nil {
+		return
+	}
+	p.record(probeSample{at: started, latency: time.Since(started), err: err})
+}
+
+func (p *UpstreamProbe) record(sample probeSample) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.total++
+	if sample.err != nil {
+		p.failed++
+		logging.Warn("Upstream probe failed:", sample.err)
+	}
+	if len(p.samples) < probeWindow {
+		p.samples = append(p.samples, sample)
+	} else {
+		p.samples[p.next] = sample
+	}
+	p.next = (p.next + 1) % probeWindow
+}
+
+// ProbeHealth summarizes recent probes; rates and latencies cover the last probeWindow probes
+type ProbeHealth struct {
+	Probes        int64   `json:"probes"`
+	Failures      int64   `json:"failures"`
+	Window        int     `json:"window"`
+	SuccessRate   float64 `json:"success_rate"`
+	LatencyAvgMs  float64 `json:"latency_avg_ms"`
+	LatencyP50Ms  float64 `json:"latency_p50_ms"`
+	LatencyP95Ms  float64 `json:"latency_p95_ms"`
+	LatencyMaxMs  float64 `json:"latency_max_ms"`
+	LastProbeAt   int64   `json:"last_probe_at,omitempty"`
+	LastLatencyMs float64 `json:"last_latency_ms"`
+	LastError     string  `json:"last_error,omitempty"`
+}
+
+func (p *UpstreamProbe) Health() ProbeHealth {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	health := ProbeHealth{Probes: p.total, Failures: p.failed, Window: len(p.samples)}
+	if len(p.samples) == 0 {
+		return health
+	}
+
+	latencies := []float64{}
+	succeeded := 0
+	total := 0.0
+	for _, sample := range p.samples {
+		if sample.err != nil {
+			continue
+		}
+		succeeded++
+		ms := float64(sample.latency.Microseconds()) / 1000
+		latencies = append(latencies, ms)
+		total += ms
+	}
+	health.SuccessRate = float64(succeeded) / float64(len(p.samples))
+	if succeeded > 0 {
+		sort.Float64s(latencies)
+		health.LatencyAvgMs = total / float64(succeeded)
+		health.LatencyP50Ms = latencies[(succeeded-1)*50/100]
+		health.LatencyP95Ms = latencies[(succeeded-1)*95/100]
+		health.LatencyMaxMs = latencies[succeeded-1]
+	}
+
+	last := p.samples[(p.next+len(p.samples)-1)%len(p.samples)]
+	health.LastProbeAt = last.at.UnixNano() / int64(time.Millisecond)
+	health.LastLatencyMs = float64(last.latency.Microseconds()) / 1000
+	if last.err != nil {
+		health.LastError = last.err.Error()
+	}
+	return health
+}
+
+// BackoffState describes how the refresh loop is currently pacing itself
+type BackoffState struct {
+	Throttled      bool  `json:"throttled"`
+	ThrottledUntil int64 `json:"throttled_until,omitempty"`
+	RefreshDelayMs int64 `json:"refresh_delay_ms"`
+	BaseIntervalMs int64 `json:"base_interval_ms"`
+}
+
+// UpstreamStatus is the response of /status
+type UpstreamStatus struct {
+	Status   string                  `json:"status"`
+	Upstream exchange.UpstreamHealth `json:"upstream"`
+	Probe    ProbeHealth             `json:"probe"`
+	Backoff  BackoffState            `json:"backoff"`
+	Datasets map[string]Freshness    `json:"datasets"`
+}
+
+// DatasetTimes returns when each dataset last refreshed successfully
+func (c *CryptoTracker) DatasetTimes() map[string]time.Time {
+	tickers := c.TickersUpdated()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var orderBooks time.Time
+	for _, updated := range c.orderBookTimes {
+		if updated.After(orderBooks) {
+			orderBooks = updated
+		}
+	}
+	times := map[string]time.Time{
+		"markets":     c.marketsUpdated,
+		"tickers":     tickers,
+		"order_books": orderBooks,
+		"fx":          c.fxUpdated,
+		"metadata":    c.metadataUpdated,
+	}
+	if FuturesEnabled() {
+		times["futures_instruments"] = c.futuresUpdated
+		times["futures_prices"] = c.MarketView().FuturesPricesModified
+	}
+	if LendingEnabled() {
+		times["lending_rates"] = c.lendingUpdated
+	}
+	return times
+}
+
+// DatasetFreshness is FreshnessOf for a named dataset. Slow moving datasets are only
+// stale once they miss their own refresh schedule.
+func DatasetFreshness(name string, updated time.Time) Freshness {
+	switch name {
+	case "markets", "futures_instruments", "lending_rates":
+		return freshnessWithin(updated, 2*marketsRefreshInterval())
+	case "fx":
+		return freshnessWithin(updated, 2*fxRefreshInterval())
+	case "metadata":
+		return freshnessWithin(updated, 2*metadataRefreshInterval())
+	}
+	return FreshnessOf(updated)
+}
@@ -0,0 +1,323 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	redisDefaultPort  = "6379"
+	redisTimeout      = 10 * time.Second
+	redisRetryBackoff = 5 * time.Second
+)
+
+// redisConn is a connection speaking RESP, the Redis protocol
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// DialRedis connects to redis://[[user]:password@]host[:port][/db], authenticating and
+// selecting the database when the URL asks for it
+func dialRedis(rawURL string) (*redisConn, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), redisDefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", address, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if password, ok := parsed.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := parsed.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := c.command(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" && db != "0" {
+		if _, err := c.command("SELECT", db); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Command sends a command and reads its reply
+func (c *redisConn) command(args ...string) (interface{}, error) {
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(redisTimeout))
+	return c.reply()
+}
+
+func (c *redisConn) send(args ...string) error {
+	var request strings.Builder
+	fmt.Fprintf(&request, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&request, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(redisTimeout))
+	_, err := io.WriteString(c.conn, request.String())
+	return err
+}
+
+// Reply reads one reply: a string, an int64, nil or a []interface{} of those. Error
+// replies are returned as errors.
+func (c *redisConn) reply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New("redis: " + line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.reply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+func (c *redisConn) close() {
+	c.conn.Close()
+}
+
+// redisChannel names the channel of an event type and symbol, e.g. cryptotracker:ticker:BTCINR
+func redisChannel(prefix, eventType, symbol string) string {
+	if prefix == "" {
+		prefix = config.DefaultRedisChannelPrefix
+	}
+	return prefix + ":" + eventType + ":" + symbol
+}
+
+// RedisPublisher publishes every event on its Redis channel
+type RedisPublisher struct {
+	url     string
+	prefix  string
+	conn    *redisConn
+	retryAt time.Time
+	mutex   sync.Mutex
+}
+
+func newRedisPublisher(cfg config.Config) *RedisPublisher {
+	return &RedisPublisher{url: cfg.RedisURL, prefix: cfg.RedisChannelPrefix}
+}
+
+func (p *RedisPublisher) Publish(event MarketEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if time.Now().Before(p.retryAt) {
+		return nil
+	}
+	if p.conn == nil {
+		if p.conn, err = dialRedis(p.url); err != nil {
+			p.retryAt = time.Now().Add(redisRetryBackoff)
+			return fmt.Errorf("redis: %v", err)
+		}
+	}
+	if _, err := p.conn.command("PUBLISH", redisChannel(p.prefix, event.Type, event.Symbol), string(payload)); err != nil {
+		p.conn.close()
+		p.conn = nil
+		p.retryAt = time.Now().Add(redisRetryBackoff)
+		return err
+	}
+	return nil
+}
+
+func (p *RedisPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn != nil {
+		p.conn.close()
+		p.conn = nil
+	}
+	return nil
+}
+
+// RedisFollower lets a replica take ticker and order book updates from another tracker's
+// Redis channels instead of polling the exchange itself. While it is subscribed the
+// tracker skips its own ticker and order book refreshes.
+type RedisFollower struct {
+	tracker   *CryptoTracker
+	url       string
+	prefix    string
+	connected bool
+	mutex     sync.Mutex
+}
+
+func NewRedisFollower(tracker *CryptoTracker, cfg config.Config) *RedisFollower {
+	return &RedisFollower{tracker: tracker, url: cfg.RedisURL, prefix: cfg.RedisChannelPrefix}
+}
+
+// Start subscribes in the background, resubscribing with backoff until ctx is cancelled
+func (f *RedisFollower) Start(ctx context.Context) {
+	go func() {
+		backoff := streamMinBackoff
+		for ctx.Err() == nil {
+			began := time.Now()
+			err := f.follow(ctx)
+			f.setConnected(false)
+			if ctx.Err() != nil {
+				return
+			}
+			logging.Warn("Redis follower disconnected:", err)
+			if time.Since(began) > streamMaxBackoff {
+				backoff = streamMinBackoff
+			}
+			exchange.SleepContext(ctx, backoff)
+			if backoff *= 2; backoff > streamMaxBackoff {
+				backoff = streamMaxBackoff
+			}
+		}
+	}()
+}
+
+func (f *RedisFollower) isConnected() bool {
+	if f == nil {
+		return false
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.connected
+}
+
+func (f *RedisFollower) setConnected(connected bool) {
+	f.mutex.Lock()
+	f.connected = connected
+	f.mutex.Unlock()
+}
+
+// Follow subscribes and applies messages until the connection fails or ctx is cancelled
+func (f *RedisFollower) follow(ctx context.Context) error {
+	conn, err := dialRedis(f.url)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+	stop := context.AfterFunc(ctx, conn.close)
+	defer stop()
+
+	err = conn.send("PSUBSCRIBE", redisChannel(f.prefix, eventTicker, "*"), redisChannel(f.prefix, eventOrderBook, "*"))
+	if err != nil {
+		return err
+	}
+	for {
+		// Subscribed connections only ever receive, so they wait without a deadline
+		conn.conn.SetReadDeadline(time.Time{})
+		reply, err := conn.reply()
+		if err != nil {
+			return err
+		}
+		message, ok := reply.([]interface{})
+		if !ok || len(message) == 0 {
+			continue
+		}
+		switch message[0] {
+		case "psubscribe":
+			if count, _ := message[len(message)-1].(int64); count == 2 {
+				logging.Info("Following updates on Redis channels", redisChannel(f.prefix, "*", "*"))
+				f.setConnected(true)
+			}
+		case "pmessage":
+			if payload, ok := message[len(message)-1].(string); ok && len(message) == 4 {
+				f.apply([]byte(payload))
+			}
+		}
+	}
+}
+
+// Apply stores a ticker or order book another tracker published. It writes the tracker's
+// maps directly rather than through a refresh, so followed updates are never republished.
+func (f *RedisFollower) apply(payload []byte) {
+	var event struct {
+		Type   string          `json:"type"`
+		Symbol string          `json:"symbol"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		logging.Error("Error parsing Redis update:", err)
+		return
+	}
+	c := f.tracker
+	now := time.Now()
+	switch event.Type {
+	case eventTicker:
+		var ticker exchange.TickerDetails
+		if err := json.Unmarshal(event.Data, &ticker); err != nil || ticker.Market == "" {
+			return
+		}
+		c.updateTickers(func(batch *tickerBatch) { batch.set(ticker.Market, ticker, now) })
+		if price, err := strconv.ParseFloat(ticker.LastPrice, 64); err == nil {
+			c.observePrice(ticker.Market, price, ticker.Timestamp, now)
+		}
+	case eventOrderBook:
+		var orderBook exchange.OrderBook
+		if err := json.Unmarshal(event.Data, &orderBook); err != nil {
+			return
+		}
+		c.mutex.Lock()
+		if pair, exists := c.marketPairs[event.Symbol]; exists {
+			c.orderBooks[pair] = orderBook
+			c.orderBookTimes[pair] = now
+		}
+		c.mutex.Unlock()
+	}
+}
@@ -0,0 +1,351 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	historyCompactionInterval = time.Hour
+)
+
+// CandleResolutions are the downsampled resolutions, finest first. Each is stored in a
+// subdirectory of the market's history directory named after it.
+var CandleResolutions = []struct {
+	Name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"1h", time.Hour},
+}
+
+// Candle summarizes a market's last traded price over one interval starting at Timestamp
+type Candle struct {
+	Market    string  `json:"market"`
+	Timestamp int64   `json:"timestamp"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	// Points is how many raw tickers went into the candle; 0 when it was backfilled from
+	// upstream
+	Points int `json:"points"`
+}
+
+// PointCandles turns raw points into one-point candles so they downsample like candles
+func pointCandles(points []HistoryPoint) []Candle {
+	candles := make([]Candle, 0, len(points))
+	for _, point := range points {
+		if point.LastPrice <= 0 {
+			continue
+		}
+		candles = append(candles, Candle{
+			Market: point.Market, Timestamp: point.Timestamp,
+			Open: point.LastPrice, High: point.LastPrice, Low: point.LastPrice, Close: point.LastPrice,
+			Points: 1,
+		})
+	}
+	return candles
+}
+
+// Downsample merges time-ordered candles into candles of the given resolution
+func downsample(candles []Candle, resolution time.Duration) []Candle {
+	merged := []Candle{}
+	for _, candle := range candles {
+		start := exchange.CandleStart(candle.Timestamp, resolution)
+		if n := len(merged); n > 0 && merged[n-1].Timestamp == start {
+			last := &merged[n-1]
+			if candle.High > last.High {
+				last.High = candle.High
+			}
+			if candle.Low < last.Low {
+				last.Low = candle.Low
+			}
+			last.Close = candle.Close
+			last.Points += candle.Points
+			continue
+		}
+		candle.Timestamp = start
+		merged = append(merged, candle)
+	}
+	return merged
+}
+
+// HistoryRetention returns how many days raw points, 1m candles and 1h candles are kept;
+// 0 keeps that level forever
+func historyRetention() (raw, minute, hour int) {
+	cfg := config.Current()
+	return cfg.HistoryRawRetention, cfg.HistoryMinuteRetention, cfg.HistoryHourRetention
+}
+
+// CompactHistory downsamples and prunes persisted history according to the retention
+// settings, then backfills its gaps
+func (c *CryptoTracker) compactHistory(ctx context.Context) {
+	if c.history.dir == "" {
+		return
+	}
+	raw, minute, hour := historyRetention()
+	if err := c.history.compact(ctx, time.Now(), []int{raw, minute, hour}); err != nil {
+		logging.Error("Error compacting history:", err)
+	}
+	c.backfillHistory(ctx)
+}
+
+// Compact walks every market's history. Raw days older than retention[0] days become 1m
+// candles, 1m candle days older than retention[1] become 1h candles and 1h candle days
+// older than retention[2] are deleted. A level with 0 retention is left alone. Each day
+// is written before its source is removed, so an interrupted run just redoes that day.
+func (h *HistoryStore) compact(ctx context.Context, now time.Time, retention []int) error {
+	markets, err := h.Markets()
+	if err != nil {
+		return err
+	}
+	expired := func(day string, days int) bool {
+		start, err := time.Parse(HistoryDayLayout, day)
+		return err == nil && days > 0 && start.Add(24*time.Hour).Before(now.AddDate(0, 0, -days))
+	}
+
+	for _, market := range markets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		days, err := h.Days(market)
+		if err != nil {
+			return err
+		}
+		// Level 0 is raw points; level i > 0 is CandleResolutions[i-1]
+		for level := 0; level <= len(CandleResolutions); level++ {
+			if level > 0 {
+				if days, err = h.candleDays(market, CandleResolutions[level-1].Name); err != nil {
+					return err
+				}
+			}
+			for _, day := range days {
+				if !expired(day, retention[level]) {
+					continue
+				}
+				if err := h.compactDay(market, day, level); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CompactDay moves one day of a level into the next coarser level, or drops it from the last
+func (h *HistoryStore) compactDay(market, day string, level int) error {
+	var source string
+	var candles []Candle
+	if level == 0 {
+		points, err := h.ReadDay(market, day)
+		if err != nil {
+			return err
+		}
+		candles = pointCandles(points)
+		source = filepath.Join(h.dir, market, day+".jsonl")
+	} else {
+		name := CandleResolutions[level-1].Name
+		var err error
+		if candles, err = h.readCandleDay(market, name, day); err != nil {
+			return err
+		}
+		source = filepath.Join(h.dir, market, name, day+".jsonl")
+	}
+
+	if level < len(CandleResolutions) {
+		resolution := CandleResolutions[level]
+		// Merge with what an earlier, interrupted run may have written
+		existing, err := h.readCandleDay(market, resolution.Name, day)
+		if err != nil {
+			return err
+		}
+		merged := append(existing, candles...)
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp < merged[j].Timestamp })
+		if err := h.writeCandleDay(market, resolution.Name, day, downsample(merged, resolution.duration)); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(source); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CandleDays lists the UTC days a market has stored candles for at a resolution
+func (h *HistoryStore) candleDays(market, resolution string) ([]string, error) {
+	dir, err := h.marketDir(market)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(dir, resolution))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	days := []string{}
+	for _, entry := range entries {
+		day := strings.TrimSuffix(entry.Name(), ".jsonl")
+		if _, err := time.Parse(HistoryDayLayout, day); err == nil && !entry.IsDir() {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// ReadCandleDay reads a day of stored candles; a missing day reads as empty
+func (h *HistoryStore) readCandleDay(market, resolution, day string) ([]Candle, error) {
+	dir, err := h.marketDir(market)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(dir, resolution, day+".jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	candles := []Candle{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var candle Candle
+		if err := json.Unmarshal(scanner.Bytes(), &candle); err == nil {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, scanner.Err()
+}
+
+// WriteCandleDay replaces a day of stored candles atomically
+func (h *HistoryStore) writeCandleDay(market, resolution, day string, candles []Candle) error {
+	dir, err := h.marketDir(market)
+	if err != nil {
+		return err
+	}
+	dir = filepath.Join(dir, resolution)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var data []byte
+	for _, candle := range candles {
+		line, err := json.Marshal(candle)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	path := filepath.Join(dir, day+".jsonl")
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Candles returns a market's candles at any interval ParseCandleInterval accepts between
+// from and to, oldest first. They are downsampled from the coarsest stored resolution that
+// divides the interval: days compaction has written at that resolution are served from
+// its candles, and others from the finer data still on disk or in memory.
+func (h *HistoryStore) Candles(market string, duration time.Duration, from, to time.Time, limit int) ([]Candle, error) {
+	var candles []Candle
+	if h.dir == "" {
+		points, err := h.Query(market, from, to, 0)
+		if err != nil {
+			return nil, err
+		}
+		candles = downsample(pointCandles(points), duration)
+	} else {
+		var err error
+		if candles, err = h.storedCandles(market, duration, from, to); err != nil {
+			return nil, err
+		}
+		// Candles longer than a day span the days they were downsampled by
+		candles = downsample(candles, duration)
+	}
+
+	inRange := candles[:0]
+	for _, candle := range candles {
+		at := time.Unix(0, candle.Timestamp*int64(time.Millisecond))
+		if (from.IsZero() || !at.Add(duration).Before(from)) && (to.IsZero() || !at.After(to)) {
+			inRange = append(inRange, candle)
+		}
+	}
+	if limit > 0 && len(inRange) > limit {
+		inRange = inRange[len(inRange)-limit:]
+	}
+	return inRange, nil
+}
+
+func (h *HistoryStore) storedCandles(market string, duration time.Duration, from, to time.Time) ([]Candle, error) {
+	// Collect every day held at any level, finest first, remembering which levels have it
+	levels := map[string][]string{}
+	order := []string{}
+	add := func(level string, days []string) {
+		for _, day := range days {
+			if levels[day] == nil {
+				order = append(order, day)
+			}
+			levels[day] = append(levels[day], level)
+		}
+	}
+	rawDays, err := h.Days(market)
+	if err != nil {
+		return nil, err
+	}
+	add("", rawDays)
+	for _, candidate := range CandleResolutions {
+		if candidate.duration > duration || duration%candidate.duration != 0 {
+			break
+		}
+		days, err := h.candleDays(market, candidate.Name)
+		if err != nil {
+			return nil, err
+		}
+		add(candidate.Name, days)
+	}
+	sort.Strings(order)
+
+	candles := []Candle{}
+	for _, day := range order {
+		start, _ := time.Parse(HistoryDayLayout, day)
+		if (!to.IsZero() && start.After(to)) || (!from.IsZero() && start.Add(24*time.Hour).Before(from)) {
+			continue
+		}
+		// Part of a day can sit at several levels while compaction runs, so merge them all
+		dayCandles := []Candle{}
+		for _, level := range levels[day] {
+			if level == "" {
+				points, err := h.ReadDay(market, day)
+				if err != nil {
+					return nil, err
+				}
+				dayCandles = append(dayCandles, pointCandles(points)...)
+				continue
+			}
+			stored, err := h.readCandleDay(market, level, day)
+			if err != nil {
+				return nil, err
+			}
+			dayCandles = append(dayCandles, stored...)
+		}
+		sort.SliceStable(dayCandles, func(i, j int) bool { return dayCandles[i].Timestamp < dayCandles[j].Timestamp })
+		candles = append(candles, downsample(dayCandles, duration)...)
+	}
+	return candles, nil
+}
@@ -0,0 +1,102 @@
+package tracker
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// refreshJob is one dataset refreshed on its own schedule
+type refreshJob struct {
+	name     string
+	interval func() time.Duration
+	// backoff stretches the interval while the exchange is failing or throttling
+	backoff bool
+	// waitFirst skips the immediate first run for data loaded before the scheduler starts
+	waitFirst bool
+	run       func(ctx context.Context)
+}
+
+// RefreshJobs lists the datasets kept fresh in the background and how often each refreshes
+func (c *CryptoTracker) refreshJobs() []refreshJob {
+	return []refreshJob{
+		{name: "markets", interval: c.retryUntilLoaded(&c.marketsUpdated, marketsRefreshInterval), backoff: true, waitFirst: true, run: c.RefreshMarketData},
+		{name: "cycle", interval: RefreshInterval, backoff: true, run: c.refreshCycle},
+		{name: "order_books", interval: adaptiveCadence(RefreshInterval), backoff: true, run: c.refreshPriorityOrderBooks},
+		// Streamed order books refresh on their own, faster cadence
+		{name: "subscribed_order_books", interval: adaptiveCadence(orderBookRefreshInterval), run: c.refreshSubscribedOrderBooks},
+		{name: "fx", interval: c.retryUntilLoaded(&c.fxUpdated, fxRefreshInterval), run: c.refreshFXRates},
+		{name: "metadata", interval: c.retryUntilLoaded(&c.metadataUpdated, metadataRefreshInterval), run: c.refreshCoinMetadata},
+		{name: "futures_instruments", interval: c.retryUntilLoaded(&c.futuresUpdated, marketsRefreshInterval), backoff: true, run: c.refreshFuturesInstruments},
+		{name: "futures_prices", interval: RefreshInterval, backoff: true, run: c.refreshFuturesPrices},
+		{name: "lending_rates", interval: c.retryUntilLoaded(&c.lendingUpdated, marketsRefreshInterval), backoff: true, run: c.refreshLendingRates},
+		{name: "history_compaction", interval: func() time.Duration { return historyCompactionInterval }, run: c.compactHistory},
+		{name: "snapshot_dump", interval: snapshotDumpCadence, waitFirst: true, run: c.dumpSnapshot},
+	}
+}
+
+// Schedule runs job repeatedly under a supervisor until ctx is cancelled
+func (c *CryptoTracker) schedule(ctx context.Context, job refreshJob) {
+	go c.supervise(ctx, job)
+}
+
+// RunJob is a job's loop, reading its interval before every wait so configuration reloads
+// take effect on the next run. Each run is reported to heartbeat.
+func (c *CryptoTracker) runJob(ctx context.Context, job refreshJob, waitFirst bool, heartbeat *jobHeartbeat) {
+	if waitFirst && !exchange.SleepContext(ctx, withJitter(job.interval())) {
+		return
+	}
+	for ctx.Err() == nil {
+		heartbeat.set(time.Now())
+		c.stats.timeRefresh(job.name, func() { job.run(ctx) })
+		heartbeat.set(time.Time{})
+		delay := job.interval()
+		if job.backoff {
+			// Back off while upstream is failing or has asked us to slow down
+			delay = c.httpClient.State.RefreshDelay(delay)
+		}
+		exchange.SleepContext(ctx, withJitter(delay))
+	}
+}
+
+// RetryUntilLoaded wraps a slow interval so a dataset that has never loaded, for example
+// because upstream was down at startup, is retried at the ticker cadence instead
+func (c *CryptoTracker) retryUntilLoaded(updated *time.Time, interval func() time.Duration) func() time.Duration {
+	return func() time.Duration {
+		c.mutex.RLock()
+		loaded := !updated.IsZero()
+		c.mutex.RUnlock()
+		if !loaded {
+			return RefreshInterval()
+		}
+		return interval()
+	}
+}
+
+// WithJitter spreads delay by up to RefreshJitter in either direction
+func withJitter(delay time.Duration) time.Duration {
+	fraction := config.Current().RefreshJitter
+	if fraction <= 0 {
+		return delay
+	}
+	return delay + time.Duration((rand.Float64()*2-1)*fraction*float64(delay))
+}
+
+// MarketsRefreshInterval returns how often market details are refetched
+func marketsRefreshInterval() time.Duration {
+	if interval := config.Current().MarketsRefreshInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultMarketsRefreshInterval
+}
+
+// OrderBookRefreshInterval returns how often streamed order books are refetched
+func orderBookRefreshInterval() time.Duration {
+	if interval := config.Current().OrderBookRefreshInterval; interval > 0 {
+		return time.Duration(interval) * time.Millisecond
+	}
+	return orderBookCacheTTL()
+}
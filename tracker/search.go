@@ -0,0 +1,138 @@
+package tracker
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchMatch is a market matching a search query with its rank
+type SearchMatch struct {
+	exchange.MarketDetails
+	Score float64 `json:"score"`
+	// MatchedField is the JSON name of the field that matched best
+	MatchedField string `json:"matched_field"`
+}
+
+// searchField is one market field searched, weighted by how likely users are to type it
+type searchField struct {
+	name   string
+	value  func(exchange.MarketDetails) string
+	weight float64
+}
+
+var searchFields = []searchField{
+	{"coindcx_name", func(m exchange.MarketDetails) string { return m.CoindcxName }, 1},
+	{"target_currency_short_name", func(m exchange.MarketDetails) string { return m.TargetCurrencyShortName }, 1},
+	{"symbol", func(m exchange.MarketDetails) string { return m.Symbol }, 0.95},
+	{"target_currency_name", func(m exchange.MarketDetails) string { return m.TargetCurrencyName }, 0.9},
+	{"base_currency_short_name", func(m exchange.MarketDetails) string { return m.BaseCurrencyShortName }, 0.6},
+	{"base_currency_name", func(m exchange.MarketDetails) string { return m.BaseCurrencyName }, 0.5},
+}
+
+// MatchScore rates how well query matches value, both lower case: exact matches beat
+// prefixes, which beat substrings, which beat near misses of a typo or two. Zero means no
+// match.
+func matchScore(query, value string) float64 {
+	switch {
+	case value == "":
+		return 0
+	case value == query:
+		return 100
+	case strings.HasPrefix(value, query):
+		// Prefer the shortest completions, so "sol" ranks SOL above SOLVE
+		return 80 + 10*float64(len(query))/float64(len(value))
+	case strings.Contains(value, query):
+		return 60 + 10*float64(len(query))/float64(len(value))
+	}
+	allowed := 0
+	if len(query) >= 4 {
+		allowed = 1
+	}
+	if len(query) >= 8 {
+		allowed = 2
+	}
+	if allowed == 0 {
+		return 0
+	}
+	// Compare against the value and against its prefix of the query's length, so typos
+	// in a partially typed name still match
+	distance := editDistance(query, value)
+	if len(value) > len(query) {
+		if prefix := editDistance(query, value[:len(query)]); prefix < distance {
+			distance = prefix
+		}
+	}
+	if distance > allowed {
+		return 0
+	}
+	return 40 - 10*float64(distance)
+}
+
+// EditDistance is the Levenshtein distance between two strings, counted in bytes
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// SearchMarkets ranks the markets matching query, best first, limited to those passing
+// the status filter
+func (c *CryptoTracker) SearchMarkets(query, status string, limit int) []SearchMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := []SearchMatch{}
+	c.mutex.RLock()
+	for name, market := range c.marketDetails {
+		if !c.marketStatusMatchesLocked(name, status) {
+			continue
+		}
+		best := SearchMatch{MarketDetails: market}
+		for _, field := range searchFields {
+			if score := matchScore(query, strings.ToLower(field.value(market))) * field.weight; score > best.Score {
+				best.Score, best.MatchedField = score, field.name
+			}
+		}
+		// Upstream often repeats the ticker as the currency name, so also try the coin's
+		// name from its metadata, e.g. Solana for SOL
+		if metadata, exists := c.coinMetadata[strings.ToUpper(market.TargetCurrencyShortName)]; exists {
+			if score := matchScore(query, strings.ToLower(metadata.Name)) * 0.9; score > best.Score {
+				best.Score, best.MatchedField = score, "metadata.name"
+			}
+		}
+		if best.Score > 0 {
+			matches = append(matches, best)
+		}
+	}
+	c.mutex.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].CoindcxName < matches[j].CoindcxName
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
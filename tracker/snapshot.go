@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is every ticker, market and rate as of a single generation of the tracker,
+// copied under one lock so no part of it comes from a different refresh
+type Snapshot struct {
+	Generation  uint64               `json:"generation"`
+	GeneratedAt int64                `json:"generated_at"`
+	Tickers     []TickerView         `json:"tickers"`
+	Markets     []MarketWithMetadata `json:"markets"`
+	FXRates     map[string]float64   `json:"fx_rates"`
+	Datasets    map[string]Freshness `json:"datasets"`
+}
+
+// Snapshot copies the tracker's data in one critical section
+func (c *CryptoTracker) Snapshot() Snapshot {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	// Views are swapped under the lock, so this one matches generation
+	view := c.MarketView()
+
+	snapshot := Snapshot{
+		Generation:  c.generation,
+		GeneratedAt: time.Now().UnixNano() / int64(time.Millisecond),
+		Tickers:     make([]TickerView, 0, len(view.Tickers)),
+		Markets:     make([]MarketWithMetadata, 0, len(view.Markets)),
+		FXRates:     make(map[string]float64, len(view.fxRates)),
+	}
+	var tickersUpdated time.Time
+	for market, ticker := range view.Tickers {
+		updated := view.Times[market]
+		if updated.After(tickersUpdated) {
+			tickersUpdated = updated
+		}
+		snapshot.Tickers = append(snapshot.Tickers, TickerView{TickerDetails: ticker, Freshness: FreshnessOf(updated)})
+	}
+	for _, market := range view.Markets {
+		entry := MarketWithMetadata{MarketDetails: market}
+		if metadata, exists := c.coinMetadata[strings.ToUpper(market.TargetCurrencyShortName)]; exists {
+			entry.Metadata = &metadata
+		}
+		snapshot.Markets = append(snapshot.Markets, entry)
+	}
+	for currency, rate := range view.fxRates {
+		snapshot.FXRates[currency] = rate
+	}
+	snapshot.Datasets = map[string]Freshness{
+		"markets":  DatasetFreshness("markets", c.marketsUpdated),
+		"tickers":  DatasetFreshness("tickers", tickersUpdated),
+		"fx":       DatasetFreshness("fx", c.fxUpdated),
+		"metadata": DatasetFreshness("metadata", c.metadataUpdated),
+	}
+
+	sort.Slice(snapshot.Tickers, func(i, j int) bool { return snapshot.Tickers[i].Market < snapshot.Tickers[j].Market })
+	sort.Slice(snapshot.Markets, func(i, j int) bool {
+		return snapshot.Markets[i].CoindcxName < snapshot.Markets[j].CoindcxName
+	})
+	return snapshot
+}
+
+// SnapshotVersion returns the generation a snapshot taken now would have and the latest
+// change to any of its datasets
+func (c *CryptoTracker) SnapshotVersion() (uint64, time.Time) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	view := c.MarketView()
+	return c.generation, LatestOf(view.TickersModified, view.MarketsModified, view.FXModified, c.metadataUpdated)
+}
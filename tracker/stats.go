@@ -0,0 +1,167 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+)
+
+// TrackerStats collects operational counters reported by /admin/stats
+type TrackerStats struct {
+	StartedAt         time.Time
+	refreshCycles     int64
+	lastCycleDuration time.Duration
+	lastDurations     map[string]time.Duration
+	lastRefreshAt     map[string]time.Time
+	// failures counts each dataset's refreshes that failed since its last success
+	failures map[string]int
+	// restarts counts how often each refresh job's supervisor restarted it
+	restarts    map[string]int
+	cacheHits   int64
+	cacheMisses int64
+	clients     map[string]int
+	mutex       sync.Mutex
+}
+
+func newTrackerStats() *TrackerStats {
+	return &TrackerStats{
+		StartedAt:     time.Now(),
+		lastDurations: make(map[string]time.Duration),
+		lastRefreshAt: make(map[string]time.Time),
+		failures:      make(map[string]int),
+		restarts:      make(map[string]int),
+		clients:       make(map[string]int),
+	}
+}
+
+// TimeRefresh runs one refresh step and records how long it took under name
+func (s *TrackerStats) timeRefresh(name string, refresh func()) {
+	started := time.Now()
+	refresh()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastDurations[name] = time.Since(started)
+	s.lastRefreshAt[name] = time.Now()
+}
+
+// RecordRefreshResult counts a failed refresh of a dataset, or resets the count on success
+func (s *TrackerStats) recordRefreshResult(dataset string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil {
+		s.failures[dataset]++
+	} else {
+		s.failures[dataset] = 0
+	}
+}
+
+// ConsecutiveFailures returns how many refreshes of a dataset have failed in a row
+func (s *TrackerStats) consecutiveFailures(dataset string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.failures[dataset]
+}
+
+func (s *TrackerStats) recordRestart(job string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.restarts[job]++
+}
+
+func (s *TrackerStats) recordCycle(duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.refreshCycles++
+	s.lastCycleDuration = duration
+}
+
+// RecordCacheLookup counts whether a request could be served from a fresh cached copy
+func (s *TrackerStats) recordCacheLookup(hit bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if hit {
+		s.cacheHits++
+	} else {
+		s.cacheMisses++
+	}
+}
+
+// ClientConnected counts a streaming client of the given kind until the returned function is called
+func (s *TrackerStats) ClientConnected(kind string) func() {
+	s.mutex.Lock()
+	s.clients[kind]++
+	s.mutex.Unlock()
+	return func() {
+		s.mutex.Lock()
+		s.clients[kind]--
+		s.mutex.Unlock()
+	}
+}
+
+// RefreshStats is the refresh loop section of /admin/stats
+type RefreshStats struct {
+	Cycles          int64            `json:"cycles"`
+	LastCycleMs     int64            `json:"last_cycle_ms"`
+	LastDurationsMs map[string]int64 `json:"last_durations_ms"`
+	LastRefreshedAt map[string]int64 `json:"last_refreshed_at"`
+	// Restarts counts the jobs restarted after panicking or wedging
+	Restarts map[string]int `json:"restarts"`
+}
+
+// CacheStats is the order book cache section of /admin/stats
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// AdminStats is the response of /admin/stats
+type AdminStats struct {
+	UptimeSeconds int64                   `json:"uptime_seconds"`
+	StartedAt     int64                   `json:"started_at"`
+	Refresh       RefreshStats            `json:"refresh"`
+	Upstream      exchange.UpstreamHealth `json:"upstream"`
+	// UpstreamLatency holds call latencies and SLO compliance per upstream endpoint
+	UpstreamLatency map[string]exchange.LatencyStats `json:"upstream_latency"`
+	Markets         int                              `json:"markets"`
+	OrderBooks      int                              `json:"order_books"`
+	Clients         map[string]int                   `json:"clients"`
+	Cache           CacheStats                       `json:"cache"`
+	Goroutines      int                              `json:"goroutines"`
+	// AdaptiveIntervalsMs is each watched symbol's current order book refresh interval
+	AdaptiveIntervalsMs map[string]int64 `json:"adaptive_intervals_ms,omitempty"`
+}
+
+func (s *TrackerStats) RefreshStats() (RefreshStats, CacheStats, map[string]int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	refresh := RefreshStats{
+		Cycles:          s.refreshCycles,
+		LastCycleMs:     s.lastCycleDuration.Milliseconds(),
+		LastDurationsMs: make(map[string]int64),
+		LastRefreshedAt: make(map[string]int64),
+		Restarts:        make(map[string]int),
+	}
+	for job, count := range s.restarts {
+		refresh.Restarts[job] = count
+	}
+	for name, duration := range s.lastDurations {
+		refresh.LastDurationsMs[name] = duration.Milliseconds()
+	}
+	for name, at := range s.lastRefreshAt {
+		refresh.LastRefreshedAt[name] = at.UnixNano() / int64(time.Millisecond)
+	}
+
+	cache := CacheStats{Hits: s.cacheHits, Misses: s.cacheMisses}
+	if total := s.cacheHits + s.cacheMisses; total > 0 {
+		cache.HitRate = float64(s.cacheHits) / float64(total)
+	}
+
+	clients := map[string]int{"sse": 0, "websocket": 0}
+	for kind, count := range s.clients {
+		clients[kind] = count
+	}
+	return refresh, cache, clients
+}
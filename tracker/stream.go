@@ -0,0 +1,367 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/websocket"
+)
+
+const (
+	streamPricesChannel    = "currentPrices@spot@1s"
+	streamDialTimeout      = 10 * time.Second
+	streamMinBackoff       = time.Second
+	streamMaxBackoff       = time.Minute
+	streamResubscribeEvery = 5 * time.Second
+)
+
+// engineOpen is the handshake packet sent by an Engine.IO server
+type engineOpen struct {
+	SID          string `json:"sid"`
+	PingInterval int    `json:"pingInterval"`
+	PingTimeout  int    `json:"pingTimeout"`
+}
+
+// CoinDCXStream keeps tickers and subscribed order books current over CoinDCX's Socket.IO feed
+type CoinDCXStream struct {
+	tracker   *CryptoTracker
+	url       string
+	conn      *websocket.Conn
+	joined    map[string]bool
+	connected bool
+	isRunning bool
+	mutex     sync.Mutex
+}
+
+func NewCoinDCXStream(tracker *CryptoTracker, streamURL string) *CoinDCXStream {
+	if streamURL == "" {
+		streamURL = config.DefaultStreamURL
+	}
+	return &CoinDCXStream{
+		tracker: tracker,
+		url:     strings.TrimRight(streamURL, "/") + "/socket.io/?EIO=3&transport=websocket",
+		joined:  make(map[string]bool),
+	}
+}
+
+// Start connects to the feed and keeps reconnecting with backoff until stopped
+func (s *CoinDCXStream) Start() {
+	s.mutex.Lock()
+	s.isRunning = true
+	s.mutex.Unlock()
+
+	go func() {
+		backoff := streamMinBackoff
+		for s.running() {
+			began := time.Now()
+			err := s.session()
+			if !s.running() {
+				return
+			}
+			logging.Warn("Stream disconnected:", err)
+
+			// A session that stayed up for a while resets the backoff
+			if time.Since(began) > streamMaxBackoff {
+				backoff = streamMinBackoff
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > streamMaxBackoff {
+				backoff = streamMaxBackoff
+			}
+		}
+	}()
+}
+
+// Stop closes the feed connection and ends reconnection attempts
+func (s *CoinDCXStream) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.isRunning = false
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func (s *CoinDCXStream) running() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.isRunning
+}
+
+// Connected reports whether the feed currently has a live session
+func (s *CoinDCXStream) isConnected() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.connected
+}
+
+// Session runs one connection from handshake until it fails
+func (s *CoinDCXStream) session() error {
+	conn, err := exchange.DialWebSocket(s.url, streamDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The first packet is the Engine.IO open handshake carrying heartbeat timings
+	conn.SetReadDeadline(time.Now().Add(streamDialTimeout))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if len(message) == 0 || message[0] != '0' {
+		return fmt.Errorf("unexpected handshake packet %q", message)
+	}
+	var open engineOpen
+	if err := json.Unmarshal(message[1:], &open); err != nil {
+		return err
+	}
+	pingInterval := time.Duration(open.PingInterval) * time.Millisecond
+	pingTimeout := time.Duration(open.PingTimeout) * time.Millisecond
+	if pingInterval <= 0 {
+		pingInterval = 25 * time.Second
+	}
+	if pingTimeout <= 0 {
+		pingTimeout = 20 * time.Second
+	}
+
+	s.mutex.Lock()
+	s.conn = conn
+	s.connected = true
+	s.joined = make(map[string]bool)
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.conn = nil
+		s.connected = false
+		s.mutex.Unlock()
+	}()
+	logging.Info("Stream connected:", open.SID)
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.heartbeat(conn, pingInterval, done)
+
+	for {
+		// Any packet, including pongs, proves the connection is alive
+		conn.SetReadDeadline(time.Now().Add(pingInterval + pingTimeout))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if err := s.handlePacket(conn, message); err != nil {
+			return err
+		}
+	}
+}
+
+// Heartbeat sends Engine.IO pings and keeps channel subscriptions in sync
+func (s *CoinDCXStream) heartbeat(conn *websocket.Conn, pingInterval time.Duration, done chan struct{}) {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	resubscribe := time.NewTicker(streamResubscribeEvery)
+	defer resubscribe.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ping.C:
+			if err := conn.WriteMessage(websocket.OpText, []byte("2")); err != nil {
+				conn.Close()
+				return
+			}
+		case <-resubscribe.C:
+			if err := s.syncSubscriptions(conn); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// HandlePacket dispatches a single Engine.IO packet
+func (s *CoinDCXStream) handlePacket(conn *websocket.Conn, message []byte) error {
+	if len(message) == 0 {
+		return nil
+	}
+	switch message[0] {
+	case '2':
+		return conn.WriteMessage(websocket.OpText, append([]byte("3"), message[1:]...))
+	case '3':
+		return nil
+	case '1':
+		return errors.New("server closed the session")
+	case '4':
+		return s.handleSocketPacket(conn, message[1:])
+	}
+	return nil
+}
+
+// HandleSocketPacket dispatches a Socket.IO packet carried inside an Engine.IO message
+func (s *CoinDCXStream) handleSocketPacket(conn *websocket.Conn, packet []byte) error {
+	if len(packet) == 0 {
+		return nil
+	}
+	switch packet[0] {
+	case '0':
+		// Namespace connected, (re)join every channel
+		return s.syncSubscriptions(conn)
+	case '1':
+		return errors.New("namespace disconnected")
+	case '2':
+		var event []json.RawMessage
+		if err := json.Unmarshal(packet[1:], &event); err != nil || len(event) < 2 {
+			return nil
+		}
+		var name string
+		if err := json.Unmarshal(event[0], &name); err != nil {
+			return nil
+		}
+		s.handleEvent(name, event[1])
+	}
+	return nil
+}
+
+// DesiredChannels lists the channels the feed should be joined to
+func (s *CoinDCXStream) desiredChannels() map[string]bool {
+	channels := map[string]bool{streamPricesChannel: true}
+	c := s.tracker
+	symbols := c.OrderBookSymbols()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, symbol := range symbols {
+		if pair, exists := c.marketPairs[symbol]; exists && pair != "" {
+			channels[pair+"@orderbook@20"] = true
+		}
+	}
+	return channels
+}
+
+// SyncSubscriptions joins new channels and leaves channels no longer needed
+func (s *CoinDCXStream) syncSubscriptions(conn *websocket.Conn) error {
+	desired := s.desiredChannels()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for channel := range desired {
+		if !s.joined[channel] {
+			if err := emitSocketEvent(conn, "join", map[string]string{"channelName": channel}); err != nil {
+				return err
+			}
+			s.joined[channel] = true
+		}
+	}
+	for channel := range s.joined {
+		if !desired[channel] {
+			if err := emitSocketEvent(conn, "leave", map[string]string{"channelName": channel}); err != nil {
+				return err
+			}
+			delete(s.joined, channel)
+		}
+	}
+	return nil
+}
+
+func emitSocketEvent(conn *websocket.Conn, name string, payload interface{}) error {
+	encoded, err := json.Marshal([]interface{}{name, payload})
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.OpText, append([]byte("42"), encoded...))
+}
+
+// streamEnvelope wraps most CoinDCX events; the inner data is usually itself a JSON string
+type streamEnvelope struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// streamDepth is an order book snapshot pushed by the feed
+type streamDepth struct {
+	Symbol string            `json:"s"`
+	Pair   string            `json:"pair"`
+	Bids   map[string]string `json:"bids"`
+	Asks   map[string]string `json:"asks"`
+}
+
+// streamPrices is a batch of last traded prices keyed by market
+type streamPrices struct {
+	Timestamp int64                      `json:"ts"`
+	Prices    map[string]json.RawMessage `json:"prices"`
+}
+
+// UnwrapStreamData returns the event payload, decoding a nested JSON string if present
+func unwrapStreamData(raw json.RawMessage) (streamEnvelope, []byte) {
+	var envelope streamEnvelope
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Data == nil {
+		return envelope, raw
+	}
+	var nested string
+	if err := json.Unmarshal(envelope.Data, &nested); err == nil {
+		return envelope, []byte(nested)
+	}
+	return envelope, envelope.Data
+}
+
+// HandleEvent applies a feed event to the tracker's maps
+func (s *CoinDCXStream) handleEvent(name string, raw json.RawMessage) {
+	envelope, data := unwrapStreamData(raw)
+	c := s.tracker
+
+	switch {
+	case name == "depth-snapshot":
+		var depth streamDepth
+		if err := json.Unmarshal(data, &depth); err != nil {
+			logging.Error("Error parsing stream order book:", err)
+			return
+		}
+		pair := depth.Pair
+		if envelope.Channel != "" {
+			pair = strings.SplitN(envelope.Channel, "@", 2)[0]
+		}
+		if pair == "" {
+			return
+		}
+		c.mutex.Lock()
+		c.orderBooks[pair] = exchange.OrderBook{Bids: depth.Bids, Asks: depth.Asks}
+		c.orderBookTimes[pair] = time.Now()
+		c.Events.publish(eventOrderBook, c.marketForPairLocked(pair), c.orderBooks[pair])
+		c.mutex.Unlock()
+
+	case strings.HasPrefix(name, "currentPrices@spot"):
+		var prices streamPrices
+		if err := json.Unmarshal(data, &prices); err != nil {
+			logging.Error("Error parsing stream prices:", err)
+			return
+		}
+		c.updateTickers(func(batch *tickerBatch) {
+			for market, rawPrice := range prices.Prices {
+				ticker, exists := batch.ticker(market)
+				if !exists {
+					continue
+				}
+				price, ok := exchange.ParseRawPrice(rawPrice)
+				if !ok {
+					continue
+				}
+				ticker.LastPrice = strconv.FormatFloat(price, 'f', -1, 64)
+				if prices.Timestamp > 0 {
+					ticker.Timestamp = prices.Timestamp
+				}
+				batch.set(market, ticker, time.Now())
+				c.observePrice(market, price, ticker.Timestamp, time.Now())
+				c.Events.publish(eventTicker, market, ticker)
+			}
+		})
+	}
+}
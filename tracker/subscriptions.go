@@ -0,0 +1,130 @@
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	// AllMarketsKey records interest in bulk endpoints that cover every market
+	AllMarketsKey = "*"
+)
+
+// SubscriptionRegistry tracks which symbols have live subscribers or were requested recently
+type SubscriptionRegistry struct {
+	subscribers   map[string]int
+	lastRequested map[string]time.Time
+	window        time.Duration
+	mutex         sync.Mutex
+}
+
+func newSubscriptionRegistry(window time.Duration) *SubscriptionRegistry {
+	if window <= 0 {
+		window = config.DefaultSubscriptionWindow
+	}
+	return &SubscriptionRegistry{
+		subscribers:   make(map[string]int),
+		lastRequested: make(map[string]time.Time),
+		window:        window,
+	}
+}
+
+// SetWindow changes how long requested symbols stay active
+func (r *SubscriptionRegistry) SetWindow(window time.Duration) {
+	if window <= 0 {
+		window = config.DefaultSubscriptionWindow
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.window = window
+}
+
+// Subscribe registers a long-lived subscriber for a symbol
+func (r *SubscriptionRegistry) Subscribe(symbol string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subscribers[symbol]++
+}
+
+// Unsubscribe removes a subscriber registered with subscribe
+func (r *SubscriptionRegistry) Unsubscribe(symbol string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subscribers[symbol]--
+	if r.subscribers[symbol] <= 0 {
+		delete(r.subscribers, symbol)
+	}
+	// Keep the symbol warm for one window after the last subscriber leaves
+	r.lastRequested[symbol] = time.Now()
+}
+
+// Touch records a one-off request for a symbol
+func (r *SubscriptionRegistry) Touch(symbol string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lastRequested[symbol] = time.Now()
+}
+
+// Active returns the symbols with subscribers or requested within the window, pruning expired entries
+func (r *SubscriptionRegistry) active() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	for symbol := range r.subscribers {
+		seen[symbol] = true
+	}
+	for symbol, requested := range r.lastRequested {
+		if time.Since(requested) > r.window {
+			delete(r.lastRequested, symbol)
+			continue
+		}
+		seen[symbol] = true
+	}
+
+	symbols := []string{}
+	for symbol := range seen {
+		if symbol != AllMarketsKey {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Subscribed returns the symbols that currently have at least one live subscriber
+func (r *SubscriptionRegistry) subscribed() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	symbols := []string{}
+	for symbol := range r.subscribers {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// HasSubscribers reports whether a symbol has at least one live subscriber
+func (r *SubscriptionRegistry) hasSubscribers(symbol string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.subscribers[symbol] > 0
+}
+
+// Idle reports whether nothing has been subscribed to or requested within the window
+func (r *SubscriptionRegistry) idle() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.subscribers) > 0 {
+		return false
+	}
+	for _, requested := range r.lastRequested {
+		if time.Since(requested) <= r.window {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+const (
+	// jobWedgeTimeout is how long one run of a refresh job may take before the job is
+	// considered wedged; upstream calls time out long before this
+	jobWedgeTimeout = 5 * time.Minute
+	// jobWatchInterval is how often a supervisor checks its job for progress
+	jobWatchInterval = 10 * time.Second
+	// minJobRestartDelay and maxJobRestartDelay bound the backoff between restarts of a job
+	// that keeps crashing; a job that ran for maxJobRestartDelay starts over at the minimum
+	minJobRestartDelay = time.Second
+	maxJobRestartDelay = time.Minute
+)
+
+// jobHeartbeat is how a job's loop tells its supervisor it is making progress
+type jobHeartbeat struct {
+	// running is when the current run started, zero while the loop sleeps between runs
+	running time.Time
+	mutex   sync.Mutex
+}
+
+func (h *jobHeartbeat) set(running time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.running = running
+}
+
+// Stuck reports how long the current run has taken once that exceeds jobWedgeTimeout
+func (h *jobHeartbeat) stuck() (time.Duration, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.running.IsZero() {
+		return 0, false
+	}
+	elapsed := time.Since(h.running)
+	return elapsed, elapsed > jobWedgeTimeout
+}
+
+// Supervise runs a job's loop and restarts it, with backoff, whenever it panics or a run
+// wedges, until ctx is cancelled. A wedged loop cannot be stopped from outside, so its
+// context is cancelled and it is abandoned to exit once whatever it waits on returns.
+func (c *CryptoTracker) supervise(ctx context.Context, job refreshJob) {
+	delay := minJobRestartDelay
+	waitFirst := job.waitFirst
+	for {
+		started := time.Now()
+		runCtx, cancel := context.WithCancel(ctx)
+		heartbeat := &jobHeartbeat{}
+		crashed := make(chan string, 1)
+		go func(waitFirst bool) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					crashed <- fmt.Sprintf("panicked: %v\n%s", recovered, debug.Stack())
+				}
+			}()
+			c.runJob(runCtx, job, waitFirst, heartbeat)
+		}(waitFirst)
+		// A restarted job has been down long enough not to wait for its first run
+		waitFirst = false
+
+		incident := watchJob(ctx, heartbeat, crashed)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxJobRestartDelay {
+			delay = minJobRestartDelay
+		}
+		logging.Error(fmt.Sprintf("Refresh job %s %s; restarting in %s", job.name, incident, delay))
+		c.stats.recordRestart(job.name)
+		if !exchange.SleepContext(ctx, delay) {
+			return
+		}
+		if delay *= 2; delay > maxJobRestartDelay {
+			delay = maxJobRestartDelay
+		}
+	}
+}
+
+// WatchJob waits for a job's loop to crash or wedge and returns what happened, or "" once
+// ctx is cancelled
+func watchJob(ctx context.Context, heartbeat *jobHeartbeat, crashed <-chan string) string {
+	ticker := time.NewTicker(jobWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ""
+		case incident := <-crashed:
+			return incident
+		case <-ticker.C:
+			if elapsed, stuck := heartbeat.stuck(); stuck {
+				return fmt.Sprintf("made no progress for %s", elapsed.Round(time.Second))
+			}
+		}
+	}
+}
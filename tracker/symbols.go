@@ -0,0 +1,53 @@
+package tracker
+
+import (
+	"strings"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+// symbolSeparators split a symbol written as two currencies, e.g. BTC-INR, btc_inr or BTC/INR
+const symbolSeparators = "-_/: "
+
+// ResolveSymbol maps the ways clients commonly write a market to its coindcx_name. An
+// entry in SymbolAliases is applied first, to the whole symbol or to each currency of a
+// two-part one, so XBT/INR can mean BTCINR. Exchange pairs such as I-BTC_INR and
+// currencies joined by any of the symbol separators are then looked up, and anything
+// unrecognised is returned upper-cased with the separators removed.
+func (c *CryptoTracker) ResolveSymbol(raw string) string {
+	aliases := config.Current().SymbolAliases
+	alias := func(value string) string {
+		for from, to := range aliases {
+			if strings.EqualFold(from, value) {
+				return strings.ToUpper(strings.TrimSpace(to))
+			}
+		}
+		return value
+	}
+	symbol := alias(strings.ToUpper(strings.TrimSpace(raw)))
+	if symbol == "" {
+		return ""
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if _, exists := c.marketDetails[symbol]; exists {
+		return symbol
+	}
+	for name, pair := range c.marketPairs {
+		if strings.EqualFold(pair, symbol) {
+			return name
+		}
+	}
+	parts := strings.FieldsFunc(symbol, func(r rune) bool { return strings.ContainsRune(symbolSeparators, r) })
+	if len(parts) == 2 {
+		// Markets name the traded currency first and the currency it is priced in second
+		target, base := alias(parts[0]), alias(parts[1])
+		for name, market := range c.marketDetails {
+			if strings.EqualFold(market.TargetCurrencyShortName, target) && strings.EqualFold(market.BaseCurrencyShortName, base) {
+				return name
+			}
+		}
+	}
+	return strings.Join(parts, "")
+}
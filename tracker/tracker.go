@@ -0,0 +1,488 @@
+// Package tracker keeps the market data fetched from upstream and everything derived
+// from it, publishing each refresh as an immutable market view.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+)
+
+// CryptoTracker struct to manage crypto data
+type CryptoTracker struct {
+	// exchange serves markets, tickers and order books; httpClient fetches every other feed
+	exchange       exchange.Exchange
+	httpClient     *exchange.SafeHTTPClient
+	marketDetails  map[string]exchange.MarketDetails
+	marketsUpdated time.Time
+	// view is what handlers read, replaced on every update; see MarketView
+	view       atomic.Pointer[MarketView]
+	viewWrites sync.Mutex
+	// pendingTickers collects streamed ticker updates until they are published
+	pendingTickers  *tickerBatch
+	pendingWrites   sync.Mutex
+	orderBooks      map[string]exchange.OrderBook
+	marketPairs     map[string]string
+	fxUpdated       time.Time
+	coinMetadata    map[string]exchange.CoinMetadata
+	metadataUpdated time.Time
+	// futuresUpdated is when the active futures contracts were last listed. Contract
+	// specifications are fetched on demand into futuresInstrumentDetails.
+	futuresUpdated           time.Time
+	futuresInstrumentDetails map[string]FuturesInstrument
+	futuresInstrumentTimes   map[string]time.Time
+	futuresCalls             *exchange.FlightGroup
+	lendingUpdated           time.Time
+	PrioritySymbols          func() []string
+	refreshHooks             []func()
+	marketHooks              []func()
+	Stream                   *CoinDCXStream
+	subscriptions            *SubscriptionRegistry
+	orderBookCalls           *exchange.FlightGroup
+	orderBookTimes           map[string]time.Time
+	// orderBookFailures holds each pair's last failed fetch; it only counts while newer
+	// than the pair's order book
+	orderBookFailures map[string]fetchFailure
+	// generation counts writes to tickers, markets, FX rates and metadata so snapshots can
+	// tell which refresh they reflect
+	generation    uint64
+	cancelRefresh context.CancelFunc
+	stats         *TrackerStats
+	volatility    *VolatilityTracker
+	aggregates    *CandleAggregator
+	probe         *UpstreamProbe
+	history       *HistoryStore
+	funding       *FundingHistory
+	lending       *LendingHistory
+	openInterest  *OpenInterestHistory
+	// Events publishes updates to message brokers; nil when none are configured
+	Events *EventBus
+	// Follower applies updates published by another tracker over Redis when set
+	Follower *RedisFollower
+	mutex    sync.RWMutex
+}
+
+func NewCryptoTracker(client *exchange.SafeHTTPClient, source exchange.Exchange) *CryptoTracker {
+	tracker := &CryptoTracker{
+		exchange:                 source,
+		httpClient:               client,
+		marketDetails:            make(map[string]exchange.MarketDetails),
+		orderBooks:               make(map[string]exchange.OrderBook),
+		marketPairs:              make(map[string]string),
+		coinMetadata:             make(map[string]exchange.CoinMetadata),
+		subscriptions:            newSubscriptionRegistry(time.Duration(config.Current().SubscriptionWindow) * time.Second),
+		orderBookCalls:           exchange.NewFlightGroup(),
+		orderBookTimes:           make(map[string]time.Time),
+		orderBookFailures:        make(map[string]fetchFailure),
+		futuresInstrumentDetails: make(map[string]FuturesInstrument),
+		futuresInstrumentTimes:   make(map[string]time.Time),
+		futuresCalls:             exchange.NewFlightGroup(),
+		stats:                    newTrackerStats(),
+		volatility:               newVolatilityTracker(),
+		aggregates:               newCandleAggregator(config.Current().AggregationTimeframes),
+		funding:                  newFundingHistory(),
+		lending:                  newLendingHistory(),
+		openInterest:             newOpenInterestHistory(),
+	}
+	tracker.view.Store(newMarketView())
+	tracker.probe = newUpstreamProbe(tracker.httpClient)
+	tracker.history = NewHistoryStore("")
+	if config.Current().HistoryPersist {
+		tracker.history = NewHistoryStore(HistoryDir())
+	}
+	return tracker
+}
+
+// Tracker is the market data and analytics the API serves
+type Tracker interface {
+	MarketView() *MarketView
+	MarketInfo(marketName string) (exchange.MarketDetails, bool)
+	MarketsWithMetadata(symbol, status string, changedAt map[string]int64) []MarketWithMetadata
+	ResolveSymbol(raw string) string
+	ResolveFuturesPair(raw string) string
+	Precision() *Precision
+	TickersUpdated() time.Time
+	DatasetTimes() map[string]time.Time
+	OrderBookSymbols() []string
+	OrderBookCount() int
+	HandleDataRequest(ctx context.Context, marketName string) (map[string]interface{}, *APIError)
+	LiveUpdates(marketName string, done <-chan struct{}, push func(LiveUpdate) error)
+	MarketCandles(ctx context.Context, market string, duration time.Duration, from, to time.Time, limit int) ([]MarketCandle, *APIError)
+	FuturesInstrument(ctx context.Context, pair string) (FuturesInstrument, *APIError)
+	SearchMarkets(query, status string, limit int) []SearchMatch
+	Snapshot() Snapshot
+	SnapshotVersion() (uint64, time.Time)
+	WatchlistView(watchlist Watchlist) WatchlistView
+	ValuePortfolio(portfolio Portfolio) PortfolioValuation
+	ComputePnL(portfolio Portfolio, method string) (PortfolioPnL, error)
+	ConvertCurrency(from, to string, amount exchange.Decimal) (ConversionResult, error)
+
+	Stats() *TrackerStats
+	Upstream() *exchange.SafeHTTPClient
+	Probe() *UpstreamProbe
+	Subscriptions() *SubscriptionRegistry
+	Volatility() *VolatilityTracker
+	Aggregates() *CandleAggregator
+	History() *HistoryStore
+	Funding() *FundingHistory
+	Lending() *LendingHistory
+	OpenInterest() *OpenInterestHistory
+}
+
+// Stats returns the tracker's refresh and client statistics
+func (c *CryptoTracker) Stats() *TrackerStats {
+	return c.stats
+}
+
+// Upstream returns the client every upstream request goes through
+func (c *CryptoTracker) Upstream() *exchange.SafeHTTPClient {
+	return c.httpClient
+}
+
+// Probe returns the probe checking the exchange's reachability
+func (c *CryptoTracker) Probe() *UpstreamProbe {
+	return c.probe
+}
+
+// Subscriptions returns which markets are subscribed to or recently requested
+func (c *CryptoTracker) Subscriptions() *SubscriptionRegistry {
+	return c.subscriptions
+}
+
+// Volatility returns the recent price volatility of each market
+func (c *CryptoTracker) Volatility() *VolatilityTracker {
+	return c.volatility
+}
+
+// Aggregates returns the candles aggregated from observed prices
+func (c *CryptoTracker) Aggregates() *CandleAggregator {
+	return c.aggregates
+}
+
+// History returns the recorded ticker history
+func (c *CryptoTracker) History() *HistoryStore {
+	return c.history
+}
+
+// Funding returns the recorded futures funding rates
+func (c *CryptoTracker) Funding() *FundingHistory {
+	return c.funding
+}
+
+// Lending returns the recorded margin lending rates
+func (c *CryptoTracker) Lending() *LendingHistory {
+	return c.lending
+}
+
+// OpenInterest returns the recorded futures open interest
+func (c *CryptoTracker) OpenInterest() *OpenInterestHistory {
+	return c.openInterest
+}
+
+// OrderBookCount returns how many order books are cached
+func (c *CryptoTracker) OrderBookCount() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.orderBooks)
+}
+
+// StartBackgroundRefresh starts refreshing every dataset on its own schedule until ctx
+// is cancelled or stopBackgroundRefresh is called
+func (c *CryptoTracker) StartBackgroundRefresh(ctx context.Context) {
+	ctx, c.cancelRefresh = context.WithCancel(ctx)
+	for _, job := range c.refreshJobs() {
+		c.schedule(ctx, job)
+	}
+}
+
+// RefreshCycle refreshes tickers and then runs the refresh hooks that depend on them
+func (c *CryptoTracker) refreshCycle(ctx context.Context) {
+	started := time.Now()
+	// With selective refresh, skip the bulk ticker fetch while nobody is asking for data.
+	// A Redis follower gets tickers from the tracker it follows.
+	wanted := !config.Current().SelectiveTickerRefresh || len(c.OrderBookSymbols()) > 0 || !c.subscriptions.idle()
+	if wanted && !c.Follower.isConnected() {
+		c.stats.timeRefresh("ticker", func() { c.RefreshTickerData(ctx) })
+	}
+	c.stats.timeRefresh("hooks", func() {
+		for _, hook := range c.refreshHooks {
+			hook()
+		}
+	})
+	c.stats.recordCycle(time.Since(started))
+}
+
+// OrderBookSymbols returns the symbols whose order books are kept fresh: prioritized
+// markets such as watchlists plus anything subscribed to or requested recently
+func (c *CryptoTracker) OrderBookSymbols() []string {
+	symbols := c.subscriptions.active()
+	if c.PrioritySymbols == nil {
+		return symbols
+	}
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		seen[symbol] = true
+	}
+	for _, symbol := range c.PrioritySymbols() {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// RefreshPriorityOrderBooks refreshes order books for the symbols that currently matter
+func (c *CryptoTracker) refreshPriorityOrderBooks(ctx context.Context) {
+	if c.orderBooksPushed() {
+		return
+	}
+	c.refreshOrderBooks(ctx, c.OrderBookSymbols(), 0, RefreshInterval())
+}
+
+// OrderBooksPushed reports whether order books currently arrive without polling, from the
+// realtime feed or from the tracker a Redis follower follows
+func (c *CryptoTracker) orderBooksPushed() bool {
+	return (c.Stream != nil && c.Stream.isConnected()) || c.Follower.isConnected()
+}
+
+// RefreshOrderBooks refreshes the order books of several markets in parallel, skipping
+// any fetched within maxAge. With adaptive refresh each symbol's maxAge is scaled by its
+// volatility, starting from base.
+func (c *CryptoTracker) refreshOrderBooks(ctx context.Context, symbols []string, maxAge, base time.Duration) {
+	adaptive := config.Current().AdaptiveRefresh
+	fetches := []func(){}
+	c.mutex.RLock()
+	for _, symbol := range symbols {
+		age := maxAge
+		if adaptive {
+			age = c.volatility.RefreshInterval(symbol, base)
+		}
+		pair, exists := c.marketPairs[symbol]
+		if !exists || (age > 0 && time.Since(c.orderBookTimes[pair]) < age) {
+			continue
+		}
+		fetches = append(fetches, func() { c.refreshOrderBook(ctx, pair) })
+	}
+	c.mutex.RUnlock()
+	exchange.Parallel(fetches...)
+}
+
+// OnRefresh registers a function to run after every background refresh cycle
+func (c *CryptoTracker) OnRefresh(hook func()) {
+	c.refreshHooks = append(c.refreshHooks, hook)
+}
+
+// OnMarketsRefresh registers a function to run after every successful market details refresh
+func (c *CryptoTracker) OnMarketsRefresh(hook func()) {
+	c.marketHooks = append(c.marketHooks, hook)
+}
+
+// StopBackgroundRefresh stops periodic data refresh
+func (c *CryptoTracker) stopBackgroundRefresh() {
+	if c.cancelRefresh != nil {
+		c.cancelRefresh()
+	}
+}
+
+// RefreshMarketData fetches market details
+func (c *CryptoTracker) RefreshMarketData(ctx context.Context) {
+	markets, err := c.exchange.Markets(ctx)
+	c.stats.recordRefreshResult("markets", err)
+	if err != nil {
+		logging.Error("Error fetching market data:", err)
+		return
+	}
+
+	c.mutex.Lock()
+	for _, market := range markets {
+		c.marketDetails[market.CoindcxName] = market
+		c.marketPairs[market.CoindcxName] = market.Pair
+	}
+	c.marketsUpdated = time.Now()
+	details := make(map[string]exchange.MarketDetails, len(c.marketDetails))
+	for name, market := range c.marketDetails {
+		details[name] = market
+	}
+	pairs := make(map[string]string, len(c.marketPairs))
+	for name, pair := range c.marketPairs {
+		pairs[name] = pair
+	}
+	c.mutex.Unlock()
+	c.publish(func(next *MarketView) {
+		next.Markets, next.Pairs, next.MarketsModified = details, pairs, time.Now()
+	})
+
+	for _, hook := range c.marketHooks {
+		hook()
+	}
+}
+
+// RefreshTickerData fetches ticker details
+func (c *CryptoTracker) RefreshTickerData(ctx context.Context) {
+	tickers, err := c.exchange.Tickers(ctx)
+	c.stats.recordRefreshResult("ticker", err)
+	if err != nil {
+		logging.Error("Error fetching ticker data:", err)
+		return
+	}
+	now := time.Now()
+	c.history.record(tickers, now)
+
+	c.replaceTickers(tickers, now, func(previous, ticker exchange.TickerDetails) {
+		if previous.Timestamp != ticker.Timestamp || previous.LastPrice != ticker.LastPrice {
+			c.Events.publish(eventTicker, ticker.Market, ticker)
+		}
+		if price, err := strconv.ParseFloat(ticker.LastPrice, 64); err == nil {
+			c.observePrice(ticker.Market, price, ticker.Timestamp, now)
+		}
+	})
+}
+
+// fetchFailure is an upstream fetch that failed and when
+type fetchFailure struct {
+	err error
+	at  time.Time
+}
+
+// HandleDataRequest returns the order book response for a market. Unknown markets fail
+// with SYMBOL_NOT_FOUND, and a book that could not be fetched with the upstream failure;
+// a stale book is served as such unless its latest refresh failed, which is STALE_DATA.
+func (c *CryptoTracker) HandleDataRequest(ctx context.Context, marketName string) (map[string]interface{}, *APIError) {
+	c.mutex.RLock()
+	_, known := c.marketPairs[marketName]
+	c.mutex.RUnlock()
+	if !known {
+		return nil, ErrSymbolNotFound
+	}
+
+	c.subscriptions.Touch(marketName)
+	requested := time.Now()
+	// Subscribed symbols are kept fresh in the background, so serve them straight from the cache
+	refresh := !c.subscriptions.hasSubscribers(marketName)
+	orderBook, fetchedAt, exists := c.OrderBookFor(ctx, marketName, refresh)
+	failure, failing := c.orderBookFailure(marketName)
+	if !exists {
+		if failing {
+			return nil, c.upstreamFailure(failure.err)
+		}
+		return nil, &APIError{Code: codeUpstreamError, Detail: "Order book unavailable"}
+	}
+	freshness := FreshnessOf(fetchedAt)
+	if freshness.Stale && failing {
+		return nil, &APIError{
+			Code:   codeStaleData,
+			Detail: fmt.Sprintf("Order book is %.0f seconds old and refreshing it failed: %v", freshness.AgeSeconds, failure.err),
+		}
+	}
+
+	response := make(map[string]interface{})
+	response["pair"] = marketName
+	response["order_book"] = orderBook
+	response["cached"] = fetchedAt.Before(requested)
+	response["age_ms"] = time.Since(fetchedAt).Milliseconds()
+	response["last_updated"] = freshness.LastUpdated
+	response["age_seconds"] = freshness.AgeSeconds
+	response["stale"] = freshness.Stale
+	return response, nil
+}
+
+// OrderBookFailure returns the failed fetch of a market's order book made since it was last updated
+func (c *CryptoTracker) orderBookFailure(marketName string) (fetchFailure, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	pair := c.marketPairs[marketName]
+	failure, exists := c.orderBookFailures[pair]
+	return failure, exists && failure.at.After(c.orderBookTimes[pair])
+}
+
+// RefreshInterval returns how long the background refresh loop waits between healthy cycles
+func RefreshInterval() time.Duration {
+	if interval := config.Current().RefreshInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return config.DefaultRefreshInterval
+}
+
+// OrderBookCacheTTL returns how long a fetched order book is served before refetching
+func orderBookCacheTTL() time.Duration {
+	if ttl := config.Current().OrderBookCacheTTL; ttl > 0 {
+		return time.Duration(ttl) * time.Millisecond
+	}
+	return config.DefaultOrderBookCacheTTL
+}
+
+// OrderBookFor returns the order book of a market and when it was fetched. If refresh is set,
+// a copy older than the cache TTL is refetched first.
+func (c *CryptoTracker) OrderBookFor(ctx context.Context, marketName string, refresh bool) (exchange.OrderBook, time.Time, bool) {
+	c.mutex.RLock()
+	pair, exists := c.marketPairs[marketName]
+	fetchedAt := c.orderBookTimes[pair]
+	c.mutex.RUnlock()
+	if !exists {
+		return exchange.OrderBook{}, time.Time{}, false
+	}
+	if refresh {
+		stale := time.Since(fetchedAt) >= orderBookCacheTTL()
+		c.stats.recordCacheLookup(!stale)
+		if stale {
+			c.refreshOrderBook(ctx, pair)
+		}
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	orderBook, exists := c.orderBooks[pair]
+	return orderBook, c.orderBookTimes[pair], exists
+}
+
+// MarketInfo returns the details of a market
+func (c *CryptoTracker) MarketInfo(marketName string) (exchange.MarketDetails, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	market, exists := c.marketDetails[marketName]
+	return market, exists
+}
+
+// RefreshOrderBook fetches order book details, sharing one upstream call between concurrent requests for a pair.
+// The shared call outlives any caller that gives up, each of which stops waiting when its ctx is done.
+func (c *CryptoTracker) refreshOrderBook(ctx context.Context, pair string) {
+	c.orderBookCalls.Do(ctx, pair, func(ctx context.Context) error {
+		c.fetchOrderBook(ctx, pair)
+		return nil
+	})
+}
+
+// FetchOrderBook fetches order book details
+func (c *CryptoTracker) fetchOrderBook(ctx context.Context, pair string) {
+	orderBook, err := c.exchange.OrderBook(ctx, pair)
+	if err != nil {
+		logging.Error("Error fetching order book data:", err)
+		c.recordOrderBookFailure(pair, err)
+		return
+	}
+	c.mutex.Lock()
+	c.orderBooks[pair] = orderBook
+	c.orderBookTimes[pair] = time.Now()
+	c.Events.publish(eventOrderBook, c.marketForPairLocked(pair), orderBook)
+	c.mutex.Unlock()
+}
+
+// RecordOrderBookFailure remembers a failed order book fetch. A caller that gave up is not
+// a failure of the upstream, so cancellations are not recorded.
+func (c *CryptoTracker) recordOrderBookFailure(pair string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	c.mutex.Lock()
+	c.orderBookFailures[pair] = fetchFailure{err: err, at: time.Now()}
+	c.mutex.Unlock()
+}
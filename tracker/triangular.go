@@ -0,0 +1,175 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// triangleLeg is one conversion in a triangular cycle
+type triangleLeg struct {
+	from    string
+	to      string
+	market  string
+	inverse bool
+}
+
+// TriangularCycle is a priced three-market cycle that starts and ends in the same currency
+type TriangularCycle struct {
+	Currencies    []string `json:"currencies"`
+	Markets       []string `json:"markets"`
+	Sides         []string `json:"sides"`
+	ReturnPercent float64  `json:"return_percent"`
+}
+
+// TriangularScanner detects cycles in the markets graph and prices them after every ticker refresh
+type TriangularScanner struct {
+	tracker     *CryptoTracker
+	notifier    *AlertNotifier
+	cycles      [][3]triangleLeg
+	marketCount int
+	results     []TriangularCycle
+	profitable  map[string]bool
+	scannedAt   time.Time
+	mutex       sync.RWMutex
+}
+
+func NewTriangularScanner(tracker *CryptoTracker, notifier *AlertNotifier) *TriangularScanner {
+	return &TriangularScanner{
+		tracker:    tracker,
+		notifier:   notifier,
+		profitable: make(map[string]bool),
+	}
+}
+
+// BuildCycles enumerates every three-currency cycle in the markets graph. The caller must hold the tracker read lock.
+func (t *TriangularScanner) buildCycles() {
+	c := t.tracker
+	// edges[from][to] is the market converting from into to
+	edges := make(map[string]map[string]triangleLeg)
+	addEdge := func(leg triangleLeg) {
+		if edges[leg.from] == nil {
+			edges[leg.from] = make(map[string]triangleLeg)
+		}
+		if _, exists := edges[leg.from][leg.to]; !exists {
+			edges[leg.from][leg.to] = leg
+		}
+	}
+	for name, market := range c.marketDetails {
+		base := market.BaseCurrencyShortName
+		target := market.TargetCurrencyShortName
+		if base == "" || target == "" {
+			continue
+		}
+		// Spending base buys target, selling target returns base
+		addEdge(triangleLeg{from: base, to: target, market: name})
+		addEdge(triangleLeg{from: target, to: base, market: name, inverse: true})
+	}
+
+	cycles := [][3]triangleLeg{}
+	for a, fromA := range edges {
+		for b, first := range fromA {
+			if b <= a {
+				continue
+			}
+			for c, second := range edges[b] {
+				if c <= a || c == b {
+					continue
+				}
+				third, exists := edges[c][a]
+				if !exists {
+					continue
+				}
+				cycles = append(cycles, [3]triangleLeg{first, second, third})
+			}
+		}
+	}
+
+	t.cycles = cycles
+	t.marketCount = len(c.marketDetails)
+}
+
+// Scan prices every known cycle using the latest bid and ask of each leg
+func (t *TriangularScanner) Scan() {
+	keep := 1 - ArbitrageFeeRate()
+	results := []TriangularCycle{}
+
+	c := t.tracker
+	tickers := c.MarketView().Tickers
+	c.mutex.RLock()
+	if len(c.marketDetails) != t.marketCount {
+		t.buildCycles()
+	}
+	for _, cycle := range t.cycles {
+		growth := 1.0
+		priced := true
+		result := TriangularCycle{}
+		for _, leg := range cycle {
+			bid, ask, _, ok := tickers[leg.market].Prices()
+			if !ok {
+				priced = false
+				break
+			}
+			if leg.inverse {
+				growth *= bid * keep
+				result.Sides = append(result.Sides, "sell")
+			} else {
+				growth *= keep / ask
+				result.Sides = append(result.Sides, "buy")
+			}
+			result.Currencies = append(result.Currencies, leg.from)
+			result.Markets = append(result.Markets, leg.market)
+		}
+		if !priced {
+			continue
+		}
+		result.Currencies = append(result.Currencies, cycle[0].from)
+		result.ReturnPercent = (growth - 1) * 100
+		results = append(results, result)
+	}
+	c.mutex.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ReturnPercent > results[j].ReturnPercent
+	})
+
+	// Alert only when a cycle crosses the threshold, not on every refresh it stays profitable
+	threshold := ArbitrageThreshold()
+	profitable := make(map[string]bool)
+	for _, result := range results {
+		if result.ReturnPercent <= threshold {
+			break
+		}
+		key := strings.Join(result.Markets, ">")
+		profitable[key] = true
+		if !t.profitable[key] {
+			t.notifier.notify(Alert{
+				Type:    "triangular_arbitrage",
+				Symbol:  key,
+				Message: fmt.Sprintf("%s implies %.3f%% return", strings.Join(result.Currencies, "→"), result.ReturnPercent),
+				Value:   result.ReturnPercent,
+			})
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.results = results
+	t.profitable = profitable
+	t.scannedAt = time.Now()
+}
+
+// Above returns the cycles from the last scan whose return exceeds threshold percent
+func (t *TriangularScanner) Above(threshold float64) ([]TriangularCycle, time.Time) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	cycles := []TriangularCycle{}
+	for _, result := range t.results {
+		if result.ReturnPercent > threshold {
+			cycles = append(cycles, result)
+		}
+	}
+	return cycles, t.scannedAt
+}
@@ -0,0 +1,23 @@
+package tracker
+
+import (
+	"errors"
+	"strings"
+)
+
+// OwnedKey is where a resource owned by user is kept in a store keyed by name. Shared
+// resources keep their bare names, so existing stores load unchanged.
+func ownedKey(user, name string) string {
+	if user == "" {
+		return name
+	}
+	return user + "\x00" + name
+}
+
+// ValidResourceName rejects the one character ownedKey reserves
+func validResourceName(name string) error {
+	if strings.ContainsRune(name, 0) {
+		return errors.New("'name' must not contain NUL")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package tracker
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/config"
+)
+
+const (
+	// maxPricePoints bounds the history kept per market when prices stream in every second
+	maxPricePoints = 120
+)
+
+// pricePoint is one observed last traded price
+type pricePoint struct {
+	price float64
+	at    time.Time
+}
+
+// VolatilityTracker keeps a short price history per market to drive adaptive refresh
+type VolatilityTracker struct {
+	prices map[string][]pricePoint
+	mutex  sync.Mutex
+}
+
+func newVolatilityTracker() *VolatilityTracker {
+	return &VolatilityTracker{prices: make(map[string][]pricePoint)}
+}
+
+// AdaptiveSettings returns the adaptive refresh window, threshold and bounds with defaults applied
+func adaptiveSettings() (time.Duration, float64, time.Duration, time.Duration) {
+	cfg := config.Current()
+	window, threshold := config.DefaultAdaptiveWindow, config.DefaultAdaptiveVolatilityThreshold
+	minInterval, maxInterval := config.DefaultAdaptiveMinInterval, config.DefaultAdaptiveMaxInterval
+	if cfg.AdaptiveWindow > 0 {
+		window = time.Duration(cfg.AdaptiveWindow) * time.Second
+	}
+	if cfg.AdaptiveVolatilityThreshold > 0 {
+		threshold = cfg.AdaptiveVolatilityThreshold
+	}
+	if cfg.AdaptiveMinInterval > 0 {
+		minInterval = time.Duration(cfg.AdaptiveMinInterval) * time.Millisecond
+	}
+	if cfg.AdaptiveMaxInterval > 0 {
+		maxInterval = time.Duration(cfg.AdaptiveMaxInterval) * time.Millisecond
+	}
+	return window, threshold, minInterval, maxInterval
+}
+
+// AdaptiveCadence makes a refresh job run at the minimum adaptive interval while adaptive
+// refresh is on, so symbols can be refreshed as soon as they are due
+func adaptiveCadence(interval func() time.Duration) func() time.Duration {
+	return func() time.Duration {
+		if !config.Current().AdaptiveRefresh {
+			return interval()
+		}
+		_, _, minInterval, _ := adaptiveSettings()
+		return minInterval
+	}
+}
+
+// Observe records a market's price, dropping points older than the adaptive window
+func (v *VolatilityTracker) observe(market string, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	window, _, _, _ := adaptiveSettings()
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	points := v.prices[market]
+	start := 0
+	for start < len(points) && at.Sub(points[start].at) > window {
+		start++
+	}
+	if len(points)-start >= maxPricePoints {
+		start = len(points) - maxPricePoints + 1
+	}
+	v.prices[market] = append(points[start:], pricePoint{price: price, at: at})
+}
+
+// Volatility returns the range of a market's price over the adaptive window as a
+// percentage of its latest price
+func (v *VolatilityTracker) volatility(market string) float64 {
+	window, _, _, _ := adaptiveSettings()
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	points := v.prices[market]
+	if len(points) < 2 {
+		return 0
+	}
+	latest := points[len(points)-1]
+	low, high := latest.price, latest.price
+	for _, point := range points {
+		if latest.at.Sub(point.at) > window {
+			continue
+		}
+		low = math.Min(low, point.price)
+		high = math.Max(high, point.price)
+	}
+	return (high - low) / latest.price * 100
+}
+
+// RefreshInterval scales base by how far the market's volatility is from the threshold:
+// twice the threshold halves the interval and a flat market waits the maximum
+func (v *VolatilityTracker) RefreshInterval(market string, base time.Duration) time.Duration {
+	_, threshold, minInterval, maxInterval := adaptiveSettings()
+	volatility := v.volatility(market)
+	interval := maxInterval
+	if volatility > 0 {
+		if scaled := float64(base) * threshold / volatility; scaled < float64(maxInterval) {
+			interval = time.Duration(scaled)
+		}
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	return interval
+}
+
+// History returns a market's recent prices, oldest first
+func (v *VolatilityTracker) History(market string) []float64 {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	prices := make([]float64, len(v.prices[market]))
+	for i, point := range v.prices[market] {
+		prices[i] = point.price
+	}
+	return prices
+}
@@ -0,0 +1,161 @@
+package tracker
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/exchange"
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const watchlistStorageKey = "watchlists"
+
+// Watchlist is a named group of market symbols
+type Watchlist struct {
+	Name string `json:"name"`
+	// Owner is the user the watchlist belongs to, empty for a shared one
+	Owner   string   `json:"owner,omitempty"`
+	Symbols []string `json:"symbols"`
+}
+
+// WatchlistView is a watchlist together with the live tickers of its symbols
+type WatchlistView struct {
+	Watchlist
+	Tickers []exchange.TickerDetails `json:"tickers"`
+	Missing []string                 `json:"missing,omitempty"`
+}
+
+// WatchlistStore keeps watchlists in memory and persists them to storage
+type WatchlistStore struct {
+	storage    storage.Storage
+	watchlists map[string]Watchlist
+	mutex      sync.RWMutex
+}
+
+func NewWatchlistStore(backend storage.Storage) *WatchlistStore {
+	store := &WatchlistStore{
+		storage:    backend,
+		watchlists: make(map[string]Watchlist),
+	}
+	err := backend.Load(watchlistStorageKey, &store.watchlists)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading watchlists:", err)
+	}
+	return store
+}
+
+func (s *WatchlistStore) Get(user, name string) (Watchlist, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	watchlist, exists := s.watchlists[ownedKey(user, name)]
+	return watchlist, exists && watchlist.Owner == user
+}
+
+func (s *WatchlistStore) List(user string) []Watchlist {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	watchlists := []Watchlist{}
+	for _, watchlist := range s.watchlists {
+		if watchlist.Owner == user {
+			watchlists = append(watchlists, watchlist)
+		}
+	}
+	sort.Slice(watchlists, func(i, j int) bool { return watchlists[i].Name < watchlists[j].Name })
+	return watchlists
+}
+
+func (s *WatchlistStore) Put(watchlist Watchlist) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	watchlists := s.copy()
+	watchlists[ownedKey(watchlist.Owner, watchlist.Name)] = watchlist
+	return s.save(watchlists)
+}
+
+func (s *WatchlistStore) Remove(user, name string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := ownedKey(user, name)
+	if watchlist, exists := s.watchlists[key]; !exists || watchlist.Owner != user {
+		return false, nil
+	}
+	watchlists := s.copy()
+	delete(watchlists, key)
+	return true, s.save(watchlists)
+}
+
+// Copy returns a copy of the watchlists to change and save. The caller must hold the mutex.
+func (s *WatchlistStore) copy() map[string]Watchlist {
+	watchlists := make(map[string]Watchlist, len(s.watchlists))
+	for key, watchlist := range s.watchlists {
+		watchlists[key] = watchlist
+	}
+	return watchlists
+}
+
+// Save persists watchlists and only then makes them current, so a failed save changes
+// nothing. The caller must hold the mutex.
+func (s *WatchlistStore) save(watchlists map[string]Watchlist) error {
+	if err := s.storage.Save(watchlistStorageKey, watchlists); err != nil {
+		return err
+	}
+	s.watchlists = watchlists
+	return nil
+}
+
+// Symbols returns every distinct symbol across all watchlists, whoever owns them
+func (s *WatchlistStore) Symbols() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	seen := make(map[string]bool)
+	symbols := []string{}
+	for _, watchlist := range s.watchlists {
+		for _, symbol := range watchlist.Symbols {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// NormalizeWatchlist validates a submitted watchlist and removes duplicate symbols
+func NormalizeWatchlist(watchlist *Watchlist) error {
+	watchlist.Name = strings.TrimSpace(watchlist.Name)
+	if watchlist.Name == "" {
+		return errors.New("missing 'name'")
+	}
+	if err := validResourceName(watchlist.Name); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	symbols := []string{}
+	for _, symbol := range watchlist.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	watchlist.Symbols = symbols
+	return nil
+}
+
+// WatchlistView collects the cached tickers for a watchlist's symbols
+func (c *CryptoTracker) WatchlistView(watchlist Watchlist) WatchlistView {
+	view := WatchlistView{Watchlist: watchlist, Tickers: []exchange.TickerDetails{}}
+	tickers := c.MarketView().Tickers
+	for _, symbol := range watchlist.Symbols {
+		if ticker, exists := tickers[symbol]; exists {
+			view.Tickers = append(view.Tickers, ticker)
+		} else {
+			view.Missing = append(view.Missing, symbol)
+		}
+	}
+	return view
+}
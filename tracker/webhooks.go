@@ -0,0 +1,348 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namithsaliyan/CryptoTrackerAPI/logging"
+	"github.com/namithsaliyan/CryptoTrackerAPI/storage"
+)
+
+const (
+	webhookStorageKey     = "webhooks"
+	webhookAttempts       = 3
+	webhookRetryDelay     = 2 * time.Second
+	maxWebhookBatchWindow = 3600
+)
+
+// WebhookSubscription asks for a POST to URL whenever the last price of one of Symbols,
+// or of any market when Symbols is empty, moves by at least MinChangePercent since the
+// price last delivered to it. Changes are collected for BatchWindow seconds and delivered
+// together; with no window every refresh that has changes is delivered on its own.
+type WebhookSubscription struct {
+	ID               string                `json:"id"`
+	URL              string                `json:"url"`
+	Symbols          []string              `json:"symbols,omitempty"`
+	MinChangePercent float64               `json:"min_change_percent"`
+	BatchWindow      int                   `json:"batch_window"`
+	CreatedAt        int64                 `json:"created_at"`
+	Delivery         WebhookDeliveryStatus `json:"delivery"`
+}
+
+// WebhookDeliveryStatus tracks how deliveries to a subscription have gone
+type WebhookDeliveryStatus struct {
+	Delivered           int    `json:"delivered"`
+	Failed              int    `json:"failed"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Pending             int    `json:"pending"`
+	LastAttempt         int64  `json:"last_attempt,omitempty"`
+	LastSuccess         int64  `json:"last_success,omitempty"`
+	LastStatusCode      int    `json:"last_status_code,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+// WebhookChange is one market's price move in a delivery
+type WebhookChange struct {
+	Market        string  `json:"market"`
+	LastPrice     string  `json:"last_price"`
+	PreviousPrice string  `json:"previous_price"`
+	ChangePercent float64 `json:"change_percent"`
+	Timestamp     int64   `json:"timestamp"`
+}
+
+// WebhookBatch is the body POSTed to a subscription's URL
+type WebhookBatch struct {
+	SubscriptionID string          `json:"subscription_id"`
+	Changes        []WebhookChange `json:"changes"`
+	SentAt         int64           `json:"sent_at"`
+}
+
+// webhookState is the delivery bookkeeping kept alongside a subscription
+type webhookState struct {
+	subscription *WebhookSubscription
+	// baselines holds the price each market was last delivered, or first seen, at
+	baselines  map[string]float64
+	pending    map[string]WebhookChange
+	timer      *time.Timer
+	delivering bool
+}
+
+// WebhookDispatcher matches ticker changes against webhook subscriptions after every
+// refresh and delivers them in batches
+type WebhookDispatcher struct {
+	tracker       *CryptoTracker
+	storage       storage.Storage
+	subscriptions map[string]*webhookState
+	client        *http.Client
+	mutex         sync.Mutex
+}
+
+func NewWebhookDispatcher(tracker *CryptoTracker, backend storage.Storage) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		tracker:       tracker,
+		storage:       backend,
+		subscriptions: make(map[string]*webhookState),
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+	var saved []*WebhookSubscription
+	err := backend.Load(webhookStorageKey, &saved)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Error("Error loading webhooks:", err)
+	}
+	for _, subscription := range saved {
+		subscription.Delivery.Pending = 0
+		d.subscriptions[subscription.ID] = newWebhookState(subscription)
+	}
+	return d
+}
+
+func newWebhookState(subscription *WebhookSubscription) *webhookState {
+	return &webhookState{
+		subscription: subscription,
+		baselines:    make(map[string]float64),
+		pending:      make(map[string]WebhookChange),
+	}
+}
+
+// Save persists all subscriptions. The caller must hold the mutex.
+func (d *WebhookDispatcher) save() error {
+	subscriptions := make([]*WebhookSubscription, 0, len(d.subscriptions))
+	for _, state := range d.subscriptions {
+		subscriptions = append(subscriptions, state.subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].CreatedAt < subscriptions[j].CreatedAt })
+	return d.storage.Save(webhookStorageKey, subscriptions)
+}
+
+// NormalizeWebhook validates a submitted subscription and removes duplicate symbols
+func NormalizeWebhook(subscription *WebhookSubscription) error {
+	parsed, err := url.Parse(subscription.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("'url' must be an http or https URL")
+	}
+	if subscription.MinChangePercent < 0 || math.IsNaN(subscription.MinChangePercent) {
+		return errors.New("'min_change_percent' must not be negative")
+	}
+	if subscription.BatchWindow < 0 || subscription.BatchWindow > maxWebhookBatchWindow {
+		return fmt.Errorf("'batch_window' must be between 0 and %d seconds", maxWebhookBatchWindow)
+	}
+	seen := make(map[string]bool)
+	symbols := []string{}
+	for _, symbol := range subscription.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	subscription.Symbols = symbols
+	return nil
+}
+
+func (d *WebhookDispatcher) Create(subscription WebhookSubscription) (WebhookSubscription, error) {
+	subscription.ID = logging.NewRequestID()
+	subscription.CreatedAt = time.Now().UnixNano() / int64(time.Millisecond)
+	subscription.Delivery = WebhookDeliveryStatus{}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.subscriptions[subscription.ID] = newWebhookState(&subscription)
+	return subscription, d.save()
+}
+
+func (d *WebhookDispatcher) Get(id string) (WebhookSubscription, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	state, exists := d.subscriptions[id]
+	if !exists {
+		return WebhookSubscription{}, false
+	}
+	return *state.subscription, true
+}
+
+func (d *WebhookDispatcher) List() []WebhookSubscription {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	subscriptions := []WebhookSubscription{}
+	for _, state := range d.subscriptions {
+		subscriptions = append(subscriptions, *state.subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].CreatedAt < subscriptions[j].CreatedAt })
+	return subscriptions
+}
+
+func (d *WebhookDispatcher) Remove(id string) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	state, exists := d.subscriptions[id]
+	if !exists {
+		return false, nil
+	}
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	delete(d.subscriptions, id)
+	return true, d.save()
+}
+
+// Check compares the latest tickers with every subscription's baselines and queues the
+// changes that pass its filters
+func (d *WebhookDispatcher) Check() {
+	// The set is never modified, so it needs no copy
+	tickers := d.tracker.MarketView().Tickers
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for id, state := range d.subscriptions {
+		markets := state.subscription.Symbols
+		if len(markets) == 0 {
+			markets = make([]string, 0, len(tickers))
+			for name := range tickers {
+				markets = append(markets, name)
+			}
+		}
+		queued := false
+		for _, market := range markets {
+			ticker, exists := tickers[market]
+			if !exists {
+				continue
+			}
+			price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+			if err != nil || price <= 0 {
+				continue
+			}
+			baseline, seen := state.baselines[market]
+			if !seen {
+				state.baselines[market] = price
+				continue
+			}
+			change := (price - baseline) / baseline * 100
+			if price == baseline || math.Abs(change) < state.subscription.MinChangePercent {
+				continue
+			}
+			state.baselines[market] = price
+			previous := strconv.FormatFloat(baseline, 'f', -1, 64)
+			if queuedChange, exists := state.pending[market]; exists {
+				// A market that moves again within the window is delivered once, measured
+				// from where it stood before the window, and not at all if it moved back
+				previous = queuedChange.PreviousPrice
+				first, _ := strconv.ParseFloat(previous, 64)
+				change = (price - first) / first * 100
+				if price == first || math.Abs(change) < state.subscription.MinChangePercent {
+					state.baselines[market] = first
+					delete(state.pending, market)
+					continue
+				}
+			}
+			state.pending[market] = WebhookChange{
+				Market:        market,
+				LastPrice:     ticker.LastPrice,
+				PreviousPrice: previous,
+				ChangePercent: change,
+				Timestamp:     ticker.Timestamp,
+			}
+			queued = true
+		}
+		state.subscription.Delivery.Pending = len(state.pending)
+		if queued {
+			d.scheduleLocked(id, state)
+		}
+	}
+}
+
+// ScheduleLocked arranges for a subscription's pending changes to be flushed at the end of
+// its batch window. The caller must hold the mutex.
+func (d *WebhookDispatcher) scheduleLocked(id string, state *webhookState) {
+	if state.timer != nil || state.delivering || len(state.pending) == 0 {
+		return
+	}
+	window := time.Duration(state.subscription.BatchWindow) * time.Second
+	state.timer = time.AfterFunc(window, func() { d.flush(id) })
+}
+
+// Flush delivers a subscription's pending changes, retrying failed attempts, and records
+// the outcome. Only one delivery per subscription is in flight; changes queued meanwhile
+// go out in the next batch.
+func (d *WebhookDispatcher) flush(id string) {
+	d.mutex.Lock()
+	state, exists := d.subscriptions[id]
+	if !exists {
+		d.mutex.Unlock()
+		return
+	}
+	state.timer = nil
+	batch := WebhookBatch{SubscriptionID: id, Changes: make([]WebhookChange, 0, len(state.pending))}
+	for _, change := range state.pending {
+		batch.Changes = append(batch.Changes, change)
+	}
+	sort.Slice(batch.Changes, func(i, j int) bool { return batch.Changes[i].Market < batch.Changes[j].Market })
+	state.pending = make(map[string]WebhookChange)
+	state.subscription.Delivery.Pending = 0
+	state.delivering = true
+	target := state.subscription.URL
+	d.mutex.Unlock()
+
+	var statusCode int
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		batch.SentAt = time.Now().UnixNano() / int64(time.Millisecond)
+		if statusCode, err = d.deliver(target, batch); err == nil {
+			break
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(webhookRetryDelay * time.Duration(attempt))
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	state.delivering = false
+	if d.subscriptions[id] != state {
+		return
+	}
+	status := &state.subscription.Delivery
+	status.LastAttempt = time.Now().UnixNano() / int64(time.Millisecond)
+	status.LastStatusCode = statusCode
+	if err != nil {
+		logging.Error("Error delivering webhook "+id+":", err)
+		status.Failed++
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+	} else {
+		status.Delivered++
+		status.ConsecutiveFailures = 0
+		status.LastSuccess = status.LastAttempt
+		status.LastError = ""
+	}
+	if err := d.save(); err != nil {
+		logging.Error("Error saving webhooks:", err)
+	}
+	d.scheduleLocked(id, state)
+}
+
+func (d *WebhookDispatcher) deliver(target string, batch WebhookBatch) (int, error) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := d.client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
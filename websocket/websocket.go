@@ -0,0 +1,301 @@
+// Package websocket is a minimal RFC 6455 implementation covering what the upstream
+// stream and the live endpoints need.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+const maxMessageSize = 16 << 20
+
+var errClosed = errors.New("websocket closed")
+
+// Errors Upgrade returns before taking over the connection, while the request can
+// still be answered
+var (
+	ErrNotHandshake  = errors.New("not a websocket handshake")
+	ErrNotHijackable = errors.New("response writer cannot be hijacked")
+)
+
+// Conn is a minimal RFC 6455 connection usable by both clients and servers
+type Conn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	client     bool
+	writeMutex sync.Mutex
+}
+
+// WebSocketAccept computes the Sec-WebSocket-Accept value for a handshake key
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Connect opens a client connection to target over a connection from dial,
+// sending header with the handshake
+func Connect(target *url.URL, header http.Header, timeout time.Duration, dial func(address string) (net.Conn, error)) (*Conn, error) {
+	host := target.Host
+	var conn net.Conn
+	var err error
+	switch target.Scheme {
+	case "wss":
+		if target.Port() == "" {
+			host += ":443"
+		}
+		if conn, err = dial(host); err == nil {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: target.Hostname()})
+			tlsConn.SetDeadline(time.Now().Add(timeout))
+			if err = tlsConn.Handshake(); err != nil {
+				tlsConn.Close()
+			}
+			conn = tlsConn
+		}
+	case "ws":
+		if target.Port() == "" {
+			host += ":80"
+		}
+		conn, err = dial(host)
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", target.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        target,
+		Host:       target.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", response.Status)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		conn.Close()
+		return nil, errors.New("websocket handshake failed: bad accept key")
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &Conn{conn: conn, reader: reader, client: true}, nil
+}
+
+// Upgrade completes the server side of a websocket handshake. A request that is
+// not a handshake, or one whose connection cannot be taken over, is left unanswered.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Header.Get("Sec-WebSocket-Version") != "13" || r.Header.Get("Sec-WebSocket-Key") == "" {
+		return nil, ErrNotHandshake
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, ErrNotHijackable
+	}
+	conn, buffer, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	accept := websocketAccept(r.Header.Get("Sec-WebSocket-Key"))
+	_, err = fmt.Fprintf(buffer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, reader: buffer.Reader}, nil
+}
+
+// ReadMessage returns the next data message, answering pings and reassembling fragments
+func (c *Conn) ReadMessage() (byte, []byte, error) {
+	var message []byte
+	var messageOpcode byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case OpPing:
+			if err := c.WriteMessage(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			c.WriteMessage(OpClose, payload)
+			return 0, nil, errClosed
+		case OpContinuation:
+			if messageOpcode == 0 {
+				return 0, nil, errors.New("unexpected continuation frame")
+			}
+		default:
+			messageOpcode = opcode
+			message = nil
+		}
+
+		message = append(message, payload...)
+		if len(message) > maxMessageSize {
+			return 0, nil, errors.New("websocket message too large")
+		}
+		if fin {
+			return messageOpcode, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, errors.New("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a single unfragmented frame, masking it when acting as a client
+func (c *Conn) WriteMessage(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+
+	length := len(payload)
+	switch {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, maskBit|127)
+		var extended [8]byte
+		binary.BigEndian.PutUint64(extended[:], uint64(length))
+		frame = append(frame, extended[:]...)
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		offset := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[offset+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *Conn) SetReadDeadline(deadline time.Time) error {
+	return c.conn.SetReadDeadline(deadline)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}